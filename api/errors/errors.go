@@ -0,0 +1,85 @@
+package errors
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/equinor/radix-job-scheduler-server/models"
+	apiErrors "github.com/equinor/radix-job-scheduler/api/errors"
+	schedulerModels "github.com/equinor/radix-job-scheduler/models/common"
+)
+
+// StatusError An error with the status to respond with, handled by ControllerBase.HandleError
+type StatusError struct {
+	ErrStatus schedulerModels.Status
+	// Causes Invalid fields of the request
+	Causes []models.StatusCause
+}
+
+// Error The message of the status
+func (e *StatusError) Error() string {
+	return e.ErrStatus.Message
+}
+
+// Status The status to respond with
+func (e *StatusError) Status() *schedulerModels.Status {
+	return &e.ErrStatus
+}
+
+// NewBadRequest Creates an error for an invalid request, e.g. an invalid query parameter
+func NewBadRequest(message string) *StatusError {
+	return newStatusError(http.StatusBadRequest, models.StatusReasonBadRequest, message)
+}
+
+// NewInvalid Creates an error for invalid data in a request
+func NewInvalid(message string) *StatusError {
+	return newStatusError(http.StatusUnprocessableEntity, schedulerModels.StatusReasonInvalid, message)
+}
+
+// NewNotFound Creates an error for a resource which does not exist
+func NewNotFound(message string) *StatusError {
+	return newStatusError(http.StatusNotFound, schedulerModels.StatusReasonNotFound, message)
+}
+
+// NewConflict Creates an error for a request conflicting with an earlier request
+func NewConflict(message string) *StatusError {
+	return newStatusError(http.StatusConflict, models.StatusReasonConflict, message)
+}
+
+// NewInvalidFields Creates an error for invalid fields in a request, listing each field and the reason it is invalid
+func NewInvalidFields(causes []models.StatusCause) *StatusError {
+	messages := make([]string, 0, len(causes))
+	for _, cause := range causes {
+		if len(cause.Field) == 0 {
+			messages = append(messages, cause.Message)
+			continue
+		}
+		messages = append(messages, fmt.Sprintf("%s: %s", cause.Field, cause.Message))
+	}
+	err := NewInvalid("Invalid request: " + strings.Join(messages, "; "))
+	err.Causes = causes
+	return err
+}
+
+// NewRequestEntityTooLarge Creates an error for a request body larger than the maximum size
+func NewRequestEntityTooLarge(maxBytes int64) *StatusError {
+	return newStatusError(http.StatusRequestEntityTooLarge, models.StatusReasonRequestEntityTooLarge, fmt.Sprintf("The request body is larger than the maximum size of %d bytes", maxBytes))
+}
+
+// IsNotFound Returns true when the error has the status reason NotFound
+func IsNotFound(err error) bool {
+	status, ok := err.(apiErrors.APIStatus)
+	return ok && status.Status().Reason == schedulerModels.StatusReasonNotFound
+}
+
+func newStatusError(code int, reason schedulerModels.StatusReason, message string) *StatusError {
+	return &StatusError{
+		ErrStatus: schedulerModels.Status{
+			Status:  schedulerModels.StatusFailure,
+			Code:    code,
+			Reason:  reason,
+			Message: message,
+		},
+	}
+}
@@ -10,15 +10,21 @@ import (
 
 	"github.com/equinor/radix-job-scheduler-server/models"
 	"github.com/equinor/radix-job-scheduler-server/router"
-	schedulerModels "github.com/equinor/radix-job-scheduler/models"
+	"github.com/equinor/radix-operator/pkg/apis/kube"
+	radixfake "github.com/equinor/radix-operator/pkg/client/clientset/versioned/fake"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	secretproviderfake "sigs.k8s.io/secrets-store-csi-driver/pkg/client/clientset/versioned/fake"
 )
 
 type ControllerTestUtils struct {
+	kubeUtil    *kube.Kube
 	controllers []models.Controller
 }
 
 func New(controllers ...models.Controller) ControllerTestUtils {
+	kubeUtil, _ := kube.New(kubefake.NewSimpleClientset(), radixfake.NewSimpleClientset(), secretproviderfake.NewSimpleClientset())
 	return ControllerTestUtils{
+		kubeUtil:    kubeUtil,
 		controllers: controllers,
 	}
 }
@@ -30,6 +36,11 @@ func (ctrl *ControllerTestUtils) ExecuteRequest(method, path string) <-chan *htt
 
 // ExecuteRequestWithBody Helper method to issue a http request with body
 func (ctrl *ControllerTestUtils) ExecuteRequestWithBody(method, path string, body interface{}) <-chan *http.Response {
+	return ctrl.ExecuteRequestWithHeader(method, path, body, nil)
+}
+
+// ExecuteRequestWithHeader Helper method to issue a http request with body and header
+func (ctrl *ControllerTestUtils) ExecuteRequestWithHeader(method, path string, body interface{}, header http.Header) <-chan *http.Response {
 	responseChan := make(chan *http.Response)
 
 	go func() {
@@ -40,7 +51,10 @@ func (ctrl *ControllerTestUtils) ExecuteRequestWithBody(method, path string, bod
 			reader = bytes.NewReader(payload)
 		}
 
-		serverRouter := router.NewServer(schedulerModels.NewEnv(), ctrl.controllers...)
+		serverRouter, err := router.NewServer(models.NewEnv(), ctrl.kubeUtil, ctrl.controllers...)
+		if err != nil {
+			panic(err)
+		}
 		server := httptest.NewServer(serverRouter)
 		defer server.Close()
 		serverUrl := buildURLFromServer(server, path)
@@ -48,6 +62,9 @@ func (ctrl *ControllerTestUtils) ExecuteRequestWithBody(method, path string, bod
 		if err != nil {
 			panic(err)
 		}
+		for name, values := range header {
+			request.Header[name] = values
+		}
 		response, err := http.DefaultClient.Do(request)
 		if err != nil {
 			panic(err)
@@ -68,6 +85,8 @@ func GetResponseBody(response *http.Response, target interface{}) error {
 
 func buildURLFromServer(server *httptest.Server, path string) string {
 	serverUrl, _ := url.Parse(server.URL)
-	serverUrl.Path = path
+	pathUrl, _ := url.Parse(path)
+	serverUrl.Path = pathUrl.Path
+	serverUrl.RawQuery = pathUrl.RawQuery
 	return serverUrl.String()
 }
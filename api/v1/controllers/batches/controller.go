@@ -1,19 +1,34 @@
 package batch
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
+	"time"
 
-	"github.com/equinor/radix-job-scheduler-server/api/controllers"
+	serverErrors "github.com/equinor/radix-job-scheduler-server/api/errors"
+	"github.com/equinor/radix-job-scheduler-server/api/v1/controllers"
+	"github.com/equinor/radix-job-scheduler-server/bulk"
+	"github.com/equinor/radix-job-scheduler-server/events"
+	"github.com/equinor/radix-job-scheduler-server/idempotency"
+	"github.com/equinor/radix-job-scheduler-server/logs"
+	"github.com/equinor/radix-job-scheduler-server/metrics"
 	"github.com/equinor/radix-job-scheduler-server/models"
+	"github.com/equinor/radix-job-scheduler-server/pending"
+	"github.com/equinor/radix-job-scheduler-server/rerun"
+	"github.com/equinor/radix-job-scheduler-server/schedules"
+	"github.com/equinor/radix-job-scheduler-server/tracing"
 	"github.com/equinor/radix-job-scheduler-server/utils"
-	apiErrors "github.com/equinor/radix-job-scheduler/api/errors"
+	"github.com/equinor/radix-job-scheduler-server/validation"
+	"github.com/equinor/radix-job-scheduler-server/webhooks"
 	api "github.com/equinor/radix-job-scheduler/api/v1/batches"
 	schedulerModels "github.com/equinor/radix-job-scheduler/models/common"
+	modelsV1 "github.com/equinor/radix-job-scheduler/models/v1"
+	"github.com/equinor/radix-operator/pkg/apis/kube"
 	"github.com/gorilla/mux"
 	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 const (
@@ -23,14 +38,38 @@ const (
 
 type batchController struct {
 	*controllers.ControllerBase
-	handler api.BatchHandler
+	handler         api.BatchHandler
+	jobWatcher      *events.JobWatcher
+	logReader       *logs.Reader
+	notifier        *webhooks.Notifier
+	idempotencyKeys *idempotency.Keys
+	payloadSchema   *validation.PayloadSchema
+	descriptions    *rerun.Descriptions
+	bulkRunner      *bulk.Runner
+	pendingItems    *pending.Items
 }
 
-// New create a new batch controller
-func New(handler api.BatchHandler) models.Controller {
-	return &batchController{
-		handler: handler,
+// New create a new batch controller. The controller creates the pending batches when they are released from the queue,
+// and the batches of the batch schedules
+func New(handler api.BatchHandler, jobWatcher *events.JobWatcher, logReader *logs.Reader, notifier *webhooks.Notifier, idempotencyKeys *idempotency.Keys, payloadSchema *validation.PayloadSchema, descriptions *rerun.Descriptions, bulkRunner *bulk.Runner, pendingItems *pending.Items, batchSchedules *schedules.Schedules) models.Controller {
+	controller := &batchController{
+		handler:         handler,
+		jobWatcher:      jobWatcher,
+		logReader:       logReader,
+		notifier:        notifier,
+		idempotencyKeys: idempotencyKeys,
+		payloadSchema:   payloadSchema,
+		descriptions:    descriptions,
+		bulkRunner:      bulkRunner,
+		pendingItems:    pendingItems,
 	}
+	pendingItems.SetCreator(pending.KindBatch, controller.createPendingBatch)
+	batchSchedules.SetRunner(schedules.KindBatch, &schedules.Runner{
+		Create:   controller.createScheduledBatch,
+		IsActive: controller.isBatchActive,
+		Stop:     controller.stopBatch,
+	})
+	return controller
 }
 
 // GetRoutes List the supported routes of this controller
@@ -39,38 +78,93 @@ func (controller *batchController) GetRoutes() models.Routes {
 		models.Route{
 			Path:        "/batches",
 			Method:      http.MethodPost,
+			Permission:  models.PermissionMutate,
 			HandlerFunc: controller.CreateBatch,
 		},
 		models.Route{
 			Path:        "/batches",
 			Method:      http.MethodGet,
+			Permission:  models.PermissionRead,
 			HandlerFunc: controller.GetBatches,
 		},
+		models.Route{
+			Path:        "/batches",
+			Method:      http.MethodDelete,
+			Permission:  models.PermissionMutate,
+			HandlerFunc: controller.DeleteBatches,
+		},
+		models.Route{
+			Path:        "/batches/stop",
+			Method:      http.MethodPost,
+			Permission:  models.PermissionMutate,
+			HandlerFunc: controller.StopBatches,
+		},
 		models.Route{
 			Path:        fmt.Sprintf("/batches/{%s}", batchNameParam),
 			Method:      http.MethodGet,
+			Permission:  models.PermissionRead,
 			HandlerFunc: controller.GetBatch,
 		},
+		models.Route{
+			Path:        fmt.Sprintf("/batches/{%s}/events", batchNameParam),
+			Method:      http.MethodGet,
+			Permission:  models.PermissionRead,
+			HandlerFunc: controller.GetBatchEvents,
+		},
+		models.Route{
+			Path:        fmt.Sprintf("/batches/{%s}/jobs", batchNameParam),
+			Method:      http.MethodGet,
+			Permission:  models.PermissionRead,
+			HandlerFunc: controller.GetBatchJobs,
+		},
 		models.Route{
 			Path:        fmt.Sprintf("/batches/{%s}/jobs/{%s}", batchNameParam, jobNameParam),
 			Method:      http.MethodGet,
+			Permission:  models.PermissionRead,
 			HandlerFunc: controller.GetBatchJob,
 		},
+		models.Route{
+			Path:        fmt.Sprintf("/batches/{%s}/jobs/{%s}/logs", batchNameParam, jobNameParam),
+			Method:      http.MethodGet,
+			Permission:  models.PermissionRead,
+			HandlerFunc: controller.GetBatchJobLogs,
+		},
 		models.Route{
 			Path:        fmt.Sprintf("/batches/{%s}", batchNameParam),
 			Method:      http.MethodDelete,
+			Permission:  models.PermissionMutate,
 			HandlerFunc: controller.DeleteBatch,
 		},
+		models.Route{
+			Path:        fmt.Sprintf("/batches/{%s}", batchNameParam),
+			Method:      http.MethodPatch,
+			Permission:  models.PermissionMutate,
+			HandlerFunc: controller.PatchBatch,
+		},
 		models.Route{
 			Path:        fmt.Sprintf("/batches/{%s}/stop", batchNameParam),
 			Method:      http.MethodPost,
+			Permission:  models.PermissionMutate,
 			HandlerFunc: controller.StopBatch,
 		},
 		models.Route{
 			Path:        fmt.Sprintf("/batches/{%s}/jobs/{%s}/stop", batchNameParam, jobNameParam),
 			Method:      http.MethodPost,
+			Permission:  models.PermissionMutate,
 			HandlerFunc: controller.StopBatchJob,
 		},
+		models.Route{
+			Path:        fmt.Sprintf("/batches/{%s}/jobs/{%s}/rerun", batchNameParam, jobNameParam),
+			Method:      http.MethodPost,
+			Permission:  models.PermissionMutate,
+			HandlerFunc: controller.RerunBatchJob,
+		},
+		models.Route{
+			Path:        fmt.Sprintf("/batches/{%s}/retry-failed", batchNameParam),
+			Method:      http.MethodPost,
+			Permission:  models.PermissionMutate,
+			HandlerFunc: controller.RetryFailedBatchJobs,
+		},
 	}
 	return routes
 }
@@ -79,17 +173,22 @@ func (controller *batchController) GetRoutes() models.Routes {
 // ---
 // summary: Create batch
 // parameters:
+// - name: Idempotency-Key
+//   in: header
+//   description: Key of the request, at most 255 characters. A repeated request with the same key and body within the TTL of the key gets the response of the first request
+//   type: string
+//   required: false
 // - name: batchCreation
 //   in: body
-//   description: Batch to create
+//   description: Batch to create, with an optional callbackUrl receiving the final BatchStatus when the batch is completed, and an optional notBefore time
 //   required: true
 //   schema:
-//       "$ref": "#/definitions/BatchScheduleDescription"
+//       "$ref": "#/definitions/BatchScheduleRequest"
 // responses:
 //   "200":
-//     description: "Successful create batch"
+//     description: "Successful create batch, a Scheduled batch when notBefore is in the future, or a Queued batch when its jobs would exceed the maximum number of active jobs"
 //     schema:
-//        "$ref": "#/definitions/BatchStatus"
+//        "$ref": "#/definitions/QueuedBatchStatus"
 //   "400":
 //     description: "Bad request"
 //     schema:
@@ -98,8 +197,16 @@ func (controller *batchController) GetRoutes() models.Routes {
 //     description: "Not found"
 //     schema:
 //        "$ref": "#/definitions/Status"
+//   "409":
+//     description: "Idempotency-Key used with a different request body"
+//     schema:
+//        "$ref": "#/definitions/Status"
+//   "413":
+//     description: "Request body larger than the maximum size"
+//     schema:
+//        "$ref": "#/definitions/Status"
 //   "422":
-//     description: "Invalid data in request"
+//     description: "Invalid data in request, e.g. a payload not matching the payload schema, with each invalid field in causes"
 //     schema:
 //        "$ref": "#/definitions/Status"
 //   "500":
@@ -107,57 +214,264 @@ func (controller *batchController) GetRoutes() models.Routes {
 //     schema:
 //        "$ref": "#/definitions/Status"
 func (controller *batchController) CreateBatch(w http.ResponseWriter, r *http.Request) {
-	var batchScheduleDescription schedulerModels.BatchScheduleDescription
+	var batchScheduleRequest models.BatchScheduleRequest
 
-	if body, _ := io.ReadAll(r.Body); len(body) > 0 {
-		if err := json.Unmarshal(body, &batchScheduleDescription); err != nil {
-			controller.HandleError(w, apiErrors.NewInvalid("BatchScheduleDescription"))
-			return
-		}
+	_, span := tracing.StartSpan(r.Context(), "DecodeRequestBody")
+	err := utils.DecodeRequestBody(r, &batchScheduleRequest)
+	tracing.EndSpan(span, err)
+	if err != nil {
+		controller.HandleError(w, r, err)
+		return
+	}
+	causes := validation.ValidateBatchScheduleDescription(&batchScheduleRequest.BatchScheduleDescription)
+	causes = append(causes, controller.payloadSchema.ValidateBatchPayloads(&batchScheduleRequest.BatchScheduleDescription)...)
+	causes = append(causes, webhooks.ValidateCallback(&batchScheduleRequest.Callback)...)
+	causes = append(causes, pending.ValidateNotBefore(batchScheduleRequest.NotBefore)...)
+	causes = append(causes, controller.pendingItems.ValidatePriority(&batchScheduleRequest.QueuePriority)...)
+	if len(causes) > 0 {
+		controller.HandleError(w, r, serverErrors.NewInvalidFields(causes))
+		return
 	}
 
-	batchState, err := controller.handler.CreateBatch(&batchScheduleDescription)
+	key, err := idempotency.GetKey(r)
 	if err != nil {
-		controller.HandleError(w, err)
+		controller.HandleError(w, r, err)
 		return
 	}
-	err = controller.handler.MaintainHistoryLimit()
+	response, replayed, err := controller.idempotencyKeys.Do(r.Context(), "batches", key, &batchScheduleRequest, func() (interface{}, error) {
+		return controller.scheduleBatch(r.Context(), &batchScheduleRequest, nil)
+	})
 	if err != nil {
-		log.Warnf("failed to maintain batch history: %v", err)
+		controller.HandleError(w, r, err)
+		return
 	}
+	if replayed {
+		w.Header().Set(idempotency.ReplayedHeader, "true")
+	}
+	utils.JSONResponse(w, response)
+}
 
-	utils.JSONResponse(w, &batchState)
+// scheduleBatch Holds the batch until its notBefore time when it is in the future, queues the batch when there is no room
+// for its jobs among the active jobs, otherwise creates the batch. retryOf is the batch with the failed jobs the batch retries, if any
+func (controller *batchController) scheduleBatch(ctx context.Context, batchScheduleRequest *models.BatchScheduleRequest, retryOf *models.BatchRetry) (*models.QueuedBatchStatus, error) {
+	notBefore, ok := controller.pendingItems.ParseNotBefore(batchScheduleRequest.NotBefore)
+	if !ok && controller.pendingItems.Admit(len(batchScheduleRequest.JobScheduleDescriptions)) {
+		batchState, err := controller.createBatch(ctx, batchScheduleRequest, retryOf, nil)
+		if err != nil {
+			controller.pendingItems.CancelAdmit(len(batchScheduleRequest.JobScheduleDescriptions))
+			return nil, err
+		}
+		return &models.QueuedBatchStatus{BatchStatus: *batchState}, nil
+	}
+	// The callback is kept by the notifier until the batch is created, so the secret is not kept in the pending item
+	pendingRequest := *batchScheduleRequest
+	pendingRequest.Callback = models.Callback{}
+	item, err := controller.pendingItems.AddBatch(&pendingRequest, retryOf, notBefore)
+	if err != nil {
+		return nil, err
+	}
+	if err := controller.notifier.RegisterPending(webhooks.KindBatch, item.Name, &batchScheduleRequest.Callback); err != nil {
+		log.WithContext(ctx).Errorf("failed to register the callback of the pending batch %s: %v", item.Name, err)
+	}
+	if ok {
+		log.WithContext(ctx).Debugf("Scheduled the batch %s to start at %s", item.Name, batchScheduleRequest.NotBefore)
+	} else {
+		log.WithContext(ctx).Debugf("Queued the batch %s at position %d", item.Name, item.QueuePosition)
+	}
+	batchStatus := item.BatchStatus()
+	return &batchStatus, nil
+}
+
+// createPendingBatch Creates the batch of a pending batch when it is released from the queue
+func (controller *batchController) createPendingBatch(ctx context.Context, item *pending.Item) (string, error) {
+	batchState, err := controller.createBatch(ctx, item.Batch, item.RetryOf, item)
+	if err != nil {
+		return "", err
+	}
+	return batchState.Name, nil
+}
+
+// removePendingBatch Cancels the pending batch with the name, if any, and deletes its callback
+func (controller *batchController) removePendingBatch(ctx context.Context, batchName string) (bool, error) {
+	removed, err := controller.pendingItems.Remove(pending.KindBatch, batchName)
+	if err != nil || !removed {
+		return removed, err
+	}
+	if err := controller.notifier.Unregister(webhooks.KindBatch, batchName); err != nil {
+		log.WithContext(ctx).Errorf("failed to delete the callback of the pending batch %s: %v", batchName, err)
+	}
+	return true, nil
+}
+
+// createScheduledBatch Creates the batch of a run of a batch schedule
+func (controller *batchController) createScheduledBatch(ctx context.Context, schedule *models.Schedule) (string, error) {
+	batchState, err := controller.scheduleBatch(ctx, &models.BatchScheduleRequest{BatchScheduleDescription: *schedule.Batch}, nil)
+	if err != nil {
+		return "", err
+	}
+	return batchState.Name, nil
+}
+
+// isBatchActive Returns true when the batch is pending or not completed
+func (controller *batchController) isBatchActive(ctx context.Context, batchName string) (bool, error) {
+	item, err := controller.pendingItems.Get(pending.KindBatch, batchName)
+	if err != nil || item != nil {
+		return item != nil, err
+	}
+	_, span := tracing.StartSpan(ctx, "BatchHandler.GetBatch")
+	batch, err := controller.handler.GetBatch(controller.pendingItems.GetReleasedName(pending.KindBatch, batchName))
+	tracing.EndSpan(span, err)
+	if err != nil {
+		if serverErrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return !events.IsTerminal(batch.Status), nil
+}
+
+// createBatch Creates the batch, keeps its description to re-run its jobs, registers its callback and maintains the history limit.
+// item is the pending batch it is created for, if any
+func (controller *batchController) createBatch(ctx context.Context, batchScheduleRequest *models.BatchScheduleRequest, retryOf *models.BatchRetry, item *pending.Item) (*modelsV1.BatchStatus, error) {
+	_, span := tracing.StartSpan(ctx, "BatchHandler.CreateBatch")
+	batchState, err := controller.handler.CreateBatch(&batchScheduleRequest.BatchScheduleDescription)
+	tracing.EndSpan(span, err)
+	if err != nil {
+		return nil, err
+	}
+	metrics.AddBatchCreated()
+	if item != nil {
+		if err := controller.pendingItems.KeepReleasedName(item, batchState.Name); err != nil {
+			log.WithContext(ctx).Errorf("failed to keep the name of the batch %s created for %s: %v", batchState.Name, item.Name, err)
+		}
+	}
+	if err := controller.descriptions.PutBatch(batchState.Name, &batchScheduleRequest.BatchScheduleDescription, retryOf); err != nil {
+		log.WithContext(ctx).Errorf("failed to keep the description of the batch %s: %v", batchState.Name, err)
+	}
+	if err := controller.notifier.Register(webhooks.KindBatch, batchState.Name, &batchScheduleRequest.Callback); err != nil {
+		log.WithContext(ctx).Errorf("failed to register the callback of the batch %s: %v", batchState.Name, err)
+	}
+	if item != nil {
+		if err := controller.notifier.Release(webhooks.KindBatch, item.Name, batchState.Name); err != nil {
+			log.WithContext(ctx).Errorf("failed to move the callback of the pending batch %s to the batch %s: %v", item.Name, batchState.Name, err)
+		}
+	}
+	_, span = tracing.StartSpan(ctx, "BatchHandler.MaintainHistoryLimit")
+	err = controller.handler.MaintainHistoryLimit()
+	tracing.EndSpan(span, err)
+	if err != nil {
+		metrics.AddHistoryLimitFailure("batch")
+		log.WithContext(ctx).Warnf("failed to maintain batch history: %v", err)
+	}
+	return batchState, nil
 }
 
 // swagger:operation GET /batches/ Batch getBatches
 // ---
 // summary: Gets batches
 // parameters:
+// - name: status
+//   in: query
+//   description: Statuses to include, repeated or comma separated. One of Scheduled, Queued, Waiting, Running, Succeeded, Failed, Stopping, Stopped
+//   type: array
+//   items:
+//     type: string
+//   collectionFormat: multi
+//   required: false
+// - name: jobId
+//   in: query
+//   description: Job ID set in the job schedule description. Includes batches with a job with the job ID
+//   type: string
+//   required: false
+// - name: batchName
+//   in: query
+//   description: Name of the batch
+//   type: string
+//   required: false
+// - name: createdAfter
+//   in: query
+//   description: Include items created at or after the time, RFC3339
+//   type: string
+//   format: date-time
+//   required: false
+// - name: createdBefore
+//   in: query
+//   description: Include items created at or before the time, RFC3339
+//   type: string
+//   format: date-time
+//   required: false
+// - name: endedAfter
+//   in: query
+//   description: Include items ended at or after the time, RFC3339
+//   type: string
+//   format: date-time
+//   required: false
+// - name: limit
+//   in: query
+//   description: Maximum number of batches in the response, between 1 and 1000. All batches when not set
+//   type: integer
+//   required: false
+// - name: continue
+//   in: query
+//   description: Continuation token from the Link header of the previous page
+//   type: string
+//   required: false
 // responses:
 //   "200":
-//     description: "Successful get batches"
+//     description: "Successful get batches, sorted by creation time and name"
+//     headers:
+//       Link:
+//         description: Link to the next page, with rel="next", when there are more batches
+//         type: string
+//       X-Total-Count:
+//         description: Total number of batches
+//         type: integer
 //     schema:
 //        type: "array"
 //        items:
 //           "$ref": "#/definitions/BatchStatus"
+//   "400":
+//     description: "Invalid filter, limit or continuation token"
+//     schema:
+//        "$ref": "#/definitions/Status"
 //   "500":
 //     description: "Internal server error"
 //     schema:
 //        "$ref": "#/definitions/Status"
 func (controller *batchController) GetBatches(w http.ResponseWriter, r *http.Request) {
-	log.Debug("Get batch list")
-	batches, err := controller.handler.GetBatches()
+	log.WithContext(r.Context()).Debug("Get batch list")
+	filter, err := utils.ParseJobStatusFilter(r)
+	if err != nil {
+		controller.HandleError(w, r, err)
+		return
+	}
+	pageRequest, err := utils.ParsePageRequest(r)
+	if err != nil {
+		controller.HandleError(w, r, err)
+		return
+	}
+	batches, queuePositions, err := controller.getBatches(r.Context())
 	if err != nil {
-		controller.HandleError(w, err)
+		controller.HandleError(w, r, err)
 		return
 	}
-	log.Debugf("Found %d batches", len(batches))
-	utils.JSONResponse(w, batches)
+	log.WithContext(r.Context()).Debugf("Found %d batches", len(batches))
+	batches = filter.FilterBatchStatuses(batches)
+	utils.SortBatchStatuses(batches)
+	start, end := pageRequest.Page(w, r, len(batches), func(i int) string { return utils.JobStatusSortKey(&batches[i].JobStatus) })
+	page := make([]models.QueuedBatchStatus, 0, end-start)
+	for _, batch := range batches[start:end] {
+		page = append(page, models.QueuedBatchStatus{BatchStatus: batch, QueuePosition: queuePositions[batch.Name]})
+	}
+	utils.JSONResponse(w, page)
 }
 
 // swagger:operation GET /batches/{batchName} Batch getBatch
 // ---
 // summary: Gets batch
+// description: >-
+//   A batch retrying the failed jobs of a batch also has the name of the original batch in retryOf,
+//   and the names of the failed jobs mapped to the names of the jobs created so far in jobNames.
 // parameters:
 // - name: batchName
 //   in: path
@@ -168,7 +482,7 @@ func (controller *batchController) GetBatches(w http.ResponseWriter, r *http.Req
 //   "200":
 //     description: "Successful get batch"
 //     schema:
-//        "$ref": "#/definitions/BatchStatus"
+//        "$ref": "#/definitions/QueuedBatchStatus"
 //   "404":
 //     description: "Not found"
 //     schema:
@@ -179,15 +493,253 @@ func (controller *batchController) GetBatches(w http.ResponseWriter, r *http.Req
 //        "$ref": "#/definitions/Status"
 func (controller *batchController) GetBatch(w http.ResponseWriter, r *http.Request) {
 	batchName := mux.Vars(r)[batchNameParam]
-	log.Debugf("Get batch %s", batchName)
-	batch, err := controller.handler.GetBatch(batchName)
+	log.WithContext(r.Context()).Debugf("Get batch %s", batchName)
+	item, err := controller.pendingItems.Get(pending.KindBatch, batchName)
 	if err != nil {
-		controller.HandleError(w, err)
+		controller.HandleError(w, r, err)
+		return
+	}
+	if item != nil {
+		if item.RetryOf != nil {
+			utils.JSONResponse(w, &models.RetryFailedBatchStatus{QueuedBatchStatus: item.BatchStatus(), RetryOf: item.RetryOf.BatchName, JobNames: map[string]string{}})
+			return
+		}
+		utils.JSONResponse(w, item.BatchStatus())
+		return
+	}
+	_, span := tracing.StartSpan(r.Context(), "BatchHandler.GetBatch")
+	batch, err := controller.handler.GetBatch(controller.pendingItems.GetReleasedName(pending.KindBatch, batchName))
+	tracing.EndSpan(span, err)
+	if err != nil {
+		controller.HandleError(w, r, err)
+		return
+	}
+	retryOf, jobNames, err := controller.descriptions.GetRetriedJobNames(r.Context(), batch)
+	if err != nil {
+		controller.HandleError(w, r, err)
+		return
+	}
+	if len(retryOf) > 0 {
+		utils.JSONResponse(w, &models.RetryFailedBatchStatus{QueuedBatchStatus: models.QueuedBatchStatus{BatchStatus: *batch}, RetryOf: retryOf, JobNames: jobNames})
 		return
 	}
 	utils.JSONResponse(w, batch)
 }
 
+// getBatches Gets the batches, and the pending batches in the Scheduled or Queued status with the queue positions of the Queued batches by name
+func (controller *batchController) getBatches(ctx context.Context) ([]modelsV1.BatchStatus, map[string]int, error) {
+	_, span := tracing.StartSpan(ctx, "BatchHandler.GetBatches")
+	batches, err := controller.handler.GetBatches()
+	tracing.EndSpan(span, err)
+	if err != nil {
+		return nil, nil, err
+	}
+	items, err := controller.pendingItems.List(pending.KindBatch)
+	if err != nil {
+		return nil, nil, err
+	}
+	queuePositions := make(map[string]int)
+	for i := range items {
+		batches = append(batches, items[i].BatchStatus().BatchStatus)
+		if items[i].QueuePosition > 0 {
+			queuePositions[items[i].Name] = items[i].QueuePosition
+		}
+	}
+	return batches, queuePositions, nil
+}
+
+// swagger:operation GET /batches/{batchName}/events Batch getBatchEvents
+// ---
+// summary: Streams the progress of a batch as server-sent events
+// description: >-
+//   Sends a status event with the JobStatus of each job in the batch when the stream starts, and when the status of a job changes.
+//   A progress event with the BatchProgress is sent every 10 seconds, and a deleted event when a job is deleted.
+//   The stream ends with a progress event and an end event when the batch is Succeeded, Failed or Stopped.
+//   Comments are sent as heartbeats on idle streams.
+//   A client reconnecting with the Last-Event-ID header gets the changes after the event.
+// produces:
+// - text/event-stream
+// parameters:
+// - name: batchName
+//   in: path
+//   description: Name of batch
+//   type: string
+//   required: true
+// - name: Last-Event-ID
+//   in: header
+//   description: ID of the last event received before reconnecting
+//   type: string
+//   required: false
+// responses:
+//   "200":
+//     description: "Stream of status and deleted events with JobStatus data, and progress events with BatchProgress data"
+//     schema:
+//        "$ref": "#/definitions/BatchProgress"
+//   "404":
+//     description: "Not found"
+//     schema:
+//        "$ref": "#/definitions/Status"
+//   "500":
+//     description: "Internal server error"
+//     schema:
+//        "$ref": "#/definitions/Status"
+func (controller *batchController) GetBatchEvents(w http.ResponseWriter, r *http.Request) {
+	batchName := controller.pendingItems.GetReleasedName(pending.KindBatch, mux.Vars(r)[batchNameParam])
+	log.WithContext(r.Context()).Debugf("Stream events of the batch %s", batchName)
+	var batch *modelsV1.BatchStatus
+	getBatch := func() error {
+		batchStatus, err := controller.handler.GetBatch(batchName)
+		if err == nil {
+			batch = batchStatus
+		}
+		return err
+	}
+	err := controller.jobWatcher.Serve(w, r, &events.Stream{
+		Labels: labels.Set{kube.RadixBatchNameLabel: batchName, kube.RadixJobTypeLabel: kube.RadixJobTypeJobSchedule},
+		GetJobStatuses: func() ([]modelsV1.JobStatus, error) {
+			if err := getBatch(); err != nil {
+				return nil, err
+			}
+			return batch.JobStatuses, nil
+		},
+		GetJobStatus: func(jobName string) (*modelsV1.JobStatus, error) {
+			return controller.handler.GetBatchJob(batchName, jobName)
+		},
+		IsComplete: func(jobStatuses map[string]modelsV1.JobStatus) bool {
+			if events.IsTerminal(batch.Status) {
+				return true
+			}
+			// The batch can still be creating jobs when all the jobs created are completed
+			if !events.AllTerminal(jobStatuses) {
+				return false
+			}
+			if err := getBatch(); err != nil {
+				log.WithContext(r.Context()).Warnf("failed to get the batch %s: %v", batchName, err)
+				return false
+			}
+			return events.IsTerminal(batch.Status)
+		},
+		Progress: func(jobStatuses map[string]modelsV1.JobStatus) interface{} {
+			return events.NewBatchProgress(batchName, getBatchStarted(batch), jobStatuses, time.Now())
+		},
+	})
+	if err != nil {
+		controller.HandleError(w, r, err)
+	}
+}
+
+// getBatchStarted Gets the time the batch was started, or created when not started
+func getBatchStarted(batch *modelsV1.BatchStatus) time.Time {
+	for _, timestamp := range []string{batch.Started, batch.Created} {
+		if started, err := time.Parse(time.RFC3339, timestamp); err == nil {
+			return started
+		}
+	}
+	return time.Time{}
+}
+
+// swagger:operation GET /batches/{batchName}/jobs Batch getBatchJobs
+// ---
+// summary: Gets jobs of a batch
+// parameters:
+// - name: batchName
+//   in: path
+//   description: Name of batch
+//   type: string
+//   required: true
+// - name: status
+//   in: query
+//   description: Statuses to include, repeated or comma separated. One of Waiting, Running, Succeeded, Failed, Stopping, Stopped
+//   type: array
+//   items:
+//     type: string
+//   collectionFormat: multi
+//   required: false
+// - name: jobId
+//   in: query
+//   description: Job ID set in the job schedule description
+//   type: string
+//   required: false
+// - name: createdAfter
+//   in: query
+//   description: Include items created at or after the time, RFC3339
+//   type: string
+//   format: date-time
+//   required: false
+// - name: createdBefore
+//   in: query
+//   description: Include items created at or before the time, RFC3339
+//   type: string
+//   format: date-time
+//   required: false
+// - name: endedAfter
+//   in: query
+//   description: Include items ended at or after the time, RFC3339
+//   type: string
+//   format: date-time
+//   required: false
+// - name: limit
+//   in: query
+//   description: Maximum number of jobs in the response, between 1 and 1000. All jobs when not set
+//   type: integer
+//   required: false
+// - name: continue
+//   in: query
+//   description: Continuation token from the Link header of the previous page
+//   type: string
+//   required: false
+// responses:
+//   "200":
+//     description: "Successful get batch jobs, sorted by creation time and name"
+//     headers:
+//       Link:
+//         description: Link to the next page, with rel="next", when there are more jobs
+//         type: string
+//       X-Total-Count:
+//         description: Total number of jobs
+//         type: integer
+//     schema:
+//        type: "array"
+//        items:
+//           "$ref": "#/definitions/JobStatus"
+//   "400":
+//     description: "Invalid filter, limit or continuation token"
+//     schema:
+//        "$ref": "#/definitions/Status"
+//   "404":
+//     description: "Not found"
+//     schema:
+//        "$ref": "#/definitions/Status"
+//   "500":
+//     description: "Internal server error"
+//     schema:
+//        "$ref": "#/definitions/Status"
+func (controller *batchController) GetBatchJobs(w http.ResponseWriter, r *http.Request) {
+	batchName := controller.pendingItems.GetReleasedName(pending.KindBatch, mux.Vars(r)[batchNameParam])
+	log.WithContext(r.Context()).Debugf("Get jobs of the batch %s", batchName)
+	filter, err := utils.ParseJobStatusFilter(r)
+	if err != nil {
+		controller.HandleError(w, r, err)
+		return
+	}
+	pageRequest, err := utils.ParsePageRequest(r)
+	if err != nil {
+		controller.HandleError(w, r, err)
+		return
+	}
+	_, span := tracing.StartSpan(r.Context(), "BatchHandler.GetBatch")
+	batch, err := controller.handler.GetBatch(batchName)
+	tracing.EndSpan(span, err)
+	if err != nil {
+		controller.HandleError(w, r, err)
+		return
+	}
+	jobs := filter.FilterJobStatuses(batch.JobStatuses)
+	utils.SortJobStatuses(jobs)
+	start, end := pageRequest.Page(w, r, len(jobs), func(i int) string { return utils.JobStatusSortKey(&jobs[i]) })
+	utils.JSONResponse(w, jobs[start:end])
+}
+
 // swagger:operation GET /batches/{batchName}/jobs/{jobName} Batch getBatchJob
 // ---
 // summary: Gets batch job
@@ -216,20 +768,109 @@ func (controller *batchController) GetBatch(w http.ResponseWriter, r *http.Reque
 //     schema:
 //        "$ref": "#/definitions/Status"
 func (controller *batchController) GetBatchJob(w http.ResponseWriter, r *http.Request) {
-	batchName := mux.Vars(r)[batchNameParam]
+	batchName := controller.pendingItems.GetReleasedName(pending.KindBatch, mux.Vars(r)[batchNameParam])
 	jobName := mux.Vars(r)[jobNameParam]
-	log.Debugf("Get job %s from the batch %s", jobName, batchName)
+	log.WithContext(r.Context()).Debugf("Get job %s from the batch %s", jobName, batchName)
+	_, span := tracing.StartSpan(r.Context(), "BatchHandler.GetBatchJob")
 	job, err := controller.handler.GetBatchJob(batchName, jobName)
+	tracing.EndSpan(span, err)
 	if err != nil {
-		controller.HandleError(w, err)
+		controller.HandleError(w, r, err)
 		return
 	}
 	utils.JSONResponse(w, job)
 }
 
+// swagger:operation GET /batches/{batchName}/jobs/{jobName}/logs Batch getBatchJobLogs
+// ---
+// summary: Gets the log of a job in a batch
+// produces:
+// - text/plain
+// - application/x-ndjson
+// parameters:
+// - name: batchName
+//   in: path
+//   description: Name of batch
+//   type: string
+//   required: true
+// - name: jobName
+//   in: path
+//   description: Name of job
+//   type: string
+//   required: true
+// - name: follow
+//   in: query
+//   description: Stream new log lines until the job container ends
+//   type: boolean
+//   required: false
+// - name: tailLines
+//   in: query
+//   description: Number of lines from the end of the log
+//   type: integer
+//   required: false
+// - name: sinceSeconds
+//   in: query
+//   description: Only log lines newer than the number of seconds
+//   type: integer
+//   required: false
+// - name: timestamps
+//   in: query
+//   description: Prefix each log line with its RFC3339 time
+//   type: boolean
+//   required: false
+// - name: previous
+//   in: query
+//   description: Log of the previous attempt of the job, or of the previous instance of the container when the job has one attempt
+//   type: boolean
+//   required: false
+// - name: format
+//   in: query
+//   description: Format of the log, text or ndjson. Defaults to ndjson when the Accept header is application/x-ndjson, otherwise text
+//   type: string
+//   enum: [text, ndjson]
+//   required: false
+// responses:
+//   "200":
+//     description: "Log of the job as plain text, or as newline delimited JSON objects with pod, container, timestamp and message"
+//     schema:
+//        type: string
+//   "400":
+//     description: "Invalid parameter, or the container has no log yet"
+//     schema:
+//        "$ref": "#/definitions/Status"
+//   "404":
+//     description: "Not found"
+//     schema:
+//        "$ref": "#/definitions/Status"
+//   "500":
+//     description: "Internal server error"
+//     schema:
+//        "$ref": "#/definitions/Status"
+func (controller *batchController) GetBatchJobLogs(w http.ResponseWriter, r *http.Request) {
+	batchName := controller.pendingItems.GetReleasedName(pending.KindBatch, mux.Vars(r)[batchNameParam])
+	jobName := mux.Vars(r)[jobNameParam]
+	log.WithContext(r.Context()).Debugf("Get log of the job %s in the batch %s", jobName, batchName)
+	options, err := logs.ParseOptions(r)
+	if err != nil {
+		controller.HandleError(w, r, err)
+		return
+	}
+	_, span := tracing.StartSpan(r.Context(), "BatchHandler.GetBatchJob")
+	_, err = controller.handler.GetBatchJob(batchName, jobName)
+	tracing.EndSpan(span, err)
+	if err != nil {
+		controller.HandleError(w, r, err)
+		return
+	}
+	if err := controller.logReader.Stream(w, r, jobName, options); err != nil {
+		controller.HandleError(w, r, err)
+	}
+}
+
 // swagger:operation DELETE /batches/{batchName} Batch deleteBatch
 // ---
 // summary: Delete batch
+// description: A Scheduled or Queued batch is cancelled, and is not created
 // parameters:
 // - name: batchName
 //   in: path
@@ -251,10 +892,9 @@ func (controller *batchController) GetBatchJob(w http.ResponseWriter, r *http.Re
 //        "$ref": "#/definitions/Status"
 func (controller *batchController) DeleteBatch(w http.ResponseWriter, r *http.Request) {
 	batchName := mux.Vars(r)[batchNameParam]
-	log.Debugf("Delete batch %s", batchName)
-	err := controller.handler.DeleteBatch(batchName)
-	if err != nil {
-		controller.HandleError(w, err)
+	log.WithContext(r.Context()).Debugf("Delete batch %s", batchName)
+	if err := controller.deleteBatch(r, batchName); err != nil {
+		controller.HandleError(w, r, err)
 		return
 	}
 
@@ -263,12 +903,109 @@ func (controller *batchController) DeleteBatch(w http.ResponseWriter, r *http.Re
 		Code:    http.StatusOK,
 		Message: fmt.Sprintf("batch %s successfully deleted", batchName),
 	}
-	utils.StatusResponse(w, &status)
+	utils.StatusResponse(w, r, &status)
+}
+
+// deleteBatch Deletes the batch and its description. A pending batch is cancelled
+func (controller *batchController) deleteBatch(r *http.Request, batchName string) error {
+	if removed, err := controller.removePendingBatch(r.Context(), batchName); err != nil || removed {
+		return err
+	}
+	batchName = controller.pendingItems.GetReleasedName(pending.KindBatch, batchName)
+	_, span := tracing.StartSpan(r.Context(), "BatchHandler.DeleteBatch")
+	err := controller.handler.DeleteBatch(batchName)
+	tracing.EndSpan(span, err)
+	if err != nil {
+		return err
+	}
+	if err := controller.descriptions.DeleteBatch(batchName); err != nil {
+		log.WithContext(r.Context()).Errorf("failed to delete the description of the batch %s: %v", batchName, err)
+	}
+	return nil
+}
+
+// swagger:operation PATCH /batches/{batchName} Batch patchBatch
+// ---
+// summary: Change the priority of a Scheduled or Queued batch
+// parameters:
+// - name: batchName
+//   in: path
+//   description: Name of batch
+//   type: string
+//   required: true
+// - name: priority
+//   in: body
+//   description: The new priority, or the name of a priority class
+//   required: true
+//   schema:
+//       "$ref": "#/definitions/QueuePriority"
+// responses:
+//   "200":
+//     description: "Successful change of the priority"
+//     schema:
+//        "$ref": "#/definitions/QueuedBatchStatus"
+//   "400":
+//     description: "Bad request"
+//     schema:
+//        "$ref": "#/definitions/Status"
+//   "404":
+//     description: "Not found"
+//     schema:
+//        "$ref": "#/definitions/Status"
+//   "409":
+//     description: "The batch is already created"
+//     schema:
+//        "$ref": "#/definitions/Status"
+//   "422":
+//     description: "Invalid priority, with each invalid field in causes"
+//     schema:
+//        "$ref": "#/definitions/Status"
+//   "500":
+//     description: "Internal server error"
+//     schema:
+//        "$ref": "#/definitions/Status"
+func (controller *batchController) PatchBatch(w http.ResponseWriter, r *http.Request) {
+	batchName := mux.Vars(r)[batchNameParam]
+	log.WithContext(r.Context()).Debugf("Change the priority of the batch %s", batchName)
+	var priority models.QueuePriority
+	_, span := tracing.StartSpan(r.Context(), "DecodeRequestBody")
+	err := utils.DecodeRequestBody(r, &priority)
+	tracing.EndSpan(span, err)
+	if err != nil {
+		controller.HandleError(w, r, err)
+		return
+	}
+	causes := controller.pendingItems.ValidatePriority(&priority)
+	if priority.Priority == nil && len(priority.PriorityClass) == 0 {
+		causes = append(causes, models.StatusCause{Field: "priority", Message: "either priority or priorityClass must be set"})
+	}
+	if len(causes) > 0 {
+		controller.HandleError(w, r, serverErrors.NewInvalidFields(causes))
+		return
+	}
+
+	item, err := controller.pendingItems.SetPriority(pending.KindBatch, batchName, controller.pendingItems.GetPriority(&priority))
+	if err != nil {
+		controller.HandleError(w, r, err)
+		return
+	}
+	if item == nil {
+		_, span = tracing.StartSpan(r.Context(), "BatchHandler.GetBatch")
+		_, err = controller.handler.GetBatch(controller.pendingItems.GetReleasedName(pending.KindBatch, batchName))
+		tracing.EndSpan(span, err)
+		if err == nil {
+			err = serverErrors.NewConflict(fmt.Sprintf("The batch %s is already created. Only Scheduled and Queued batches can change priority", batchName))
+		}
+		controller.HandleError(w, r, err)
+		return
+	}
+	utils.JSONResponse(w, item.BatchStatus())
 }
 
 // swagger:operation POST /batches/{batchName}/stop Batch stopBatch
 // ---
 // summary: Stop batch
+// description: A Scheduled or Queued batch is cancelled, and is not created
 // parameters:
 // - name: batchName
 //   in: path
@@ -290,9 +1027,8 @@ func (controller *batchController) DeleteBatch(w http.ResponseWriter, r *http.Re
 //        "$ref": "#/definitions/Status"
 func (controller *batchController) StopBatch(w http.ResponseWriter, r *http.Request) {
 	batchName := mux.Vars(r)[batchNameParam]
-	err := controller.handler.StopBatch(batchName)
-	if err != nil {
-		controller.HandleError(w, err)
+	if err := controller.stopBatch(r.Context(), batchName); err != nil {
+		controller.HandleError(w, r, err)
 		return
 	}
 
@@ -301,7 +1037,116 @@ func (controller *batchController) StopBatch(w http.ResponseWriter, r *http.Requ
 		Code:    http.StatusOK,
 		Message: fmt.Sprintf("batch %s successfully stopped", batchName),
 	}
-	utils.StatusResponse(w, &status)
+	utils.StatusResponse(w, r, &status)
+}
+
+// stopBatch Stops the batch. A pending batch is cancelled
+func (controller *batchController) stopBatch(ctx context.Context, batchName string) error {
+	if removed, err := controller.removePendingBatch(ctx, batchName); err != nil || removed {
+		return err
+	}
+	_, span := tracing.StartSpan(ctx, "BatchHandler.StopBatch")
+	err := controller.handler.StopBatch(controller.pendingItems.GetReleasedName(pending.KindBatch, batchName))
+	tracing.EndSpan(span, err)
+	return err
+}
+
+// swagger:operation DELETE /batches Batch deleteBatches
+// ---
+// summary: Delete the batches matching a selector
+// description: >-
+//   Deletes the batches matching all conditions of the selector, a few batches at a time.
+//   Each batch is deleted even when others fail, and the result of each batch is in the report.
+// parameters:
+// - name: selector
+//   in: body
+//   description: Selector of the batches, with at least one condition
+//   required: true
+//   schema:
+//       "$ref": "#/definitions/Selector"
+// responses:
+//   "200":
+//     description: "All selected batches deleted, or no batches selected"
+//     schema:
+//        "$ref": "#/definitions/BulkReport"
+//   "207":
+//     description: "Some or all selected batches failed to be deleted"
+//     schema:
+//        "$ref": "#/definitions/BulkReport"
+//   "413":
+//     description: "Request body larger than the maximum size"
+//     schema:
+//        "$ref": "#/definitions/Status"
+//   "422":
+//     description: "Invalid selector, with each invalid field in causes"
+//     schema:
+//        "$ref": "#/definitions/Status"
+//   "500":
+//     description: "Internal server error"
+//     schema:
+//        "$ref": "#/definitions/Status"
+func (controller *batchController) DeleteBatches(w http.ResponseWriter, r *http.Request) {
+	log.WithContext(r.Context()).Debug("Delete batches")
+	controller.runBulk(w, r, func(batchName string) error {
+		return controller.deleteBatch(r, batchName)
+	})
+}
+
+// swagger:operation POST /batches/stop Batch stopBatches
+// ---
+// summary: Stop the batches matching a selector
+// description: >-
+//   Stops the batches matching all conditions of the selector, a few batches at a time.
+//   Each batch is stopped even when others fail, and the result of each batch is in the report.
+// parameters:
+// - name: selector
+//   in: body
+//   description: Selector of the batches, with at least one condition
+//   required: true
+//   schema:
+//       "$ref": "#/definitions/Selector"
+// responses:
+//   "200":
+//     description: "All selected batches stopped, or no batches selected"
+//     schema:
+//        "$ref": "#/definitions/BulkReport"
+//   "207":
+//     description: "Some or all selected batches failed to be stopped"
+//     schema:
+//        "$ref": "#/definitions/BulkReport"
+//   "413":
+//     description: "Request body larger than the maximum size"
+//     schema:
+//        "$ref": "#/definitions/Status"
+//   "422":
+//     description: "Invalid selector, with each invalid field in causes"
+//     schema:
+//        "$ref": "#/definitions/Status"
+//   "500":
+//     description: "Internal server error"
+//     schema:
+//        "$ref": "#/definitions/Status"
+func (controller *batchController) StopBatches(w http.ResponseWriter, r *http.Request) {
+	log.WithContext(r.Context()).Debug("Stop batches")
+	controller.runBulk(w, r, func(batchName string) error {
+		return controller.stopBatch(r.Context(), batchName)
+	})
+}
+
+// runBulk Runs the operation on each batch selected by the selector in the request body
+func (controller *batchController) runBulk(w http.ResponseWriter, r *http.Request, operation func(batchName string) error) {
+	selector, err := bulk.DecodeSelector(r)
+	if err != nil {
+		controller.HandleError(w, r, err)
+		return
+	}
+	batches, _, err := controller.getBatches(r.Context())
+	if err != nil {
+		controller.HandleError(w, r, err)
+		return
+	}
+	report := controller.bulkRunner.Run(r.Context(), bulk.SelectBatches(selector, batches), operation)
+	bulk.ReportResponse(w, report)
 }
 
 // swagger:operation POST /batches/{batchName}/jobs/{jobName}/stop Batch stopBatchJob
@@ -332,18 +1177,180 @@ func (controller *batchController) StopBatch(w http.ResponseWriter, r *http.Requ
 //     schema:
 //        "$ref": "#/definitions/Status"
 func (controller *batchController) StopBatchJob(w http.ResponseWriter, r *http.Request) {
-	batchName := mux.Vars(r)[batchNameParam]
+	batchName := controller.pendingItems.GetReleasedName(pending.KindBatch, mux.Vars(r)[batchNameParam])
 	jobName := mux.Vars(r)[jobNameParam]
+	_, span := tracing.StartSpan(r.Context(), "BatchHandler.StopBatchJob")
 	err := controller.handler.StopBatchJob(batchName, jobName)
+	tracing.EndSpan(span, err)
 	if err != nil {
-		controller.HandleError(w, err)
+		controller.HandleError(w, r, err)
 		return
 	}
+	metrics.AddJobStopped()
 
 	status := schedulerModels.Status{
 		Status:  schedulerModels.StatusSuccess,
 		Code:    http.StatusOK,
 		Message: fmt.Sprintf("job %s in the batch %s successfully stopped", jobName, batchName),
 	}
-	utils.StatusResponse(w, &status)
+	utils.StatusResponse(w, r, &status)
+}
+
+// swagger:operation POST /batches/{batchName}/jobs/{jobName}/rerun Batch rerunBatchJob
+// ---
+// summary: Re-run job in a batch
+// description: >-
+//   Creates a new job, outside of the batch, with the payload and config the job in the batch was created with,
+//   and the default config of the batch. Only jobs in batches created by this server can be re-run.
+//   The body is an optional JSON merge patch (RFC 7386) of the JobScheduleRequest, overriding fields of the original job.
+// parameters:
+// - name: batchName
+//   in: path
+//   description: Name of batch
+//   type: string
+//   required: true
+// - name: jobName
+//   in: path
+//   description: Name of job
+//   type: string
+//   required: true
+// - name: overrides
+//   in: body
+//   description: JSON merge patch of the JobScheduleRequest of the original job
+//   required: false
+//   schema:
+//       "$ref": "#/definitions/JobScheduleRequest"
+// responses:
+//   "200":
+//     description: "Successful re-run job, with the names of the original job and batch in rerunOf and rerunOfBatch"
+//     schema:
+//        "$ref": "#/definitions/RerunJobStatus"
+//   "404":
+//     description: "Not found, or the description of the job is not known"
+//     schema:
+//        "$ref": "#/definitions/Status"
+//   "413":
+//     description: "Request body larger than the maximum size"
+//     schema:
+//        "$ref": "#/definitions/Status"
+//   "422":
+//     description: "Invalid overrides, or an invalid job with the overrides applied, with each invalid field in causes"
+//     schema:
+//        "$ref": "#/definitions/Status"
+//   "500":
+//     description: "Internal server error"
+//     schema:
+//        "$ref": "#/definitions/Status"
+func (controller *batchController) RerunBatchJob(w http.ResponseWriter, r *http.Request) {
+	batchName := controller.pendingItems.GetReleasedName(pending.KindBatch, mux.Vars(r)[batchNameParam])
+	jobName := mux.Vars(r)[jobNameParam]
+	log.WithContext(r.Context()).Debugf("Re-run job %s in the batch %s", jobName, batchName)
+	_, span := tracing.StartSpan(r.Context(), "BatchHandler.GetBatch")
+	batch, err := controller.handler.GetBatch(batchName)
+	tracing.EndSpan(span, err)
+	if err != nil {
+		controller.HandleError(w, r, err)
+		return
+	}
+	description, err := controller.descriptions.GetBatchJob(r.Context(), batch, jobName)
+	if err != nil {
+		controller.HandleError(w, r, err)
+		return
+	}
+	var overrides json.RawMessage
+	_, span = tracing.StartSpan(r.Context(), "DecodeRequestBody")
+	err = utils.DecodeRequestBody(r, &overrides)
+	tracing.EndSpan(span, err)
+	if err != nil {
+		controller.HandleError(w, r, err)
+		return
+	}
+	job, err := controller.descriptions.RerunJob(r.Context(), description, overrides, jobName)
+	if err != nil {
+		controller.HandleError(w, r, err)
+		return
+	}
+	utils.JSONResponse(w, &models.RerunJobStatus{JobStatus: job.JobStatus, RerunOf: jobName, RerunOfBatch: batchName})
+}
+
+// swagger:operation POST /batches/{batchName}/retry-failed Batch retryFailedBatchJobs
+// ---
+// summary: Retry the failed jobs of a batch
+// description: >-
+//   Creates a new batch with the payloads and configs the failed jobs of the batch were created with,
+//   and the default config of the batch. Only batches created by this server can be retried.
+// parameters:
+// - name: batchName
+//   in: path
+//   description: Name of batch
+//   type: string
+//   required: true
+// - name: callback
+//   in: body
+//   description: Optional callbackUrl receiving the final BatchStatus when the new batch is completed
+//   required: false
+//   schema:
+//       "$ref": "#/definitions/Callback"
+// responses:
+//   "200":
+//     description: "Successful retry. The jobs of the new batch are not created yet, get the batch for the names of the failed jobs mapped to the names of the new jobs in jobNames"
+//     schema:
+//        "$ref": "#/definitions/RetryFailedBatchStatus"
+//   "404":
+//     description: "Not found, or the description of the batch is not known"
+//     schema:
+//        "$ref": "#/definitions/Status"
+//   "413":
+//     description: "Request body larger than the maximum size"
+//     schema:
+//        "$ref": "#/definitions/Status"
+//   "422":
+//     description: "The batch has no failed jobs, or invalid callback"
+//     schema:
+//        "$ref": "#/definitions/Status"
+//   "500":
+//     description: "Internal server error"
+//     schema:
+//        "$ref": "#/definitions/Status"
+func (controller *batchController) RetryFailedBatchJobs(w http.ResponseWriter, r *http.Request) {
+	batchName := controller.pendingItems.GetReleasedName(pending.KindBatch, mux.Vars(r)[batchNameParam])
+	log.WithContext(r.Context()).Debugf("Retry failed jobs of the batch %s", batchName)
+	var callback models.Callback
+	_, span := tracing.StartSpan(r.Context(), "DecodeRequestBody")
+	err := utils.DecodeRequestBody(r, &callback)
+	tracing.EndSpan(span, err)
+	if err != nil {
+		controller.HandleError(w, r, err)
+		return
+	}
+	if causes := webhooks.ValidateCallback(&callback); len(causes) > 0 {
+		controller.HandleError(w, r, serverErrors.NewInvalidFields(causes))
+		return
+	}
+
+	_, span = tracing.StartSpan(r.Context(), "BatchHandler.GetBatch")
+	batch, err := controller.handler.GetBatch(batchName)
+	tracing.EndSpan(span, err)
+	if err != nil {
+		controller.HandleError(w, r, err)
+		return
+	}
+	jobNames, description, err := controller.descriptions.GetFailedBatchJobs(r.Context(), batch)
+	if err != nil {
+		controller.HandleError(w, r, err)
+		return
+	}
+	if len(jobNames) == 0 {
+		controller.HandleError(w, r, serverErrors.NewInvalid(fmt.Sprintf("The batch %s has no failed jobs", batchName)))
+		return
+	}
+
+	retryOf := &models.BatchRetry{BatchName: batchName, JobNames: jobNames}
+	newBatch, err := controller.scheduleBatch(r.Context(), &models.BatchScheduleRequest{BatchScheduleDescription: *description, Callback: callback}, retryOf)
+	if err != nil {
+		controller.HandleError(w, r, err)
+		return
+	}
+	// The jobs of the new batch are not created yet, they are mapped to the failed jobs when the batch is read
+	utils.JSONResponse(w, &models.RetryFailedBatchStatus{QueuedBatchStatus: *newBatch, RetryOf: batchName, JobNames: map[string]string{}})
 }
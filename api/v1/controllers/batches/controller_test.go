@@ -1,30 +1,94 @@
 package batch
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	commonUtils "github.com/equinor/radix-common/utils"
 	"github.com/equinor/radix-job-scheduler-server/api/utils/test"
+	"github.com/equinor/radix-job-scheduler-server/bulk"
+	"github.com/equinor/radix-job-scheduler-server/events"
+	"github.com/equinor/radix-job-scheduler-server/idempotency"
+	"github.com/equinor/radix-job-scheduler-server/logs"
+	serverModels "github.com/equinor/radix-job-scheduler-server/models"
+	"github.com/equinor/radix-job-scheduler-server/pending"
+	"github.com/equinor/radix-job-scheduler-server/rerun"
+	"github.com/equinor/radix-job-scheduler-server/store"
+	"github.com/equinor/radix-job-scheduler-server/validation"
+	"github.com/equinor/radix-job-scheduler-server/webhooks"
 	apiErrors "github.com/equinor/radix-job-scheduler/api/errors"
 	api "github.com/equinor/radix-job-scheduler/api/v1/batches"
 	"github.com/equinor/radix-job-scheduler/api/v1/batches/mock"
 	models "github.com/equinor/radix-job-scheduler/models/common"
 	modelsV1 "github.com/equinor/radix-job-scheduler/models/v1"
+	"github.com/equinor/radix-operator/pkg/apis/kube"
 	"github.com/equinor/radix-operator/pkg/apis/radix/v1"
+	radixfake "github.com/equinor/radix-operator/pkg/client/clientset/versioned/fake"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	secretproviderfake "sigs.k8s.io/secrets-store-csi-driver/pkg/client/clientset/versioned/fake"
 )
 
 func setupTest(handler api.BatchHandler) *test.ControllerTestUtils {
-	controller := batchController{handler: handler}
+	return setupTestWith(handler, nil, newDescriptions(handler))
+}
+
+func setupTestWith(handler api.BatchHandler, payloadSchema *validation.PayloadSchema, descriptions *rerun.Descriptions) *test.ControllerTestUtils {
+	env := serverModels.NewEnv()
+	kubeClient := kubefake.NewSimpleClientset()
+	kubeUtil, _ := kube.New(kubeClient, radixfake.NewSimpleClientset(), secretproviderfake.NewSimpleClientset())
+	jobWatcher := events.NewJobWatcher(kubeClient, env)
+	controller := batchController{
+		handler:         handler,
+		jobWatcher:      jobWatcher,
+		logReader:       logs.NewReader(kubeUtil, env),
+		notifier:        webhooks.NewNotifier(store.NewMemoryStore(), nil, handler, jobWatcher),
+		idempotencyKeys: idempotency.New(store.NewMemoryStore(), time.Hour),
+		payloadSchema:   payloadSchema,
+		descriptions:    descriptions,
+		bulkRunner:      bulk.NewRunner(2),
+		pendingItems:    pending.New(store.NewMemoryStore(), "", 0, nil, 0),
+	}
 	controllerTestUtils := test.New(&controller)
 	return &controllerTestUtils
 }
 
+// newDescriptions Descriptions of the batches, with the Kubernetes jobs of the batches
+func newDescriptions(handler api.BatchHandler, jobs ...runtime.Object) *rerun.Descriptions {
+	return rerun.NewDescriptions(store.NewMemoryStore(), nil, handler, events.NewJobWatcher(kubefake.NewSimpleClientset(jobs...), serverModels.NewEnv()))
+}
+
+// newBatchJob A Kubernetes job in the batch, mounting the payload of the description with the index
+func newBatchJob(batchName, jobName string, index int) *batchv1.Job {
+	env := serverModels.NewEnv()
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      jobName,
+			Namespace: env.RadixDeploymentNamespace,
+			Labels:    map[string]string{kube.RadixAppLabel: env.RadixAppName, kube.RadixComponentLabel: env.RadixComponentName, kube.RadixBatchNameLabel: batchName},
+		},
+		Spec: batchv1.JobSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Volumes: []corev1.Volume{{
+			Name: "job-payload",
+			VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{
+				SecretName: batchName + "-payloads-0",
+				Items:      []corev1.KeyToPath{{Key: strconv.Itoa(index), Path: "payload"}},
+			}},
+		}}}}},
+	}
+}
+
 func TestGetBatches(t *testing.T) {
 	t.Run("Get batches - success", func(t *testing.T) {
 		t.Parallel()
@@ -89,6 +153,155 @@ func TestGetBatches(t *testing.T) {
 	})
 }
 
+func TestGetBatchesFilter(t *testing.T) {
+	batchStates := []modelsV1.BatchStatus{
+		{JobStatus: modelsV1.JobStatus{Name: "batch1", Status: "Succeeded"}, JobStatuses: []modelsV1.JobStatus{{Name: "job1", JobId: "id1"}}},
+		{JobStatus: modelsV1.JobStatus{Name: "batch2", Status: "Running"}, JobStatuses: []modelsV1.JobStatus{{Name: "job2", JobId: "id2"}}},
+	}
+
+	t.Run("job id - success", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		batchHandler := mock.NewMockBatchHandler(ctrl)
+		batchHandler.
+			EXPECT().
+			GetBatches().
+			Return(append([]modelsV1.BatchStatus{}, batchStates...), nil).
+			Times(1)
+
+		controllerTestUtils := setupTest(batchHandler)
+		responseChannel := controllerTestUtils.ExecuteRequest(http.MethodGet, "/api/v1/batches?jobId=id2&status=Running")
+		response := <-responseChannel
+		assert.NotNil(t, response)
+
+		if response != nil {
+			assert.Equal(t, http.StatusOK, response.StatusCode)
+			var returnedBatches []modelsV1.BatchStatus
+			test.GetResponseBody(response, &returnedBatches)
+			assert.Len(t, returnedBatches, 1)
+			assert.Equal(t, "batch2", returnedBatches[0].Name)
+		}
+	})
+
+	t.Run("invalid status - status code 400", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		batchHandler := mock.NewMockBatchHandler(ctrl)
+		batchHandler.
+			EXPECT().
+			GetBatches().
+			Times(0)
+
+		controllerTestUtils := setupTest(batchHandler)
+		responseChannel := controllerTestUtils.ExecuteRequest(http.MethodGet, "/api/v1/batches?status=Done")
+		response := <-responseChannel
+		assert.NotNil(t, response)
+
+		if response != nil {
+			assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+		}
+	})
+}
+
+func TestGetBatchesPagination(t *testing.T) {
+	created := time.Now()
+	batchStates := []modelsV1.BatchStatus{
+		{JobStatus: modelsV1.JobStatus{Name: "batch3", Created: commonUtils.FormatTimestamp(created.Add(2 * time.Minute))}},
+		{JobStatus: modelsV1.JobStatus{Name: "batch1", Created: commonUtils.FormatTimestamp(created)}},
+		{JobStatus: modelsV1.JobStatus{Name: "batch2", Created: commonUtils.FormatTimestamp(created.Add(1 * time.Minute))}},
+	}
+
+	t.Run("first and next page - success", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		batchHandler := mock.NewMockBatchHandler(ctrl)
+		batchHandler.
+			EXPECT().
+			GetBatches().
+			DoAndReturn(func() ([]modelsV1.BatchStatus, error) {
+				return append([]modelsV1.BatchStatus{}, batchStates...), nil
+			}).
+			Times(2)
+
+		controllerTestUtils := setupTest(batchHandler)
+		response := <-controllerTestUtils.ExecuteRequest(http.MethodGet, "/api/v1/batches?limit=2")
+		assert.NotNil(t, response)
+
+		if response != nil {
+			assert.Equal(t, http.StatusOK, response.StatusCode)
+			var returnedBatches []modelsV1.BatchStatus
+			test.GetResponseBody(response, &returnedBatches)
+			assert.Len(t, returnedBatches, 2)
+			assert.Equal(t, "batch1", returnedBatches[0].Name)
+			assert.Equal(t, "batch2", returnedBatches[1].Name)
+			assert.Equal(t, "3", response.Header.Get("X-Total-Count"))
+
+			nextLink := response.Header.Get("Link")
+			assert.Contains(t, nextLink, `rel="next"`)
+			nextPath := nextLink[strings.Index(nextLink, "<")+1 : strings.Index(nextLink, ">")]
+			response = <-controllerTestUtils.ExecuteRequest(http.MethodGet, nextPath)
+			assert.NotNil(t, response)
+		}
+
+		if response != nil {
+			assert.Equal(t, http.StatusOK, response.StatusCode)
+			var returnedBatches []modelsV1.BatchStatus
+			test.GetResponseBody(response, &returnedBatches)
+			assert.Len(t, returnedBatches, 1)
+			assert.Equal(t, "batch3", returnedBatches[0].Name)
+			assert.Equal(t, "3", response.Header.Get("X-Total-Count"))
+			assert.Empty(t, response.Header.Get("Link"))
+		}
+	})
+
+	t.Run("invalid limit - status code 400", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		batchHandler := mock.NewMockBatchHandler(ctrl)
+		batchHandler.
+			EXPECT().
+			GetBatches().
+			Times(0)
+
+		controllerTestUtils := setupTest(batchHandler)
+		responseChannel := controllerTestUtils.ExecuteRequest(http.MethodGet, "/api/v1/batches?limit=1001")
+		response := <-responseChannel
+		assert.NotNil(t, response)
+
+		if response != nil {
+			assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+			var returnedStatus models.Status
+			test.GetResponseBody(response, &returnedStatus)
+			assert.Equal(t, http.StatusBadRequest, returnedStatus.Code)
+			assert.Equal(t, models.StatusFailure, returnedStatus.Status)
+		}
+	})
+
+	t.Run("invalid continuation token - status code 400", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		batchHandler := mock.NewMockBatchHandler(ctrl)
+		batchHandler.
+			EXPECT().
+			GetBatches().
+			Times(0)
+
+		controllerTestUtils := setupTest(batchHandler)
+		responseChannel := controllerTestUtils.ExecuteRequest(http.MethodGet, "/api/v1/batches?limit=2&continue=invalid")
+		response := <-responseChannel
+		assert.NotNil(t, response)
+
+		if response != nil {
+			assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+		}
+	})
+}
+
 func TestGetBatch(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		t.Parallel()
@@ -182,19 +395,112 @@ func TestGetBatch(t *testing.T) {
 }
 
 func TestCreateBatch(t *testing.T) {
-	t.Run("empty body - successful", func(t *testing.T) {
+	t.Run("empty body - unprocessable", func(t *testing.T) {
 		t.Parallel()
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
-		batchScheduleDescription := models.BatchScheduleDescription{}
-		createdBatch := modelsV1.BatchStatus{
-			JobStatus: modelsV1.JobStatus{
-				Name:    "newbatch",
-				Started: commonUtils.FormatTimestamp(time.Now()),
-				Ended:   commonUtils.FormatTimestamp(time.Now().Add(1 * time.Minute)),
-				Status:  "batchstatus",
+		batchHandler := mock.NewMockBatchHandler(ctrl)
+		batchHandler.
+			EXPECT().
+			CreateBatch(gomock.Any()).
+			Times(0)
+		controllerTestUtils := setupTest(batchHandler)
+		responseChannel := controllerTestUtils.ExecuteRequestWithBody(http.MethodPost, "/api/v1/batches", nil)
+		response := <-responseChannel
+		assert.NotNil(t, response)
+
+		if response != nil {
+			assert.Equal(t, http.StatusUnprocessableEntity, response.StatusCode)
+			var returnedStatus serverModels.Status
+			test.GetResponseBody(response, &returnedStatus)
+			assert.Equal(t, models.StatusReasonInvalid, returnedStatus.Reason)
+			assert.Equal(t, []serverModels.StatusCause{{Field: "jobScheduleDescriptions", Message: "must have at least one job"}}, returnedStatus.Causes)
+		}
+	})
+
+	t.Run("invalid fields - unprocessable with all causes", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		batchHandler := mock.NewMockBatchHandler(ctrl)
+		batchHandler.
+			EXPECT().
+			CreateBatch(gomock.Any()).
+			Times(0)
+		controllerTestUtils := setupTest(batchHandler)
+		timeLimitSeconds := int64(-1)
+		batchScheduleDescription := models.BatchScheduleDescription{
+			JobScheduleDescriptions: []models.JobScheduleDescription{
+				{Payload: "a_payload"},
+				{
+					RadixJobComponentConfig: models.RadixJobComponentConfig{
+						TimeLimitSeconds: &timeLimitSeconds,
+						Resources: &v1.ResourceRequirements{
+							Requests: v1.ResourceList{"cpu": "a lot", "memory": "256M"},
+						},
+					},
+				},
+			},
+		}
+		responseChannel := controllerTestUtils.ExecuteRequestWithBody(http.MethodPost, "/api/v1/batches", batchScheduleDescription)
+		response := <-responseChannel
+		assert.NotNil(t, response)
+
+		if response != nil {
+			assert.Equal(t, http.StatusUnprocessableEntity, response.StatusCode)
+			var returnedStatus serverModels.Status
+			test.GetResponseBody(response, &returnedStatus)
+			assert.Equal(t, models.StatusReasonInvalid, returnedStatus.Reason)
+			assert.Equal(t, []string{
+				"jobScheduleDescriptions[1].timeLimitSeconds",
+				"jobScheduleDescriptions[1].resources.requests.cpu",
+			}, getCauseFields(returnedStatus.Causes))
+		}
+	})
+
+	t.Run("payloads not matching the schema - unprocessable by job index", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		batchHandler := mock.NewMockBatchHandler(ctrl)
+		batchHandler.
+			EXPECT().
+			CreateBatch(gomock.Any()).
+			Times(0)
+		payloadSchema, err := validation.NewPayloadSchema("", `{"type": "object", "required": ["name"]}`)
+		assert.NoError(t, err)
+		controllerTestUtils := setupTestWith(batchHandler, payloadSchema, newDescriptions(batchHandler))
+		batchScheduleDescription := models.BatchScheduleDescription{
+			JobScheduleDescriptions: []models.JobScheduleDescription{
+				{Payload: `{"name": "a"}`},
+				{Payload: `{"size": 1}`},
+				{Payload: `not json`},
 			},
 		}
+		responseChannel := controllerTestUtils.ExecuteRequestWithBody(http.MethodPost, "/api/v1/batches", batchScheduleDescription)
+		response := <-responseChannel
+		assert.NotNil(t, response)
+
+		if response != nil {
+			assert.Equal(t, http.StatusUnprocessableEntity, response.StatusCode)
+			var returnedStatus serverModels.Status
+			test.GetResponseBody(response, &returnedStatus)
+			assert.Equal(t, models.StatusReasonInvalid, returnedStatus.Reason)
+			assert.Equal(t, []string{
+				"jobScheduleDescriptions[1].payload",
+				"jobScheduleDescriptions[2].payload",
+			}, getCauseFields(returnedStatus.Causes))
+		}
+	})
+
+	t.Run("repeated request with idempotency key - original batch", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		batchScheduleDescription := models.BatchScheduleDescription{
+			JobScheduleDescriptions: []models.JobScheduleDescription{{Payload: "a_payload"}},
+		}
+		createdBatch := modelsV1.BatchStatus{JobStatus: modelsV1.JobStatus{Name: "newbatch", Status: "Waiting"}}
 		batchHandler := mock.NewMockBatchHandler(ctrl)
 		batchHandler.
 			EXPECT().
@@ -207,18 +513,18 @@ func TestCreateBatch(t *testing.T) {
 			Return(nil).
 			Times(1)
 		controllerTestUtils := setupTest(batchHandler)
-		responseChannel := controllerTestUtils.ExecuteRequestWithBody(http.MethodPost, "/api/v1/batches", nil)
-		response := <-responseChannel
-		assert.NotNil(t, response)
+		header := http.Header{idempotency.KeyHeader: []string{"key1"}}
 
-		if response != nil {
-			assert.Equal(t, http.StatusOK, response.StatusCode)
-			var returnedBatch modelsV1.BatchStatus
-			test.GetResponseBody(response, &returnedBatch)
-			assert.Equal(t, createdBatch.Name, returnedBatch.Name)
-			assert.Equal(t, createdBatch.Started, returnedBatch.Started)
-			assert.Equal(t, createdBatch.Ended, returnedBatch.Ended)
-			assert.Equal(t, createdBatch.Status, returnedBatch.Status)
+		for i, replayed := range []string{"", "true"} {
+			response := <-controllerTestUtils.ExecuteRequestWithHeader(http.MethodPost, "/api/v1/batches", batchScheduleDescription, header)
+			assert.NotNil(t, response)
+			if response != nil {
+				assert.Equal(t, http.StatusOK, response.StatusCode, "request %d", i)
+				assert.Equal(t, replayed, response.Header.Get(idempotency.ReplayedHeader), "request %d", i)
+				var returnedBatch modelsV1.BatchStatus
+				test.GetResponseBody(response, &returnedBatch)
+				assert.Equal(t, createdBatch, returnedBatch)
+			}
 		}
 	})
 
@@ -349,12 +655,12 @@ func TestCreateBatch(t *testing.T) {
 
 		if response != nil {
 			assert.Equal(t, http.StatusUnprocessableEntity, response.StatusCode)
-			var returnedStatus models.Status
+			var returnedStatus serverModels.Status
 			test.GetResponseBody(response, &returnedStatus)
 			assert.Equal(t, http.StatusUnprocessableEntity, returnedStatus.Code)
-			assert.Equal(t, models.StatusFailure, returnedStatus.Status)
+			assert.Equal(t, models.StatusFailure, returnedStatus.Status.Status)
 			assert.Equal(t, models.StatusReasonInvalid, returnedStatus.Reason)
-			assert.Equal(t, apiErrors.InvalidMessage("BatchScheduleDescription", ""), returnedStatus.Message)
+			assert.Equal(t, []serverModels.StatusCause{{Field: "jobScheduleDescriptions", Message: "must be an array, not object"}}, returnedStatus.Causes)
 		}
 	})
 
@@ -362,7 +668,9 @@ func TestCreateBatch(t *testing.T) {
 		t.Parallel()
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
-		batchScheduleDescription := models.BatchScheduleDescription{}
+		batchScheduleDescription := models.BatchScheduleDescription{
+			JobScheduleDescriptions: []models.JobScheduleDescription{{Payload: "a_payload"}},
+		}
 		batchHandler := mock.NewMockBatchHandler(ctrl)
 		anyKind, anyName := "anyKind", "anyName"
 		batchHandler.
@@ -375,7 +683,7 @@ func TestCreateBatch(t *testing.T) {
 			MaintainHistoryLimit().
 			Times(0)
 		controllerTestUtils := setupTest(batchHandler)
-		responseChannel := controllerTestUtils.ExecuteRequest(http.MethodPost, "/api/v1/batches")
+		responseChannel := controllerTestUtils.ExecuteRequestWithBody(http.MethodPost, "/api/v1/batches", batchScheduleDescription)
 		response := <-responseChannel
 		assert.NotNil(t, response)
 
@@ -394,7 +702,9 @@ func TestCreateBatch(t *testing.T) {
 		t.Parallel()
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
-		batchScheduleDescription := models.BatchScheduleDescription{}
+		batchScheduleDescription := models.BatchScheduleDescription{
+			JobScheduleDescriptions: []models.JobScheduleDescription{{Payload: "a_payload"}},
+		}
 		batchHandler := mock.NewMockBatchHandler(ctrl)
 		batchHandler.
 			EXPECT().
@@ -406,7 +716,7 @@ func TestCreateBatch(t *testing.T) {
 			MaintainHistoryLimit().
 			Times(0)
 		controllerTestUtils := setupTest(batchHandler)
-		responseChannel := controllerTestUtils.ExecuteRequest(http.MethodPost, "/api/v1/batches")
+		responseChannel := controllerTestUtils.ExecuteRequestWithBody(http.MethodPost, "/api/v1/batches", batchScheduleDescription)
 		response := <-responseChannel
 		assert.NotNil(t, response)
 
@@ -502,6 +812,57 @@ func TestDeleteBatch(t *testing.T) {
 	})
 }
 
+func TestStopBatches(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	batchHandler := mock.NewMockBatchHandler(ctrl)
+	batchHandler.EXPECT().GetBatches().Return([]modelsV1.BatchStatus{
+		{JobStatus: modelsV1.JobStatus{Name: "batch1", Status: "Running", Created: "2022-10-01T12:00:00Z"}},
+		{JobStatus: modelsV1.JobStatus{Name: "batch2", Status: "Running", Created: "2022-10-01T13:00:00Z"}},
+	}, nil).Times(1)
+	batchHandler.EXPECT().StopBatch("batch1").Return(nil).Times(1)
+	controllerTestUtils := setupTest(batchHandler)
+	responseChannel := controllerTestUtils.ExecuteRequestWithBody(http.MethodPost, "/api/v1/batches/stop", serverModels.Selector{CreatedBefore: "2022-10-01T12:30:00Z"})
+	response := <-responseChannel
+	assert.NotNil(t, response)
+
+	if response != nil {
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		var report serverModels.BulkReport
+		test.GetResponseBody(response, &report)
+		assert.Equal(t, 1, report.Succeeded)
+		assert.Equal(t, 0, report.Failed)
+		assert.Equal(t, "batch1", report.Results[0].Name)
+	}
+}
+
+func TestDeleteBatches(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	batchHandler := mock.NewMockBatchHandler(ctrl)
+	batchHandler.EXPECT().GetBatches().Return([]modelsV1.BatchStatus{
+		{JobStatus: modelsV1.JobStatus{Name: "batch1", Status: "Failed"}},
+		{JobStatus: modelsV1.JobStatus{Name: "batch2", Status: "Succeeded"}},
+		{JobStatus: modelsV1.JobStatus{Name: "batch3", Status: "Failed"}},
+	}, nil).Times(1)
+	batchHandler.EXPECT().DeleteBatch("batch1").Return(nil).Times(1)
+	batchHandler.EXPECT().DeleteBatch("batch3").Return(nil).Times(1)
+	controllerTestUtils := setupTest(batchHandler)
+	responseChannel := controllerTestUtils.ExecuteRequestWithBody(http.MethodDelete, "/api/v1/batches", serverModels.Selector{Status: []string{"Failed"}})
+	response := <-responseChannel
+	assert.NotNil(t, response)
+
+	if response != nil {
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		var report serverModels.BulkReport
+		test.GetResponseBody(response, &report)
+		assert.Equal(t, 2, report.Succeeded)
+		assert.Len(t, report.Results, 2)
+	}
+}
+
 func TestStopBatch(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		t.Parallel()
@@ -675,6 +1036,233 @@ func TestStopBatchJob(t *testing.T) {
 	})
 }
 
+func TestRerunBatchJob(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	batchHandler := mock.NewMockBatchHandler(ctrl)
+	descriptions := newDescriptions(batchHandler, newBatchJob("batch1", "batch1-job1", 0), newBatchJob("batch1", "batch1-job2", 1))
+	defaultTimeLimitSeconds := int64(60)
+	assert.NoError(t, descriptions.PutBatch("batch1", &models.BatchScheduleDescription{
+		JobScheduleDescriptions:        []models.JobScheduleDescription{{Payload: "payload1"}, {Payload: "payload2"}},
+		DefaultRadixJobComponentConfig: &models.RadixJobComponentConfig{TimeLimitSeconds: &defaultTimeLimitSeconds},
+	}, nil))
+	batchHandler.EXPECT().GetBatch("batch1").Return(&modelsV1.BatchStatus{
+		JobStatus: modelsV1.JobStatus{Name: "batch1"},
+		JobStatuses: []modelsV1.JobStatus{
+			{Name: "batch1-job2", Created: "2022-10-01T12:00:01Z"},
+			{Name: "batch1-job1", Created: "2022-10-01T12:00:00Z"},
+		},
+	}, nil).Times(1)
+	var rerunRequest *serverModels.JobScheduleRequest
+	descriptions.SetJobRerunner(func(ctx context.Context, jobScheduleRequest *serverModels.JobScheduleRequest, rerunOf string) (*serverModels.QueuedJobStatus, error) {
+		assert.Equal(t, "batch1-job2", rerunOf)
+		rerunRequest = jobScheduleRequest
+		return &serverModels.QueuedJobStatus{JobStatus: modelsV1.JobStatus{Name: "newjob"}}, nil
+	})
+	controllerTestUtils := setupTestWith(batchHandler, nil, descriptions)
+	responseChannel := controllerTestUtils.ExecuteRequest(http.MethodPost, "/api/v1/batches/batch1/jobs/batch1-job2/rerun")
+	response := <-responseChannel
+	assert.NotNil(t, response)
+
+	if response != nil {
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		var returnedJob serverModels.RerunJobStatus
+		test.GetResponseBody(response, &returnedJob)
+		assert.Equal(t, "newjob", returnedJob.Name)
+		assert.Equal(t, "batch1-job2", returnedJob.RerunOf)
+		assert.Equal(t, "batch1", returnedJob.RerunOfBatch)
+	}
+	if assert.NotNil(t, rerunRequest) {
+		assert.Equal(t, models.JobScheduleDescription{Payload: "payload2", RadixJobComponentConfig: models.RadixJobComponentConfig{TimeLimitSeconds: &defaultTimeLimitSeconds}}, rerunRequest.JobScheduleDescription)
+	}
+}
+
+func TestRetryFailedBatchJobs(t *testing.T) {
+	batch := modelsV1.BatchStatus{
+		JobStatus: modelsV1.JobStatus{Name: "batch1", Status: "Failed"},
+		JobStatuses: []modelsV1.JobStatus{
+			{Name: "batch1-job1", Created: "2022-10-01T12:00:00Z", Status: "Succeeded"},
+			{Name: "batch1-job2", Created: "2022-10-01T12:00:01Z", Status: "Failed"},
+			{Name: "batch1-job3", Created: "2022-10-01T12:00:02Z", Status: "Failed"},
+		},
+	}
+	timeLimitSeconds := int64(60)
+	batchDescription := models.BatchScheduleDescription{
+		JobScheduleDescriptions:        []models.JobScheduleDescription{{Payload: "payload1"}, {Payload: "payload2"}, {Payload: "payload3"}},
+		DefaultRadixJobComponentConfig: &models.RadixJobComponentConfig{TimeLimitSeconds: &timeLimitSeconds},
+	}
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		batchHandler := mock.NewMockBatchHandler(ctrl)
+		descriptions := newDescriptions(batchHandler,
+			newBatchJob("batch1", "batch1-job1", 0), newBatchJob("batch1", "batch1-job2", 1), newBatchJob("batch1", "batch1-job3", 2),
+			newBatchJob("batch2", "batch2-job1", 0), newBatchJob("batch2", "batch2-job2", 1))
+		assert.NoError(t, descriptions.PutBatch("batch1", &batchDescription, nil))
+		batchHandler.EXPECT().GetBatch("batch1").Return(&batch, nil).Times(1)
+		batchHandler.EXPECT().
+			CreateBatch(&models.BatchScheduleDescription{
+				JobScheduleDescriptions:        []models.JobScheduleDescription{{Payload: "payload2"}, {Payload: "payload3"}},
+				DefaultRadixJobComponentConfig: &models.RadixJobComponentConfig{TimeLimitSeconds: &timeLimitSeconds},
+			}).
+			Return(&modelsV1.BatchStatus{JobStatus: modelsV1.JobStatus{Name: "batch2"}}, nil).
+			Times(1)
+		batchHandler.EXPECT().MaintainHistoryLimit().Return(nil).Times(1)
+		controllerTestUtils := setupTestWith(batchHandler, nil, descriptions)
+		responseChannel := controllerTestUtils.ExecuteRequest(http.MethodPost, "/api/v1/batches/batch1/retry-failed")
+		response := <-responseChannel
+		assert.NotNil(t, response)
+
+		if response != nil {
+			assert.Equal(t, http.StatusOK, response.StatusCode)
+			var returnedBatch serverModels.RetryFailedBatchStatus
+			test.GetResponseBody(response, &returnedBatch)
+			assert.Equal(t, "batch2", returnedBatch.Name)
+			assert.Equal(t, "batch1", returnedBatch.RetryOf)
+			assert.Empty(t, returnedBatch.JobNames)
+		}
+
+		// The failed jobs are mapped to the jobs of the new batch when it is read
+		batchHandler.EXPECT().GetBatch("batch2").Return(&modelsV1.BatchStatus{
+			JobStatus: modelsV1.JobStatus{Name: "batch2"},
+			JobStatuses: []modelsV1.JobStatus{
+				{Name: "batch2-job2", Created: "2022-10-01T13:00:00Z"},
+				{Name: "batch2-job1", Created: "2022-10-01T13:00:00Z"},
+			},
+		}, nil).Times(1)
+		responseChannel = controllerTestUtils.ExecuteRequest(http.MethodGet, "/api/v1/batches/batch2")
+		response = <-responseChannel
+		assert.NotNil(t, response)
+
+		if response != nil {
+			assert.Equal(t, http.StatusOK, response.StatusCode)
+			var returnedBatch serverModels.RetryFailedBatchStatus
+			test.GetResponseBody(response, &returnedBatch)
+			assert.Equal(t, "batch1", returnedBatch.RetryOf)
+			assert.Equal(t, map[string]string{"batch1-job2": "batch2-job1", "batch1-job3": "batch2-job2"}, returnedBatch.JobNames)
+		}
+	})
+
+	t.Run("no failed jobs - unprocessable", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		batchHandler := mock.NewMockBatchHandler(ctrl)
+		descriptions := newDescriptions(batchHandler)
+		assert.NoError(t, descriptions.PutBatch("batch1", &batchDescription, nil))
+		succeededBatch := modelsV1.BatchStatus{JobStatus: batch.JobStatus, JobStatuses: batch.JobStatuses[:1]}
+		batchHandler.EXPECT().GetBatch("batch1").Return(&succeededBatch, nil).Times(1)
+		batchHandler.EXPECT().CreateBatch(gomock.Any()).Times(0)
+		controllerTestUtils := setupTestWith(batchHandler, nil, descriptions)
+		responseChannel := controllerTestUtils.ExecuteRequest(http.MethodPost, "/api/v1/batches/batch1/retry-failed")
+		response := <-responseChannel
+		assert.NotNil(t, response)
+
+		if response != nil {
+			assert.Equal(t, http.StatusUnprocessableEntity, response.StatusCode)
+		}
+	})
+
+	t.Run("unknown description - 404 not found", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		batchHandler := mock.NewMockBatchHandler(ctrl)
+		batchHandler.EXPECT().GetBatch("batch1").Return(&batch, nil).Times(1)
+		batchHandler.EXPECT().CreateBatch(gomock.Any()).Times(0)
+		controllerTestUtils := setupTest(batchHandler)
+		responseChannel := controllerTestUtils.ExecuteRequest(http.MethodPost, "/api/v1/batches/batch1/retry-failed")
+		response := <-responseChannel
+		assert.NotNil(t, response)
+
+		if response != nil {
+			assert.Equal(t, http.StatusNotFound, response.StatusCode)
+		}
+	})
+}
+
+func TestGetBatchJobs(t *testing.T) {
+	created := time.Date(2022, 11, 1, 12, 0, 0, 0, time.UTC)
+	batchState := modelsV1.BatchStatus{
+		JobStatus: modelsV1.JobStatus{Name: "batch-name1", Status: "Running"},
+		JobStatuses: []modelsV1.JobStatus{
+			{Name: "job2", JobId: "id2", Status: "Failed", Created: commonUtils.FormatTimestamp(created.Add(time.Minute))},
+			{Name: "job1", JobId: "id1", Status: "Succeeded", Created: commonUtils.FormatTimestamp(created)},
+			{Name: "job3", JobId: "id3", Status: "Running", Created: commonUtils.FormatTimestamp(created.Add(2 * time.Minute))},
+		},
+	}
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		batchHandler := mock.NewMockBatchHandler(ctrl)
+		batchHandler.
+			EXPECT().
+			GetBatch(batchState.Name).
+			Return(&batchState, nil).
+			Times(1)
+
+		controllerTestUtils := setupTest(batchHandler)
+		responseChannel := controllerTestUtils.ExecuteRequest(http.MethodGet, fmt.Sprintf("/api/v1/batches/%s/jobs?status=Failed,Running&limit=1", batchState.Name))
+		response := <-responseChannel
+		assert.NotNil(t, response)
+
+		if response != nil {
+			assert.Equal(t, http.StatusOK, response.StatusCode)
+			var returnedJobs []modelsV1.JobStatus
+			test.GetResponseBody(response, &returnedJobs)
+			assert.Len(t, returnedJobs, 1)
+			assert.Equal(t, "job2", returnedJobs[0].Name)
+			assert.Equal(t, "2", response.Header.Get("X-Total-Count"))
+		}
+	})
+
+	t.Run("invalid filter - status code 400", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		batchHandler := mock.NewMockBatchHandler(ctrl)
+		batchHandler.
+			EXPECT().
+			GetBatch(gomock.Any()).
+			Times(0)
+
+		controllerTestUtils := setupTest(batchHandler)
+		responseChannel := controllerTestUtils.ExecuteRequest(http.MethodGet, fmt.Sprintf("/api/v1/batches/%s/jobs?createdBefore=yesterday", batchState.Name))
+		response := <-responseChannel
+		assert.NotNil(t, response)
+
+		if response != nil {
+			assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		batchHandler := mock.NewMockBatchHandler(ctrl)
+		batchHandler.
+			EXPECT().
+			GetBatch(gomock.Any()).
+			Return(nil, apiErrors.NewNotFound("batch", "anybatch")).
+			Times(1)
+
+		controllerTestUtils := setupTest(batchHandler)
+		responseChannel := controllerTestUtils.ExecuteRequest(http.MethodGet, "/api/v1/batches/anybatch/jobs")
+		response := <-responseChannel
+		assert.NotNil(t, response)
+
+		if response != nil {
+			assert.Equal(t, http.StatusNotFound, response.StatusCode)
+		}
+	})
+}
+
 func TestGetBatchJob(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		t.Parallel()
@@ -765,3 +1353,98 @@ func TestGetBatchJob(t *testing.T) {
 		}
 	})
 }
+
+func TestGetBatchEvents(t *testing.T) {
+	t.Run("not found", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		batchHandler := mock.NewMockBatchHandler(ctrl)
+		batchHandler.
+			EXPECT().
+			GetBatch("anybatch").
+			Return(nil, apiErrors.NewNotFound("batch", "anybatch")).
+			Times(1)
+
+		controllerTestUtils := setupTest(batchHandler)
+		responseChannel := controllerTestUtils.ExecuteRequest(http.MethodGet, "/api/v1/batches/anybatch/events")
+		response := <-responseChannel
+		assert.NotNil(t, response)
+
+		if response != nil {
+			assert.Equal(t, http.StatusNotFound, response.StatusCode)
+			var returnedStatus models.Status
+			test.GetResponseBody(response, &returnedStatus)
+			assert.Equal(t, models.StatusReasonNotFound, returnedStatus.Reason)
+		}
+	})
+
+	t.Run("completed batch - stream ends", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		batchHandler := mock.NewMockBatchHandler(ctrl)
+		batchHandler.
+			EXPECT().
+			GetBatch("batch1").
+			Return(&modelsV1.BatchStatus{
+				JobStatus:   modelsV1.JobStatus{Name: "batch1", Status: "Succeeded"},
+				JobStatuses: []modelsV1.JobStatus{{Name: "job1", Status: "Succeeded"}},
+			}, nil).
+			Times(1)
+
+		controllerTestUtils := setupTest(batchHandler)
+		responseChannel := controllerTestUtils.ExecuteRequest(http.MethodGet, "/api/v1/batches/batch1/events")
+		response := <-responseChannel
+		assert.NotNil(t, response)
+
+		if response != nil {
+			assert.Equal(t, http.StatusOK, response.StatusCode)
+			assert.Equal(t, "text/event-stream", response.Header.Get("Content-Type"))
+			body, _ := io.ReadAll(response.Body)
+			assert.Contains(t, string(body), "event: progress\ndata: {\"batchName\":\"batch1\",\"total\":1,\"counts\":{\"Succeeded\":1},\"completed\":1,\"percentComplete\":100}\n\n")
+			assert.True(t, strings.HasSuffix(string(body), "event: end\ndata: {}\n\n"))
+		}
+	})
+}
+
+func getCauseFields(causes []serverModels.StatusCause) []string {
+	var fields []string
+	for _, cause := range causes {
+		fields = append(fields, cause.Field)
+	}
+	return fields
+}
+
+func TestScheduledBatch(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	batchHandler := mock.NewMockBatchHandler(ctrl)
+	batchHandler.EXPECT().CreateBatch(gomock.Any()).Times(0)
+	batchHandler.EXPECT().GetBatches().Return([]modelsV1.BatchStatus{}, nil).Times(1)
+	batchHandler.EXPECT().StopBatch(gomock.Any()).Times(0)
+	controllerTestUtils := setupTest(batchHandler)
+	request := serverModels.BatchScheduleRequest{
+		BatchScheduleDescription: models.BatchScheduleDescription{JobScheduleDescriptions: []models.JobScheduleDescription{{Payload: "payload"}}},
+		NotBefore:                commonUtils.FormatTimestamp(time.Now().Add(time.Hour)),
+	}
+
+	response := <-controllerTestUtils.ExecuteRequestWithBody(http.MethodPost, "/api/v1/batches", request)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	var scheduled modelsV1.BatchStatus
+	test.GetResponseBody(response, &scheduled)
+	assert.Equal(t, pending.StatusScheduled, scheduled.Status)
+
+	response = <-controllerTestUtils.ExecuteRequest(http.MethodGet, "/api/v1/batches")
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	var batches []modelsV1.BatchStatus
+	test.GetResponseBody(response, &batches)
+	assert.Equal(t, []modelsV1.BatchStatus{scheduled}, batches)
+
+	response = <-controllerTestUtils.ExecuteRequest(http.MethodPost, fmt.Sprintf("/api/v1/batches/%s/stop", scheduled.Name))
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	batchHandler.EXPECT().GetBatch(scheduled.Name).Return(nil, apiErrors.NewNotFound("batch", scheduled.Name)).Times(1)
+	response = <-controllerTestUtils.ExecuteRequest(http.MethodGet, fmt.Sprintf("/api/v1/batches/%s", scheduled.Name))
+	assert.Equal(t, http.StatusNotFound, response.StatusCode)
+}
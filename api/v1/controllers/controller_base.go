@@ -3,6 +3,8 @@ package controllers
 import (
 	"net/http"
 
+	serverErrors "github.com/equinor/radix-job-scheduler-server/api/errors"
+	serverModels "github.com/equinor/radix-job-scheduler-server/models"
 	"github.com/equinor/radix-job-scheduler-server/utils"
 	apiErrors "github.com/equinor/radix-job-scheduler/api/errors"
 	models "github.com/equinor/radix-job-scheduler/models/common"
@@ -12,16 +14,27 @@ import (
 type ControllerBase struct {
 }
 
-func (controller *ControllerBase) HandleError(w http.ResponseWriter, err error) {
+func (controller *ControllerBase) HandleError(w http.ResponseWriter, r *http.Request, err error) {
 	var status *models.Status
+	var causes []serverModels.StatusCause
 
 	switch t := err.(type) {
+	case *serverErrors.StatusError:
+		status, causes = t.Status(), t.Causes
 	case apiErrors.APIStatus:
 		status = t.Status()
 	default:
 		status = apiErrors.NewFromError(err).Status()
 	}
 
-	log.Errorf("failed: %v", err)
-	utils.StatusResponse(w, status)
+	logger := log.WithContext(r.Context()).WithFields(log.Fields{
+		"code":   status.Code,
+		"reason": status.Reason,
+	}).WithError(err)
+	if status.Code >= http.StatusInternalServerError {
+		logger.Error("request failed")
+	} else {
+		logger.Warn("request failed")
+	}
+	utils.StatusResponse(w, r, status, causes...)
 }
@@ -1,33 +1,78 @@
 package jobs
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 
+	serverErrors "github.com/equinor/radix-job-scheduler-server/api/errors"
 	"github.com/equinor/radix-job-scheduler-server/api/v1/controllers"
+	"github.com/equinor/radix-job-scheduler-server/bulk"
+	"github.com/equinor/radix-job-scheduler-server/events"
+	"github.com/equinor/radix-job-scheduler-server/idempotency"
+	"github.com/equinor/radix-job-scheduler-server/logs"
+	"github.com/equinor/radix-job-scheduler-server/metrics"
 	"github.com/equinor/radix-job-scheduler-server/models"
+	"github.com/equinor/radix-job-scheduler-server/pending"
+	"github.com/equinor/radix-job-scheduler-server/rerun"
+	"github.com/equinor/radix-job-scheduler-server/schedules"
+	"github.com/equinor/radix-job-scheduler-server/tracing"
 	"github.com/equinor/radix-job-scheduler-server/utils"
-	apiErrors "github.com/equinor/radix-job-scheduler/api/errors"
+	"github.com/equinor/radix-job-scheduler-server/validation"
+	"github.com/equinor/radix-job-scheduler-server/webhooks"
 	jobApi "github.com/equinor/radix-job-scheduler/api/v1/jobs"
 	apiModels "github.com/equinor/radix-job-scheduler/models/common"
+	modelsV1 "github.com/equinor/radix-job-scheduler/models/v1"
+	"github.com/equinor/radix-operator/pkg/apis/kube"
 	"github.com/gorilla/mux"
 	log "github.com/sirupsen/logrus"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
-const jobNameParam = "jobName"
+const (
+	jobNameParam = "jobName"
+)
 
 type jobController struct {
 	*controllers.ControllerBase
-	handler jobApi.JobHandler
+	handler         jobApi.JobHandler
+	jobWatcher      *events.JobWatcher
+	logReader       *logs.Reader
+	notifier        *webhooks.Notifier
+	idempotencyKeys *idempotency.Keys
+	payloadSchema   *validation.PayloadSchema
+	descriptions    *rerun.Descriptions
+	bulkRunner      *bulk.Runner
+	pendingItems    *pending.Items
 }
 
-// New create a new job controller
-func New(handler jobApi.JobHandler) models.Controller {
-	return &jobController{
-		handler: handler,
+// New create a new job controller. The batch handler gets the jobs in batches to re-run, and the active jobs of batches.
+// The controller creates the pending jobs when they are released from the queue, and the jobs of the job schedules.
+// It counts the active jobs for the queue
+func New(handler jobApi.JobHandler, jobWatcher *events.JobWatcher, logReader *logs.Reader, notifier *webhooks.Notifier, idempotencyKeys *idempotency.Keys, payloadSchema *validation.PayloadSchema, descriptions *rerun.Descriptions, bulkRunner *bulk.Runner, pendingItems *pending.Items, jobSchedules *schedules.Schedules) models.Controller {
+	controller := &jobController{
+		handler:         handler,
+		jobWatcher:      jobWatcher,
+		logReader:       logReader,
+		notifier:        notifier,
+		idempotencyKeys: idempotencyKeys,
+		payloadSchema:   payloadSchema,
+		descriptions:    descriptions,
+		bulkRunner:      bulkRunner,
+		pendingItems:    pendingItems,
 	}
+	pendingItems.SetCreator(pending.KindJob, controller.createPendingJob)
+	pendingItems.SetActiveCounter(controller.countActiveJobs)
+	descriptions.SetJobRerunner(controller.rerunJob)
+	jobSchedules.SetRunner(schedules.KindJob, &schedules.Runner{
+		Create:   controller.createScheduledJob,
+		IsActive: controller.isJobActive,
+		Stop:     controller.stopJob,
+	})
+	return controller
 }
 
 // GetRoutes List the supported routes of this controller
@@ -36,28 +81,75 @@ func (controller *jobController) GetRoutes() models.Routes {
 		models.Route{
 			Path:        "/jobs",
 			Method:      http.MethodPost,
+			Permission:  models.PermissionMutate,
 			HandlerFunc: controller.CreateJob,
 		},
 		models.Route{
 			Path:        "/jobs",
 			Method:      http.MethodGet,
+			Permission:  models.PermissionRead,
 			HandlerFunc: controller.GetJobs,
 		},
+		models.Route{
+			Path:        "/jobs",
+			Method:      http.MethodDelete,
+			Permission:  models.PermissionMutate,
+			HandlerFunc: controller.DeleteJobs,
+		},
+		models.Route{
+			Path:        "/jobs/stop",
+			Method:      http.MethodPost,
+			Permission:  models.PermissionMutate,
+			HandlerFunc: controller.StopJobs,
+		},
+		models.Route{
+			Path:        "/jobs/events",
+			Method:      http.MethodGet,
+			Permission:  models.PermissionRead,
+			HandlerFunc: controller.GetJobsEvents,
+		},
 		models.Route{
 			Path:        fmt.Sprintf("/jobs/{%s}", jobNameParam),
 			Method:      http.MethodGet,
+			Permission:  models.PermissionRead,
 			HandlerFunc: controller.GetJob,
 		},
+		models.Route{
+			Path:        fmt.Sprintf("/jobs/{%s}/events", jobNameParam),
+			Method:      http.MethodGet,
+			Permission:  models.PermissionRead,
+			HandlerFunc: controller.GetJobEvents,
+		},
+		models.Route{
+			Path:        fmt.Sprintf("/jobs/{%s}/logs", jobNameParam),
+			Method:      http.MethodGet,
+			Permission:  models.PermissionRead,
+			HandlerFunc: controller.GetJobLogs,
+		},
 		models.Route{
 			Path:        fmt.Sprintf("/jobs/{%s}", jobNameParam),
 			Method:      http.MethodDelete,
+			Permission:  models.PermissionMutate,
 			HandlerFunc: controller.DeleteJob,
 		},
+		models.Route{
+			Path:        fmt.Sprintf("/jobs/{%s}", jobNameParam),
+			Method:      http.MethodPatch,
+			Permission:  models.PermissionMutate,
+			HandlerFunc: controller.PatchJob,
+		},
 		models.Route{
 			Path:        fmt.Sprintf("/jobs/{%s}/stop", jobNameParam),
 			Method:      http.MethodPost,
+			Permission:  models.PermissionMutate,
 			HandlerFunc: controller.StopJob,
 		},
+		models.Route{
+			Path:        fmt.Sprintf("/jobs/{%s}/rerun", jobNameParam),
+			Method:      http.MethodPost,
+			Permission:  models.PermissionMutate,
+			HandlerFunc: controller.RerunJob,
+		},
 	}
 	return routes
 }
@@ -66,17 +158,22 @@ func (controller *jobController) GetRoutes() models.Routes {
 // ---
 // summary: Create job
 // parameters:
+// - name: Idempotency-Key
+//   in: header
+//   description: Key of the request, at most 255 characters. A repeated request with the same key and body within the TTL of the key gets the response of the first request
+//   type: string
+//   required: false
 // - name: jobCreation
 //   in: body
-//   description: Job to create
+//   description: Job to create, with an optional callbackUrl receiving the final JobStatus when the job is completed, and an optional notBefore time
 //   required: true
 //   schema:
-//       "$ref": "#/definitions/JobScheduleDescription"
+//       "$ref": "#/definitions/JobScheduleRequest"
 // responses:
 //   "200":
-//     description: "Successful create job"
+//     description: "Successful create job, a Scheduled job when notBefore is in the future, or a Queued job when the maximum number of active jobs is reached"
 //     schema:
-//        "$ref": "#/definitions/JobStatus"
+//        "$ref": "#/definitions/QueuedJobStatus"
 //   "400":
 //     description: "Bad request"
 //     schema:
@@ -85,8 +182,16 @@ func (controller *jobController) GetRoutes() models.Routes {
 //     description: "Not found"
 //     schema:
 //        "$ref": "#/definitions/Status"
+//   "409":
+//     description: "Idempotency-Key used with a different request body"
+//     schema:
+//        "$ref": "#/definitions/Status"
+//   "413":
+//     description: "Request body larger than the maximum size"
+//     schema:
+//        "$ref": "#/definitions/Status"
 //   "422":
-//     description: "Invalid data in request"
+//     description: "Invalid data in request, e.g. a payload not matching the payload schema, with each invalid field in causes"
 //     schema:
 //        "$ref": "#/definitions/Status"
 //   "500":
@@ -94,52 +199,299 @@ func (controller *jobController) GetRoutes() models.Routes {
 //     schema:
 //        "$ref": "#/definitions/Status"
 func (controller *jobController) CreateJob(w http.ResponseWriter, r *http.Request) {
-	var jobScheduleDescription apiModels.JobScheduleDescription
+	var jobScheduleRequest models.JobScheduleRequest
 
-	if body, _ := io.ReadAll(r.Body); len(body) > 0 {
-		if err := json.Unmarshal(body, &jobScheduleDescription); err != nil {
-			controller.HandleError(w, apiErrors.NewInvalid("payload"))
-			return
-		}
+	_, span := tracing.StartSpan(r.Context(), "DecodeRequestBody")
+	err := utils.DecodeRequestBody(r, &jobScheduleRequest)
+	tracing.EndSpan(span, err)
+	if err != nil {
+		controller.HandleError(w, r, err)
+		return
+	}
+	causes := validation.ValidateJobScheduleDescription("", &jobScheduleRequest.JobScheduleDescription)
+	causes = append(causes, controller.payloadSchema.ValidateJobPayload("", &jobScheduleRequest.JobScheduleDescription)...)
+	causes = append(causes, webhooks.ValidateCallback(&jobScheduleRequest.Callback)...)
+	causes = append(causes, pending.ValidateNotBefore(jobScheduleRequest.NotBefore)...)
+	causes = append(causes, controller.pendingItems.ValidatePriority(&jobScheduleRequest.QueuePriority)...)
+	if len(causes) > 0 {
+		controller.HandleError(w, r, serverErrors.NewInvalidFields(causes))
+		return
 	}
 
-	jobState, err := controller.handler.CreateJob(&jobScheduleDescription)
+	key, err := idempotency.GetKey(r)
 	if err != nil {
-		controller.HandleError(w, err)
+		controller.HandleError(w, r, err)
 		return
 	}
-	err = controller.handler.MaintainHistoryLimit()
+	response, replayed, err := controller.idempotencyKeys.Do(r.Context(), "jobs", key, &jobScheduleRequest, func() (interface{}, error) {
+		return controller.scheduleJob(r.Context(), &jobScheduleRequest, "")
+	})
+	if err != nil {
+		controller.HandleError(w, r, err)
+		return
+	}
+	if replayed {
+		w.Header().Set(idempotency.ReplayedHeader, "true")
+	}
+	utils.JSONResponse(w, response)
+}
+
+// scheduleJob Holds the job until its notBefore time when it is in the future, queues the job when there is no room for it
+// among the active jobs, otherwise creates the job
+func (controller *jobController) scheduleJob(ctx context.Context, jobScheduleRequest *models.JobScheduleRequest, rerunOf string) (*models.QueuedJobStatus, error) {
+	notBefore, ok := controller.pendingItems.ParseNotBefore(jobScheduleRequest.NotBefore)
+	if !ok && controller.pendingItems.Admit(1) {
+		jobState, err := controller.createJob(ctx, jobScheduleRequest, rerunOf, nil)
+		if err != nil {
+			controller.pendingItems.CancelAdmit(1)
+			return nil, err
+		}
+		return &models.QueuedJobStatus{JobStatus: *jobState}, nil
+	}
+	// The callback is kept by the notifier until the job is created, so the secret is not kept in the pending item
+	pendingRequest := *jobScheduleRequest
+	pendingRequest.Callback = models.Callback{}
+	item, err := controller.pendingItems.AddJob(&pendingRequest, rerunOf, notBefore)
 	if err != nil {
-		log.Warnf("failed to maintain job history: %v", err)
+		return nil, err
 	}
+	if err := controller.notifier.RegisterPending(webhooks.KindJob, item.Name, &jobScheduleRequest.Callback); err != nil {
+		log.WithContext(ctx).Errorf("failed to register the callback of the pending job %s: %v", item.Name, err)
+	}
+	if ok {
+		log.WithContext(ctx).Debugf("Scheduled the job %s to start at %s", item.Name, jobScheduleRequest.NotBefore)
+	} else {
+		log.WithContext(ctx).Debugf("Queued the job %s at position %d", item.Name, item.QueuePosition)
+	}
+	jobStatus := item.JobStatus()
+	return &jobStatus, nil
+}
+
+// createPendingJob Creates the job of a pending job when it is released from the queue
+func (controller *jobController) createPendingJob(ctx context.Context, item *pending.Item) (string, error) {
+	jobState, err := controller.createJob(ctx, item.Job, item.RerunOf, item)
+	if err != nil {
+		return "", err
+	}
+	return jobState.Name, nil
+}
+
+// removePendingJob Cancels the pending job with the name, if any, and deletes its callback
+func (controller *jobController) removePendingJob(ctx context.Context, jobName string) (bool, error) {
+	removed, err := controller.pendingItems.Remove(pending.KindJob, jobName)
+	if err != nil || !removed {
+		return removed, err
+	}
+	if err := controller.notifier.Unregister(webhooks.KindJob, jobName); err != nil {
+		log.WithContext(ctx).Errorf("failed to delete the callback of the pending job %s: %v", jobName, err)
+	}
+	return true, nil
+}
 
-	utils.JSONResponse(w, &jobState)
+// createScheduledJob Creates the job of a run of a job schedule
+func (controller *jobController) createScheduledJob(ctx context.Context, schedule *models.Schedule) (string, error) {
+	jobState, err := controller.scheduleJob(ctx, &models.JobScheduleRequest{JobScheduleDescription: *schedule.Job}, "")
+	if err != nil {
+		return "", err
+	}
+	return jobState.Name, nil
+}
+
+// countActiveJobs Counts the jobs which are not completed, including the jobs of batches, with a single list of the
+// Kubernetes jobs of the job component. A batch whose jobs are not created yet counts as one job
+func (controller *jobController) countActiveJobs(ctx context.Context) (int, error) {
+	_, span := tracing.StartSpan(ctx, "JobWatcher.GetJobs")
+	jobs, err := controller.jobWatcher.GetJobs(ctx, nil)
+	tracing.EndSpan(span, err)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	var activeBatches []string
+	batchesWithActiveJobs := make(map[string]bool)
+	for i := range jobs {
+		if isJobFinished(&jobs[i]) {
+			continue
+		}
+		batchName := jobs[i].Labels[kube.RadixBatchNameLabel]
+		if len(batchName) > 0 && batchName == jobs[i].Name {
+			// The job of the batch itself, creating the jobs of the batch
+			activeBatches = append(activeBatches, batchName)
+			continue
+		}
+		count++
+		batchesWithActiveJobs[batchName] = true
+	}
+	for _, batchName := range activeBatches {
+		if !batchesWithActiveJobs[batchName] {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// isJobFinished Returns true when the Kubernetes job is complete or failed
+func isJobFinished(job *batchv1.Job) bool {
+	for _, condition := range job.Status.Conditions {
+		if (condition.Type == batchv1.JobComplete || condition.Type == batchv1.JobFailed) && condition.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+// isJobActive Returns true when the job is pending or not completed
+func (controller *jobController) isJobActive(ctx context.Context, jobName string) (bool, error) {
+	item, err := controller.pendingItems.Get(pending.KindJob, jobName)
+	if err != nil || item != nil {
+		return item != nil, err
+	}
+	_, span := tracing.StartSpan(ctx, "JobHandler.GetJob")
+	job, err := controller.handler.GetJob(controller.pendingItems.GetReleasedName(pending.KindJob, jobName))
+	tracing.EndSpan(span, err)
+	if err != nil {
+		if serverErrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return !events.IsTerminal(job.Status), nil
+}
+
+// createJob Creates the job, keeps its description to re-run it, registers its callback and maintains the history limit.
+// rerunOf is the name of the job the job is a re-run of, if any, and item the pending job it is created for, if any
+func (controller *jobController) createJob(ctx context.Context, jobScheduleRequest *models.JobScheduleRequest, rerunOf string, item *pending.Item) (*modelsV1.JobStatus, error) {
+	_, span := tracing.StartSpan(ctx, "JobHandler.CreateJob")
+	jobState, err := controller.handler.CreateJob(&jobScheduleRequest.JobScheduleDescription)
+	tracing.EndSpan(span, err)
+	if err != nil {
+		return nil, err
+	}
+	metrics.AddJobCreated()
+	if item != nil {
+		if err := controller.pendingItems.KeepReleasedName(item, jobState.Name); err != nil {
+			log.WithContext(ctx).Errorf("failed to keep the name of the job %s created for %s: %v", jobState.Name, item.Name, err)
+		}
+	}
+	if err := controller.descriptions.PutJob(jobState.Name, &jobScheduleRequest.JobScheduleDescription, rerunOf); err != nil {
+		log.WithContext(ctx).Errorf("failed to keep the description of the job %s: %v", jobState.Name, err)
+	}
+	if err := controller.notifier.Register(webhooks.KindJob, jobState.Name, &jobScheduleRequest.Callback); err != nil {
+		log.WithContext(ctx).Errorf("failed to register the callback of the job %s: %v", jobState.Name, err)
+	}
+	if item != nil {
+		if err := controller.notifier.Release(webhooks.KindJob, item.Name, jobState.Name); err != nil {
+			log.WithContext(ctx).Errorf("failed to move the callback of the pending job %s to the job %s: %v", item.Name, jobState.Name, err)
+		}
+	}
+	_, span = tracing.StartSpan(ctx, "JobHandler.MaintainHistoryLimit")
+	err = controller.handler.MaintainHistoryLimit()
+	tracing.EndSpan(span, err)
+	if err != nil {
+		metrics.AddHistoryLimitFailure("job")
+		log.WithContext(ctx).Warnf("failed to maintain job history: %v", err)
+	}
+	return jobState, nil
 }
 
 // swagger:operation GET /jobs/ Job getJobs
 // ---
 // summary: Gets jobs
 // parameters:
+// - name: status
+//   in: query
+//   description: Statuses to include, repeated or comma separated. One of Scheduled, Queued, Waiting, Running, Succeeded, Failed, Stopping, Stopped
+//   type: array
+//   items:
+//     type: string
+//   collectionFormat: multi
+//   required: false
+// - name: jobId
+//   in: query
+//   description: Job ID set in the job schedule description
+//   type: string
+//   required: false
+// - name: batchName
+//   in: query
+//   description: Name of the batch
+//   type: string
+//   required: false
+// - name: createdAfter
+//   in: query
+//   description: Include items created at or after the time, RFC3339
+//   type: string
+//   format: date-time
+//   required: false
+// - name: createdBefore
+//   in: query
+//   description: Include items created at or before the time, RFC3339
+//   type: string
+//   format: date-time
+//   required: false
+// - name: endedAfter
+//   in: query
+//   description: Include items ended at or after the time, RFC3339
+//   type: string
+//   format: date-time
+//   required: false
+// - name: limit
+//   in: query
+//   description: Maximum number of jobs in the response, between 1 and 1000. All jobs when not set
+//   type: integer
+//   required: false
+// - name: continue
+//   in: query
+//   description: Continuation token from the Link header of the previous page
+//   type: string
+//   required: false
 // responses:
 //   "200":
-//     description: "Successful get jobs"
+//     description: "Successful get jobs, sorted by creation time and name. Queued jobs have their position in the queue"
+//     headers:
+//       Link:
+//         description: Link to the next page, with rel="next", when there are more jobs
+//         type: string
+//       X-Total-Count:
+//         description: Total number of jobs
+//         type: integer
 //     schema:
 //        type: "array"
 //        items:
-//           "$ref": "#/definitions/JobStatus"
+//           "$ref": "#/definitions/QueuedJobStatus"
+//   "400":
+//     description: "Invalid filter, limit or continuation token"
+//     schema:
+//        "$ref": "#/definitions/Status"
 //   "500":
 //     description: "Internal server error"
 //     schema:
 //        "$ref": "#/definitions/Status"
 func (controller *jobController) GetJobs(w http.ResponseWriter, r *http.Request) {
-	log.Debug("Get job list")
-	jobs, err := controller.handler.GetJobs()
+	log.WithContext(r.Context()).Debug("Get job list")
+	filter, err := utils.ParseJobStatusFilter(r)
+	if err != nil {
+		controller.HandleError(w, r, err)
+		return
+	}
+	pageRequest, err := utils.ParsePageRequest(r)
 	if err != nil {
-		controller.HandleError(w, err)
+		controller.HandleError(w, r, err)
 		return
 	}
-	log.Debugf("Found %d jobs", len(jobs))
-	utils.JSONResponse(w, jobs)
+	jobs, queuePositions, err := controller.getJobs(r.Context())
+	if err != nil {
+		controller.HandleError(w, r, err)
+		return
+	}
+	log.WithContext(r.Context()).Debugf("Found %d jobs", len(jobs))
+	jobs = filter.FilterJobStatuses(jobs)
+	utils.SortJobStatuses(jobs)
+	start, end := pageRequest.Page(w, r, len(jobs), func(i int) string { return utils.JobStatusSortKey(&jobs[i]) })
+	page := make([]models.QueuedJobStatus, 0, end-start)
+	for _, job := range jobs[start:end] {
+		page = append(page, models.QueuedJobStatus{JobStatus: job, QueuePosition: queuePositions[job.Name]})
+	}
+	utils.JSONResponse(w, page)
 }
 
 // swagger:operation GET /jobs/{jobName} Job getJob
@@ -155,7 +507,7 @@ func (controller *jobController) GetJobs(w http.ResponseWriter, r *http.Request)
 //   "200":
 //     description: "Successful get job"
 //     schema:
-//        "$ref": "#/definitions/JobStatus"
+//        "$ref": "#/definitions/QueuedJobStatus"
 //   "404":
 //     description: "Not found"
 //     schema:
@@ -166,18 +518,222 @@ func (controller *jobController) GetJobs(w http.ResponseWriter, r *http.Request)
 //        "$ref": "#/definitions/Status"
 func (controller *jobController) GetJob(w http.ResponseWriter, r *http.Request) {
 	jobName := mux.Vars(r)[jobNameParam]
-	log.Debugf("Get job %s", jobName)
-	job, err := controller.handler.GetJob(jobName)
+	log.WithContext(r.Context()).Debugf("Get job %s", jobName)
+	item, err := controller.pendingItems.Get(pending.KindJob, jobName)
 	if err != nil {
-		controller.HandleError(w, err)
+		controller.HandleError(w, r, err)
+		return
+	}
+	if item != nil {
+		utils.JSONResponse(w, item.JobStatus())
+		return
+	}
+	_, span := tracing.StartSpan(r.Context(), "JobHandler.GetJob")
+	job, err := controller.handler.GetJob(controller.pendingItems.GetReleasedName(pending.KindJob, jobName))
+	tracing.EndSpan(span, err)
+	if err != nil {
+		controller.HandleError(w, r, err)
 		return
 	}
 	utils.JSONResponse(w, job)
 }
 
+// getJobs Gets the jobs, and the pending jobs in the Scheduled or Queued status with the queue positions of the Queued jobs by name
+func (controller *jobController) getJobs(ctx context.Context) ([]modelsV1.JobStatus, map[string]int, error) {
+	_, span := tracing.StartSpan(ctx, "JobHandler.GetJobs")
+	jobs, err := controller.handler.GetJobs()
+	tracing.EndSpan(span, err)
+	if err != nil {
+		return nil, nil, err
+	}
+	items, err := controller.pendingItems.List(pending.KindJob)
+	if err != nil {
+		return nil, nil, err
+	}
+	queuePositions := make(map[string]int)
+	for i := range items {
+		jobs = append(jobs, items[i].JobStatus().JobStatus)
+		if items[i].QueuePosition > 0 {
+			queuePositions[items[i].Name] = items[i].QueuePosition
+		}
+	}
+	return jobs, queuePositions, nil
+}
+
+// swagger:operation GET /jobs/events Job getJobsEvents
+// ---
+// summary: Streams status changes of jobs as server-sent events
+// description: >-
+//   Sends a status event with the JobStatus of each job when the stream starts, and when the status of a job changes.
+//   A deleted event is sent when a job is deleted. Comments are sent as heartbeats on idle streams.
+//   A client reconnecting with the Last-Event-ID header gets the changes after the event.
+// produces:
+// - text/event-stream
+// parameters:
+// - name: Last-Event-ID
+//   in: header
+//   description: ID of the last event received before reconnecting
+//   type: string
+//   required: false
+// responses:
+//   "200":
+//     description: "Stream of status and deleted events with JobStatus data"
+//     schema:
+//        "$ref": "#/definitions/JobStatus"
+//   "500":
+//     description: "Internal server error"
+//     schema:
+//        "$ref": "#/definitions/Status"
+func (controller *jobController) GetJobsEvents(w http.ResponseWriter, r *http.Request) {
+	log.WithContext(r.Context()).Debug("Stream job events")
+	err := controller.jobWatcher.Serve(w, r, &events.Stream{
+		Labels:         labels.Set{kube.RadixJobTypeLabel: kube.RadixJobTypeJobSchedule},
+		GetJobStatuses: controller.handler.GetJobs,
+		GetJobStatus:   controller.handler.GetJob,
+	})
+	if err != nil {
+		controller.HandleError(w, r, err)
+	}
+}
+
+// swagger:operation GET /jobs/{jobName}/events Job getJobEvents
+// ---
+// summary: Streams status changes of a job as server-sent events
+// description: >-
+//   Sends a status event with the JobStatus of the job when the stream starts, and when the status changes.
+//   The stream ends with an end event when the job is Succeeded, Failed or Stopped, or after a deleted event.
+//   Comments are sent as heartbeats on idle streams.
+//   A client reconnecting with the Last-Event-ID header gets the changes after the event.
+// produces:
+// - text/event-stream
+// parameters:
+// - name: jobName
+//   in: path
+//   description: Name of job
+//   type: string
+//   required: true
+// - name: Last-Event-ID
+//   in: header
+//   description: ID of the last event received before reconnecting
+//   type: string
+//   required: false
+// responses:
+//   "200":
+//     description: "Stream of status, deleted and end events with JobStatus data"
+//     schema:
+//        "$ref": "#/definitions/JobStatus"
+//   "404":
+//     description: "Not found"
+//     schema:
+//        "$ref": "#/definitions/Status"
+//   "500":
+//     description: "Internal server error"
+//     schema:
+//        "$ref": "#/definitions/Status"
+func (controller *jobController) GetJobEvents(w http.ResponseWriter, r *http.Request) {
+	jobName := controller.pendingItems.GetReleasedName(pending.KindJob, mux.Vars(r)[jobNameParam])
+	log.WithContext(r.Context()).Debugf("Stream events of the job %s", jobName)
+	err := controller.jobWatcher.Serve(w, r, &events.Stream{
+		JobName: jobName,
+		GetJobStatuses: func() ([]modelsV1.JobStatus, error) {
+			job, err := controller.handler.GetJob(jobName)
+			if err != nil {
+				return nil, err
+			}
+			return []modelsV1.JobStatus{*job}, nil
+		},
+		GetJobStatus: controller.handler.GetJob,
+		IsComplete:   events.IsJobComplete(jobName),
+	})
+	if err != nil {
+		controller.HandleError(w, r, err)
+	}
+}
+
+// swagger:operation GET /jobs/{jobName}/logs Job getJobLogs
+// ---
+// summary: Gets the log of a job
+// produces:
+// - text/plain
+// - application/x-ndjson
+// parameters:
+// - name: jobName
+//   in: path
+//   description: Name of job
+//   type: string
+//   required: true
+// - name: follow
+//   in: query
+//   description: Stream new log lines until the job container ends
+//   type: boolean
+//   required: false
+// - name: tailLines
+//   in: query
+//   description: Number of lines from the end of the log
+//   type: integer
+//   required: false
+// - name: sinceSeconds
+//   in: query
+//   description: Only log lines newer than the number of seconds
+//   type: integer
+//   required: false
+// - name: timestamps
+//   in: query
+//   description: Prefix each log line with its RFC3339 time
+//   type: boolean
+//   required: false
+// - name: previous
+//   in: query
+//   description: Log of the previous attempt of the job, or of the previous instance of the container when the job has one attempt
+//   type: boolean
+//   required: false
+// - name: format
+//   in: query
+//   description: Format of the log, text or ndjson. Defaults to ndjson when the Accept header is application/x-ndjson, otherwise text
+//   type: string
+//   enum: [text, ndjson]
+//   required: false
+// responses:
+//   "200":
+//     description: "Log of the job as plain text, or as newline delimited JSON objects with pod, container, timestamp and message"
+//     schema:
+//        type: string
+//   "400":
+//     description: "Invalid parameter, or the container has no log yet"
+//     schema:
+//        "$ref": "#/definitions/Status"
+//   "404":
+//     description: "Not found"
+//     schema:
+//        "$ref": "#/definitions/Status"
+//   "500":
+//     description: "Internal server error"
+//     schema:
+//        "$ref": "#/definitions/Status"
+func (controller *jobController) GetJobLogs(w http.ResponseWriter, r *http.Request) {
+	jobName := controller.pendingItems.GetReleasedName(pending.KindJob, mux.Vars(r)[jobNameParam])
+	log.WithContext(r.Context()).Debugf("Get log of the job %s", jobName)
+	options, err := logs.ParseOptions(r)
+	if err != nil {
+		controller.HandleError(w, r, err)
+		return
+	}
+	_, span := tracing.StartSpan(r.Context(), "JobHandler.GetJob")
+	_, err = controller.handler.GetJob(jobName)
+	tracing.EndSpan(span, err)
+	if err != nil {
+		controller.HandleError(w, r, err)
+		return
+	}
+	if err := controller.logReader.Stream(w, r, jobName, options); err != nil {
+		controller.HandleError(w, r, err)
+	}
+}
+
 // swagger:operation DELETE /jobs/{jobName} Job deleteJob
 // ---
 // summary: Delete job
+// description: A Scheduled or Queued job is cancelled, and is not created
 // parameters:
 // - name: jobName
 //   in: path
@@ -199,10 +755,9 @@ func (controller *jobController) GetJob(w http.ResponseWriter, r *http.Request)
 //        "$ref": "#/definitions/Status"
 func (controller *jobController) DeleteJob(w http.ResponseWriter, r *http.Request) {
 	jobName := mux.Vars(r)[jobNameParam]
-	log.Debugf("Delete job %s", jobName)
-	err := controller.handler.DeleteJob(jobName)
-	if err != nil {
-		controller.HandleError(w, err)
+	log.WithContext(r.Context()).Debugf("Delete job %s", jobName)
+	if err := controller.deleteJob(r, jobName); err != nil {
+		controller.HandleError(w, r, err)
 		return
 	}
 
@@ -211,12 +766,110 @@ func (controller *jobController) DeleteJob(w http.ResponseWriter, r *http.Reques
 		Code:    http.StatusOK,
 		Message: fmt.Sprintf("job %s successfully deleted", jobName),
 	}
-	utils.StatusResponse(w, &status)
+	utils.StatusResponse(w, r, &status)
+}
+
+// deleteJob Deletes the job and its description. A pending job is cancelled
+func (controller *jobController) deleteJob(r *http.Request, jobName string) error {
+	if removed, err := controller.removePendingJob(r.Context(), jobName); err != nil || removed {
+		return err
+	}
+	jobName = controller.pendingItems.GetReleasedName(pending.KindJob, jobName)
+	_, span := tracing.StartSpan(r.Context(), "JobHandler.DeleteJob")
+	err := controller.handler.DeleteJob(jobName)
+	tracing.EndSpan(span, err)
+	if err != nil {
+		return err
+	}
+	metrics.AddJobDeleted()
+	if err := controller.descriptions.DeleteJob(jobName); err != nil {
+		log.WithContext(r.Context()).Errorf("failed to delete the description of the job %s: %v", jobName, err)
+	}
+	return nil
+}
+
+// swagger:operation PATCH /jobs/{jobName} Job patchJob
+// ---
+// summary: Change the priority of a Scheduled or Queued job
+// parameters:
+// - name: jobName
+//   in: path
+//   description: Name of job
+//   type: string
+//   required: true
+// - name: priority
+//   in: body
+//   description: The new priority, or the name of a priority class
+//   required: true
+//   schema:
+//       "$ref": "#/definitions/QueuePriority"
+// responses:
+//   "200":
+//     description: "Successful change of the priority"
+//     schema:
+//        "$ref": "#/definitions/QueuedJobStatus"
+//   "400":
+//     description: "Bad request"
+//     schema:
+//        "$ref": "#/definitions/Status"
+//   "404":
+//     description: "Not found"
+//     schema:
+//        "$ref": "#/definitions/Status"
+//   "409":
+//     description: "The job is already created"
+//     schema:
+//        "$ref": "#/definitions/Status"
+//   "422":
+//     description: "Invalid priority, with each invalid field in causes"
+//     schema:
+//        "$ref": "#/definitions/Status"
+//   "500":
+//     description: "Internal server error"
+//     schema:
+//        "$ref": "#/definitions/Status"
+func (controller *jobController) PatchJob(w http.ResponseWriter, r *http.Request) {
+	jobName := mux.Vars(r)[jobNameParam]
+	log.WithContext(r.Context()).Debugf("Change the priority of the job %s", jobName)
+	var priority models.QueuePriority
+	_, span := tracing.StartSpan(r.Context(), "DecodeRequestBody")
+	err := utils.DecodeRequestBody(r, &priority)
+	tracing.EndSpan(span, err)
+	if err != nil {
+		controller.HandleError(w, r, err)
+		return
+	}
+	causes := controller.pendingItems.ValidatePriority(&priority)
+	if priority.Priority == nil && len(priority.PriorityClass) == 0 {
+		causes = append(causes, models.StatusCause{Field: "priority", Message: "either priority or priorityClass must be set"})
+	}
+	if len(causes) > 0 {
+		controller.HandleError(w, r, serverErrors.NewInvalidFields(causes))
+		return
+	}
+
+	item, err := controller.pendingItems.SetPriority(pending.KindJob, jobName, controller.pendingItems.GetPriority(&priority))
+	if err != nil {
+		controller.HandleError(w, r, err)
+		return
+	}
+	if item == nil {
+		_, span = tracing.StartSpan(r.Context(), "JobHandler.GetJob")
+		_, err = controller.handler.GetJob(controller.pendingItems.GetReleasedName(pending.KindJob, jobName))
+		tracing.EndSpan(span, err)
+		if err == nil {
+			err = serverErrors.NewConflict(fmt.Sprintf("The job %s is already created. Only Scheduled and Queued jobs can change priority", jobName))
+		}
+		controller.HandleError(w, r, err)
+		return
+	}
+	utils.JSONResponse(w, item.JobStatus())
 }
 
 // swagger:operation POST /jobs/{jobName}/stop Job stopJob
 // ---
 // summary: Stop job
+// description: A Scheduled or Queued job is cancelled, and is not created
 // parameters:
 // - name: jobName
 //   in: path
@@ -239,9 +892,8 @@ func (controller *jobController) DeleteJob(w http.ResponseWriter, r *http.Reques
 func (controller *jobController) StopJob(w http.ResponseWriter, r *http.Request) {
 	jobName := mux.Vars(r)[jobNameParam]
 
-	err := controller.handler.StopJob(jobName)
-	if err != nil {
-		controller.HandleError(w, err)
+	if err := controller.stopJob(r.Context(), jobName); err != nil {
+		controller.HandleError(w, r, err)
 		return
 	}
 
@@ -250,5 +902,202 @@ func (controller *jobController) StopJob(w http.ResponseWriter, r *http.Request)
 		Code:    http.StatusOK,
 		Message: fmt.Sprintf("job %s was successfully stopped", jobName),
 	}
-	utils.StatusResponse(w, &status)
+	utils.StatusResponse(w, r, &status)
+}
+
+// stopJob Stops the job. A pending job is cancelled
+func (controller *jobController) stopJob(ctx context.Context, jobName string) error {
+	if removed, err := controller.removePendingJob(ctx, jobName); err != nil || removed {
+		return err
+	}
+	_, span := tracing.StartSpan(ctx, "JobHandler.StopJob")
+	err := controller.handler.StopJob(controller.pendingItems.GetReleasedName(pending.KindJob, jobName))
+	tracing.EndSpan(span, err)
+	if err != nil {
+		return err
+	}
+	metrics.AddJobStopped()
+	return nil
+}
+
+// swagger:operation DELETE /jobs Job deleteJobs
+// ---
+// summary: Delete the jobs matching a selector
+// description: >-
+//   Deletes the jobs matching all conditions of the selector, a few jobs at a time.
+//   Each job is deleted even when others fail, and the result of each job is in the report.
+// parameters:
+// - name: selector
+//   in: body
+//   description: Selector of the jobs, with at least one condition
+//   required: true
+//   schema:
+//       "$ref": "#/definitions/Selector"
+// responses:
+//   "200":
+//     description: "All selected jobs deleted, or no jobs selected"
+//     schema:
+//        "$ref": "#/definitions/BulkReport"
+//   "207":
+//     description: "Some or all selected jobs failed to be deleted"
+//     schema:
+//        "$ref": "#/definitions/BulkReport"
+//   "413":
+//     description: "Request body larger than the maximum size"
+//     schema:
+//        "$ref": "#/definitions/Status"
+//   "422":
+//     description: "Invalid selector, with each invalid field in causes"
+//     schema:
+//        "$ref": "#/definitions/Status"
+//   "500":
+//     description: "Internal server error"
+//     schema:
+//        "$ref": "#/definitions/Status"
+func (controller *jobController) DeleteJobs(w http.ResponseWriter, r *http.Request) {
+	log.WithContext(r.Context()).Debug("Delete jobs")
+	controller.runBulk(w, r, func(jobName string) error {
+		return controller.deleteJob(r, jobName)
+	})
+}
+
+// swagger:operation POST /jobs/stop Job stopJobs
+// ---
+// summary: Stop the jobs matching a selector
+// description: >-
+//   Stops the jobs matching all conditions of the selector, a few jobs at a time.
+//   Each job is stopped even when others fail, and the result of each job is in the report.
+// parameters:
+// - name: selector
+//   in: body
+//   description: Selector of the jobs, with at least one condition
+//   required: true
+//   schema:
+//       "$ref": "#/definitions/Selector"
+// responses:
+//   "200":
+//     description: "All selected jobs stopped, or no jobs selected"
+//     schema:
+//        "$ref": "#/definitions/BulkReport"
+//   "207":
+//     description: "Some or all selected jobs failed to be stopped"
+//     schema:
+//        "$ref": "#/definitions/BulkReport"
+//   "413":
+//     description: "Request body larger than the maximum size"
+//     schema:
+//        "$ref": "#/definitions/Status"
+//   "422":
+//     description: "Invalid selector, with each invalid field in causes"
+//     schema:
+//        "$ref": "#/definitions/Status"
+//   "500":
+//     description: "Internal server error"
+//     schema:
+//        "$ref": "#/definitions/Status"
+func (controller *jobController) StopJobs(w http.ResponseWriter, r *http.Request) {
+	log.WithContext(r.Context()).Debug("Stop jobs")
+	controller.runBulk(w, r, func(jobName string) error {
+		return controller.stopJob(r.Context(), jobName)
+	})
+}
+
+// runBulk Runs the operation on each job selected by the selector in the request body
+func (controller *jobController) runBulk(w http.ResponseWriter, r *http.Request, operation func(jobName string) error) {
+	selector, err := bulk.DecodeSelector(r)
+	if err != nil {
+		controller.HandleError(w, r, err)
+		return
+	}
+	jobs, _, err := controller.getJobs(r.Context())
+	if err != nil {
+		controller.HandleError(w, r, err)
+		return
+	}
+	report := controller.bulkRunner.Run(r.Context(), bulk.SelectJobs(selector, jobs), operation)
+	bulk.ReportResponse(w, report)
+}
+
+// swagger:operation POST /jobs/{jobName}/rerun Job rerunJob
+// ---
+// summary: Re-run job
+// description: >-
+//   Creates a new job with the payload and config the job was created with. Only jobs created by this server can be re-run.
+//   The body is an optional JSON merge patch (RFC 7386) of the JobScheduleRequest, overriding fields of the original job,
+//   e.g. {"timeLimitSeconds": 600, "resources": {"limits": {"memory": "2Gi"}}}. The callback of the original job is not re-used.
+// parameters:
+// - name: jobName
+//   in: path
+//   description: Name of job
+//   type: string
+//   required: true
+// - name: overrides
+//   in: body
+//   description: JSON merge patch of the JobScheduleRequest of the original job
+//   required: false
+//   schema:
+//       "$ref": "#/definitions/JobScheduleRequest"
+// responses:
+//   "200":
+//     description: "Successful re-run job, with the name of the original job in rerunOf"
+//     schema:
+//        "$ref": "#/definitions/RerunJobStatus"
+//   "404":
+//     description: "Not found, or the description of the job is not known"
+//     schema:
+//        "$ref": "#/definitions/Status"
+//   "413":
+//     description: "Request body larger than the maximum size"
+//     schema:
+//        "$ref": "#/definitions/Status"
+//   "422":
+//     description: "Invalid overrides, or an invalid job with the overrides applied, with each invalid field in causes"
+//     schema:
+//        "$ref": "#/definitions/Status"
+//   "500":
+//     description: "Internal server error"
+//     schema:
+//        "$ref": "#/definitions/Status"
+func (controller *jobController) RerunJob(w http.ResponseWriter, r *http.Request) {
+	jobName := controller.pendingItems.GetReleasedName(pending.KindJob, mux.Vars(r)[jobNameParam])
+	log.WithContext(r.Context()).Debugf("Re-run job %s", jobName)
+	_, span := tracing.StartSpan(r.Context(), "JobHandler.GetJob")
+	_, err := controller.handler.GetJob(jobName)
+	tracing.EndSpan(span, err)
+	if err != nil {
+		controller.HandleError(w, r, err)
+		return
+	}
+	description, err := controller.descriptions.GetJob(jobName)
+	if err != nil {
+		controller.HandleError(w, r, err)
+		return
+	}
+	var overrides json.RawMessage
+	_, span = tracing.StartSpan(r.Context(), "DecodeRequestBody")
+	err = utils.DecodeRequestBody(r, &overrides)
+	tracing.EndSpan(span, err)
+	if err != nil {
+		controller.HandleError(w, r, err)
+		return
+	}
+	job, err := controller.descriptions.RerunJob(r.Context(), description, overrides, jobName)
+	if err != nil {
+		controller.HandleError(w, r, err)
+		return
+	}
+	utils.JSONResponse(w, &models.RerunJobStatus{JobStatus: job.JobStatus, RerunOf: jobName})
+}
+
+// rerunJob Validates and schedules the job re-running the job rerunOf, in or outside of a batch
+func (controller *jobController) rerunJob(ctx context.Context, jobScheduleRequest *models.JobScheduleRequest, rerunOf string) (*models.QueuedJobStatus, error) {
+	causes := validation.ValidateJobScheduleDescription("", &jobScheduleRequest.JobScheduleDescription)
+	causes = append(causes, controller.payloadSchema.ValidateJobPayload("", &jobScheduleRequest.JobScheduleDescription)...)
+	causes = append(causes, webhooks.ValidateCallback(&jobScheduleRequest.Callback)...)
+	causes = append(causes, pending.ValidateNotBefore(jobScheduleRequest.NotBefore)...)
+	causes = append(causes, controller.pendingItems.ValidatePriority(&jobScheduleRequest.QueuePriority)...)
+	if len(causes) > 0 {
+		return nil, serverErrors.NewInvalidFields(causes)
+	}
+	return controller.scheduleJob(ctx, jobScheduleRequest, rerunOf)
 }
@@ -1,26 +1,67 @@
 package jobs
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/equinor/radix-common/utils"
 	"github.com/equinor/radix-job-scheduler-server/api/utils/test"
+	"github.com/equinor/radix-job-scheduler-server/bulk"
+	"github.com/equinor/radix-job-scheduler-server/events"
+	"github.com/equinor/radix-job-scheduler-server/idempotency"
+	"github.com/equinor/radix-job-scheduler-server/logs"
+	serverModels "github.com/equinor/radix-job-scheduler-server/models"
+	"github.com/equinor/radix-job-scheduler-server/pending"
+	"github.com/equinor/radix-job-scheduler-server/rerun"
+	"github.com/equinor/radix-job-scheduler-server/store"
+	"github.com/equinor/radix-job-scheduler-server/webhooks"
 	apiErrors "github.com/equinor/radix-job-scheduler/api/errors"
 	"github.com/equinor/radix-job-scheduler/api/v1/jobs"
 	"github.com/equinor/radix-job-scheduler/api/v1/jobs/mock"
 	models "github.com/equinor/radix-job-scheduler/models/common"
 	modelsV1 "github.com/equinor/radix-job-scheduler/models/v1"
+	"github.com/equinor/radix-operator/pkg/apis/kube"
 	v1 "github.com/equinor/radix-operator/pkg/apis/radix/v1"
+	radixfake "github.com/equinor/radix-operator/pkg/client/clientset/versioned/fake"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	secretproviderfake "sigs.k8s.io/secrets-store-csi-driver/pkg/client/clientset/versioned/fake"
 )
 
 func setupTest(handler jobs.JobHandler) *test.ControllerTestUtils {
-	jobController := jobController{handler: handler}
+	return setupTestWithRerun(handler, rerun.NewDescriptions(store.NewMemoryStore(), handler, nil, nil))
+}
+
+func setupTestWithRerun(handler jobs.JobHandler, descriptions *rerun.Descriptions) *test.ControllerTestUtils {
+	return setupTestWithPending(handler, descriptions, pending.New(store.NewMemoryStore(), "", 0, nil, 0))
+}
+
+func setupTestWithPending(handler jobs.JobHandler, descriptions *rerun.Descriptions, pendingItems *pending.Items) *test.ControllerTestUtils {
+	env := serverModels.NewEnv()
+	kubeClient := kubefake.NewSimpleClientset()
+	kubeUtil, _ := kube.New(kubeClient, radixfake.NewSimpleClientset(), secretproviderfake.NewSimpleClientset())
+	jobWatcher := events.NewJobWatcher(kubeClient, env)
+	jobController := jobController{
+		handler:         handler,
+		jobWatcher:      jobWatcher,
+		logReader:       logs.NewReader(kubeUtil, env),
+		notifier:        webhooks.NewNotifier(store.NewMemoryStore(), handler, nil, jobWatcher),
+		idempotencyKeys: idempotency.New(store.NewMemoryStore(), time.Hour),
+		descriptions:    descriptions,
+		bulkRunner:      bulk.NewRunner(2),
+		pendingItems:    pendingItems,
+	}
+	descriptions.SetJobRerunner(jobController.rerunJob)
 	controllerTestUtils := test.New(&jobController)
 	return &controllerTestUtils
 }
@@ -88,6 +129,117 @@ func TestGetJobs(t *testing.T) {
 	})
 }
 
+func TestGetJobsPagination(t *testing.T) {
+	created := time.Now()
+	jobStates := []modelsV1.JobStatus{
+		{Name: "job3", Created: utils.FormatTimestamp(created.Add(2 * time.Minute))},
+		{Name: "job1", Created: utils.FormatTimestamp(created)},
+		{Name: "job2", Created: utils.FormatTimestamp(created.Add(1 * time.Minute))},
+	}
+
+	t.Run("first page - success", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		jobHandler := mock.NewMockJobHandler(ctrl)
+		jobHandler.
+			EXPECT().
+			GetJobs().
+			Return(append([]modelsV1.JobStatus{}, jobStates...), nil).
+			Times(1)
+
+		controllerTestUtils := setupTest(jobHandler)
+		responseChannel := controllerTestUtils.ExecuteRequest(http.MethodGet, "/api/v1/jobs?limit=2")
+		response := <-responseChannel
+		assert.NotNil(t, response)
+
+		if response != nil {
+			assert.Equal(t, http.StatusOK, response.StatusCode)
+			var returnedJobs []modelsV1.JobStatus
+			test.GetResponseBody(response, &returnedJobs)
+			assert.Len(t, returnedJobs, 2)
+			assert.Equal(t, "job1", returnedJobs[0].Name)
+			assert.Equal(t, "job2", returnedJobs[1].Name)
+			assert.Equal(t, "3", response.Header.Get("X-Total-Count"))
+			assert.Contains(t, response.Header.Get("Link"), `rel="next"`)
+		}
+	})
+
+	t.Run("next page - success", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		jobHandler := mock.NewMockJobHandler(ctrl)
+		jobHandler.
+			EXPECT().
+			GetJobs().
+			Return(append([]modelsV1.JobStatus{}, jobStates...), nil).
+			Times(2)
+
+		controllerTestUtils := setupTest(jobHandler)
+		response := <-controllerTestUtils.ExecuteRequest(http.MethodGet, "/api/v1/jobs?limit=2")
+		assert.NotNil(t, response)
+		nextLink := response.Header.Get("Link")
+		nextPath := nextLink[strings.Index(nextLink, "<")+1 : strings.Index(nextLink, ">")]
+
+		response = <-controllerTestUtils.ExecuteRequest(http.MethodGet, nextPath)
+		assert.NotNil(t, response)
+
+		if response != nil {
+			assert.Equal(t, http.StatusOK, response.StatusCode)
+			var returnedJobs []modelsV1.JobStatus
+			test.GetResponseBody(response, &returnedJobs)
+			assert.Len(t, returnedJobs, 1)
+			assert.Equal(t, "job3", returnedJobs[0].Name)
+			assert.Empty(t, response.Header.Get("Link"))
+		}
+	})
+
+	t.Run("invalid limit - status code 400", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		jobHandler := mock.NewMockJobHandler(ctrl)
+		jobHandler.
+			EXPECT().
+			GetJobs().
+			Times(0)
+
+		controllerTestUtils := setupTest(jobHandler)
+		responseChannel := controllerTestUtils.ExecuteRequest(http.MethodGet, "/api/v1/jobs?limit=0")
+		response := <-responseChannel
+		assert.NotNil(t, response)
+
+		if response != nil {
+			assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+			var returnedStatus models.Status
+			test.GetResponseBody(response, &returnedStatus)
+			assert.Equal(t, http.StatusBadRequest, returnedStatus.Code)
+			assert.Equal(t, models.StatusFailure, returnedStatus.Status)
+		}
+	})
+
+	t.Run("invalid continuation token - status code 400", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		jobHandler := mock.NewMockJobHandler(ctrl)
+		jobHandler.
+			EXPECT().
+			GetJobs().
+			Times(0)
+
+		controllerTestUtils := setupTest(jobHandler)
+		responseChannel := controllerTestUtils.ExecuteRequest(http.MethodGet, "/api/v1/jobs?limit=2&continue=invalid")
+		response := <-responseChannel
+		assert.NotNil(t, response)
+
+		if response != nil {
+			assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+		}
+	})
+}
+
 func TestGetJob(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		t.Parallel()
@@ -338,12 +490,121 @@ func TestCreateJob(t *testing.T) {
 
 		if response != nil {
 			assert.Equal(t, http.StatusUnprocessableEntity, response.StatusCode)
-			var returnedStatus models.Status
+			var returnedStatus serverModels.Status
 			test.GetResponseBody(response, &returnedStatus)
 			assert.Equal(t, http.StatusUnprocessableEntity, returnedStatus.Code)
-			assert.Equal(t, models.StatusFailure, returnedStatus.Status)
+			assert.Equal(t, models.StatusFailure, returnedStatus.Status.Status)
+			assert.Equal(t, models.StatusReasonInvalid, returnedStatus.Reason)
+			assert.Equal(t, []serverModels.StatusCause{{Field: "payload", Message: "must be a string, not object"}}, returnedStatus.Causes)
+		}
+	})
+
+	t.Run("repeated request with idempotency key - original job", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		jobScheduleDescription := models.JobScheduleDescription{Payload: "a_payload"}
+		createdJob := modelsV1.JobStatus{Name: "newjob", Status: "Waiting"}
+		jobHandler := mock.NewMockJobHandler(ctrl)
+		jobHandler.
+			EXPECT().
+			CreateJob(&jobScheduleDescription).
+			Return(&createdJob, nil).
+			Times(1)
+		jobHandler.
+			EXPECT().
+			MaintainHistoryLimit().
+			Return(nil).
+			Times(1)
+		controllerTestUtils := setupTest(jobHandler)
+		header := http.Header{idempotency.KeyHeader: []string{"key1"}}
+
+		for i, replayed := range []string{"", "true"} {
+			response := <-controllerTestUtils.ExecuteRequestWithHeader(http.MethodPost, "/api/v1/jobs", jobScheduleDescription, header)
+			assert.NotNil(t, response)
+			if response != nil {
+				assert.Equal(t, http.StatusOK, response.StatusCode, "request %d", i)
+				assert.Equal(t, replayed, response.Header.Get(idempotency.ReplayedHeader), "request %d", i)
+				var returnedJob modelsV1.JobStatus
+				test.GetResponseBody(response, &returnedJob)
+				assert.Equal(t, createdJob, returnedJob)
+			}
+		}
+	})
+
+	t.Run("idempotency key with different body - conflict", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		jobHandler := mock.NewMockJobHandler(ctrl)
+		jobHandler.
+			EXPECT().
+			CreateJob(gomock.Any()).
+			Return(&modelsV1.JobStatus{Name: "newjob"}, nil).
+			Times(1)
+		jobHandler.
+			EXPECT().
+			MaintainHistoryLimit().
+			Return(nil).
+			Times(1)
+		controllerTestUtils := setupTest(jobHandler)
+		header := http.Header{idempotency.KeyHeader: []string{"key1"}}
+
+		response := <-controllerTestUtils.ExecuteRequestWithHeader(http.MethodPost, "/api/v1/jobs", models.JobScheduleDescription{Payload: "a"}, header)
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		response = <-controllerTestUtils.ExecuteRequestWithHeader(http.MethodPost, "/api/v1/jobs", models.JobScheduleDescription{Payload: "b"}, header)
+		assert.Equal(t, http.StatusConflict, response.StatusCode)
+		var returnedStatus models.Status
+		test.GetResponseBody(response, &returnedStatus)
+		assert.Equal(t, serverModels.StatusReasonConflict, returnedStatus.Reason)
+	})
+
+	t.Run("unknown field - unprocessable", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		jobHandler := mock.NewMockJobHandler(ctrl)
+		jobHandler.
+			EXPECT().
+			CreateJob(gomock.Any()).
+			Times(0)
+		controllerTestUtils := setupTest(jobHandler)
+		request := map[string]interface{}{"payload": "a_payload", "timeLimit": 10}
+		responseChannel := controllerTestUtils.ExecuteRequestWithBody(http.MethodPost, "/api/v1/jobs", request)
+		response := <-responseChannel
+		assert.NotNil(t, response)
+
+		if response != nil {
+			assert.Equal(t, http.StatusUnprocessableEntity, response.StatusCode)
+			var returnedStatus serverModels.Status
+			test.GetResponseBody(response, &returnedStatus)
+			assert.Equal(t, models.StatusReasonInvalid, returnedStatus.Reason)
+			assert.Equal(t, []serverModels.StatusCause{{Field: "timeLimit", Message: "unknown field"}}, returnedStatus.Causes)
+		}
+	})
+
+	t.Run("invalid callback URL - unprocessable", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		jobHandler := mock.NewMockJobHandler(ctrl)
+		jobHandler.
+			EXPECT().
+			CreateJob(gomock.Any()).
+			Times(0)
+		controllerTestUtils := setupTest(jobHandler)
+		request := serverModels.JobScheduleRequest{Callback: serverModels.Callback{CallbackURL: "not-a-url"}}
+		responseChannel := controllerTestUtils.ExecuteRequestWithBody(http.MethodPost, "/api/v1/jobs", request)
+		response := <-responseChannel
+		assert.NotNil(t, response)
+
+		if response != nil {
+			assert.Equal(t, http.StatusUnprocessableEntity, response.StatusCode)
+			var returnedStatus models.Status
+			test.GetResponseBody(response, &returnedStatus)
 			assert.Equal(t, models.StatusReasonInvalid, returnedStatus.Reason)
-			assert.Equal(t, apiErrors.InvalidMessage("payload", ""), returnedStatus.Message)
 		}
 	})
 
@@ -410,6 +671,80 @@ func TestCreateJob(t *testing.T) {
 	})
 }
 
+func TestStopJobs(t *testing.T) {
+	t.Run("partial success - multi status", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		jobHandler := mock.NewMockJobHandler(ctrl)
+		jobHandler.EXPECT().GetJobs().Return([]modelsV1.JobStatus{
+			{Name: "job1", Status: "Running", Created: "2022-10-01T12:00:00Z"},
+			{Name: "job2", Status: "Running", Created: "2022-10-01T12:00:01Z"},
+			{Name: "job3", Status: "Succeeded", Created: "2022-10-01T12:00:02Z"},
+		}, nil).Times(1)
+		jobHandler.EXPECT().StopJob("job1").Return(nil).Times(1)
+		jobHandler.EXPECT().StopJob("job2").Return(apiErrors.NewUnknown(errors.New("unhandled error"))).Times(1)
+		controllerTestUtils := setupTest(jobHandler)
+		responseChannel := controllerTestUtils.ExecuteRequestWithBody(http.MethodPost, "/api/v1/jobs/stop", serverModels.Selector{Status: []string{"Running"}})
+		response := <-responseChannel
+		assert.NotNil(t, response)
+
+		if response != nil {
+			assert.Equal(t, http.StatusMultiStatus, response.StatusCode)
+			var report serverModels.BulkReport
+			test.GetResponseBody(response, &report)
+			assert.Equal(t, 1, report.Succeeded)
+			assert.Equal(t, 1, report.Failed)
+			assert.Len(t, report.Results, 2)
+			assert.Equal(t, "job1", report.Results[0].Name)
+			assert.Equal(t, http.StatusOK, report.Results[0].Code)
+			assert.Equal(t, "job2", report.Results[1].Name)
+			assert.Equal(t, http.StatusInternalServerError, report.Results[1].Code)
+		}
+	})
+
+	t.Run("empty selector - unprocessable", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		jobHandler := mock.NewMockJobHandler(ctrl)
+		jobHandler.EXPECT().GetJobs().Times(0)
+		jobHandler.EXPECT().StopJob(gomock.Any()).Times(0)
+		controllerTestUtils := setupTest(jobHandler)
+		responseChannel := controllerTestUtils.ExecuteRequestWithBody(http.MethodPost, "/api/v1/jobs/stop", serverModels.Selector{})
+		response := <-responseChannel
+		assert.NotNil(t, response)
+
+		if response != nil {
+			assert.Equal(t, http.StatusUnprocessableEntity, response.StatusCode)
+		}
+	})
+}
+
+func TestDeleteJobs(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	jobHandler := mock.NewMockJobHandler(ctrl)
+	jobHandler.EXPECT().GetJobs().Return([]modelsV1.JobStatus{{Name: "job1"}, {Name: "job2"}}, nil).Times(1)
+	jobHandler.EXPECT().DeleteJob("job2").Return(nil).Times(1)
+	jobHandler.EXPECT().DeleteJob("job9").Return(apiErrors.NewNotFound("job", "job9")).Times(1)
+	controllerTestUtils := setupTest(jobHandler)
+	responseChannel := controllerTestUtils.ExecuteRequestWithBody(http.MethodDelete, "/api/v1/jobs", serverModels.Selector{Names: []string{"job2", "job9"}})
+	response := <-responseChannel
+	assert.NotNil(t, response)
+
+	if response != nil {
+		assert.Equal(t, http.StatusMultiStatus, response.StatusCode)
+		var report serverModels.BulkReport
+		test.GetResponseBody(response, &report)
+		assert.Equal(t, 1, report.Succeeded)
+		assert.Equal(t, 1, report.Failed)
+		assert.Equal(t, "job9", report.Results[1].Name)
+		assert.Equal(t, http.StatusNotFound, report.Results[1].Code)
+	}
+}
+
 func TestDeleteJob(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		t.Parallel()
@@ -571,3 +906,518 @@ func TestStopJob(t *testing.T) {
 		}
 	})
 }
+
+func TestRerunJob(t *testing.T) {
+	timeLimitSeconds := int64(60)
+	originalJob := models.JobScheduleDescription{
+		JobId:   "job1",
+		Payload: "a_payload",
+		RadixJobComponentConfig: models.RadixJobComponentConfig{
+			Resources:        &v1.ResourceRequirements{Limits: v1.ResourceList{"cpu": "10m", "memory": "128M"}},
+			TimeLimitSeconds: &timeLimitSeconds,
+		},
+	}
+
+	t.Run("with overrides - successful", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		jobHandler := mock.NewMockJobHandler(ctrl)
+		descriptions := rerun.NewDescriptions(store.NewMemoryStore(), jobHandler, nil, nil)
+		assert.NoError(t, descriptions.PutJob("oldjob", &originalJob, ""))
+		overriddenTimeLimitSeconds := int64(600)
+		expectedJob := models.JobScheduleDescription{
+			JobId:   "job1",
+			Payload: "a_payload",
+			RadixJobComponentConfig: models.RadixJobComponentConfig{
+				Resources:        &v1.ResourceRequirements{Limits: v1.ResourceList{"cpu": "10m", "memory": "2Gi"}},
+				TimeLimitSeconds: &overriddenTimeLimitSeconds,
+			},
+		}
+		jobHandler.EXPECT().GetJob("oldjob").Return(&modelsV1.JobStatus{Name: "oldjob", Status: "Failed"}, nil).Times(1)
+		jobHandler.EXPECT().CreateJob(&expectedJob).Return(&modelsV1.JobStatus{Name: "newjob", Status: "Waiting"}, nil).Times(1)
+		jobHandler.EXPECT().MaintainHistoryLimit().Return(nil).Times(1)
+		controllerTestUtils := setupTestWithRerun(jobHandler, descriptions)
+		overrides := map[string]interface{}{"timeLimitSeconds": 600, "resources": map[string]interface{}{"limits": map[string]interface{}{"memory": "2Gi"}}}
+		responseChannel := controllerTestUtils.ExecuteRequestWithBody(http.MethodPost, "/api/v1/jobs/oldjob/rerun", overrides)
+		response := <-responseChannel
+		assert.NotNil(t, response)
+
+		if response != nil {
+			assert.Equal(t, http.StatusOK, response.StatusCode)
+			var returnedJob serverModels.RerunJobStatus
+			test.GetResponseBody(response, &returnedJob)
+			assert.Equal(t, "newjob", returnedJob.Name)
+			assert.Equal(t, "oldjob", returnedJob.RerunOf)
+			assert.Empty(t, returnedJob.RerunOfBatch)
+		}
+		// The re-run can be re-run itself
+		description, err := descriptions.GetJob("newjob")
+		assert.NoError(t, err)
+		assert.Equal(t, expectedJob, *description)
+	})
+
+	t.Run("unknown description - 404 not found", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		jobHandler := mock.NewMockJobHandler(ctrl)
+		jobHandler.EXPECT().GetJob("oldjob").Return(&modelsV1.JobStatus{Name: "oldjob"}, nil).Times(1)
+		jobHandler.EXPECT().CreateJob(gomock.Any()).Times(0)
+		controllerTestUtils := setupTest(jobHandler)
+		responseChannel := controllerTestUtils.ExecuteRequest(http.MethodPost, "/api/v1/jobs/oldjob/rerun")
+		response := <-responseChannel
+		assert.NotNil(t, response)
+
+		if response != nil {
+			assert.Equal(t, http.StatusNotFound, response.StatusCode)
+			var returnedStatus models.Status
+			test.GetResponseBody(response, &returnedStatus)
+			assert.Equal(t, models.StatusReasonNotFound, returnedStatus.Reason)
+		}
+	})
+
+	t.Run("handler returning not found - 404 not found", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		jobHandler := mock.NewMockJobHandler(ctrl)
+		jobHandler.EXPECT().GetJob("oldjob").Return(nil, apiErrors.NewNotFound("job", "oldjob")).Times(1)
+		controllerTestUtils := setupTest(jobHandler)
+		responseChannel := controllerTestUtils.ExecuteRequest(http.MethodPost, "/api/v1/jobs/oldjob/rerun")
+		response := <-responseChannel
+		assert.NotNil(t, response)
+
+		if response != nil {
+			assert.Equal(t, http.StatusNotFound, response.StatusCode)
+		}
+	})
+
+	t.Run("invalid overrides - unprocessable", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		jobHandler := mock.NewMockJobHandler(ctrl)
+		descriptions := rerun.NewDescriptions(store.NewMemoryStore(), jobHandler, nil, nil)
+		assert.NoError(t, descriptions.PutJob("oldjob", &originalJob, ""))
+		jobHandler.EXPECT().GetJob("oldjob").Return(&modelsV1.JobStatus{Name: "oldjob"}, nil).Times(1)
+		jobHandler.EXPECT().CreateJob(gomock.Any()).Times(0)
+		controllerTestUtils := setupTestWithRerun(jobHandler, descriptions)
+		responseChannel := controllerTestUtils.ExecuteRequestWithBody(http.MethodPost, "/api/v1/jobs/oldjob/rerun", map[string]interface{}{"timeLimitSeconds": 0})
+		response := <-responseChannel
+		assert.NotNil(t, response)
+
+		if response != nil {
+			assert.Equal(t, http.StatusUnprocessableEntity, response.StatusCode)
+			var returnedStatus serverModels.Status
+			test.GetResponseBody(response, &returnedStatus)
+			assert.Equal(t, models.StatusReasonInvalid, returnedStatus.Reason)
+			assert.Len(t, returnedStatus.Causes, 1)
+			assert.Equal(t, "timeLimitSeconds", returnedStatus.Causes[0].Field)
+		}
+	})
+}
+
+func TestGetJobsFilter(t *testing.T) {
+	created := time.Date(2022, 11, 1, 12, 0, 0, 0, time.UTC)
+	jobStates := []modelsV1.JobStatus{
+		{Name: "job1", JobId: "id1", Status: "Succeeded", Created: utils.FormatTimestamp(created), Ended: utils.FormatTimestamp(created.Add(time.Hour))},
+		{Name: "job2", JobId: "id2", Status: "Failed", Created: utils.FormatTimestamp(created.Add(time.Minute)), Ended: utils.FormatTimestamp(created.Add(2 * time.Hour))},
+		{Name: "job3", JobId: "id3", BatchName: "batch1", Status: "Running", Created: utils.FormatTimestamp(created.Add(2 * time.Minute))},
+	}
+
+	scenarios := map[string]struct {
+		query         string
+		expectedNames []string
+	}{
+		"no filter":           {query: "", expectedNames: []string{"job1", "job2", "job3"}},
+		"status repeated":     {query: "status=Succeeded&status=Running", expectedNames: []string{"job1", "job3"}},
+		"status comma":        {query: "status=succeeded,failed", expectedNames: []string{"job1", "job2"}},
+		"job id":              {query: "jobId=id2", expectedNames: []string{"job2"}},
+		"batch name":          {query: "batchName=batch1", expectedNames: []string{"job3"}},
+		"created after":       {query: "createdAfter=2022-11-01T12:01:00Z", expectedNames: []string{"job2", "job3"}},
+		"created before":      {query: "createdBefore=2022-11-01T12:01:00Z", expectedNames: []string{"job1", "job2"}},
+		"created time window": {query: "createdAfter=2022-11-01T12:01:00Z&createdBefore=2022-11-01T12:01:00Z", expectedNames: []string{"job2"}},
+		"ended after":         {query: "endedAfter=2022-11-01T13:30:00Z", expectedNames: []string{"job2"}},
+		"combined":            {query: "status=Failed&jobId=id1", expectedNames: []string{}},
+	}
+
+	for name, scenario := range scenarios {
+		scenario := scenario
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			jobHandler := mock.NewMockJobHandler(ctrl)
+			jobHandler.
+				EXPECT().
+				GetJobs().
+				Return(append([]modelsV1.JobStatus{}, jobStates...), nil).
+				Times(1)
+
+			controllerTestUtils := setupTest(jobHandler)
+			response := <-controllerTestUtils.ExecuteRequest(http.MethodGet, "/api/v1/jobs?"+scenario.query)
+			assert.NotNil(t, response)
+
+			if response != nil {
+				assert.Equal(t, http.StatusOK, response.StatusCode)
+				var returnedJobs []modelsV1.JobStatus
+				test.GetResponseBody(response, &returnedJobs)
+				names := make([]string, 0, len(returnedJobs))
+				for _, job := range returnedJobs {
+					names = append(names, job.Name)
+				}
+				assert.Equal(t, scenario.expectedNames, names)
+				assert.Equal(t, fmt.Sprint(len(scenario.expectedNames)), response.Header.Get("X-Total-Count"))
+			}
+		})
+	}
+
+	for _, query := range []string{"status=Unknown", "createdAfter=yesterday", "endedAfter=2022-11-01", "createdAfter=2022-11-02T00:00:00Z&createdBefore=2022-11-01T00:00:00Z"} {
+		query := query
+		t.Run("invalid "+query+" - status code 400", func(t *testing.T) {
+			t.Parallel()
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			jobHandler := mock.NewMockJobHandler(ctrl)
+			jobHandler.
+				EXPECT().
+				GetJobs().
+				Times(0)
+
+			controllerTestUtils := setupTest(jobHandler)
+			response := <-controllerTestUtils.ExecuteRequest(http.MethodGet, "/api/v1/jobs?"+query)
+			assert.NotNil(t, response)
+
+			if response != nil {
+				assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+				var returnedStatus models.Status
+				test.GetResponseBody(response, &returnedStatus)
+				assert.Equal(t, http.StatusBadRequest, returnedStatus.Code)
+				assert.Equal(t, models.StatusFailure, returnedStatus.Status)
+			}
+		})
+	}
+}
+
+func TestGetJobEvents(t *testing.T) {
+	t.Run("not found", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		jobHandler := mock.NewMockJobHandler(ctrl)
+		jobHandler.
+			EXPECT().
+			GetJob("anyjob").
+			Return(nil, apiErrors.NewNotFound("job", "anyjob")).
+			Times(1)
+
+		controllerTestUtils := setupTest(jobHandler)
+		responseChannel := controllerTestUtils.ExecuteRequest(http.MethodGet, "/api/v1/jobs/anyjob/events")
+		response := <-responseChannel
+		assert.NotNil(t, response)
+
+		if response != nil {
+			assert.Equal(t, http.StatusNotFound, response.StatusCode)
+		}
+	})
+
+	t.Run("completed job - stream ends", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		jobHandler := mock.NewMockJobHandler(ctrl)
+		jobHandler.
+			EXPECT().
+			GetJob("job1").
+			Return(&modelsV1.JobStatus{Name: "job1", Status: "Failed"}, nil).
+			Times(1)
+
+		controllerTestUtils := setupTest(jobHandler)
+		responseChannel := controllerTestUtils.ExecuteRequest(http.MethodGet, "/api/v1/jobs/job1/events")
+		response := <-responseChannel
+		assert.NotNil(t, response)
+
+		if response != nil {
+			assert.Equal(t, http.StatusOK, response.StatusCode)
+			body, _ := io.ReadAll(response.Body)
+			assert.Equal(t, "event: status\ndata: {\"name\":\"job1\",\"status\":\"Failed\"}\n\nevent: end\ndata: {}\n\n", string(body))
+		}
+	})
+}
+
+func TestGetJobLogs(t *testing.T) {
+	t.Run("job not found", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		jobHandler := mock.NewMockJobHandler(ctrl)
+		jobHandler.
+			EXPECT().
+			GetJob("anyjob").
+			Return(nil, apiErrors.NewNotFound("job", "anyjob")).
+			Times(1)
+
+		controllerTestUtils := setupTest(jobHandler)
+		responseChannel := controllerTestUtils.ExecuteRequest(http.MethodGet, "/api/v1/jobs/anyjob/logs")
+		response := <-responseChannel
+		assert.NotNil(t, response)
+
+		if response != nil {
+			assert.Equal(t, http.StatusNotFound, response.StatusCode)
+		}
+	})
+
+	t.Run("no pods - not found", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		jobHandler := mock.NewMockJobHandler(ctrl)
+		jobHandler.
+			EXPECT().
+			GetJob("job1").
+			Return(&modelsV1.JobStatus{Name: "job1", Status: "Waiting"}, nil).
+			Times(1)
+
+		controllerTestUtils := setupTest(jobHandler)
+		responseChannel := controllerTestUtils.ExecuteRequest(http.MethodGet, "/api/v1/jobs/job1/logs")
+		response := <-responseChannel
+		assert.NotNil(t, response)
+
+		if response != nil {
+			assert.Equal(t, http.StatusNotFound, response.StatusCode)
+		}
+	})
+
+	t.Run("invalid parameter - status code 400", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		jobHandler := mock.NewMockJobHandler(ctrl)
+		jobHandler.
+			EXPECT().
+			GetJob(gomock.Any()).
+			Times(0)
+
+		controllerTestUtils := setupTest(jobHandler)
+		responseChannel := controllerTestUtils.ExecuteRequest(http.MethodGet, "/api/v1/jobs/job1/logs?tailLines=many")
+		response := <-responseChannel
+		assert.NotNil(t, response)
+
+		if response != nil {
+			assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+		}
+	})
+}
+
+func TestScheduledJob(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	jobHandler := mock.NewMockJobHandler(ctrl)
+	jobHandler.EXPECT().CreateJob(gomock.Any()).Times(0)
+	jobHandler.EXPECT().GetJobs().Return([]modelsV1.JobStatus{{Name: "job1", Status: "Running"}}, nil).Times(1)
+	jobHandler.EXPECT().DeleteJob(gomock.Any()).Times(0)
+	controllerTestUtils := setupTest(jobHandler)
+	request := serverModels.JobScheduleRequest{
+		JobScheduleDescription: models.JobScheduleDescription{JobId: "later", Payload: "payload"},
+		NotBefore:              utils.FormatTimestamp(time.Now().Add(time.Hour)),
+	}
+
+	response := <-controllerTestUtils.ExecuteRequestWithBody(http.MethodPost, "/api/v1/jobs", request)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	var scheduled modelsV1.JobStatus
+	test.GetResponseBody(response, &scheduled)
+	assert.Equal(t, pending.StatusScheduled, scheduled.Status)
+	assert.Equal(t, "later", scheduled.JobId)
+
+	response = <-controllerTestUtils.ExecuteRequest(http.MethodGet, "/api/v1/jobs?status=Scheduled")
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	var jobs []modelsV1.JobStatus
+	test.GetResponseBody(response, &jobs)
+	assert.Equal(t, []modelsV1.JobStatus{scheduled}, jobs)
+
+	response = <-controllerTestUtils.ExecuteRequest(http.MethodGet, fmt.Sprintf("/api/v1/jobs/%s", scheduled.Name))
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+
+	response = <-controllerTestUtils.ExecuteRequest(http.MethodDelete, fmt.Sprintf("/api/v1/jobs/%s", scheduled.Name))
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	jobHandler.EXPECT().GetJob(scheduled.Name).Return(nil, apiErrors.NewNotFound("job", scheduled.Name)).Times(1)
+	response = <-controllerTestUtils.ExecuteRequest(http.MethodGet, fmt.Sprintf("/api/v1/jobs/%s", scheduled.Name))
+	assert.Equal(t, http.StatusNotFound, response.StatusCode)
+}
+
+func TestScheduledJob_CallbackSecretNotKeptInPendingItem(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	jobHandler := mock.NewMockJobHandler(ctrl)
+	jobHandler.EXPECT().CreateJob(gomock.Any()).Times(0)
+	pendingItems := pending.New(store.NewMemoryStore(), "", 0, nil, 0)
+	controllerTestUtils := setupTestWithPending(jobHandler, rerun.NewDescriptions(store.NewMemoryStore(), jobHandler, nil, nil), pendingItems)
+	request := serverModels.JobScheduleRequest{
+		Callback:  serverModels.Callback{CallbackURL: "https://example.com/callback", CallbackSecret: "secret"},
+		NotBefore: utils.FormatTimestamp(time.Now().Add(time.Hour)),
+	}
+
+	response := <-controllerTestUtils.ExecuteRequestWithBody(http.MethodPost, "/api/v1/jobs", request)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	var scheduled modelsV1.JobStatus
+	test.GetResponseBody(response, &scheduled)
+	item, err := pendingItems.Get(pending.KindJob, scheduled.Name)
+	assert.NoError(t, err)
+	if assert.NotNil(t, item) {
+		assert.Empty(t, item.Job.CallbackSecret)
+	}
+}
+
+func TestCreateJob_InvalidNotBefore(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	jobHandler := mock.NewMockJobHandler(ctrl)
+	jobHandler.EXPECT().CreateJob(gomock.Any()).Times(0)
+	controllerTestUtils := setupTest(jobHandler)
+
+	response := <-controllerTestUtils.ExecuteRequestWithBody(http.MethodPost, "/api/v1/jobs", serverModels.JobScheduleRequest{NotBefore: "tomorrow"})
+	assert.Equal(t, http.StatusUnprocessableEntity, response.StatusCode)
+	var status serverModels.Status
+	test.GetResponseBody(response, &status)
+	assert.Equal(t, []serverModels.StatusCause{{Field: "notBefore", Message: "must be a RFC3339 timestamp, e.g. 2022-11-01T15:04:05Z"}}, status.Causes)
+}
+
+func TestQueuedJob(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	jobHandler := mock.NewMockJobHandler(ctrl)
+	jobHandler.EXPECT().CreateJob(gomock.Any()).Times(0)
+	jobHandler.EXPECT().GetJobs().Return([]modelsV1.JobStatus{{Name: "job1", Status: "Running"}}, nil).Times(1)
+	jobHandler.EXPECT().StopJob(gomock.Any()).Times(0)
+	// Jobs are queued until the active jobs are counted
+	controllerTestUtils := setupTestWithPending(jobHandler, rerun.NewDescriptions(store.NewMemoryStore(), jobHandler, nil, nil), pending.New(store.NewMemoryStore(), "", 1, nil, 0))
+
+	var queued []serverModels.QueuedJobStatus
+	for _, jobId := range []string{"first", "second"} {
+		response := <-controllerTestUtils.ExecuteRequestWithBody(http.MethodPost, "/api/v1/jobs", serverModels.JobScheduleRequest{JobScheduleDescription: models.JobScheduleDescription{JobId: jobId}})
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		var status serverModels.QueuedJobStatus
+		test.GetResponseBody(response, &status)
+		assert.Equal(t, pending.StatusQueued, status.Status)
+		assert.Equal(t, jobId, status.JobId)
+		queued = append(queued, status)
+	}
+	assert.Equal(t, 1, queued[0].QueuePosition)
+	assert.Equal(t, 2, queued[1].QueuePosition)
+
+	response := <-controllerTestUtils.ExecuteRequest(http.MethodGet, "/api/v1/jobs?status=Queued")
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	var jobs []serverModels.QueuedJobStatus
+	test.GetResponseBody(response, &jobs)
+	assert.ElementsMatch(t, queued, jobs)
+
+	response = <-controllerTestUtils.ExecuteRequest(http.MethodPost, fmt.Sprintf("/api/v1/jobs/%s/stop", queued[0].Name))
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	response = <-controllerTestUtils.ExecuteRequest(http.MethodGet, fmt.Sprintf("/api/v1/jobs/%s", queued[1].Name))
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	var second serverModels.QueuedJobStatus
+	test.GetResponseBody(response, &second)
+	assert.Equal(t, 1, second.QueuePosition)
+	assert.Equal(t, "Waiting at position 1 in the queue for active jobs to complete", second.Message)
+}
+
+func TestReleasedJob(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	jobHandler := mock.NewMockJobHandler(ctrl)
+	pendingStore := store.NewMemoryStore()
+	// The job1 was created for the scheduled job
+	assert.NoError(t, pendingStore.Put("released/job/compute-20221101120000-abcdefgh", map[string]string{"name": "job1"}))
+	controllerTestUtils := setupTestWithPending(jobHandler, rerun.NewDescriptions(store.NewMemoryStore(), jobHandler, nil, nil), pending.New(pendingStore, "compute", 0, nil, 0))
+	jobHandler.EXPECT().GetJob("job1").Return(nil, apiErrors.NewNotFound("job", "job1")).Times(2)
+
+	response := <-controllerTestUtils.ExecuteRequest(http.MethodGet, "/api/v1/jobs/compute-20221101120000-abcdefgh/logs")
+	assert.Equal(t, http.StatusNotFound, response.StatusCode)
+	response = <-controllerTestUtils.ExecuteRequest(http.MethodPost, "/api/v1/jobs/compute-20221101120000-abcdefgh/rerun")
+	assert.Equal(t, http.StatusNotFound, response.StatusCode)
+}
+
+func TestPatchJob(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	jobHandler := mock.NewMockJobHandler(ctrl)
+	jobHandler.EXPECT().CreateJob(gomock.Any()).Times(0)
+	jobHandler.EXPECT().GetJob("job1").Return(&modelsV1.JobStatus{Name: "job1", Status: "Running"}, nil).Times(1)
+	jobHandler.EXPECT().GetJob("job2").Return(nil, apiErrors.NewNotFound("job", "job2")).Times(1)
+	pendingItems := pending.New(store.NewMemoryStore(), "", 1, map[string]int{"urgent": 100}, time.Hour)
+	controllerTestUtils := setupTestWithPending(jobHandler, rerun.NewDescriptions(store.NewMemoryStore(), jobHandler, nil, nil), pendingItems)
+
+	var queued []serverModels.QueuedJobStatus
+	for _, jobId := range []string{"nightly", "urgent"} {
+		response := <-controllerTestUtils.ExecuteRequestWithBody(http.MethodPost, "/api/v1/jobs", serverModels.JobScheduleRequest{JobScheduleDescription: models.JobScheduleDescription{JobId: jobId}})
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		var status serverModels.QueuedJobStatus
+		test.GetResponseBody(response, &status)
+		queued = append(queued, status)
+	}
+	assert.Equal(t, 2, queued[1].QueuePosition)
+
+	response := <-controllerTestUtils.ExecuteRequestWithBody(http.MethodPatch, fmt.Sprintf("/api/v1/jobs/%s", queued[1].Name), serverModels.QueuePriority{PriorityClass: "urgent"})
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	var urgent serverModels.QueuedJobStatus
+	test.GetResponseBody(response, &urgent)
+	assert.Equal(t, 100, urgent.Priority)
+	assert.Equal(t, 1, urgent.QueuePosition)
+
+	response = <-controllerTestUtils.ExecuteRequestWithBody(http.MethodPatch, fmt.Sprintf("/api/v1/jobs/%s", queued[0].Name), serverModels.QueuePriority{PriorityClass: "nightly"})
+	assert.Equal(t, http.StatusUnprocessableEntity, response.StatusCode)
+	response = <-controllerTestUtils.ExecuteRequestWithBody(http.MethodPatch, fmt.Sprintf("/api/v1/jobs/%s", queued[0].Name), serverModels.QueuePriority{})
+	assert.Equal(t, http.StatusUnprocessableEntity, response.StatusCode)
+	var status serverModels.Status
+	test.GetResponseBody(response, &status)
+	assert.Equal(t, []serverModels.StatusCause{{Field: "priority", Message: "either priority or priorityClass must be set"}}, status.Causes)
+
+	priority := 10
+	response = <-controllerTestUtils.ExecuteRequestWithBody(http.MethodPatch, "/api/v1/jobs/job1", serverModels.QueuePriority{Priority: &priority})
+	assert.Equal(t, http.StatusConflict, response.StatusCode)
+	response = <-controllerTestUtils.ExecuteRequestWithBody(http.MethodPatch, "/api/v1/jobs/job2", serverModels.QueuePriority{Priority: &priority})
+	assert.Equal(t, http.StatusNotFound, response.StatusCode)
+}
+
+func TestCountActiveJobs(t *testing.T) {
+	env := serverModels.NewEnv()
+	newJob := func(name, batchName string, finished batchv1.JobConditionType) *batchv1.Job {
+		job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: env.RadixDeploymentNamespace,
+			Labels:    map[string]string{kube.RadixAppLabel: env.RadixAppName, kube.RadixComponentLabel: env.RadixComponentName},
+		}}
+		if len(batchName) > 0 {
+			job.Labels[kube.RadixBatchNameLabel] = batchName
+		}
+		if len(finished) > 0 {
+			job.Status.Conditions = []batchv1.JobCondition{{Type: finished, Status: corev1.ConditionTrue}}
+		}
+		return job
+	}
+	kubeClient := kubefake.NewSimpleClientset(
+		newJob("job1", "", ""),
+		newJob("job2", "", batchv1.JobComplete),
+		// A batch whose jobs are not created yet
+		newJob("batch1", "batch1", ""),
+		// A batch with an active and a failed job
+		newJob("batch2", "batch2", batchv1.JobComplete),
+		newJob("batch2-a", "batch2", ""),
+		newJob("batch2-b", "batch2", batchv1.JobFailed),
+		// A batch creating its jobs, with an active job
+		newJob("batch3", "batch3", ""),
+		newJob("batch3-a", "batch3", ""),
+	)
+	controller := jobController{jobWatcher: events.NewJobWatcher(kubeClient, env)}
+
+	count, err := controller.countActiveJobs(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 4, count)
+}
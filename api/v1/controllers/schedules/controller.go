@@ -0,0 +1,427 @@
+package schedules
+
+import (
+	"fmt"
+	"net/http"
+
+	serverErrors "github.com/equinor/radix-job-scheduler-server/api/errors"
+	"github.com/equinor/radix-job-scheduler-server/api/v1/controllers"
+	"github.com/equinor/radix-job-scheduler-server/models"
+	serverSchedules "github.com/equinor/radix-job-scheduler-server/schedules"
+	"github.com/equinor/radix-job-scheduler-server/tracing"
+	"github.com/equinor/radix-job-scheduler-server/utils"
+	"github.com/equinor/radix-job-scheduler-server/validation"
+	schedulerModels "github.com/equinor/radix-job-scheduler/models/common"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+const scheduleNameParam = "scheduleName"
+
+type scheduleController struct {
+	*controllers.ControllerBase
+	schedules     *serverSchedules.Schedules
+	payloadSchema *validation.PayloadSchema
+}
+
+// New create a new schedule controller
+func New(schedules *serverSchedules.Schedules, payloadSchema *validation.PayloadSchema) models.Controller {
+	return &scheduleController{
+		schedules:     schedules,
+		payloadSchema: payloadSchema,
+	}
+}
+
+// GetRoutes List the supported routes of this controller
+func (controller *scheduleController) GetRoutes() models.Routes {
+	routes := models.Routes{
+		models.Route{
+			Path:        "/schedules",
+			Method:      http.MethodPost,
+			Permission:  models.PermissionMutate,
+			HandlerFunc: controller.CreateSchedule,
+		},
+		models.Route{
+			Path:        "/schedules",
+			Method:      http.MethodGet,
+			Permission:  models.PermissionRead,
+			HandlerFunc: controller.GetSchedules,
+		},
+		models.Route{
+			Path:        fmt.Sprintf("/schedules/{%s}", scheduleNameParam),
+			Method:      http.MethodGet,
+			Permission:  models.PermissionRead,
+			HandlerFunc: controller.GetSchedule,
+		},
+		models.Route{
+			Path:        fmt.Sprintf("/schedules/{%s}", scheduleNameParam),
+			Method:      http.MethodPut,
+			Permission:  models.PermissionMutate,
+			HandlerFunc: controller.UpdateSchedule,
+		},
+		models.Route{
+			Path:        fmt.Sprintf("/schedules/{%s}", scheduleNameParam),
+			Method:      http.MethodDelete,
+			Permission:  models.PermissionMutate,
+			HandlerFunc: controller.DeleteSchedule,
+		},
+		models.Route{
+			Path:        fmt.Sprintf("/schedules/{%s}/suspend", scheduleNameParam),
+			Method:      http.MethodPost,
+			Permission:  models.PermissionMutate,
+			HandlerFunc: controller.SuspendSchedule,
+		},
+		models.Route{
+			Path:        fmt.Sprintf("/schedules/{%s}/resume", scheduleNameParam),
+			Method:      http.MethodPost,
+			Permission:  models.PermissionMutate,
+			HandlerFunc: controller.ResumeSchedule,
+		},
+		models.Route{
+			Path:        fmt.Sprintf("/schedules/{%s}/trigger", scheduleNameParam),
+			Method:      http.MethodPost,
+			Permission:  models.PermissionMutate,
+			HandlerFunc: controller.TriggerSchedule,
+		},
+	}
+	return routes
+}
+
+// swagger:operation POST /schedules Schedule createSchedule
+// ---
+// summary: Create schedule
+// description: >-
+//   Creates a schedule creating the job or batch of the schedule at the times of its cron expression, in its time zone.
+// parameters:
+// - name: schedule
+//   in: body
+//   description: Schedule to create, with either a job or a batch
+//   required: true
+//   schema:
+//       "$ref": "#/definitions/Schedule"
+// responses:
+//   "200":
+//     description: "Successful create schedule"
+//     schema:
+//        "$ref": "#/definitions/ScheduleStatus"
+//   "409":
+//     description: "A schedule with the name exists"
+//     schema:
+//        "$ref": "#/definitions/Status"
+//   "413":
+//     description: "Request body larger than the maximum size"
+//     schema:
+//        "$ref": "#/definitions/Status"
+//   "422":
+//     description: "Invalid schedule, with each invalid field in causes"
+//     schema:
+//        "$ref": "#/definitions/Status"
+//   "500":
+//     description: "Internal server error"
+//     schema:
+//        "$ref": "#/definitions/Status"
+func (controller *scheduleController) CreateSchedule(w http.ResponseWriter, r *http.Request) {
+	schedule, err := controller.decodeSchedule(r)
+	if err != nil {
+		controller.HandleError(w, r, err)
+		return
+	}
+	log.WithContext(r.Context()).Debugf("Create schedule %s", schedule.Name)
+	status, err := controller.schedules.Create(schedule)
+	if err != nil {
+		controller.HandleError(w, r, err)
+		return
+	}
+	utils.JSONResponse(w, status)
+}
+
+// swagger:operation GET /schedules Schedule getSchedules
+// ---
+// summary: Gets schedules
+// responses:
+//   "200":
+//     description: "Successful get schedules, sorted by name"
+//     schema:
+//        type: "array"
+//        items:
+//           "$ref": "#/definitions/ScheduleStatus"
+//   "500":
+//     description: "Internal server error"
+//     schema:
+//        "$ref": "#/definitions/Status"
+func (controller *scheduleController) GetSchedules(w http.ResponseWriter, r *http.Request) {
+	log.WithContext(r.Context()).Debug("Get schedule list")
+	schedules, err := controller.schedules.List()
+	if err != nil {
+		controller.HandleError(w, r, err)
+		return
+	}
+	utils.JSONResponse(w, schedules)
+}
+
+// swagger:operation GET /schedules/{scheduleName} Schedule getSchedule
+// ---
+// summary: Gets schedule
+// parameters:
+// - name: scheduleName
+//   in: path
+//   description: Name of schedule
+//   type: string
+//   required: true
+// responses:
+//   "200":
+//     description: "Successful get schedule"
+//     schema:
+//        "$ref": "#/definitions/ScheduleStatus"
+//   "404":
+//     description: "Not found"
+//     schema:
+//        "$ref": "#/definitions/Status"
+//   "500":
+//     description: "Internal server error"
+//     schema:
+//        "$ref": "#/definitions/Status"
+func (controller *scheduleController) GetSchedule(w http.ResponseWriter, r *http.Request) {
+	scheduleName := mux.Vars(r)[scheduleNameParam]
+	log.WithContext(r.Context()).Debugf("Get schedule %s", scheduleName)
+	status, err := controller.schedules.Get(scheduleName)
+	if err != nil {
+		controller.HandleError(w, r, err)
+		return
+	}
+	utils.JSONResponse(w, status)
+}
+
+// swagger:operation PUT /schedules/{scheduleName} Schedule updateSchedule
+// ---
+// summary: Update schedule
+// description: >-
+//   Replaces the schedule. The history of its runs is kept, and the next run is at the next time of the cron expression.
+// parameters:
+// - name: scheduleName
+//   in: path
+//   description: Name of schedule
+//   type: string
+//   required: true
+// - name: schedule
+//   in: body
+//   description: Schedule, with either a job or a batch. The name is optional, and must be the name in the path when set
+//   required: true
+//   schema:
+//       "$ref": "#/definitions/Schedule"
+// responses:
+//   "200":
+//     description: "Successful update schedule"
+//     schema:
+//        "$ref": "#/definitions/ScheduleStatus"
+//   "404":
+//     description: "Not found"
+//     schema:
+//        "$ref": "#/definitions/Status"
+//   "413":
+//     description: "Request body larger than the maximum size"
+//     schema:
+//        "$ref": "#/definitions/Status"
+//   "422":
+//     description: "Invalid schedule, with each invalid field in causes"
+//     schema:
+//        "$ref": "#/definitions/Status"
+//   "500":
+//     description: "Internal server error"
+//     schema:
+//        "$ref": "#/definitions/Status"
+func (controller *scheduleController) UpdateSchedule(w http.ResponseWriter, r *http.Request) {
+	scheduleName := mux.Vars(r)[scheduleNameParam]
+	log.WithContext(r.Context()).Debugf("Update schedule %s", scheduleName)
+	schedule, err := controller.decodeSchedule(r)
+	if err != nil {
+		controller.HandleError(w, r, err)
+		return
+	}
+	status, err := controller.schedules.Update(schedule)
+	if err != nil {
+		controller.HandleError(w, r, err)
+		return
+	}
+	utils.JSONResponse(w, status)
+}
+
+// swagger:operation DELETE /schedules/{scheduleName} Schedule deleteSchedule
+// ---
+// summary: Delete schedule
+// description: The jobs and batches created by the schedule are not deleted
+// parameters:
+// - name: scheduleName
+//   in: path
+//   description: Name of schedule
+//   type: string
+//   required: true
+// responses:
+//   "200":
+//     description: "Successful delete schedule"
+//     schema:
+//        "$ref": "#/definitions/Status"
+//   "404":
+//     description: "Not found"
+//     schema:
+//        "$ref": "#/definitions/Status"
+//   "500":
+//     description: "Internal server error"
+//     schema:
+//        "$ref": "#/definitions/Status"
+func (controller *scheduleController) DeleteSchedule(w http.ResponseWriter, r *http.Request) {
+	scheduleName := mux.Vars(r)[scheduleNameParam]
+	log.WithContext(r.Context()).Debugf("Delete schedule %s", scheduleName)
+	if err := controller.schedules.Delete(scheduleName); err != nil {
+		controller.HandleError(w, r, err)
+		return
+	}
+
+	status := schedulerModels.Status{
+		Status:  schedulerModels.StatusSuccess,
+		Code:    http.StatusOK,
+		Message: fmt.Sprintf("schedule %s successfully deleted", scheduleName),
+	}
+	utils.StatusResponse(w, r, &status)
+}
+
+// swagger:operation POST /schedules/{scheduleName}/suspend Schedule suspendSchedule
+// ---
+// summary: Suspend schedule
+// description: A suspended schedule does not run at the times of its cron expression, but can be triggered
+// parameters:
+// - name: scheduleName
+//   in: path
+//   description: Name of schedule
+//   type: string
+//   required: true
+// responses:
+//   "200":
+//     description: "Successful suspend schedule"
+//     schema:
+//        "$ref": "#/definitions/ScheduleStatus"
+//   "404":
+//     description: "Not found"
+//     schema:
+//        "$ref": "#/definitions/Status"
+//   "500":
+//     description: "Internal server error"
+//     schema:
+//        "$ref": "#/definitions/Status"
+func (controller *scheduleController) SuspendSchedule(w http.ResponseWriter, r *http.Request) {
+	controller.setSuspend(w, r, true)
+}
+
+// swagger:operation POST /schedules/{scheduleName}/resume Schedule resumeSchedule
+// ---
+// summary: Resume schedule
+// description: The schedule runs at the next time of its cron expression. Runs missed while it was suspended are not run
+// parameters:
+// - name: scheduleName
+//   in: path
+//   description: Name of schedule
+//   type: string
+//   required: true
+// responses:
+//   "200":
+//     description: "Successful resume schedule"
+//     schema:
+//        "$ref": "#/definitions/ScheduleStatus"
+//   "404":
+//     description: "Not found"
+//     schema:
+//        "$ref": "#/definitions/Status"
+//   "500":
+//     description: "Internal server error"
+//     schema:
+//        "$ref": "#/definitions/Status"
+func (controller *scheduleController) ResumeSchedule(w http.ResponseWriter, r *http.Request) {
+	controller.setSuspend(w, r, false)
+}
+
+func (controller *scheduleController) setSuspend(w http.ResponseWriter, r *http.Request, suspend bool) {
+	scheduleName := mux.Vars(r)[scheduleNameParam]
+	log.WithContext(r.Context()).Debugf("Set suspend of schedule %s to %t", scheduleName, suspend)
+	status, err := controller.schedules.SetSuspend(scheduleName, suspend)
+	if err != nil {
+		controller.HandleError(w, r, err)
+		return
+	}
+	utils.JSONResponse(w, status)
+}
+
+// swagger:operation POST /schedules/{scheduleName}/trigger Schedule triggerSchedule
+// ---
+// summary: Trigger schedule
+// description: >-
+//   Runs the schedule now, also when it is suspended. The concurrency policy of the schedule applies.
+//   The run is added to the history of the schedule, also when it is skipped or fails.
+// parameters:
+// - name: scheduleName
+//   in: path
+//   description: Name of schedule
+//   type: string
+//   required: true
+// responses:
+//   "200":
+//     description: "Successful trigger schedule, with the name of the created job or batch"
+//     schema:
+//        "$ref": "#/definitions/ScheduleRun"
+//   "404":
+//     description: "Not found"
+//     schema:
+//        "$ref": "#/definitions/Status"
+//   "409":
+//     description: "Run skipped, as the concurrency policy is Forbid and the previous run is still active, or another run of the schedule is in progress"
+//     schema:
+//        "$ref": "#/definitions/Status"
+//   "500":
+//     description: "Internal server error"
+//     schema:
+//        "$ref": "#/definitions/Status"
+func (controller *scheduleController) TriggerSchedule(w http.ResponseWriter, r *http.Request) {
+	scheduleName := mux.Vars(r)[scheduleNameParam]
+	log.WithContext(r.Context()).Debugf("Trigger schedule %s", scheduleName)
+	run, err := controller.schedules.Trigger(r.Context(), scheduleName)
+	if err != nil {
+		controller.HandleError(w, r, err)
+		return
+	}
+	utils.JSONResponse(w, run)
+}
+
+// decodeSchedule Decodes and validates the schedule in the request body. The name in the path, if any, is the name of the schedule
+func (controller *scheduleController) decodeSchedule(r *http.Request) (*models.Schedule, error) {
+	var schedule models.Schedule
+	_, span := tracing.StartSpan(r.Context(), "DecodeRequestBody")
+	err := utils.DecodeRequestBody(r, &schedule)
+	tracing.EndSpan(span, err)
+	if err != nil {
+		return nil, err
+	}
+
+	var causes []models.StatusCause
+	if scheduleName, ok := mux.Vars(r)[scheduleNameParam]; ok {
+		if len(schedule.Name) > 0 && schedule.Name != scheduleName {
+			causes = append(causes, models.StatusCause{Field: "name", Message: fmt.Sprintf("must be the name in the path, %s", scheduleName)})
+		}
+		schedule.Name = scheduleName
+	}
+	causes = append(causes, serverSchedules.Validate(&schedule)...)
+	if schedule.Job != nil {
+		causes = append(causes, validation.ValidateJobScheduleDescription("job", schedule.Job)...)
+		causes = append(causes, controller.payloadSchema.ValidateJobPayload("job", schedule.Job)...)
+	}
+	if schedule.Batch != nil {
+		batchCauses := validation.ValidateBatchScheduleDescription(schedule.Batch)
+		batchCauses = append(batchCauses, controller.payloadSchema.ValidateBatchPayloads(schedule.Batch)...)
+		for _, cause := range batchCauses {
+			cause.Field = "batch." + cause.Field
+			causes = append(causes, cause)
+		}
+	}
+	if len(causes) > 0 {
+		return nil, serverErrors.NewInvalidFields(causes)
+	}
+	return &schedule, nil
+}
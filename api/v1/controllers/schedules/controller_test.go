@@ -0,0 +1,134 @@
+package schedules
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/equinor/radix-job-scheduler-server/api/utils/test"
+	serverModels "github.com/equinor/radix-job-scheduler-server/models"
+	serverSchedules "github.com/equinor/radix-job-scheduler-server/schedules"
+	"github.com/equinor/radix-job-scheduler-server/store"
+	models "github.com/equinor/radix-job-scheduler/models/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupTest() (*test.ControllerTestUtils, *serverSchedules.Schedules) {
+	schedules := serverSchedules.New(store.NewMemoryStore())
+	count := 0
+	schedules.SetRunner(serverSchedules.KindJob, &serverSchedules.Runner{
+		Create: func(ctx context.Context, schedule *serverModels.Schedule) (string, error) {
+			count++
+			return fmt.Sprintf("%s-%d", schedule.Name, count), nil
+		},
+		IsActive: func(ctx context.Context, name string) (bool, error) { return true, nil },
+		Stop:     func(ctx context.Context, name string) error { return nil },
+	})
+	controllerTestUtils := test.New(New(schedules, nil))
+	return &controllerTestUtils, schedules
+}
+
+func TestCreateSchedule(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		controllerTestUtils, _ := setupTest()
+		schedule := serverModels.Schedule{Name: "nightly", Cron: "30 2 * * *", TimeZone: "Europe/Oslo", Job: &models.JobScheduleDescription{Payload: "payload"}}
+		response := <-controllerTestUtils.ExecuteRequestWithBody(http.MethodPost, "/api/v1/schedules", schedule)
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		var status serverModels.ScheduleStatus
+		test.GetResponseBody(response, &status)
+		assert.Equal(t, schedule, status.Schedule)
+		assert.NotEmpty(t, status.NextRun)
+
+		response = <-controllerTestUtils.ExecuteRequestWithBody(http.MethodPost, "/api/v1/schedules", schedule)
+		assert.Equal(t, http.StatusConflict, response.StatusCode)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+		controllerTestUtils, _ := setupTest()
+		schedule := serverModels.Schedule{
+			Name:  "nightly",
+			Cron:  "30 2 * * *",
+			Batch: &models.BatchScheduleDescription{},
+		}
+		response := <-controllerTestUtils.ExecuteRequestWithBody(http.MethodPost, "/api/v1/schedules", schedule)
+		assert.Equal(t, http.StatusUnprocessableEntity, response.StatusCode)
+		var status serverModels.Status
+		test.GetResponseBody(response, &status)
+		assert.Equal(t, []serverModels.StatusCause{{Field: "batch.jobScheduleDescriptions", Message: "must have at least one job"}}, status.Causes)
+	})
+}
+
+func TestUpdateSchedule(t *testing.T) {
+	t.Parallel()
+	controllerTestUtils, schedules := setupTest()
+	_, err := schedules.Create(&serverModels.Schedule{Name: "nightly", Cron: "30 2 * * *", Job: &models.JobScheduleDescription{}})
+	assert.NoError(t, err)
+
+	response := <-controllerTestUtils.ExecuteRequestWithBody(http.MethodPut, "/api/v1/schedules/nightly", serverModels.Schedule{Cron: "@hourly", Job: &models.JobScheduleDescription{}})
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	var status serverModels.ScheduleStatus
+	test.GetResponseBody(response, &status)
+	assert.Equal(t, "nightly", status.Name)
+	assert.Equal(t, "@hourly", status.Cron)
+
+	response = <-controllerTestUtils.ExecuteRequestWithBody(http.MethodPut, "/api/v1/schedules/nightly", serverModels.Schedule{Name: "weekly", Cron: "@hourly", Job: &models.JobScheduleDescription{}})
+	assert.Equal(t, http.StatusUnprocessableEntity, response.StatusCode)
+
+	response = <-controllerTestUtils.ExecuteRequestWithBody(http.MethodPut, "/api/v1/schedules/weekly", serverModels.Schedule{Cron: "@weekly", Job: &models.JobScheduleDescription{}})
+	assert.Equal(t, http.StatusNotFound, response.StatusCode)
+}
+
+func TestSuspendAndTriggerSchedule(t *testing.T) {
+	t.Parallel()
+	controllerTestUtils, schedules := setupTest()
+	_, err := schedules.Create(&serverModels.Schedule{Name: "nightly", Cron: "30 2 * * *", ConcurrencyPolicy: serverModels.ConcurrencyPolicyForbid, Job: &models.JobScheduleDescription{}})
+	assert.NoError(t, err)
+
+	response := <-controllerTestUtils.ExecuteRequest(http.MethodPost, "/api/v1/schedules/nightly/suspend")
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	var status serverModels.ScheduleStatus
+	test.GetResponseBody(response, &status)
+	assert.True(t, status.Suspend)
+	assert.Empty(t, status.NextRun)
+
+	response = <-controllerTestUtils.ExecuteRequest(http.MethodPost, "/api/v1/schedules/nightly/trigger")
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	var run serverModels.ScheduleRun
+	test.GetResponseBody(response, &run)
+	assert.Equal(t, serverModels.ScheduleRunCreated, run.Result)
+	assert.Equal(t, serverModels.ScheduleTriggerManual, run.Trigger)
+	assert.Equal(t, "nightly-1", run.Name)
+
+	// The first run is still active
+	response = <-controllerTestUtils.ExecuteRequest(http.MethodPost, "/api/v1/schedules/nightly/trigger")
+	assert.Equal(t, http.StatusConflict, response.StatusCode)
+
+	response = <-controllerTestUtils.ExecuteRequest(http.MethodPost, "/api/v1/schedules/nightly/resume")
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	status = serverModels.ScheduleStatus{}
+	test.GetResponseBody(response, &status)
+	assert.False(t, status.Suspend)
+	assert.NotEmpty(t, status.NextRun)
+	assert.Len(t, status.Runs, 2)
+	assert.Equal(t, serverModels.ScheduleRunSkipped, status.Runs[0].Result)
+}
+
+func TestDeleteSchedule(t *testing.T) {
+	t.Parallel()
+	controllerTestUtils, schedules := setupTest()
+	_, err := schedules.Create(&serverModels.Schedule{Name: "nightly", Cron: "30 2 * * *", Job: &models.JobScheduleDescription{}})
+	assert.NoError(t, err)
+
+	response := <-controllerTestUtils.ExecuteRequest(http.MethodDelete, "/api/v1/schedules/nightly")
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	response = <-controllerTestUtils.ExecuteRequest(http.MethodGet, "/api/v1/schedules")
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	var list []serverModels.ScheduleStatus
+	test.GetResponseBody(response, &list)
+	assert.Empty(t, list)
+	response = <-controllerTestUtils.ExecuteRequest(http.MethodGet, "/api/v1/schedules/nightly")
+	assert.Equal(t, http.StatusNotFound, response.StatusCode)
+}
@@ -0,0 +1,59 @@
+package schema
+
+import (
+	"net/http"
+
+	serverErrors "github.com/equinor/radix-job-scheduler-server/api/errors"
+	"github.com/equinor/radix-job-scheduler-server/api/v1/controllers"
+	"github.com/equinor/radix-job-scheduler-server/models"
+	"github.com/equinor/radix-job-scheduler-server/validation"
+)
+
+type schemaController struct {
+	*controllers.ControllerBase
+	payloadSchema *validation.PayloadSchema
+}
+
+// New create a new schema controller
+func New(payloadSchema *validation.PayloadSchema) models.Controller {
+	return &schemaController{
+		payloadSchema: payloadSchema,
+	}
+}
+
+// GetRoutes List the supported routes of this controller
+func (controller *schemaController) GetRoutes() models.Routes {
+	routes := models.Routes{
+		models.Route{
+			Path:        "/schema/payload",
+			Method:      http.MethodGet,
+			Permission:  models.PermissionRead,
+			HandlerFunc: controller.GetPayloadSchema,
+		},
+	}
+	return routes
+}
+
+// swagger:operation GET /schema/payload Schema getPayloadSchema
+// ---
+// summary: Gets the JSON Schema the payloads of jobs must match
+// produces:
+// - application/schema+json
+// responses:
+//   "200":
+//     description: "The JSON Schema of payloads"
+//     schema:
+//       type: object
+//   "404":
+//     description: "No payload schema is configured"
+//     schema:
+//        "$ref": "#/definitions/Status"
+func (controller *schemaController) GetPayloadSchema(w http.ResponseWriter, r *http.Request) {
+	if controller.payloadSchema == nil {
+		controller.HandleError(w, r, serverErrors.NewNotFound("No payload schema is configured"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/schema+json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(controller.payloadSchema.Document())
+}
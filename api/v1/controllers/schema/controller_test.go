@@ -0,0 +1,53 @@
+package schema
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/equinor/radix-job-scheduler-server/api/utils/test"
+	"github.com/equinor/radix-job-scheduler-server/validation"
+	models "github.com/equinor/radix-job-scheduler/models/common"
+	"github.com/stretchr/testify/assert"
+)
+
+const payloadSchemaDocument = `{"type": "object", "required": ["name"]}`
+
+func setupTest(payloadSchema *validation.PayloadSchema) *test.ControllerTestUtils {
+	controllerTestUtils := test.New(New(payloadSchema))
+	return &controllerTestUtils
+}
+
+func TestGetPayloadSchema(t *testing.T) {
+	t.Run("schema - success", func(t *testing.T) {
+		t.Parallel()
+		payloadSchema, err := validation.NewPayloadSchema("", payloadSchemaDocument)
+		assert.NoError(t, err)
+		controllerTestUtils := setupTest(payloadSchema)
+		responseChannel := controllerTestUtils.ExecuteRequest(http.MethodGet, "/api/v1/schema/payload")
+		response := <-responseChannel
+		assert.NotNil(t, response)
+
+		if response != nil {
+			assert.Equal(t, http.StatusOK, response.StatusCode)
+			assert.Equal(t, "application/schema+json", response.Header.Get("Content-Type"))
+			body, _ := io.ReadAll(response.Body)
+			assert.JSONEq(t, payloadSchemaDocument, string(body))
+		}
+	})
+
+	t.Run("no schema - 404 not found", func(t *testing.T) {
+		t.Parallel()
+		controllerTestUtils := setupTest(nil)
+		responseChannel := controllerTestUtils.ExecuteRequest(http.MethodGet, "/api/v1/schema/payload")
+		response := <-responseChannel
+		assert.NotNil(t, response)
+
+		if response != nil {
+			assert.Equal(t, http.StatusNotFound, response.StatusCode)
+			var returnedStatus models.Status
+			test.GetResponseBody(response, &returnedStatus)
+			assert.Equal(t, models.StatusReasonNotFound, returnedStatus.Reason)
+		}
+	})
+}
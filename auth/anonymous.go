@@ -0,0 +1,14 @@
+package auth
+
+import "net/http"
+
+type anonymousAuthenticator struct{}
+
+// NewAnonymousAuthenticator Creates an authenticator accepting all requests as anonymous
+func NewAnonymousAuthenticator() Authenticator {
+	return &anonymousAuthenticator{}
+}
+
+func (authenticator *anonymousAuthenticator) Authenticate(*http.Request) (*Identity, error) {
+	return anonymousIdentity(), nil
+}
@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/equinor/radix-job-scheduler-server/models"
+	"github.com/equinor/radix-job-scheduler-server/utils"
+	schedulerModels "github.com/equinor/radix-job-scheduler/models/common"
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/negroni/v2"
+	"k8s.io/client-go/kubernetes"
+)
+
+var (
+	// ErrMissingToken The request has no bearer token
+	ErrMissingToken = errors.New("missing bearer token")
+	// ErrInvalidToken The bearer token of the request is not valid
+	ErrInvalidToken = errors.New("invalid bearer token")
+	// ErrUnavailable The bearer token of the request could not be validated, e.g. the API server is not available
+	ErrUnavailable = errors.New("authentication is unavailable")
+)
+
+// Authenticator Authenticates the caller of a request
+type Authenticator interface {
+	// Authenticate Returns the identity of the caller, or an error when the request is not authenticated
+	Authenticate(r *http.Request) (*Identity, error)
+}
+
+// NewAuthenticator Creates the authenticator for the authentication mode of the environment
+func NewAuthenticator(env *models.Env, kubeClient kubernetes.Interface) (Authenticator, error) {
+	switch env.AuthMode {
+	case models.AuthModeAnonymous:
+		return NewAnonymousAuthenticator(), nil
+	case models.AuthModeToken:
+		return NewStaticTokenAuthenticator(env.AuthTokenFile)
+	case models.AuthModeServiceAccount:
+		return NewServiceAccountAuthenticator(kubeClient, env.AuthAudiences), nil
+	default:
+		return nil, fmt.Errorf("unsupported authentication mode %s", env.AuthMode)
+	}
+}
+
+// NewMiddleware Creates a middleware authenticating each request, responding 401 when authentication fails,
+// and 503 when the token could not be validated
+func NewMiddleware(authenticator Authenticator) negroni.Handler {
+	return negroni.HandlerFunc(func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		identity, err := authenticator.Authenticate(r)
+		if errors.Is(err, ErrUnavailable) {
+			log.WithContext(r.Context()).Errorf("authentication failed: %v", err)
+			utils.StatusResponse(w, r, &schedulerModels.Status{
+				Status:  schedulerModels.StatusFailure,
+				Code:    http.StatusServiceUnavailable,
+				Reason:  models.StatusReasonServiceUnavailable,
+				Message: ErrUnavailable.Error(),
+			})
+			return
+		}
+		if err != nil {
+			log.WithContext(r.Context()).Warnf("authentication failed: %v", err)
+			message := "authentication failed"
+			if errors.Is(err, ErrMissingToken) || errors.Is(err, ErrInvalidToken) {
+				message = err.Error()
+			}
+			w.Header().Set("WWW-Authenticate", `Bearer realm="radix-job-scheduler"`)
+			utils.StatusResponse(w, r, &schedulerModels.Status{
+				Status:  schedulerModels.StatusFailure,
+				Code:    http.StatusUnauthorized,
+				Reason:  models.StatusReasonUnauthorized,
+				Message: message,
+			})
+			return
+		}
+		next(w, r.WithContext(WithIdentity(r.Context(), identity)))
+	})
+}
+
+func getBearerToken(r *http.Request) (string, error) {
+	authorization := r.Header.Get("Authorization")
+	if len(authorization) == 0 {
+		return "", ErrMissingToken
+	}
+	scheme, token, found := strings.Cut(authorization, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") || len(strings.TrimSpace(token)) == 0 {
+		return "", ErrMissingToken
+	}
+	return strings.TrimSpace(token), nil
+}
@@ -0,0 +1,189 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/equinor/radix-job-scheduler-server/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/negroni/v2"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func newRequest(token string) *http.Request {
+	request := httptest.NewRequest(http.MethodGet, "/api/v1/jobs", nil)
+	if len(token) > 0 {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+	return request
+}
+
+func writeTokenFile(t *testing.T, token string) string {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte(token+"\n"), 0600))
+	return tokenFile
+}
+
+func newFakeKubeClient(validTokens map[string]string) (*kubefake.Clientset, *int) {
+	kubeClient := kubefake.NewSimpleClientset()
+	reviews := 0
+	kubeClient.PrependReactor("create", "tokenreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		reviews++
+		tokenReview := action.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+		if userName, ok := validTokens[tokenReview.Spec.Token]; ok {
+			tokenReview.Status.Authenticated = true
+			tokenReview.Status.User = authenticationv1.UserInfo{Username: userName, Groups: []string{"system:serviceaccounts"}}
+		}
+		return true, tokenReview, nil
+	})
+	return kubeClient, &reviews
+}
+
+func TestAnonymousAuthenticator(t *testing.T) {
+	identity, err := NewAnonymousAuthenticator().Authenticate(newRequest(""))
+	assert.NoError(t, err)
+	assert.Equal(t, AnonymousName, identity.Name)
+}
+
+func TestStaticTokenAuthenticator(t *testing.T) {
+	t.Run("valid token", func(t *testing.T) {
+		authenticator, err := NewStaticTokenAuthenticator(writeTokenFile(t, "secret-token"))
+		require.NoError(t, err)
+		identity, err := authenticator.Authenticate(newRequest("secret-token"))
+		assert.NoError(t, err)
+		assert.Equal(t, StaticTokenName, identity.Name)
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		authenticator, err := NewStaticTokenAuthenticator(writeTokenFile(t, "secret-token"))
+		require.NoError(t, err)
+		_, err = authenticator.Authenticate(newRequest("other-token"))
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("missing token", func(t *testing.T) {
+		authenticator, err := NewStaticTokenAuthenticator(writeTokenFile(t, "secret-token"))
+		require.NoError(t, err)
+		_, err = authenticator.Authenticate(newRequest(""))
+		assert.ErrorIs(t, err, ErrMissingToken)
+	})
+
+	t.Run("rotated token file", func(t *testing.T) {
+		tokenFile := writeTokenFile(t, "secret-token")
+		authenticator, err := NewStaticTokenAuthenticator(tokenFile)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(tokenFile, []byte("new-token"), 0600))
+		require.NoError(t, os.Chtimes(tokenFile, time.Now(), time.Now().Add(time.Minute)))
+		_, err = authenticator.Authenticate(newRequest("secret-token"))
+		assert.ErrorIs(t, err, ErrInvalidToken)
+		_, err = authenticator.Authenticate(newRequest("new-token"))
+		assert.NoError(t, err)
+	})
+
+	t.Run("removed token file", func(t *testing.T) {
+		tokenFile := writeTokenFile(t, "secret-token")
+		authenticator, err := NewStaticTokenAuthenticator(tokenFile)
+		require.NoError(t, err)
+		require.NoError(t, os.Remove(tokenFile))
+		_, err = authenticator.Authenticate(newRequest("secret-token"))
+		assert.ErrorIs(t, err, ErrUnavailable)
+	})
+
+	t.Run("missing token file", func(t *testing.T) {
+		_, err := NewStaticTokenAuthenticator(filepath.Join(t.TempDir(), "missing"))
+		assert.Error(t, err)
+	})
+}
+
+func TestServiceAccountAuthenticator(t *testing.T) {
+	t.Run("valid token", func(t *testing.T) {
+		kubeClient, reviews := newFakeKubeClient(map[string]string{"sa-token": "system:serviceaccount:app-env:orchestrator"})
+		authenticator := NewServiceAccountAuthenticator(kubeClient, nil)
+		identity, err := authenticator.Authenticate(newRequest("sa-token"))
+		assert.NoError(t, err)
+		assert.Equal(t, "system:serviceaccount:app-env:orchestrator", identity.Name)
+		assert.Equal(t, []string{"system:serviceaccounts"}, identity.Groups)
+
+		_, err = authenticator.Authenticate(newRequest("sa-token"))
+		assert.NoError(t, err)
+		assert.Equal(t, 1, *reviews, "successful review should be cached")
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		kubeClient, _ := newFakeKubeClient(map[string]string{"sa-token": "system:serviceaccount:app-env:orchestrator"})
+		_, err := NewServiceAccountAuthenticator(kubeClient, nil).Authenticate(newRequest("other-token"))
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("failed review", func(t *testing.T) {
+		kubeClient := kubefake.NewSimpleClientset()
+		kubeClient.PrependReactor("create", "tokenreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			return true, nil, errors.New("connection refused")
+		})
+		authenticator := NewServiceAccountAuthenticator(kubeClient, nil)
+		_, err := authenticator.Authenticate(newRequest("sa-token"))
+		assert.ErrorIs(t, err, ErrUnavailable)
+
+		recorder := httptest.NewRecorder()
+		negroni.New(NewMiddleware(authenticator)).ServeHTTP(recorder, newRequest("sa-token"))
+		assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+		assert.Empty(t, recorder.Header().Get("WWW-Authenticate"))
+		var status models.Status
+		assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &status))
+		assert.Equal(t, models.StatusReasonServiceUnavailable, status.Reason)
+	})
+
+	t.Run("expired cache entry is reviewed again", func(t *testing.T) {
+		kubeClient, reviews := newFakeKubeClient(map[string]string{"sa-token": "system:serviceaccount:app-env:orchestrator"})
+		authenticator := NewServiceAccountAuthenticator(kubeClient, nil).(*serviceAccountAuthenticator)
+		now := time.Now()
+		authenticator.now = func() time.Time { return now }
+		_, err := authenticator.Authenticate(newRequest("sa-token"))
+		assert.NoError(t, err)
+		now = now.Add(tokenReviewCacheTTL + time.Second)
+		_, err = authenticator.Authenticate(newRequest("sa-token"))
+		assert.NoError(t, err)
+		assert.Equal(t, 2, *reviews)
+	})
+}
+
+func TestMiddleware(t *testing.T) {
+	authenticator, err := NewStaticTokenAuthenticator(writeTokenFile(t, "secret-token"))
+	require.NoError(t, err)
+	var handledIdentity *Identity
+	n := negroni.New(NewMiddleware(authenticator))
+	n.UseHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handledIdentity = GetIdentity(r.Context())
+	})
+
+	t.Run("authenticated request is handled with identity", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		n.ServeHTTP(recorder, newRequest("secret-token"))
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, StaticTokenName, handledIdentity.Name)
+	})
+
+	t.Run("unauthenticated request - status code 401", func(t *testing.T) {
+		handledIdentity = nil
+		recorder := httptest.NewRecorder()
+		n.ServeHTTP(recorder, newRequest("other-token"))
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+		assert.NotEmpty(t, recorder.Header().Get("WWW-Authenticate"))
+		assert.Nil(t, handledIdentity)
+		var status models.Status
+		assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &status))
+		assert.Equal(t, http.StatusUnauthorized, status.Code)
+		assert.Equal(t, models.StatusReasonUnauthorized, status.Reason)
+		assert.Equal(t, ErrInvalidToken.Error(), status.Message)
+	})
+}
@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/equinor/radix-job-scheduler-server/models"
+	"sigs.k8s.io/yaml"
+)
+
+// ErrForbidden The caller does not have the permission required by the route
+var ErrForbidden = errors.New("forbidden")
+
+// Policy Roles with permissions, and the identities bound to the roles
+type Policy struct {
+	// Roles Permissions of each role, by role name
+	Roles map[string][]models.Permission `json:"roles"`
+	// Bindings Identities bound to roles
+	Bindings []RoleBinding `json:"bindings"`
+}
+
+// RoleBinding Binds users and groups to a role
+type RoleBinding struct {
+	// Role Name of the role
+	Role string `json:"role"`
+	// Users Names of identities bound to the role
+	Users []string `json:"users,omitempty"`
+	// Groups Groups of identities bound to the role
+	Groups []string `json:"groups,omitempty"`
+}
+
+type allowAllAuthorizer struct{}
+
+type policyAuthorizer struct {
+	policy Policy
+}
+
+// NewAuthorizer Creates an authorizer with the policy in the YAML or JSON file.
+// When no file is set, all callers have all permissions
+func NewAuthorizer(policyFile string) (models.Authorizer, error) {
+	if len(policyFile) == 0 {
+		return &allowAllAuthorizer{}, nil
+	}
+	content, err := os.ReadFile(policyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the policy file: %w", err)
+	}
+	var policy Policy
+	if err := yaml.UnmarshalStrict(content, &policy); err != nil {
+		return nil, fmt.Errorf("failed to parse the policy file %s: %w", policyFile, err)
+	}
+	return NewPolicyAuthorizer(policy)
+}
+
+// NewPolicyAuthorizer Creates an authorizer with the policy
+func NewPolicyAuthorizer(policy Policy) (models.Authorizer, error) {
+	for role, permissions := range policy.Roles {
+		for _, permission := range permissions {
+			if permission != models.PermissionRead && permission != models.PermissionMutate {
+				return nil, fmt.Errorf("the role %s has unknown permission %s, expected %s or %s", role, permission, models.PermissionRead, models.PermissionMutate)
+			}
+		}
+	}
+	for _, binding := range policy.Bindings {
+		if _, ok := policy.Roles[binding.Role]; !ok {
+			return nil, fmt.Errorf("the policy binds undefined role %s", binding.Role)
+		}
+	}
+	return &policyAuthorizer{policy: policy}, nil
+}
+
+func (authorizer *allowAllAuthorizer) Authorize(*http.Request, models.Permission) error {
+	return nil
+}
+
+func (authorizer *policyAuthorizer) Authorize(r *http.Request, permission models.Permission) error {
+	identity := GetIdentity(r.Context())
+	if identity == nil {
+		return ErrForbidden
+	}
+	for _, binding := range authorizer.policy.Bindings {
+		if !binding.matches(identity) {
+			continue
+		}
+		for _, rolePermission := range authorizer.policy.Roles[binding.Role] {
+			if rolePermission == permission {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("%w: %s does not have the permission %s", ErrForbidden, identity.Name, permission)
+}
+
+func (binding *RoleBinding) matches(identity *Identity) bool {
+	for _, user := range binding.Users {
+		if user == identity.Name {
+			return true
+		}
+	}
+	for _, group := range binding.Groups {
+		for _, identityGroup := range identity.Groups {
+			if group == identityGroup {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/equinor/radix-job-scheduler-server/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPolicy = `
+roles:
+  reader: [read]
+  operator: [read, mutate]
+bindings:
+  - role: reader
+    users: ["system:serviceaccount:app-env:dashboard"]
+  - role: operator
+    users: ["system:serviceaccount:app-env:orchestrator"]
+  - role: operator
+    groups: ["operations"]
+`
+
+func newRequestWithIdentity(identity *Identity) *http.Request {
+	request := httptest.NewRequest(http.MethodGet, "/api/v1/jobs", nil)
+	if identity != nil {
+		request = request.WithContext(WithIdentity(request.Context(), identity))
+	}
+	return request
+}
+
+func TestAuthorizer(t *testing.T) {
+	policyFile := filepath.Join(t.TempDir(), "policy.yaml")
+	require.NoError(t, os.WriteFile(policyFile, []byte(testPolicy), 0600))
+	authorizer, err := NewAuthorizer(policyFile)
+	require.NoError(t, err)
+
+	scenarios := []struct {
+		name       string
+		identity   *Identity
+		permission models.Permission
+		allowed    bool
+	}{
+		{name: "reader can read", identity: &Identity{Name: "system:serviceaccount:app-env:dashboard"}, permission: models.PermissionRead, allowed: true},
+		{name: "reader cannot mutate", identity: &Identity{Name: "system:serviceaccount:app-env:dashboard"}, permission: models.PermissionMutate, allowed: false},
+		{name: "operator can read", identity: &Identity{Name: "system:serviceaccount:app-env:orchestrator"}, permission: models.PermissionRead, allowed: true},
+		{name: "operator can mutate", identity: &Identity{Name: "system:serviceaccount:app-env:orchestrator"}, permission: models.PermissionMutate, allowed: true},
+		{name: "group member can mutate", identity: &Identity{Name: "user", Groups: []string{"operations"}}, permission: models.PermissionMutate, allowed: true},
+		{name: "unbound identity cannot read", identity: &Identity{Name: "other"}, permission: models.PermissionRead, allowed: false},
+		{name: "missing identity cannot read", identity: nil, permission: models.PermissionRead, allowed: false},
+	}
+
+	for _, scenario := range scenarios {
+		scenario := scenario
+		t.Run(scenario.name, func(t *testing.T) {
+			t.Parallel()
+			err := authorizer.Authorize(newRequestWithIdentity(scenario.identity), scenario.permission)
+			if scenario.allowed {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrForbidden)
+			}
+		})
+	}
+}
+
+func TestAuthorizer_NoPolicyFileAllowsAll(t *testing.T) {
+	authorizer, err := NewAuthorizer("")
+	require.NoError(t, err)
+	assert.NoError(t, authorizer.Authorize(newRequestWithIdentity(nil), models.PermissionMutate))
+}
+
+func TestAuthorizer_InvalidPolicy(t *testing.T) {
+	_, err := NewPolicyAuthorizer(Policy{Bindings: []RoleBinding{{Role: "undefined", Users: []string{"user"}}}})
+	assert.Error(t, err)
+
+	_, err = NewPolicyAuthorizer(Policy{Roles: map[string][]models.Permission{"writer": {models.PermissionRead, "write"}}})
+	assert.EqualError(t, err, "the role writer has unknown permission write, expected read or mutate")
+}
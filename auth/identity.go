@@ -0,0 +1,35 @@
+package auth
+
+import "context"
+
+const (
+	// AnonymousName Name of the identity of unauthenticated requests
+	AnonymousName = "system:anonymous"
+	// UnauthenticatedGroup Group of the identity of unauthenticated requests
+	UnauthenticatedGroup = "system:unauthenticated"
+)
+
+// Identity The authenticated caller of a request
+type Identity struct {
+	// Name of the caller, e.g. the ServiceAccount user name system:serviceaccount:<namespace>:<name>
+	Name string
+	// Groups the caller is member of
+	Groups []string
+}
+
+type identityContextKey struct{}
+
+// WithIdentity Returns a copy of ctx holding the identity
+func WithIdentity(ctx context.Context, identity *Identity) context.Context {
+	return context.WithValue(ctx, identityContextKey{}, identity)
+}
+
+// GetIdentity Gets the identity of the request, or nil when ctx does not hold an identity
+func GetIdentity(ctx context.Context) *Identity {
+	identity, _ := ctx.Value(identityContextKey{}).(*Identity)
+	return identity
+}
+
+func anonymousIdentity() *Identity {
+	return &Identity{Name: AnonymousName, Groups: []string{UnauthenticatedGroup}}
+}
@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const tokenReviewCacheTTL = time.Minute
+
+type tokenReviewResult struct {
+	identity *Identity
+	expires  time.Time
+}
+
+type serviceAccountAuthenticator struct {
+	kubeClient kubernetes.Interface
+	audiences  []string
+	mu         sync.Mutex
+	cache      map[[sha256.Size]byte]tokenReviewResult
+	now        func() time.Time
+}
+
+// NewServiceAccountAuthenticator Creates an authenticator validating bearer tokens, e.g. ServiceAccount tokens,
+// with a Kubernetes TokenReview. Successful reviews are cached for a minute
+func NewServiceAccountAuthenticator(kubeClient kubernetes.Interface, audiences []string) Authenticator {
+	return &serviceAccountAuthenticator{
+		kubeClient: kubeClient,
+		audiences:  audiences,
+		cache:      make(map[[sha256.Size]byte]tokenReviewResult),
+		now:        time.Now,
+	}
+}
+
+func (authenticator *serviceAccountAuthenticator) Authenticate(r *http.Request) (*Identity, error) {
+	token, err := getBearerToken(r)
+	if err != nil {
+		return nil, err
+	}
+
+	key := sha256.Sum256([]byte(token))
+	if identity := authenticator.getCached(key); identity != nil {
+		return identity, nil
+	}
+
+	tokenReview, err := authenticator.kubeClient.AuthenticationV1().TokenReviews().Create(r.Context(),
+		&authenticationv1.TokenReview{Spec: authenticationv1.TokenReviewSpec{Token: token, Audiences: authenticator.audiences}},
+		metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to review the token: %v", ErrUnavailable, err)
+	}
+	if !tokenReview.Status.Authenticated {
+		return nil, ErrInvalidToken
+	}
+
+	identity := &Identity{Name: tokenReview.Status.User.Username, Groups: tokenReview.Status.User.Groups}
+	authenticator.setCached(key, identity)
+	return identity, nil
+}
+
+func (authenticator *serviceAccountAuthenticator) getCached(key [sha256.Size]byte) *Identity {
+	authenticator.mu.Lock()
+	defer authenticator.mu.Unlock()
+	result, ok := authenticator.cache[key]
+	if !ok {
+		return nil
+	}
+	if authenticator.now().After(result.expires) {
+		delete(authenticator.cache, key)
+		return nil
+	}
+	return result.identity
+}
+
+func (authenticator *serviceAccountAuthenticator) setCached(key [sha256.Size]byte, identity *Identity) {
+	authenticator.mu.Lock()
+	defer authenticator.mu.Unlock()
+	now := authenticator.now()
+	for cachedKey, result := range authenticator.cache {
+		if now.After(result.expires) {
+			delete(authenticator.cache, cachedKey)
+		}
+	}
+	authenticator.cache[key] = tokenReviewResult{identity: identity, expires: now.Add(tokenReviewCacheTTL)}
+}
@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StaticTokenName Name of the identity of requests authenticated with the static token
+const StaticTokenName = "static-token"
+
+type staticTokenAuthenticator struct {
+	tokenFile string
+	mu        sync.RWMutex
+	token     string
+	modTime   time.Time
+}
+
+// NewStaticTokenAuthenticator Creates an authenticator accepting requests with the bearer token in the file.
+// The file is read again when it is modified, e.g. when a mounted secret is rotated
+func NewStaticTokenAuthenticator(tokenFile string) (Authenticator, error) {
+	authenticator := &staticTokenAuthenticator{tokenFile: tokenFile}
+	if _, err := authenticator.getToken(); err != nil {
+		return nil, err
+	}
+	return authenticator, nil
+}
+
+func (authenticator *staticTokenAuthenticator) Authenticate(r *http.Request) (*Identity, error) {
+	token, err := getBearerToken(r)
+	if err != nil {
+		return nil, err
+	}
+	expectedToken, err := authenticator.getToken()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(expectedToken)) != 1 {
+		return nil, ErrInvalidToken
+	}
+	return &Identity{Name: StaticTokenName}, nil
+}
+
+func (authenticator *staticTokenAuthenticator) getToken() (string, error) {
+	fileInfo, err := os.Stat(authenticator.tokenFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the token file: %w", err)
+	}
+
+	authenticator.mu.RLock()
+	token, modTime := authenticator.token, authenticator.modTime
+	authenticator.mu.RUnlock()
+	if len(token) > 0 && modTime.Equal(fileInfo.ModTime()) {
+		return token, nil
+	}
+
+	content, err := os.ReadFile(authenticator.tokenFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the token file: %w", err)
+	}
+	token = strings.TrimSpace(string(content))
+	if len(token) == 0 {
+		return "", fmt.Errorf("the token file %s is empty", authenticator.tokenFile)
+	}
+
+	authenticator.mu.Lock()
+	authenticator.token, authenticator.modTime = token, fileInfo.ModTime()
+	authenticator.mu.Unlock()
+	return token, nil
+}
@@ -0,0 +1,90 @@
+package bulk
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/equinor/radix-job-scheduler-server/models"
+	"github.com/equinor/radix-job-scheduler-server/utils"
+	apiErrors "github.com/equinor/radix-job-scheduler/api/errors"
+	schedulerModels "github.com/equinor/radix-job-scheduler/models/common"
+)
+
+// Runner Runs operations on many jobs or batches, with a bounded number of operations at a time
+type Runner struct {
+	concurrency int
+}
+
+// NewRunner Constructor. concurrency is the maximum number of operations running at a time
+func NewRunner(concurrency int) *Runner {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &Runner{concurrency: concurrency}
+}
+
+// Run Runs the operation for each name, and reports the result for each name in the same order.
+// The operation fails for the names not started when the context is done
+func (runner *Runner) Run(ctx context.Context, names []string, operation func(name string) error) *models.BulkReport {
+	report := models.BulkReport{Results: make([]models.BulkItemResult, len(names))}
+	semaphore := make(chan struct{}, runner.concurrency)
+	var wg sync.WaitGroup
+	for i, name := range names {
+		if !acquire(ctx, semaphore) {
+			report.Results[i] = getResult(name, ctx.Err())
+			continue
+		}
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			report.Results[i] = getResult(name, operation(name))
+		}(i, name)
+	}
+	wg.Wait()
+
+	for _, result := range report.Results {
+		if result.Status.Status == schedulerModels.StatusSuccess {
+			report.Succeeded++
+		} else {
+			report.Failed++
+		}
+	}
+	return &report
+}
+
+// acquire Waits for a free slot in the semaphore. Returns false when the context is done first
+func acquire(ctx context.Context, semaphore chan struct{}) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	select {
+	case <-ctx.Done():
+		return false
+	case semaphore <- struct{}{}:
+		return true
+	}
+}
+
+func getResult(name string, err error) models.BulkItemResult {
+	result := models.BulkItemResult{Name: name}
+	switch t := err.(type) {
+	case nil:
+		result.Status = schedulerModels.Status{Status: schedulerModels.StatusSuccess, Code: http.StatusOK}
+	case apiErrors.APIStatus:
+		result.Status = *t.Status()
+	default:
+		result.Status = *apiErrors.NewFromError(err).Status()
+	}
+	return result
+}
+
+// ReportResponse Writes the report, with status code 207 when the operation failed for any job or batch
+func ReportResponse(w http.ResponseWriter, report *models.BulkReport) {
+	statusCode := http.StatusOK
+	if report.Failed > 0 {
+		statusCode = http.StatusMultiStatus
+	}
+	utils.JSONResponseWithStatusCode(w, statusCode, report)
+}
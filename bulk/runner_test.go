@@ -0,0 +1,69 @@
+package bulk
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	apiErrors "github.com/equinor/radix-job-scheduler/api/errors"
+	schedulerModels "github.com/equinor/radix-job-scheduler/models/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunner_Run(t *testing.T) {
+	runner := NewRunner(2)
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	report := runner.Run(context.Background(), []string{"job1", "job2", "job3", "job4", "job5"}, func(name string) error {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+
+		switch name {
+		case "job2":
+			return apiErrors.NewNotFound("job", name)
+		case "job4":
+			return errors.New("failed")
+		}
+		return nil
+	})
+
+	assert.LessOrEqual(t, maxRunning, 2)
+	assert.Equal(t, 3, report.Succeeded)
+	assert.Equal(t, 2, report.Failed)
+	var names []string
+	for _, result := range report.Results {
+		names = append(names, result.Name)
+	}
+	assert.Equal(t, []string{"job1", "job2", "job3", "job4", "job5"}, names)
+	assert.Equal(t, schedulerModels.StatusSuccess, report.Results[0].Status.Status)
+	assert.Equal(t, http.StatusOK, report.Results[0].Code)
+	assert.Equal(t, http.StatusNotFound, report.Results[1].Code)
+	assert.Equal(t, schedulerModels.StatusReasonNotFound, report.Results[1].Reason)
+	assert.Equal(t, http.StatusInternalServerError, report.Results[3].Code)
+	assert.Equal(t, "failed", report.Results[3].Message)
+}
+
+func TestRunner_RunCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	called := false
+	report := NewRunner(2).Run(ctx, []string{"job1"}, func(string) error {
+		called = true
+		return nil
+	})
+
+	assert.False(t, called)
+	assert.Equal(t, 1, report.Failed)
+	assert.Equal(t, schedulerModels.StatusFailure, report.Results[0].Status.Status)
+}
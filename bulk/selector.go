@@ -0,0 +1,138 @@
+package bulk
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	serverErrors "github.com/equinor/radix-job-scheduler-server/api/errors"
+	"github.com/equinor/radix-job-scheduler-server/models"
+	"github.com/equinor/radix-job-scheduler-server/tracing"
+	"github.com/equinor/radix-job-scheduler-server/utils"
+	modelsV1 "github.com/equinor/radix-job-scheduler/models/v1"
+)
+
+// ValidateSelector Gets the invalid fields of the selector. A selector without conditions is invalid,
+// so a bulk operation never applies to all jobs or batches by mistake
+func ValidateSelector(selector *models.Selector) []models.StatusCause {
+	var causes []models.StatusCause
+	if len(selector.Names) == 0 && len(selector.Status) == 0 && len(selector.JobIdPrefix) == 0 && len(selector.CreatedBefore) == 0 {
+		causes = append(causes, models.StatusCause{Message: "at least one of names, status, jobIdPrefix or createdBefore must be set"})
+	}
+	for i, name := range selector.Names {
+		if len(strings.TrimSpace(name)) == 0 {
+			causes = append(causes, models.StatusCause{Field: fmt.Sprintf("names[%d]", i), Message: "must not be empty"})
+		}
+	}
+	for i, status := range selector.Status {
+		if !isJobStatus(status) {
+			causes = append(causes, models.StatusCause{Field: fmt.Sprintf("status[%d]", i), Message: fmt.Sprintf("%q is not one of %s", status, strings.Join(utils.JobStatuses, ", "))})
+		}
+	}
+	if len(selector.CreatedBefore) > 0 {
+		if _, err := time.Parse(time.RFC3339, selector.CreatedBefore); err != nil {
+			causes = append(causes, models.StatusCause{Field: "createdBefore", Message: "must be a RFC3339 timestamp, e.g. 2022-11-01T15:04:05Z"})
+		}
+	}
+	return causes
+}
+
+// SelectJobs Gets the names of the jobs matching the selector, in the order of the names in the selector, otherwise by creation time and name.
+// Names in the selector of jobs which do not exist are included, for the operation to report them as not found
+func SelectJobs(selector *models.Selector, jobStatuses []modelsV1.JobStatus) []string {
+	filter := getFilter(selector)
+	return selectNames(selector, jobStatuses, func(jobStatus *modelsV1.JobStatus) bool {
+		return filter.Matches(jobStatus) && strings.HasPrefix(jobStatus.JobId, selector.JobIdPrefix)
+	})
+}
+
+// SelectBatches Gets the names of the batches matching the selector, in the order of the names in the selector, otherwise by creation time and name.
+// Names in the selector of batches which do not exist are included, for the operation to report them as not found
+func SelectBatches(selector *models.Selector, batchStatuses []modelsV1.BatchStatus) []string {
+	filter := getFilter(selector)
+	jobStatuses := make([]modelsV1.JobStatus, 0, len(batchStatuses))
+	matches := make(map[string]bool, len(batchStatuses))
+	for i := range batchStatuses {
+		jobStatuses = append(jobStatuses, batchStatuses[i].JobStatus)
+		matches[batchStatuses[i].Name] = filter.MatchesBatch(&batchStatuses[i]) && hasJobWithJobIdPrefix(&batchStatuses[i], selector.JobIdPrefix)
+	}
+	return selectNames(selector, jobStatuses, func(jobStatus *modelsV1.JobStatus) bool {
+		return matches[jobStatus.Name]
+	})
+}
+
+func selectNames(selector *models.Selector, jobStatuses []modelsV1.JobStatus, matches func(*modelsV1.JobStatus) bool) []string {
+	var names []string
+	if len(selector.Names) > 0 {
+		byName := make(map[string]*modelsV1.JobStatus, len(jobStatuses))
+		for i := range jobStatuses {
+			byName[jobStatuses[i].Name] = &jobStatuses[i]
+		}
+		selected := make(map[string]bool, len(selector.Names))
+		for _, name := range selector.Names {
+			if selected[name] {
+				continue
+			}
+			if jobStatus, ok := byName[name]; ok && !matches(jobStatus) {
+				continue
+			}
+			selected[name] = true
+			names = append(names, name)
+		}
+		return names
+	}
+
+	jobStatuses = append([]modelsV1.JobStatus{}, jobStatuses...)
+	utils.SortJobStatuses(jobStatuses)
+	for i := range jobStatuses {
+		if matches(&jobStatuses[i]) {
+			names = append(names, jobStatuses[i].Name)
+		}
+	}
+	return names
+}
+
+func getFilter(selector *models.Selector) *utils.JobStatusFilter {
+	filter := utils.JobStatusFilter{Statuses: selector.Status}
+	if createdBefore, err := time.Parse(time.RFC3339, selector.CreatedBefore); err == nil {
+		filter.CreatedBefore = &createdBefore
+	}
+	return &filter
+}
+
+func isJobStatus(value string) bool {
+	for _, status := range utils.JobStatuses {
+		if strings.EqualFold(status, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasJobWithJobIdPrefix(batchStatus *modelsV1.BatchStatus, jobIdPrefix string) bool {
+	if len(jobIdPrefix) == 0 {
+		return true
+	}
+	for _, jobStatus := range batchStatus.JobStatuses {
+		if strings.HasPrefix(jobStatus.JobId, jobIdPrefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// DecodeSelector Decodes and validates the selector in the request body
+func DecodeSelector(r *http.Request) (*models.Selector, error) {
+	var selector models.Selector
+	_, span := tracing.StartSpan(r.Context(), "DecodeRequestBody")
+	err := utils.DecodeRequestBody(r, &selector)
+	tracing.EndSpan(span, err)
+	if err != nil {
+		return nil, err
+	}
+	if causes := ValidateSelector(&selector); len(causes) > 0 {
+		return nil, serverErrors.NewInvalidFields(causes)
+	}
+	return &selector, nil
+}
@@ -0,0 +1,78 @@
+package bulk
+
+import (
+	"testing"
+
+	"github.com/equinor/radix-job-scheduler-server/models"
+	modelsV1 "github.com/equinor/radix-job-scheduler/models/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateSelector(t *testing.T) {
+	scenarios := []struct {
+		name     string
+		selector models.Selector
+		fields   []string
+	}{
+		{name: "names", selector: models.Selector{Names: []string{"job1"}}},
+		{name: "all conditions", selector: models.Selector{Status: []string{"running", "Waiting"}, JobIdPrefix: "import-", CreatedBefore: "2022-11-01T15:04:05Z"}},
+		{name: "no conditions", fields: []string{""}},
+		{name: "empty name", selector: models.Selector{Names: []string{"job1", " "}}, fields: []string{"names[1]"}},
+		{name: "invalid status", selector: models.Selector{Status: []string{"Running", "Done"}}, fields: []string{"status[1]"}},
+		{name: "invalid time", selector: models.Selector{CreatedBefore: "yesterday"}, fields: []string{"createdBefore"}},
+	}
+	for _, scenario := range scenarios {
+		scenario := scenario
+		t.Run(scenario.name, func(t *testing.T) {
+			var fields []string
+			for _, cause := range ValidateSelector(&scenario.selector) {
+				fields = append(fields, cause.Field)
+			}
+			assert.Equal(t, scenario.fields, fields)
+		})
+	}
+}
+
+func TestSelectJobs(t *testing.T) {
+	jobStatuses := []modelsV1.JobStatus{
+		{Name: "job3", JobId: "import-3", Status: "Running", Created: "2022-10-01T12:00:02Z"},
+		{Name: "job1", JobId: "import-1", Status: "Failed", Created: "2022-10-01T12:00:00Z"},
+		{Name: "job2", JobId: "export-2", Status: "Running", Created: "2022-10-01T12:00:01Z"},
+	}
+	scenarios := []struct {
+		name     string
+		selector models.Selector
+		expected []string
+	}{
+		{name: "status", selector: models.Selector{Status: []string{"running"}}, expected: []string{"job2", "job3"}},
+		{name: "job ID prefix", selector: models.Selector{JobIdPrefix: "import-"}, expected: []string{"job1", "job3"}},
+		{name: "created before", selector: models.Selector{CreatedBefore: "2022-10-01T12:00:01Z"}, expected: []string{"job1", "job2"}},
+		{name: "all conditions", selector: models.Selector{Status: []string{"Running"}, JobIdPrefix: "import-", CreatedBefore: "2022-10-01T12:00:02Z"}, expected: []string{"job3"}},
+		{name: "names in order, without duplicates, including unknown names", selector: models.Selector{Names: []string{"job3", "job9", "job1", "job3"}}, expected: []string{"job3", "job9", "job1"}},
+		{name: "names and status", selector: models.Selector{Names: []string{"job1", "job2"}, Status: []string{"Running"}}, expected: []string{"job2"}},
+		{name: "no match", selector: models.Selector{Status: []string{"Stopped"}}},
+	}
+	for _, scenario := range scenarios {
+		scenario := scenario
+		t.Run(scenario.name, func(t *testing.T) {
+			assert.Equal(t, scenario.expected, SelectJobs(&scenario.selector, jobStatuses))
+		})
+	}
+}
+
+func TestSelectBatches(t *testing.T) {
+	batchStatuses := []modelsV1.BatchStatus{
+		{
+			JobStatus:   modelsV1.JobStatus{Name: "batch1", Status: "Succeeded", Created: "2022-10-01T12:00:00Z"},
+			JobStatuses: []modelsV1.JobStatus{{Name: "batch1-job1", JobId: "import-1"}},
+		},
+		{
+			JobStatus:   modelsV1.JobStatus{Name: "batch2", Status: "Running", Created: "2022-10-01T12:00:01Z"},
+			JobStatuses: []modelsV1.JobStatus{{Name: "batch2-job1", JobId: "export-1"}, {Name: "batch2-job2", JobId: "import-2"}},
+		},
+	}
+	assert.Equal(t, []string{"batch1", "batch2"}, SelectBatches(&models.Selector{JobIdPrefix: "import-"}, batchStatuses))
+	assert.Equal(t, []string{"batch2"}, SelectBatches(&models.Selector{JobIdPrefix: "export-"}, batchStatuses))
+	assert.Equal(t, []string{"batch1"}, SelectBatches(&models.Selector{Status: []string{"Succeeded"}}, batchStatuses))
+	assert.Equal(t, []string{"batch3"}, SelectBatches(&models.Selector{Names: []string{"batch3"}}, batchStatuses))
+}
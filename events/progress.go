@@ -0,0 +1,79 @@
+package events
+
+import (
+	"time"
+
+	commonUtils "github.com/equinor/radix-common/utils"
+	modelsV1 "github.com/equinor/radix-job-scheduler/models/v1"
+)
+
+// BatchProgress Aggregate progress of the jobs in a batch
+// swagger:model BatchProgress
+type BatchProgress struct {
+	// Name of the batch
+	//
+	// required: true
+	// example: batch-20220906084237-flqhlyza
+	BatchName string `json:"batchName"`
+
+	// Total number of jobs in the batch
+	//
+	// required: true
+	Total int `json:"total"`
+
+	// Number of jobs by status
+	//
+	// required: true
+	Counts map[string]int `json:"counts"`
+
+	// Number of jobs that are Succeeded, Failed or Stopped
+	//
+	// required: true
+	Completed int `json:"completed"`
+
+	// Percent of the jobs completed, between 0 and 100
+	//
+	// required: true
+	PercentComplete float64 `json:"percentComplete"`
+
+	// Estimated time when all jobs are completed, extrapolated from the time to complete the completed jobs.
+	// Empty until a job is completed, and when all jobs are completed
+	//
+	// required: false
+	// example: 2006-01-02T15:04:05Z
+	EstimatedFinish string `json:"estimatedFinish,omitempty"`
+}
+
+// NewBatchProgress Gets the progress of the jobs in a batch started at the time
+func NewBatchProgress(batchName string, started time.Time, jobStatuses map[string]modelsV1.JobStatus, now time.Time) *BatchProgress {
+	progress := BatchProgress{
+		BatchName: batchName,
+		Total:     len(jobStatuses),
+		Counts:    make(map[string]int),
+	}
+	for _, jobStatus := range jobStatuses {
+		progress.Counts[jobStatus.Status]++
+		if IsTerminal(jobStatus.Status) {
+			progress.Completed++
+		}
+	}
+	if progress.Total == 0 {
+		return &progress
+	}
+	progress.PercentComplete = float64(progress.Completed*100) / float64(progress.Total)
+	if elapsed := now.Sub(started); progress.Completed > 0 && progress.Completed < progress.Total && !started.IsZero() && elapsed > 0 {
+		remaining := elapsed * time.Duration(progress.Total-progress.Completed) / time.Duration(progress.Completed)
+		progress.EstimatedFinish = commonUtils.FormatTimestamp(now.Add(remaining))
+	}
+	return &progress
+}
+
+// AllTerminal Returns true when there are jobs, and all are Succeeded, Failed or Stopped
+func AllTerminal(jobStatuses map[string]modelsV1.JobStatus) bool {
+	for _, jobStatus := range jobStatuses {
+		if !IsTerminal(jobStatus.Status) {
+			return false
+		}
+	}
+	return len(jobStatuses) > 0
+}
@@ -0,0 +1,80 @@
+package events
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	modelsV1 "github.com/equinor/radix-job-scheduler/models/v1"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+func TestNewBatchProgress(t *testing.T) {
+	started := time.Date(2022, 11, 1, 12, 0, 0, 0, time.UTC)
+	now := started.Add(10 * time.Minute)
+	jobStatuses := map[string]modelsV1.JobStatus{
+		"job1": {Name: "job1", Status: "Succeeded"},
+		"job2": {Name: "job2", Status: "Failed"},
+		"job3": {Name: "job3", Status: "Running"},
+		"job4": {Name: "job4", Status: "Running"},
+		"job5": {Name: "job5", Status: "Waiting"},
+	}
+
+	progress := NewBatchProgress("batch1", started, jobStatuses, now)
+
+	assert.Equal(t, "batch1", progress.BatchName)
+	assert.Equal(t, 5, progress.Total)
+	assert.Equal(t, 2, progress.Completed)
+	assert.Equal(t, map[string]int{"Succeeded": 1, "Failed": 1, "Running": 2, "Waiting": 1}, progress.Counts)
+	assert.Equal(t, float64(40), progress.PercentComplete)
+	assert.Equal(t, "2022-11-01T12:25:00Z", progress.EstimatedFinish)
+}
+
+func TestNewBatchProgress_NoEstimate(t *testing.T) {
+	started := time.Date(2022, 11, 1, 12, 0, 0, 0, time.UTC)
+	scenarios := map[string]map[string]modelsV1.JobStatus{
+		"no jobs":         {},
+		"none completed":  {"job1": {Name: "job1", Status: "Running"}},
+		"all completed":   {"job1": {Name: "job1", Status: "Stopped"}},
+		"unknown started": {"job1": {Name: "job1", Status: "Running"}, "job2": {Name: "job2", Status: "Succeeded"}},
+	}
+	for name, jobStatuses := range scenarios {
+		batchStarted := started
+		if name == "unknown started" {
+			batchStarted = time.Time{}
+		}
+		progress := NewBatchProgress("batch1", batchStarted, jobStatuses, started.Add(time.Minute))
+		assert.Empty(t, progress.EstimatedFinish, name)
+	}
+}
+
+func TestServe_Progress_SentOnStartAndEnd(t *testing.T) {
+	jobWatcher, _ := newTestJobWatcher(
+		watch.Event{Type: watch.Modified, Object: newTestJob("job2", "31")},
+	)
+	stream := &Stream{
+		GetJobStatuses: func() ([]modelsV1.JobStatus, error) {
+			return []modelsV1.JobStatus{{Name: "job1", Status: "Succeeded"}, {Name: "job2", Status: "Running"}}, nil
+		},
+		GetJobStatus: func(jobName string) (*modelsV1.JobStatus, error) {
+			return &modelsV1.JobStatus{Name: jobName, Status: "Succeeded"}, nil
+		},
+		IsComplete: AllTerminal,
+		Progress: func(jobStatuses map[string]modelsV1.JobStatus) interface{} {
+			return NewBatchProgress("batch1", time.Time{}, jobStatuses, time.Now())
+		},
+	}
+
+	recorder := httptest.NewRecorder()
+	err := jobWatcher.Serve(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/batches/batch1/events", nil), stream)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "event: status\ndata: {\"name\":\"job1\",\"status\":\"Succeeded\"}\n\n"+
+		"event: status\ndata: {\"name\":\"job2\",\"status\":\"Running\"}\n\n"+
+		"event: progress\ndata: {\"batchName\":\"batch1\",\"total\":2,\"counts\":{\"Running\":1,\"Succeeded\":1},\"completed\":1,\"percentComplete\":50}\n\n"+
+		"id: 31\nevent: status\ndata: {\"name\":\"job2\",\"status\":\"Succeeded\"}\n\n"+
+		"event: progress\ndata: {\"batchName\":\"batch1\",\"total\":2,\"counts\":{\"Succeeded\":2},\"completed\":2,\"percentComplete\":100}\n\n"+
+		"event: end\ndata: {}\n\n", recorder.Body.String())
+}
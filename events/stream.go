@@ -0,0 +1,291 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	serverErrors "github.com/equinor/radix-job-scheduler-server/api/errors"
+	"github.com/equinor/radix-job-scheduler-server/utils"
+	modelsV1 "github.com/equinor/radix-job-scheduler/models/v1"
+	log "github.com/sirupsen/logrus"
+	batchv1 "k8s.io/api/batch/v1"
+	kubeErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+const (
+	// rewatchDelay Delay before watching again when the API server ends a watch
+	rewatchDelay = time.Second
+
+	// EventStatus Event with the JobStatus of a job, sent when the stream starts and when the status changes
+	EventStatus = "status"
+	// EventDeleted Event with the name of a deleted job
+	EventDeleted = "deleted"
+	// EventProgress Event with the aggregate progress of the jobs, sent periodically by streams with progress
+	EventProgress = "progress"
+	// EventEnd Last event of a stream that is complete. Clients should close the stream instead of reconnecting
+	EventEnd = "end"
+)
+
+var errStreamComplete = errors.New("stream is complete")
+
+// Stream Jobs in a stream of status changes
+type Stream struct {
+	// JobName Name of the single job in the stream. Empty for all jobs with the Labels
+	JobName string
+	// Labels Labels of the Kubernetes jobs in the stream, in addition to the labels of the job component
+	Labels labels.Set
+	// GetJobStatuses Gets the current statuses of the jobs in the stream
+	GetJobStatuses func() ([]modelsV1.JobStatus, error)
+	// GetJobStatus Gets the current status of a job in the stream
+	GetJobStatus func(jobName string) (*modelsV1.JobStatus, error)
+	// IsComplete Returns true when the stream is complete with the current statuses of the jobs. Nil for a stream that does not complete
+	IsComplete func(jobStatuses map[string]modelsV1.JobStatus) bool
+	// Progress Gets the data of the progress event with the current statuses of the jobs. Nil for a stream without progress events
+	Progress func(jobStatuses map[string]modelsV1.JobStatus) interface{}
+}
+
+// streamState Statuses known and sent in a stream being served
+type streamState struct {
+	watcher *JobWatcher
+	stream  *Stream
+	writer  *Writer
+	// current Current statuses of the jobs, by job name
+	current map[string]modelsV1.JobStatus
+	// sent Statuses sent to the client, by job name
+	sent map[string]modelsV1.JobStatus
+}
+
+// IsTerminal Returns true when the job status does not change anymore
+func IsTerminal(status string) bool {
+	switch status {
+	case "Succeeded", "Failed", "Stopped":
+		return true
+	}
+	return false
+}
+
+// Serve Streams the statuses of the jobs as server-sent events until the stream is complete, the client disconnects
+// or the watcher is closed. A client reconnecting with the Last-Event-ID header gets the changes after the event.
+// Returns an error when the stream could not be started. Errors after the stream is started end the stream
+func (watcher *JobWatcher) Serve(w http.ResponseWriter, r *http.Request, stream *Stream) error {
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	go func() {
+		select {
+		case <-watcher.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	state := streamState{
+		watcher: watcher,
+		stream:  stream,
+		current: make(map[string]modelsV1.JobStatus),
+		sent:    make(map[string]modelsV1.JobStatus),
+	}
+	resourceVersion, err := state.load(ctx)
+	if err != nil {
+		return err
+	}
+	if state.writer, err = NewWriter(w); err != nil {
+		return err
+	}
+
+	lastEventID := r.Header.Get(LastEventIDHeader)
+	if len(lastEventID) == 0 || state.isComplete() {
+		if err := state.sendChanges(resourceVersion); err != nil {
+			return nil
+		}
+	} else {
+		resourceVersion = lastEventID
+	}
+	if state.isComplete() {
+		state.end()
+		return nil
+	}
+	if err := state.sendProgress(); err != nil {
+		return nil
+	}
+
+	if err := state.run(ctx, resourceVersion); err != nil && ctx.Err() == nil {
+		log.WithContext(r.Context()).Warnf("job status stream ended: %v", err)
+	}
+	return nil
+}
+
+// load Loads the current statuses of the jobs and returns the resource version to watch changes from
+func (state *streamState) load(ctx context.Context) (string, error) {
+	resourceVersion, err := state.watcher.listResourceVersion(ctx, state.stream)
+	if err != nil {
+		return "", err
+	}
+	jobStatuses, err := state.stream.GetJobStatuses()
+	if err != nil {
+		return "", err
+	}
+	state.current = make(map[string]modelsV1.JobStatus, len(jobStatuses))
+	for _, jobStatus := range jobStatuses {
+		state.current[jobStatus.Name] = jobStatus
+	}
+	return resourceVersion, nil
+}
+
+// run Watches the jobs from the resource version until the stream is complete, reloading the statuses when the
+// resource version is too old
+func (state *streamState) run(ctx context.Context, resourceVersion string) error {
+	heartbeat := time.NewTicker(state.watcher.HeartbeatInterval)
+	defer heartbeat.Stop()
+	var progress <-chan time.Time
+	if state.stream.Progress != nil {
+		progressTicker := time.NewTicker(state.watcher.ProgressInterval)
+		defer progressTicker.Stop()
+		progress = progressTicker.C
+	}
+	for {
+		jobWatch, err := state.watcher.watch(ctx, state.stream, resourceVersion)
+		if err == nil {
+			resourceVersion, err = state.consume(ctx, jobWatch.ResultChan(), heartbeat.C, progress, resourceVersion)
+			jobWatch.Stop()
+		}
+		switch {
+		case err == errStreamComplete:
+			return state.end()
+		case ctx.Err() != nil:
+			return nil
+		case kubeErrors.IsResourceExpired(err) || kubeErrors.IsGone(err) || kubeErrors.IsBadRequest(err):
+			if resourceVersion, err = state.load(ctx); err != nil {
+				return err
+			}
+			if err := state.sendChanges(resourceVersion); err != nil {
+				return err
+			}
+			if state.isComplete() {
+				return state.end()
+			}
+		case err != nil:
+			return err
+		default:
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(rewatchDelay):
+			}
+		}
+	}
+}
+
+// consume Sends the changes of the watched jobs, heartbeats and progress. Returns the resource version of the last change when the watch ends
+func (state *streamState) consume(ctx context.Context, events <-chan watch.Event, heartbeat, progress <-chan time.Time, resourceVersion string) (string, error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return resourceVersion, ctx.Err()
+		case <-heartbeat:
+			if err := state.writer.WriteHeartbeat(); err != nil {
+				return resourceVersion, err
+			}
+		case <-progress:
+			if err := state.sendProgress(); err != nil {
+				return resourceVersion, err
+			}
+		case event, ok := <-events:
+			if !ok {
+				return resourceVersion, nil
+			}
+			if event.Type == watch.Error {
+				return resourceVersion, kubeErrors.FromObject(event.Object)
+			}
+			if accessor, err := meta.Accessor(event.Object); err == nil && len(accessor.GetResourceVersion()) > 0 {
+				resourceVersion = accessor.GetResourceVersion()
+			}
+			job, ok := event.Object.(*batchv1.Job)
+			if !ok || !state.watcher.selects(state.stream, job) {
+				continue
+			}
+			if err := state.handleJobEvent(ctx, event.Type, job.Name, resourceVersion); err != nil {
+				return resourceVersion, err
+			}
+			if state.isComplete() {
+				return resourceVersion, errStreamComplete
+			}
+		}
+	}
+}
+
+func (state *streamState) handleJobEvent(ctx context.Context, eventType watch.EventType, jobName, resourceVersion string) error {
+	if eventType == watch.Deleted {
+		delete(state.current, jobName)
+		delete(state.sent, jobName)
+		return state.writer.WriteEvent(resourceVersion, EventDeleted, modelsV1.JobStatus{Name: jobName})
+	}
+	jobStatus, err := state.stream.GetJobStatus(jobName)
+	if err != nil {
+		if !serverErrors.IsNotFound(err) {
+			log.WithContext(ctx).Warnf("failed to get status of the job %s: %v", jobName, err)
+		}
+		return nil
+	}
+	state.current[jobName] = *jobStatus
+	return state.send(resourceVersion, jobStatus)
+}
+
+// sendChanges Sends the current statuses not sent before
+func (state *streamState) sendChanges(resourceVersion string) error {
+	jobStatuses := make([]modelsV1.JobStatus, 0, len(state.current))
+	for _, jobStatus := range state.current {
+		jobStatuses = append(jobStatuses, jobStatus)
+	}
+	utils.SortJobStatuses(jobStatuses)
+	for i := range jobStatuses {
+		if err := state.send(resourceVersion, &jobStatuses[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// send Sends the status when it is changed since the last status sent for the job
+func (state *streamState) send(resourceVersion string, jobStatus *modelsV1.JobStatus) error {
+	if sent, ok := state.sent[jobStatus.Name]; ok && sent == *jobStatus {
+		return nil
+	}
+	if err := state.writer.WriteEvent(resourceVersion, EventStatus, jobStatus); err != nil {
+		return err
+	}
+	state.sent[jobStatus.Name] = *jobStatus
+	return nil
+}
+
+// sendProgress Sends the progress of the jobs, when the stream has progress events
+func (state *streamState) sendProgress() error {
+	if state.stream.Progress == nil {
+		return nil
+	}
+	return state.writer.WriteEvent("", EventProgress, state.stream.Progress(state.current))
+}
+
+// end Sends the final progress and the end event
+func (state *streamState) end() error {
+	if err := state.sendProgress(); err != nil {
+		return err
+	}
+	return state.writer.WriteEvent("", EventEnd, struct{}{})
+}
+
+func (state *streamState) isComplete() bool {
+	return state.stream.IsComplete != nil && state.stream.IsComplete(state.current)
+}
+
+// IsJobComplete Returns a completion check of a stream with a single job, complete when the job is deleted or has a terminal status
+func IsJobComplete(jobName string) func(jobStatuses map[string]modelsV1.JobStatus) bool {
+	return func(jobStatuses map[string]modelsV1.JobStatus) bool {
+		jobStatus, ok := jobStatuses[jobName]
+		return !ok || IsTerminal(jobStatus.Status)
+	}
+}
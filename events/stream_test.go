@@ -0,0 +1,164 @@
+package events
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/equinor/radix-job-scheduler-server/models"
+	apiErrors "github.com/equinor/radix-job-scheduler/api/errors"
+	modelsV1 "github.com/equinor/radix-job-scheduler/models/v1"
+	"github.com/equinor/radix-operator/pkg/apis/kube"
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func newTestJobWatcher(events ...watch.Event) (*JobWatcher, *[]string) {
+	env := models.NewEnv()
+	env.RadixAppName, env.RadixComponentName, env.RadixDeploymentNamespace = "app", "compute", "app-dev"
+	kubeClient := kubefake.NewSimpleClientset()
+	var watchedVersions []string
+	kubeClient.PrependWatchReactor("jobs", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		watchedVersions = append(watchedVersions, action.(k8stesting.WatchAction).GetWatchRestrictions().ResourceVersion)
+		fakeWatch := watch.NewFakeWithChanSize(len(events), false)
+		for _, event := range events {
+			fakeWatch.Action(event.Type, event.Object)
+		}
+		return true, fakeWatch, nil
+	})
+	return NewJobWatcher(kubeClient, env), &watchedVersions
+}
+
+func newTestJob(name, resourceVersion string) *batchv1.Job {
+	return &batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+		Name:            name,
+		Namespace:       "app-dev",
+		ResourceVersion: resourceVersion,
+		Labels:          map[string]string{kube.RadixAppLabel: "app", kube.RadixComponentLabel: "compute"},
+	}}
+}
+
+func TestServe_SingleJob_EndsOnTerminalStatus(t *testing.T) {
+	jobWatcher, _ := newTestJobWatcher(
+		watch.Event{Type: watch.Modified, Object: newTestJob("other", "11")},
+		watch.Event{Type: watch.Modified, Object: newTestJob("job1", "12")},
+	)
+	stream := &Stream{
+		JobName: "job1",
+		GetJobStatuses: func() ([]modelsV1.JobStatus, error) {
+			return []modelsV1.JobStatus{{Name: "job1", Status: "Running"}}, nil
+		},
+		GetJobStatus: func(jobName string) (*modelsV1.JobStatus, error) {
+			assert.Equal(t, "job1", jobName)
+			return &modelsV1.JobStatus{Name: jobName, Status: "Succeeded"}, nil
+		},
+		IsComplete: IsJobComplete("job1"),
+	}
+
+	recorder := httptest.NewRecorder()
+	err := jobWatcher.Serve(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/jobs/job1/events", nil), stream)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "text/event-stream", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, "event: status\ndata: {\"name\":\"job1\",\"status\":\"Running\"}\n\n"+
+		"id: 12\nevent: status\ndata: {\"name\":\"job1\",\"status\":\"Succeeded\"}\n\n"+
+		"event: end\ndata: {}\n\n", recorder.Body.String())
+}
+
+func TestServe_SingleJob_TerminalWhenStarted(t *testing.T) {
+	jobWatcher, watchedVersions := newTestJobWatcher()
+	stream := &Stream{
+		JobName: "job1",
+		GetJobStatuses: func() ([]modelsV1.JobStatus, error) {
+			return []modelsV1.JobStatus{{Name: "job1", Status: "Failed"}}, nil
+		},
+		IsComplete: IsJobComplete("job1"),
+	}
+
+	request := httptest.NewRequest(http.MethodGet, "/api/v1/jobs/job1/events", nil)
+	request.Header.Set(LastEventIDHeader, "5")
+	recorder := httptest.NewRecorder()
+	err := jobWatcher.Serve(recorder, request, stream)
+
+	assert.NoError(t, err)
+	assert.Empty(t, *watchedVersions)
+	assert.Equal(t, "event: status\ndata: {\"name\":\"job1\",\"status\":\"Failed\"}\n\nevent: end\ndata: {}\n\n", recorder.Body.String())
+}
+
+func TestServe_NotFound_ReturnsError(t *testing.T) {
+	jobWatcher, _ := newTestJobWatcher()
+	stream := &Stream{
+		JobName: "job1",
+		GetJobStatuses: func() ([]modelsV1.JobStatus, error) {
+			return nil, apiErrors.NewNotFound("job", "job1")
+		},
+	}
+
+	recorder := httptest.NewRecorder()
+	err := jobWatcher.Serve(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/jobs/job1/events", nil), stream)
+
+	assert.Error(t, err)
+	assert.Empty(t, recorder.Body.String())
+}
+
+func TestServe_Resume_SendsChangesAfterLastEvent(t *testing.T) {
+	jobWatcher, watchedVersions := newTestJobWatcher(
+		watch.Event{Type: watch.Modified, Object: newTestJob("job2", "21")},
+		watch.Event{Type: watch.Deleted, Object: newTestJob("job1", "22")},
+	)
+	stream := &Stream{
+		GetJobStatuses: func() ([]modelsV1.JobStatus, error) {
+			return []modelsV1.JobStatus{{Name: "job1", Status: "Succeeded"}, {Name: "job2", Status: "Running"}}, nil
+		},
+		GetJobStatus: func(jobName string) (*modelsV1.JobStatus, error) {
+			return &modelsV1.JobStatus{Name: jobName, Status: "Failed"}, nil
+		},
+		IsComplete: func(jobStatuses map[string]modelsV1.JobStatus) bool {
+			if len(jobStatuses) == 1 {
+				jobWatcher.Close()
+			}
+			return false
+		},
+	}
+
+	request := httptest.NewRequest(http.MethodGet, "/api/v1/jobs/events", nil)
+	request.Header.Set(LastEventIDHeader, "20")
+	recorder := httptest.NewRecorder()
+	err := jobWatcher.Serve(recorder, request, stream)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"20"}, *watchedVersions)
+	assert.Equal(t, "id: 21\nevent: status\ndata: {\"name\":\"job2\",\"status\":\"Failed\"}\n\n"+
+		"id: 22\nevent: deleted\ndata: {\"name\":\"job1\"}\n\n", recorder.Body.String())
+}
+
+func TestServe_ExpiredResourceVersion_SendsCurrentStatuses(t *testing.T) {
+	jobWatcher, watchedVersions := newTestJobWatcher(
+		watch.Event{Type: watch.Error, Object: &metav1.Status{Status: metav1.StatusFailure, Code: http.StatusGone, Reason: metav1.StatusReasonExpired}},
+	)
+	stream := &Stream{
+		JobName: "job1",
+		GetJobStatuses: func() ([]modelsV1.JobStatus, error) {
+			if len(*watchedVersions) == 0 {
+				return []modelsV1.JobStatus{{Name: "job1", Status: "Running"}}, nil
+			}
+			return []modelsV1.JobStatus{{Name: "job1", Status: "Stopped"}}, nil
+		},
+		IsComplete: IsJobComplete("job1"),
+	}
+
+	request := httptest.NewRequest(http.MethodGet, "/api/v1/jobs/job1/events", nil)
+	request.Header.Set(LastEventIDHeader, "1")
+	recorder := httptest.NewRecorder()
+	err := jobWatcher.Serve(recorder, request, stream)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1"}, *watchedVersions)
+	assert.True(t, strings.HasPrefix(recorder.Body.String(), "event: status\ndata: {\"name\":\"job1\",\"status\":\"Stopped\"}\n\n"))
+	assert.True(t, strings.HasSuffix(recorder.Body.String(), "event: end\ndata: {}\n\n"))
+}
@@ -0,0 +1,169 @@
+package events
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/equinor/radix-job-scheduler-server/models"
+	"github.com/equinor/radix-operator/pkg/apis/kube"
+	log "github.com/sirupsen/logrus"
+	batchv1 "k8s.io/api/batch/v1"
+	kubeErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	defaultHeartbeatInterval = 15 * time.Second
+	defaultProgressInterval  = 10 * time.Second
+)
+
+// JobWatcher Watches the Kubernetes jobs of the job component and serves streams of their status changes
+type JobWatcher struct {
+	kubeClient kubernetes.Interface
+	namespace  string
+	labels     labels.Set
+	// HeartbeatInterval Interval between heartbeats on idle streams
+	HeartbeatInterval time.Duration
+	// ProgressInterval Interval between progress events on streams with progress
+	ProgressInterval time.Duration
+	done             chan struct{}
+	closeOnce        sync.Once
+}
+
+// NewJobWatcher Constructor
+func NewJobWatcher(kubeClient kubernetes.Interface, env *models.Env) *JobWatcher {
+	return &JobWatcher{
+		kubeClient: kubeClient,
+		namespace:  env.RadixDeploymentNamespace,
+		labels: labels.Set{
+			kube.RadixAppLabel:       env.RadixAppName,
+			kube.RadixComponentLabel: env.RadixComponentName,
+		},
+		HeartbeatInterval: defaultHeartbeatInterval,
+		ProgressInterval:  defaultProgressInterval,
+		done:              make(chan struct{}),
+	}
+}
+
+// Close Ends all streams, e.g. when the server shuts down
+func (watcher *JobWatcher) Close() {
+	watcher.closeOnce.Do(func() { close(watcher.done) })
+}
+
+// GetJobs Gets the Kubernetes jobs of the job component with the labels
+func (watcher *JobWatcher) GetJobs(ctx context.Context, jobLabels labels.Set) ([]batchv1.Job, error) {
+	jobs, err := watcher.kubeClient.BatchV1().Jobs(watcher.namespace).List(ctx, watcher.listOptions(&Stream{Labels: jobLabels}, ""))
+	if err != nil {
+		return nil, err
+	}
+	return jobs.Items, nil
+}
+
+// listResourceVersion Gets the current resource version of the jobs in the stream
+func (watcher *JobWatcher) listResourceVersion(ctx context.Context, stream *Stream) (string, error) {
+	opts := watcher.listOptions(stream, "")
+	opts.Limit = 1
+	jobs, err := watcher.kubeClient.BatchV1().Jobs(watcher.namespace).List(ctx, opts)
+	if err != nil {
+		return "", err
+	}
+	return jobs.ResourceVersion, nil
+}
+
+// watch Watches the jobs in the stream, with changes after the resource version
+func (watcher *JobWatcher) watch(ctx context.Context, stream *Stream, resourceVersion string) (watch.Interface, error) {
+	opts := watcher.listOptions(stream, resourceVersion)
+	opts.AllowWatchBookmarks = true
+	return watcher.kubeClient.BatchV1().Jobs(watcher.namespace).Watch(ctx, opts)
+}
+
+func (watcher *JobWatcher) listOptions(stream *Stream, resourceVersion string) metav1.ListOptions {
+	opts := metav1.ListOptions{
+		LabelSelector:   watcher.selector(stream).String(),
+		ResourceVersion: resourceVersion,
+	}
+	if len(stream.JobName) > 0 {
+		opts.FieldSelector = fields.OneTermEqualSelector("metadata.name", stream.JobName).String()
+	}
+	return opts
+}
+
+func (watcher *JobWatcher) selector(stream *Stream) labels.Selector {
+	return labels.SelectorFromSet(labels.Merge(watcher.labels, stream.Labels))
+}
+
+// selects Returns true when the job belongs to the stream
+func (watcher *JobWatcher) selects(stream *Stream, job *batchv1.Job) bool {
+	if len(stream.JobName) > 0 && job.Name != stream.JobName {
+		return false
+	}
+	return watcher.selector(stream).Matches(labels.Set(job.Labels))
+}
+
+// WatchChanges Calls onChange with each added, modified and deleted job of the job component, until the context is done
+// or the watcher is closed. Changes can be missed while the watch is restarted after an error
+func (watcher *JobWatcher) WatchChanges(ctx context.Context, onChange func(eventType watch.EventType, job *batchv1.Job)) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-watcher.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	stream := &Stream{}
+	resourceVersion := ""
+	for {
+		if err := watcher.watchChanges(ctx, stream, &resourceVersion, onChange); err != nil && ctx.Err() == nil {
+			log.Warnf("failed to watch jobs: %v", err)
+			resourceVersion = ""
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(rewatchDelay):
+		}
+	}
+}
+
+func (watcher *JobWatcher) watchChanges(ctx context.Context, stream *Stream, resourceVersion *string, onChange func(eventType watch.EventType, job *batchv1.Job)) error {
+	if len(*resourceVersion) == 0 {
+		listResourceVersion, err := watcher.listResourceVersion(ctx, stream)
+		if err != nil {
+			return err
+		}
+		*resourceVersion = listResourceVersion
+	}
+	jobWatch, err := watcher.watch(ctx, stream, *resourceVersion)
+	if err != nil {
+		return err
+	}
+	defer jobWatch.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-jobWatch.ResultChan():
+			if !ok {
+				return nil
+			}
+			if event.Type == watch.Error {
+				return kubeErrors.FromObject(event.Object)
+			}
+			if accessor, err := meta.Accessor(event.Object); err == nil && len(accessor.GetResourceVersion()) > 0 {
+				*resourceVersion = accessor.GetResourceVersion()
+			}
+			if job, ok := event.Object.(*batchv1.Job); ok && watcher.selects(stream, job) {
+				onChange(event.Type, job)
+			}
+		}
+	}
+}
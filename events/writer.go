@@ -0,0 +1,60 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// LastEventIDHeader Header with the ID of the last event received by a reconnecting client
+const LastEventIDHeader = "Last-Event-ID"
+
+// Writer Writes server-sent events to a response
+type Writer struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+// NewWriter Starts a text/event-stream response
+func NewWriter(w http.ResponseWriter) (*Writer, error) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, errors.New("response writer does not support streaming")
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	return &Writer{w: w, flusher: flusher}, nil
+}
+
+// WriteEvent Writes an event with the data as JSON. The id is omitted when empty
+func (writer *Writer) WriteEvent(id, event string, data interface{}) error {
+	body, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	var sb strings.Builder
+	if len(id) > 0 {
+		fmt.Fprintf(&sb, "id: %s\n", id)
+	}
+	fmt.Fprintf(&sb, "event: %s\ndata: %s\n\n", event, body)
+	return writer.write(sb.String())
+}
+
+// WriteHeartbeat Writes a comment keeping the connection open through proxies
+func (writer *Writer) WriteHeartbeat() error {
+	return writer.write(": heartbeat\n\n")
+}
+
+func (writer *Writer) write(s string) error {
+	if _, err := writer.w.Write([]byte(s)); err != nil {
+		return err
+	}
+	writer.flusher.Flush()
+	return nil
+}
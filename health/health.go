@@ -0,0 +1,129 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// StatusHealthy The check, or all checks, passed
+	StatusHealthy = "Healthy"
+	// StatusUnhealthy The check, or at least one of the checks, failed
+	StatusUnhealthy = "Unhealthy"
+
+	defaultCheckTimeout = 5 * time.Second
+)
+
+// Check A named check of a dependency of the server
+type Check interface {
+	// Name of the check, as shown in the response
+	Name() string
+	// Check Returns an error when the dependency is not available
+	Check(ctx context.Context) error
+}
+
+// CheckResult Result of a single check
+type CheckResult struct {
+	// Name of the check
+	Name string `json:"name"`
+	// Status of the check
+	//
+	// enum: Healthy,Unhealthy
+	Status string `json:"status"`
+	// Latency Time spent on the check
+	Latency string `json:"latency"`
+	// Error The reason why the check failed
+	Error string `json:"error,omitempty"`
+}
+
+// Report Aggregated result of all checks
+type Report struct {
+	// Status Healthy when all checks passed
+	//
+	// enum: Healthy,Unhealthy
+	Status string `json:"status"`
+	// Checks Result of each check
+	Checks []CheckResult `json:"checks"`
+}
+
+type checkFunc struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+// NewCheck Creates a check from a function
+func NewCheck(name string, check func(ctx context.Context) error) Check {
+	return &checkFunc{name: name, check: check}
+}
+
+func (c *checkFunc) Name() string {
+	return c.name
+}
+
+func (c *checkFunc) Check(ctx context.Context) error {
+	return c.check(ctx)
+}
+
+// NewHandler Creates a handler, running all checks concurrently on each request.
+// It responds 200 when all checks pass, otherwise 503
+func NewHandler(checks ...Check) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := Run(r.Context(), checks...)
+		statusCode := http.StatusOK
+		if report.Status != StatusHealthy {
+			statusCode = http.StatusServiceUnavailable
+		}
+
+		body, err := json.Marshal(report)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(statusCode)
+		w.Write(body)
+	})
+}
+
+// Run Runs the checks concurrently and aggregates the results
+func Run(ctx context.Context, checks ...Check) Report {
+	report := Report{Status: StatusHealthy, Checks: make([]CheckResult, len(checks))}
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			report.Checks[i] = runCheck(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if result.Status != StatusHealthy {
+			report.Status = StatusUnhealthy
+		}
+	}
+	return report
+}
+
+func runCheck(ctx context.Context, check Check) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, defaultCheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.Check(ctx)
+	result := CheckResult{
+		Name:    check.Name(),
+		Status:  StatusHealthy,
+		Latency: time.Since(start).String(),
+	}
+	if err != nil {
+		result.Status = StatusUnhealthy
+		result.Error = err.Error()
+	}
+	return result
+}
@@ -0,0 +1,110 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	v1 "github.com/equinor/radix-operator/pkg/apis/radix/v1"
+	radixfake "github.com/equinor/radix-operator/pkg/client/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+)
+
+func executeRequest(handler http.Handler) (int, Report) {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	var report Report
+	json.Unmarshal(recorder.Body.Bytes(), &report)
+	return recorder.Code, report
+}
+
+func TestHandler(t *testing.T) {
+	t.Run("no checks - healthy", func(t *testing.T) {
+		t.Parallel()
+		statusCode, report := executeRequest(NewHandler())
+		assert.Equal(t, http.StatusOK, statusCode)
+		assert.Equal(t, StatusHealthy, report.Status)
+		assert.Empty(t, report.Checks)
+	})
+
+	t.Run("all checks pass - healthy", func(t *testing.T) {
+		t.Parallel()
+		handler := NewHandler(
+			NewCheck("first", func(ctx context.Context) error { return nil }),
+			NewCheck("second", func(ctx context.Context) error { return nil }),
+		)
+		statusCode, report := executeRequest(handler)
+		assert.Equal(t, http.StatusOK, statusCode)
+		assert.Equal(t, StatusHealthy, report.Status)
+		assert.Len(t, report.Checks, 2)
+		assert.Equal(t, "first", report.Checks[0].Name)
+		assert.Equal(t, StatusHealthy, report.Checks[0].Status)
+		assert.NotEmpty(t, report.Checks[0].Latency)
+		assert.Equal(t, "second", report.Checks[1].Name)
+		assert.Equal(t, StatusHealthy, report.Checks[1].Status)
+	})
+
+	t.Run("one check fails - status code 503", func(t *testing.T) {
+		t.Parallel()
+		handler := NewHandler(
+			NewCheck("first", func(ctx context.Context) error { return nil }),
+			NewCheck("second", func(ctx context.Context) error { return errors.New("unavailable") }),
+		)
+		statusCode, report := executeRequest(handler)
+		assert.Equal(t, http.StatusServiceUnavailable, statusCode)
+		assert.Equal(t, StatusUnhealthy, report.Status)
+		assert.Equal(t, StatusHealthy, report.Checks[0].Status)
+		assert.Equal(t, StatusUnhealthy, report.Checks[1].Status)
+		assert.Equal(t, "unavailable", report.Checks[1].Error)
+	})
+}
+
+func TestKubernetesCheck(t *testing.T) {
+	check := NewKubernetesCheck(kubefake.NewSimpleClientset())
+	assert.Equal(t, "kubernetes", check.Name())
+	assert.NoError(t, check.Check(context.Background()))
+}
+
+func TestKubernetesCheck_Timeout(t *testing.T) {
+	blocked := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-blocked
+	}))
+	defer server.Close()
+	defer close(blocked)
+	kubeClient, err := kubernetes.NewForConfig(&rest.Config{Host: server.URL})
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	assert.Error(t, NewKubernetesCheck(kubeClient).Check(ctx))
+	assert.Less(t, time.Since(started), 5*time.Second)
+}
+
+func TestRadixCheck(t *testing.T) {
+	namespace, radixDeploymentName := "app-env", "env-abc-123"
+	radixDeployment := v1.RadixDeployment{ObjectMeta: metav1.ObjectMeta{Name: radixDeploymentName, Namespace: namespace}}
+	radixClient := radixfake.NewSimpleClientset(&radixDeployment)
+
+	t.Run("deployment exists", func(t *testing.T) {
+		t.Parallel()
+		check := NewRadixCheck(radixClient, namespace, radixDeploymentName)
+		assert.Equal(t, "radix", check.Name())
+		assert.NoError(t, check.Check(context.Background()))
+	})
+
+	t.Run("deployment does not exist", func(t *testing.T) {
+		t.Parallel()
+		check := NewRadixCheck(radixClient, namespace, "other-deployment")
+		assert.Error(t, check.Check(context.Background()))
+	})
+}
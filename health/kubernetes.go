@@ -0,0 +1,31 @@
+package health
+
+import (
+	"context"
+
+	radixclient "github.com/equinor/radix-operator/pkg/client/clientset/versioned"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// NewKubernetesCheck Creates a check verifying that the Kubernetes API server can be reached. The version of the
+// API server is requested with the context of the check, as ServerVersion of the discovery client has no context
+func NewKubernetesCheck(kubeClient kubernetes.Interface) Check {
+	return NewCheck("kubernetes", func(ctx context.Context) error {
+		restClient := kubeClient.Discovery().RESTClient()
+		if restClient == nil {
+			// E.g. the fake client
+			_, err := kubeClient.Discovery().ServerVersion()
+			return err
+		}
+		return restClient.Get().AbsPath("/version").Do(ctx).Error()
+	})
+}
+
+// NewRadixCheck Creates a check verifying that the Radix client can read the active Radix deployment
+func NewRadixCheck(radixClient radixclient.Interface, namespace, radixDeploymentName string) Check {
+	return NewCheck("radix", func(ctx context.Context) error {
+		_, err := radixClient.RadixV1().RadixDeployments(namespace).Get(ctx, radixDeploymentName, metav1.GetOptions{})
+		return err
+	})
+}
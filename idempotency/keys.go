@@ -0,0 +1,190 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	serverErrors "github.com/equinor/radix-job-scheduler-server/api/errors"
+	"github.com/equinor/radix-job-scheduler-server/auth"
+	"github.com/equinor/radix-job-scheduler-server/store"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// KeyHeader Header with the idempotency key of a request
+	KeyHeader = "Idempotency-Key"
+	// ReplayedHeader Header set to true in the response of a repeated request, with the response of the original request
+	ReplayedHeader = "Idempotent-Replayed"
+
+	maxKeyLength  = 255
+	purgeInterval = 10 * time.Minute
+)
+
+// record The response of the first request with a key, and the hash of its body
+type record struct {
+	RequestHash string          `json:"requestHash"`
+	Response    json.RawMessage `json:"response"`
+	Expires     time.Time       `json:"expires"`
+}
+
+type keyLock struct {
+	sync.Mutex
+	refs int
+}
+
+// Keys Responses of requests by idempotency key, kept for the TTL
+type Keys struct {
+	store store.Store
+	ttl   time.Duration
+	now   func() time.Time
+	locks map[string]*keyLock
+	mu    sync.Mutex
+}
+
+// New Constructor
+func New(store store.Store, ttl time.Duration) *Keys {
+	return &Keys{
+		store: store,
+		ttl:   ttl,
+		now:   time.Now,
+		locks: make(map[string]*keyLock),
+	}
+}
+
+// GetKey Gets the idempotency key of the request, empty when it has none
+func GetKey(r *http.Request) (string, error) {
+	key := r.Header.Get(KeyHeader)
+	if len(key) > maxKeyLength {
+		return "", serverErrors.NewBadRequest(fmt.Sprintf("%s must be at most %d characters", KeyHeader, maxKeyLength))
+	}
+	return key, nil
+}
+
+// Do Calls create and keeps its response for the key, unless the key has been used before within the TTL.
+// A repeated request with the same body gets the response of the first request, and replayed is true.
+// A repeated request with a different body gets a conflict error. Failed requests are not kept, and can be repeated.
+// Requests in the scope with the same key are run one at a time. Without a key, create is always called.
+// Keys are scoped by the identity of the caller in ctx, so callers never get the responses of each other
+func (keys *Keys) Do(ctx context.Context, scope, key string, request interface{}, create func() (interface{}, error)) (response json.RawMessage, replayed bool, err error) {
+	if len(key) == 0 {
+		return getResponse(create)
+	}
+	requestHash, err := getHash(request)
+	if err != nil {
+		return nil, false, err
+	}
+	storeKey := scope + "/" + getCaller(ctx) + "/" + key
+	unlock := keys.lock(storeKey)
+	defer unlock()
+
+	var rec record
+	found, err := keys.store.Get(storeKey, &rec)
+	if err != nil {
+		return nil, false, err
+	}
+	if found && keys.now().Before(rec.Expires) {
+		if rec.RequestHash != requestHash {
+			return nil, false, serverErrors.NewConflict(fmt.Sprintf("%s %s was used with a different request body", KeyHeader, key))
+		}
+		return rec.Response, true, nil
+	}
+
+	if response, _, err = getResponse(create); err != nil {
+		return nil, false, err
+	}
+	rec = record{RequestHash: requestHash, Response: response, Expires: keys.now().Add(keys.ttl)}
+	if err := keys.store.Put(storeKey, &rec); err != nil {
+		// The request is done, and its response is returned even though a repeated request will not get it
+		log.Errorf("failed to save the response of the request with %s %s: %v", KeyHeader, key, err)
+	}
+	return response, false, nil
+}
+
+// getCaller The escaped name of the identity of the caller, empty when the request is not authenticated
+func getCaller(ctx context.Context) string {
+	if identity := auth.GetIdentity(ctx); identity != nil {
+		return url.PathEscape(identity.Name)
+	}
+	return ""
+}
+
+// Run Deletes the expired keys periodically until the context is done
+func (keys *Keys) Run(ctx context.Context) {
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+	for {
+		keys.purge()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (keys *Keys) purge() {
+	storeKeys, err := keys.store.Keys()
+	if err != nil {
+		log.Errorf("failed to read idempotency keys: %v", err)
+		return
+	}
+	now := keys.now()
+	for _, storeKey := range storeKeys {
+		unlock := keys.lock(storeKey)
+		var rec record
+		if found, err := keys.store.Get(storeKey, &rec); err == nil && found && !now.Before(rec.Expires) {
+			if err := keys.store.Delete(storeKey); err != nil {
+				log.Errorf("failed to delete the idempotency key %s: %v", storeKey, err)
+			}
+		}
+		unlock()
+	}
+}
+
+// lock Locks the key, and returns the function unlocking it
+func (keys *Keys) lock(storeKey string) func() {
+	keys.mu.Lock()
+	l, ok := keys.locks[storeKey]
+	if !ok {
+		l = &keyLock{}
+		keys.locks[storeKey] = l
+	}
+	l.refs++
+	keys.mu.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+		keys.mu.Lock()
+		if l.refs--; l.refs == 0 {
+			delete(keys.locks, storeKey)
+		}
+		keys.mu.Unlock()
+	}
+}
+
+func getResponse(create func() (interface{}, error)) (json.RawMessage, bool, error) {
+	result, err := create()
+	if err != nil {
+		return nil, false, err
+	}
+	response, err := json.Marshal(result)
+	return response, false, err
+}
+
+// getHash Gets the hash of the decoded request, so requests differing only in formatting or the order of properties are the same
+func getHash(request interface{}) (string, error) {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:]), nil
+}
@@ -0,0 +1,207 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	serverErrors "github.com/equinor/radix-job-scheduler-server/api/errors"
+	"github.com/equinor/radix-job-scheduler-server/auth"
+	"github.com/equinor/radix-job-scheduler-server/store"
+	"github.com/stretchr/testify/assert"
+)
+
+type request struct {
+	Payload string `json:"payload"`
+}
+
+type response struct {
+	Name string `json:"name"`
+}
+
+// counter Creates responses named by the number of calls
+type counter struct {
+	calls int
+	mu    sync.Mutex
+}
+
+func (c *counter) create() (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls++
+	return &response{Name: strings.Repeat("x", c.calls)}, nil
+}
+
+func setupKeys(ttl time.Duration) (*Keys, *time.Time) {
+	keys := New(store.NewMemoryStore(), ttl)
+	now := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	keys.now = func() time.Time { return now }
+	return keys, &now
+}
+
+func TestDo_RepeatedRequest(t *testing.T) {
+	keys, _ := setupKeys(time.Hour)
+	c := &counter{}
+
+	first, replayed, err := keys.Do(context.Background(), "jobs", "key1", &request{Payload: "a"}, c.create)
+	assert.NoError(t, err)
+	assert.False(t, replayed)
+	assert.JSONEq(t, `{"name":"x"}`, string(first))
+
+	repeated, replayed, err := keys.Do(context.Background(), "jobs", "key1", &request{Payload: "a"}, c.create)
+	assert.NoError(t, err)
+	assert.True(t, replayed)
+	assert.Equal(t, first, repeated)
+	assert.Equal(t, 1, c.calls)
+
+	// The key is scoped, and can be used for another kind of request
+	other, replayed, err := keys.Do(context.Background(), "batches", "key1", &request{Payload: "a"}, c.create)
+	assert.NoError(t, err)
+	assert.False(t, replayed)
+	assert.JSONEq(t, `{"name":"xx"}`, string(other))
+}
+
+func TestDo_KeysOfEachCaller(t *testing.T) {
+	keys, _ := setupKeys(time.Hour)
+	c := &counter{}
+	orchestrator := auth.WithIdentity(context.Background(), &auth.Identity{Name: "system:serviceaccount:app:orchestrator"})
+	dashboard := auth.WithIdentity(context.Background(), &auth.Identity{Name: "system:serviceaccount:app:dashboard"})
+
+	first, _, err := keys.Do(orchestrator, "jobs", "key1", &request{Payload: "a"}, c.create)
+	assert.NoError(t, err)
+	other, replayed, err := keys.Do(dashboard, "jobs", "key1", &request{Payload: "b"}, c.create)
+	assert.NoError(t, err)
+	assert.False(t, replayed)
+	assert.NotEqual(t, string(first), string(other))
+	repeated, replayed, err := keys.Do(orchestrator, "jobs", "key1", &request{Payload: "a"}, c.create)
+	assert.NoError(t, err)
+	assert.True(t, replayed)
+	assert.JSONEq(t, string(first), string(repeated))
+}
+
+func TestDo_DifferentBody(t *testing.T) {
+	keys, _ := setupKeys(time.Hour)
+	c := &counter{}
+
+	_, _, err := keys.Do(context.Background(), "jobs", "key1", &request{Payload: "a"}, c.create)
+	assert.NoError(t, err)
+	_, _, err = keys.Do(context.Background(), "jobs", "key1", &request{Payload: "b"}, c.create)
+	var statusError *serverErrors.StatusError
+	assert.True(t, errors.As(err, &statusError))
+	assert.Equal(t, http.StatusConflict, statusError.Status().Code)
+	assert.Equal(t, 1, c.calls)
+}
+
+func TestDo_WithoutKey(t *testing.T) {
+	keys, _ := setupKeys(time.Hour)
+	c := &counter{}
+
+	for i := 0; i < 2; i++ {
+		_, replayed, err := keys.Do(context.Background(), "jobs", "", &request{Payload: "a"}, c.create)
+		assert.NoError(t, err)
+		assert.False(t, replayed)
+	}
+	assert.Equal(t, 2, c.calls)
+	storeKeys, _ := keys.store.Keys()
+	assert.Empty(t, storeKeys)
+}
+
+func TestDo_FailedRequestIsNotKept(t *testing.T) {
+	keys, _ := setupKeys(time.Hour)
+	c := &counter{}
+
+	_, _, err := keys.Do(context.Background(), "jobs", "key1", &request{Payload: "a"}, func() (interface{}, error) {
+		return nil, errors.New("an error")
+	})
+	assert.Error(t, err)
+
+	_, replayed, err := keys.Do(context.Background(), "jobs", "key1", &request{Payload: "a"}, c.create)
+	assert.NoError(t, err)
+	assert.False(t, replayed)
+	assert.Equal(t, 1, c.calls)
+}
+
+func TestDo_ExpiredKey(t *testing.T) {
+	keys, now := setupKeys(time.Hour)
+	c := &counter{}
+
+	_, _, err := keys.Do(context.Background(), "jobs", "key1", &request{Payload: "a"}, c.create)
+	assert.NoError(t, err)
+	*now = now.Add(time.Hour)
+	// An expired key can be used with any body
+	_, replayed, err := keys.Do(context.Background(), "jobs", "key1", &request{Payload: "b"}, c.create)
+	assert.NoError(t, err)
+	assert.False(t, replayed)
+	assert.Equal(t, 2, c.calls)
+}
+
+func TestDo_ConcurrentRequests(t *testing.T) {
+	keys, _ := setupKeys(time.Hour)
+	c := &counter{}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			response, _, err := keys.Do(context.Background(), "jobs", "key1", &request{Payload: "a"}, c.create)
+			assert.NoError(t, err)
+			assert.JSONEq(t, `{"name":"x"}`, string(response))
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1, c.calls)
+	assert.Empty(t, keys.locks)
+}
+
+func TestDo_KeysSurviveRestart(t *testing.T) {
+	dir := t.TempDir()
+	c := &counter{}
+	fileStore, err := store.New(dir, "idempotency")
+	assert.NoError(t, err)
+	first, _, err := New(fileStore, time.Hour).Do(context.Background(), "jobs", "key1", &request{Payload: "a"}, c.create)
+	assert.NoError(t, err)
+
+	reopened, err := store.New(dir, "idempotency")
+	assert.NoError(t, err)
+	repeated, replayed, err := New(reopened, time.Hour).Do(context.Background(), "jobs", "key1", &request{Payload: "a"}, c.create)
+	assert.NoError(t, err)
+	assert.True(t, replayed)
+	assert.Equal(t, first, repeated)
+	assert.Equal(t, 1, c.calls)
+}
+
+func TestPurge(t *testing.T) {
+	keys, now := setupKeys(time.Hour)
+	c := &counter{}
+
+	_, _, _ = keys.Do(context.Background(), "jobs", "key1", &request{Payload: "a"}, c.create)
+	*now = now.Add(30 * time.Minute)
+	_, _, _ = keys.Do(context.Background(), "jobs", "key2", &request{Payload: "a"}, c.create)
+	*now = now.Add(30 * time.Minute)
+	keys.purge()
+
+	storeKeys, err := keys.store.Keys()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"jobs//key2"}, storeKeys)
+}
+
+func TestGetKey(t *testing.T) {
+	r, _ := http.NewRequest(http.MethodPost, "/api/v1/jobs", nil)
+	key, err := GetKey(r)
+	assert.NoError(t, err)
+	assert.Empty(t, key)
+
+	r.Header.Set(KeyHeader, "key1")
+	key, err = GetKey(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "key1", key)
+
+	r.Header.Set(KeyHeader, strings.Repeat("k", maxKeyLength+1))
+	_, err = GetKey(r)
+	assert.Error(t, err)
+}
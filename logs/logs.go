@@ -0,0 +1,314 @@
+package logs
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	serverErrors "github.com/equinor/radix-job-scheduler-server/api/errors"
+	"github.com/equinor/radix-job-scheduler-server/models"
+	apiErrors "github.com/equinor/radix-job-scheduler/api/errors"
+	"github.com/equinor/radix-operator/pkg/apis/kube"
+	corev1 "k8s.io/api/core/v1"
+	kubeErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	// FollowParam Query parameter to stream new log lines until the container ends
+	FollowParam = "follow"
+	// TailLinesParam Query parameter with the number of lines from the end of the log
+	TailLinesParam = "tailLines"
+	// SinceSecondsParam Query parameter with the age in seconds of the oldest log line
+	SinceSecondsParam = "sinceSeconds"
+	// TimestampsParam Query parameter to include the time of each log line
+	TimestampsParam = "timestamps"
+	// PreviousParam Query parameter to get the log of the previous attempt of the job
+	PreviousParam = "previous"
+	// FormatParam Query parameter with the format of the log, text or ndjson. Defaults to the Accept header
+	FormatParam = "format"
+
+	// FormatText Log lines as plain text
+	FormatText = "text"
+	// FormatNDJSON Log lines as newline delimited JSON objects
+	FormatNDJSON = "ndjson"
+
+	ndjsonContentType = "application/x-ndjson"
+	// jobNameLabel Label set by Kubernetes on the pods of a job
+	jobNameLabel = "job-name"
+	// maxLineLength Maximum length of a log line in bytes. Longer lines are split
+	maxLineLength = 64 * 1024
+)
+
+// Options Options of a log request
+type Options struct {
+	Follow       bool
+	TailLines    *int64
+	SinceSeconds *int64
+	Timestamps   bool
+	Previous     bool
+	Format       string
+}
+
+// Line A log line in the NDJSON format
+type Line struct {
+	Pod       string `json:"pod"`
+	Container string `json:"container"`
+	Timestamp string `json:"timestamp,omitempty"`
+	Message   string `json:"message"`
+}
+
+// LineError An error reading the log after the response is started, in the NDJSON format
+type LineError struct {
+	Error string `json:"error"`
+}
+
+// Reader Reads the logs of the pods of jobs
+type Reader struct {
+	kubeUtil  *kube.Kube
+	namespace string
+	labels    labels.Set
+	container string
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewReader Constructor
+func NewReader(kubeUtil *kube.Kube, env *models.Env) *Reader {
+	return &Reader{
+		kubeUtil:  kubeUtil,
+		namespace: env.RadixDeploymentNamespace,
+		labels: labels.Set{
+			kube.RadixAppLabel:       env.RadixAppName,
+			kube.RadixComponentLabel: env.RadixComponentName,
+		},
+		container: env.RadixComponentName,
+		done:      make(chan struct{}),
+	}
+}
+
+// Close Ends all followed logs, e.g. when the server shuts down
+func (reader *Reader) Close() {
+	reader.closeOnce.Do(func() { close(reader.done) })
+}
+
+// ParseOptions Parses the log query parameters and the Accept header of the request
+func ParseOptions(r *http.Request) (*Options, error) {
+	query := r.URL.Query()
+	options := Options{Format: getFormat(r)}
+	var err error
+	if options.Follow, err = parseBoolParam(r, FollowParam); err != nil {
+		return nil, err
+	}
+	if options.Timestamps, err = parseBoolParam(r, TimestampsParam); err != nil {
+		return nil, err
+	}
+	if options.Previous, err = parseBoolParam(r, PreviousParam); err != nil {
+		return nil, err
+	}
+	if options.TailLines, err = parseIntParam(r, TailLinesParam, 0); err != nil {
+		return nil, err
+	}
+	if options.SinceSeconds, err = parseIntParam(r, SinceSecondsParam, 1); err != nil {
+		return nil, err
+	}
+	if format := query.Get(FormatParam); len(format) > 0 && format != FormatText && format != FormatNDJSON {
+		return nil, serverErrors.NewBadRequest(fmt.Sprintf("%s must be %s or %s", FormatParam, FormatText, FormatNDJSON))
+	}
+	return &options, nil
+}
+
+// Stream Writes the log of the job to the response, until the log ends or the client disconnects.
+// The log is read from the latest pod of the job, or with the option Previous from the pod of the previous attempt,
+// or the previous instance of the container when the job has one pod.
+// Returns an error when the log could not be read. Errors after the response is started end the response.
+// A followed log ends when the reader is closed
+func (reader *Reader) Stream(w http.ResponseWriter, r *http.Request, jobName string, options *Options) error {
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	if options.Follow {
+		go func() {
+			select {
+			case <-reader.done:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+	}
+
+	pod, previousContainer, err := reader.getPod(ctx, jobName, options.Previous)
+	if err != nil {
+		return err
+	}
+	container := reader.getContainerName(pod)
+	logOptions := corev1.PodLogOptions{
+		Container:    container,
+		Follow:       options.Follow,
+		TailLines:    options.TailLines,
+		SinceSeconds: options.SinceSeconds,
+		Timestamps:   options.Timestamps,
+		Previous:     previousContainer,
+	}
+	stream, err := reader.kubeUtil.KubeClient().CoreV1().Pods(reader.namespace).GetLogs(pod.Name, &logOptions).Stream(ctx)
+	if err != nil {
+		if kubeErrors.IsBadRequest(err) {
+			// E.g. the container is waiting to start, or has no previous instance
+			return serverErrors.NewBadRequest(err.Error())
+		}
+		return err
+	}
+	defer stream.Close()
+
+	if options.Format == FormatNDJSON {
+		w.Header().Set("Content-Type", ndjsonContentType)
+	} else {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	if err := copyLines(w, stream, pod.Name, container, options); err != nil && ctx.Err() == nil {
+		writeError(w, err, options)
+	}
+	return nil
+}
+
+// copyLines Writes the lines of the log stream, splitting lines longer than maxLineLength, so a line is never
+// buffered whole. Returns the error reading the stream, or nil when the stream ends or the client is gone
+func copyLines(w io.Writer, stream io.Reader, pod, container string, options *Options) error {
+	flusher, _ := w.(http.Flusher)
+	lines := bufio.NewReaderSize(stream, maxLineLength)
+	continued := false
+	for {
+		line, err := lines.ReadSlice('\n')
+		split := errors.Is(err, bufio.ErrBufferFull)
+		if len(line) > 0 {
+			text := string(line)
+			if split {
+				text += "\n"
+			}
+			if writeErr := writeLine(w, text, pod, container, continued, options); writeErr != nil {
+				return nil
+			}
+			if flusher != nil && lines.Buffered() == 0 {
+				flusher.Flush()
+			}
+		}
+		continued = split
+		switch {
+		case split:
+		case err == io.EOF:
+			return nil
+		case err != nil:
+			return err
+		}
+	}
+}
+
+// getPod Gets the pod with the log of the job, and whether the log is in the previous instance of the container
+func (reader *Reader) getPod(ctx context.Context, jobName string, previous bool) (*corev1.Pod, bool, error) {
+	selector := labels.Merge(reader.labels, labels.Set{jobNameLabel: jobName})
+	pods, err := reader.kubeUtil.KubeClient().CoreV1().Pods(reader.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(selector).String(),
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if len(pods.Items) == 0 {
+		return nil, false, apiErrors.NewNotFound("pod of the job", jobName)
+	}
+	sort.Slice(pods.Items, func(i, j int) bool {
+		return pods.Items[j].CreationTimestamp.Before(&pods.Items[i].CreationTimestamp)
+	})
+	switch {
+	case !previous:
+		return &pods.Items[0], false, nil
+	case len(pods.Items) > 1:
+		return &pods.Items[1], false, nil
+	default:
+		return &pods.Items[0], true, nil
+	}
+}
+
+// getContainerName Gets the name of the job container, named as the job component
+func (reader *Reader) getContainerName(pod *corev1.Pod) string {
+	for _, container := range pod.Spec.Containers {
+		if container.Name == reader.container {
+			return container.Name
+		}
+	}
+	if len(pod.Spec.Containers) > 0 {
+		return pod.Spec.Containers[0].Name
+	}
+	return ""
+}
+
+// writeLine Writes a log line as text or as a Line object. A continued line, the rest of a split line, has no timestamp
+func writeLine(w io.Writer, line, pod, container string, continued bool, options *Options) error {
+	if options.Format != FormatNDJSON {
+		_, err := io.WriteString(w, line)
+		return err
+	}
+	logLine := Line{Pod: pod, Container: container, Message: strings.TrimSuffix(line, "\n")}
+	if options.Timestamps && !continued {
+		if i := strings.IndexByte(logLine.Message, ' '); i > 0 {
+			logLine.Timestamp, logLine.Message = logLine.Message[:i], logLine.Message[i+1:]
+		}
+	}
+	return json.NewEncoder(w).Encode(&logLine)
+}
+
+// writeError Writes an error reading the log, as a line of text or as a LineError object
+func writeError(w io.Writer, err error, options *Options) {
+	message := fmt.Sprintf("failed to read the log: %v", err)
+	if options.Format != FormatNDJSON {
+		fmt.Fprintf(w, "\n%s\n", message)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(&LineError{Error: message})
+}
+
+func getFormat(r *http.Request) string {
+	if format := r.URL.Query().Get(FormatParam); len(format) > 0 {
+		return format
+	}
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		if mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept)); err == nil && mediaType == ndjsonContentType {
+			return FormatNDJSON
+		}
+	}
+	return FormatText
+}
+
+func parseBoolParam(r *http.Request, name string) (bool, error) {
+	value := r.URL.Query().Get(name)
+	if len(value) == 0 {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, serverErrors.NewBadRequest(fmt.Sprintf("%s must be true or false", name))
+	}
+	return b, nil
+}
+
+func parseIntParam(r *http.Request, name string, min int64) (*int64, error) {
+	value := r.URL.Query().Get(name)
+	if len(value) == 0 {
+		return nil, nil
+	}
+	i, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || i < min {
+		return nil, serverErrors.NewBadRequest(fmt.Sprintf("%s must be a number of at least %d", name, min))
+	}
+	return &i, nil
+}
@@ -0,0 +1,180 @@
+package logs
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/equinor/radix-job-scheduler-server/models"
+	apiErrors "github.com/equinor/radix-job-scheduler/api/errors"
+	apiModels "github.com/equinor/radix-job-scheduler/models/common"
+	"github.com/equinor/radix-operator/pkg/apis/kube"
+	radixfake "github.com/equinor/radix-operator/pkg/client/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	secretproviderfake "sigs.k8s.io/secrets-store-csi-driver/pkg/client/clientset/versioned/fake"
+)
+
+func newTestReader(pods ...runtime.Object) *Reader {
+	env := models.NewEnv()
+	env.RadixAppName, env.RadixComponentName, env.RadixDeploymentNamespace = "app", "compute", "app-dev"
+	kubeUtil, _ := kube.New(kubefake.NewSimpleClientset(pods...), radixfake.NewSimpleClientset(), secretproviderfake.NewSimpleClientset())
+	return NewReader(kubeUtil, env)
+}
+
+func newTestPod(name, jobName string, created time.Time) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "app-dev",
+			CreationTimestamp: metav1.NewTime(created),
+			Labels:            map[string]string{kube.RadixAppLabel: "app", kube.RadixComponentLabel: "compute", jobNameLabel: jobName},
+		},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "sidecar"}, {Name: "compute"}}},
+	}
+}
+
+func TestParseOptions(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/api/v1/jobs/job1/logs?follow=true&tailLines=10&sinceSeconds=60&timestamps=1&previous=false", nil)
+	options, err := ParseOptions(request)
+
+	assert.NoError(t, err)
+	assert.True(t, options.Follow)
+	assert.True(t, options.Timestamps)
+	assert.False(t, options.Previous)
+	assert.Equal(t, int64(10), *options.TailLines)
+	assert.Equal(t, int64(60), *options.SinceSeconds)
+	assert.Equal(t, FormatText, options.Format)
+
+	request = httptest.NewRequest(http.MethodGet, "/api/v1/jobs/job1/logs", nil)
+	request.Header.Set("Accept", "text/plain, application/x-ndjson")
+	options, err = ParseOptions(request)
+	assert.NoError(t, err)
+	assert.Nil(t, options.TailLines)
+	assert.Equal(t, FormatNDJSON, options.Format)
+
+	for _, query := range []string{"follow=yes", "tailLines=-1", "sinceSeconds=0", "timestamps=x", "previous=2", "format=json"} {
+		_, err := ParseOptions(httptest.NewRequest(http.MethodGet, "/api/v1/jobs/job1/logs?"+query, nil))
+		assert.Error(t, err, query)
+		status, ok := err.(apiErrors.APIStatus)
+		if assert.True(t, ok, query) {
+			assert.Equal(t, http.StatusBadRequest, status.Status().Code, query)
+		}
+	}
+}
+
+func TestStream_Text(t *testing.T) {
+	reader := newTestReader(newTestPod("job1-abc", "job1", time.Now()))
+	recorder := httptest.NewRecorder()
+
+	err := reader.Stream(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/jobs/job1/logs", nil), "job1", &Options{Format: FormatText})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "text/plain; charset=utf-8", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, "fake logs", recorder.Body.String())
+}
+
+func TestStream_NDJSON(t *testing.T) {
+	reader := newTestReader(newTestPod("job1-abc", "job1", time.Now()))
+	recorder := httptest.NewRecorder()
+
+	err := reader.Stream(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/jobs/job1/logs", nil), "job1", &Options{Format: FormatNDJSON})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "application/x-ndjson", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, "{\"pod\":\"job1-abc\",\"container\":\"compute\",\"message\":\"fake logs\"}\n", recorder.Body.String())
+}
+
+func TestStream_NoPods_NotFound(t *testing.T) {
+	reader := newTestReader(newTestPod("job2-abc", "job2", time.Now()))
+	recorder := httptest.NewRecorder()
+
+	err := reader.Stream(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/jobs/job1/logs", nil), "job1", &Options{})
+
+	status, ok := err.(apiErrors.APIStatus)
+	if assert.True(t, ok) {
+		assert.Equal(t, apiModels.StatusReasonNotFound, status.Status().Reason)
+	}
+	assert.Empty(t, recorder.Body.String())
+}
+
+func TestGetPod(t *testing.T) {
+	created := time.Date(2022, 11, 1, 12, 0, 0, 0, time.UTC)
+	reader := newTestReader(
+		newTestPod("job1-first", "job1", created),
+		newTestPod("job1-latest", "job1", created.Add(2*time.Minute)),
+		newTestPod("job1-second", "job1", created.Add(time.Minute)),
+		newTestPod("job2-only", "job2", created),
+	)
+
+	pod, previousContainer, err := reader.getPod(httptest.NewRequest(http.MethodGet, "/", nil).Context(), "job1", false)
+	assert.NoError(t, err)
+	assert.Equal(t, "job1-latest", pod.Name)
+	assert.False(t, previousContainer)
+
+	pod, previousContainer, err = reader.getPod(httptest.NewRequest(http.MethodGet, "/", nil).Context(), "job1", true)
+	assert.NoError(t, err)
+	assert.Equal(t, "job1-second", pod.Name)
+	assert.False(t, previousContainer)
+
+	pod, previousContainer, err = reader.getPod(httptest.NewRequest(http.MethodGet, "/", nil).Context(), "job2", true)
+	assert.NoError(t, err)
+	assert.Equal(t, "job2-only", pod.Name)
+	assert.True(t, previousContainer)
+}
+
+func TestWriteLine_NDJSONWithTimestamp(t *testing.T) {
+	recorder := httptest.NewRecorder()
+
+	err := writeLine(recorder, "2022-11-01T12:00:00.123456789Z processing item 1\n", "pod1", "compute", false, &Options{Format: FormatNDJSON, Timestamps: true})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "{\"pod\":\"pod1\",\"container\":\"compute\",\"timestamp\":\"2022-11-01T12:00:00.123456789Z\",\"message\":\"processing item 1\"}\n", recorder.Body.String())
+}
+
+func TestCopyLines_SplitsLongLines(t *testing.T) {
+	longLine := strings.Repeat("x", maxLineLength+10)
+	stream := strings.NewReader("2022-11-01T12:00:00Z " + longLine + "\n2022-11-01T12:00:01Z done")
+
+	text := httptest.NewRecorder()
+	assert.NoError(t, copyLines(text, stream, "pod1", "compute", &Options{Format: FormatText, Timestamps: true}))
+	lines := strings.Split(text.Body.String(), "\n")
+	assert.Len(t, lines, 3)
+	assert.Len(t, lines[0], maxLineLength)
+	assert.Equal(t, "2022-11-01T12:00:00Z "+longLine, lines[0]+lines[1])
+	assert.Equal(t, "2022-11-01T12:00:01Z done", lines[2])
+
+	_, _ = stream.Seek(0, io.SeekStart)
+	ndjson := httptest.NewRecorder()
+	assert.NoError(t, copyLines(ndjson, stream, "pod1", "compute", &Options{Format: FormatNDJSON, Timestamps: true}))
+	var logLines []Line
+	decoder := json.NewDecoder(ndjson.Body)
+	for decoder.More() {
+		var logLine Line
+		assert.NoError(t, decoder.Decode(&logLine))
+		logLines = append(logLines, logLine)
+	}
+	assert.Len(t, logLines, 3)
+	assert.Equal(t, "2022-11-01T12:00:00Z", logLines[0].Timestamp)
+	assert.Empty(t, logLines[1].Timestamp)
+	assert.Equal(t, longLine, logLines[0].Message+logLines[1].Message)
+	assert.Equal(t, "done", logLines[2].Message)
+}
+
+func TestWriteError(t *testing.T) {
+	text := httptest.NewRecorder()
+	writeError(text, errors.New("connection reset"), &Options{Format: FormatText})
+	assert.Equal(t, "\nfailed to read the log: connection reset\n", text.Body.String())
+
+	ndjson := httptest.NewRecorder()
+	writeError(ndjson, errors.New("connection reset"), &Options{Format: FormatNDJSON})
+	assert.Equal(t, "{\"error\":\"failed to read the log: connection reset\"}\n", ndjson.Body.String())
+}
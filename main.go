@@ -1,62 +1,189 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+	// The time zones of schedules are loaded from the embedded database, as the image has no zoneinfo
+	_ "time/tzdata"
 
 	batchControllers "github.com/equinor/radix-job-scheduler-server/api/v1/controllers/batches"
 	jobControllers "github.com/equinor/radix-job-scheduler-server/api/v1/controllers/jobs"
+	scheduleControllers "github.com/equinor/radix-job-scheduler-server/api/v1/controllers/schedules"
+	schemaControllers "github.com/equinor/radix-job-scheduler-server/api/v1/controllers/schema"
+	"github.com/equinor/radix-job-scheduler-server/bulk"
+	"github.com/equinor/radix-job-scheduler-server/events"
+	"github.com/equinor/radix-job-scheduler-server/idempotency"
+	"github.com/equinor/radix-job-scheduler-server/logs"
 	"github.com/equinor/radix-job-scheduler-server/models"
+	"github.com/equinor/radix-job-scheduler-server/pending"
+	"github.com/equinor/radix-job-scheduler-server/rerun"
 	"github.com/equinor/radix-job-scheduler-server/router"
+	"github.com/equinor/radix-job-scheduler-server/schedules"
+	"github.com/equinor/radix-job-scheduler-server/store"
 	_ "github.com/equinor/radix-job-scheduler-server/swaggerui"
+	"github.com/equinor/radix-job-scheduler-server/tracing"
+	apiUtils "github.com/equinor/radix-job-scheduler-server/utils"
+	"github.com/equinor/radix-job-scheduler-server/validation"
+	"github.com/equinor/radix-job-scheduler-server/webhooks"
 	batchApi "github.com/equinor/radix-job-scheduler/api/v1/batches"
 	jobApi "github.com/equinor/radix-job-scheduler/api/v1/jobs"
-	apiModels "github.com/equinor/radix-job-scheduler/models"
 	"github.com/equinor/radix-operator/pkg/apis/kube"
 	"github.com/equinor/radix-operator/pkg/apis/utils"
-	"github.com/gorilla/handlers"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/pflag"
 )
 
 func main() {
-	env := apiModels.NewEnv()
+	env := models.NewEnv()
+	initLogger(env)
 	fs := initializeFlagSet()
 
 	var (
-		port = fs.StringP("port", "p", env.RadixPort, "Port where API will be served")
+		port            = fs.StringP("port", "p", env.RadixPort, "Port where API will be served")
+		shutdownTimeout = fs.Duration("shutdown-timeout", env.ShutdownTimeout, "Maximum time to wait for in-flight requests to complete on shutdown")
 	)
 
 	log.Debugf("Port: %s\n", *port)
 	parseFlagsFromArgs(fs)
 
-	errs := make(chan error)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	shutdownTracing, err := tracing.Init(ctx, env)
+	if err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
+	}
+
+	if len(env.StateDir) == 0 {
+		log.Warn("STATE_DIR is not set: idempotency keys, job descriptions for re-runs, callbacks, pending jobs and batches, and schedules are kept in memory, and are lost when the server restarts")
+	}
+
+	// The background workers are waited for on shutdown, so they are not stopped in the middle of a change
+	var workers sync.WaitGroup
 	kubeUtil := getKubeUtil()
+	jobHandler := jobApi.New(kubeUtil, env.Env)
+	batchHandler := batchApi.New(kubeUtil, env.Env)
+	jobWatcher := events.NewJobWatcher(kubeUtil.KubeClient(), env)
+	notifier := webhooks.NewNotifier(getStore(env, "callbacks"), jobHandler, batchHandler, jobWatcher)
+	runWorker(ctx, &workers, notifier.Run)
+	idempotencyKeys := idempotency.New(getStore(env, "idempotency"), env.IdempotencyKeyTTL)
+	runWorker(ctx, &workers, idempotencyKeys.Run)
+	descriptions := rerun.NewDescriptions(getStore(env, "descriptions"), jobHandler, batchHandler, jobWatcher)
+	runWorker(ctx, &workers, descriptions.Run)
+	bulkRunner := bulk.NewRunner(env.BulkConcurrency)
+	pendingItems := pending.New(getStore(env, "pending"), env.RadixComponentName, env.MaxActiveJobs, env.PriorityClasses, env.PriorityAging)
+	cronSchedules := schedules.New(getStore(env, "schedules"))
+
+	payloadSchema, err := validation.NewPayloadSchema(env.PayloadSchemaFile, env.PayloadSchema)
+	if err != nil {
+		log.Fatalf("Failed to load the payload schema: %v", err)
+	}
+
+	logReader := logs.NewReader(kubeUtil, env)
+	handler, err := router.NewServer(env, kubeUtil,
+		jobControllers.New(jobHandler, jobWatcher, logReader, notifier, idempotencyKeys, payloadSchema, descriptions, bulkRunner, pendingItems, cronSchedules),
+		batchControllers.New(batchHandler, jobWatcher, logReader, notifier, idempotencyKeys, payloadSchema, descriptions, bulkRunner, pendingItems, cronSchedules),
+		schemaControllers.New(payloadSchema),
+		scheduleControllers.New(cronSchedules, payloadSchema),
+	)
+	if err != nil {
+		log.Fatalf("Failed to create the Radix job scheduler API server: %v", err)
+	}
+	server := &http.Server{
+		Addr:    fmt.Sprintf(":%s", *port),
+		Handler: handler,
+	}
+	// The controllers set the creators of the pending jobs and batches, and the runners of the schedules
+	runWorker(ctx, &workers, pendingItems.Run)
+	runWorker(ctx, &workers, cronSchedules.Run)
+	// Event streams and followed logs do not end by themselves, and would otherwise hold the shutdown until it times out
+	server.RegisterOnShutdown(jobWatcher.Close)
+	server.RegisterOnShutdown(logReader.Close)
 
+	errs := make(chan error, 1)
 	go func() {
 		log.Infof("Radix job scheduler API is serving on port %s", *port)
-		err := http.ListenAndServe(fmt.Sprintf(":%s", *port), handlers.CombinedLoggingHandler(os.Stdout, router.NewServer(env, getControllers(kubeUtil, env)...)))
-		errs <- err
+		errs <- server.ListenAndServe()
 	}()
 
-	err := <-errs
-	if err != nil {
+	select {
+	case err := <-errs:
 		log.Fatalf("Radix job scheduler API server crashed: %v", err)
+	case <-ctx.Done():
+	}
+	stop()
+
+	log.Infof("Shutting down Radix job scheduler API, waiting up to %s for in-flight requests", *shutdownTimeout)
+	shutdownErr := shutdown(server, &workers, *shutdownTimeout)
+	if err := shutdownTracing(context.Background()); err != nil {
+		log.Warnf("Failed to flush traces: %v", err)
+	}
+	if shutdownErr != nil {
+		log.Errorf("Radix job scheduler API server did not shut down gracefully: %v", shutdownErr)
+		os.Exit(1)
+	}
+	log.Info("Radix job scheduler API server stopped")
+}
+
+// shutdown Stops accepting new connections and waits for in-flight requests, including the history cleanup
+// run after job and batch creation, and for the background workers to complete within the timeout
+func shutdown(server *http.Server, workers *sync.WaitGroup, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	done := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("background workers did not stop: %w", ctx.Err())
 	}
 }
 
+// runWorker Runs a background worker until the context is done, and adds it to the workers waited for on shutdown
+func runWorker(ctx context.Context, workers *sync.WaitGroup, run func(ctx context.Context)) {
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		run(ctx)
+	}()
+}
+
+func initLogger(env *models.Env) {
+	if env.LogFormat == models.LogFormatText {
+		log.SetFormatter(&log.TextFormatter{})
+	} else {
+		log.SetFormatter(&log.JSONFormatter{})
+	}
+	log.AddHook(&apiUtils.RequestIDHook{})
+}
+
 func getKubeUtil() *kube.Kube {
 	kubeClient, radixClient, _, secretProviderClient := utils.GetKubernetesClient()
 	kubeUtil, _ := kube.New(kubeClient, radixClient, secretProviderClient)
 	return kubeUtil
 }
 
-func getControllers(kubeUtil *kube.Kube, env *apiModels.Env) []models.Controller {
-	return []models.Controller{
-		jobControllers.New(jobApi.New(kubeUtil, env)),
-		batchControllers.New(batchApi.New(kubeUtil, env)),
+// getStore Gets the named store in the state directory, or in memory when no state directory is set
+func getStore(env *models.Env, name string) store.Store {
+	s, err := store.New(env.StateDir, name)
+	if err != nil {
+		log.Fatalf("Failed to open the %s store in %s: %v", name, env.StateDir, err)
 	}
+	return s
 }
 
 func initializeFlagSet() *pflag.FlagSet {
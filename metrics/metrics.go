@@ -0,0 +1,104 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "radix_job_scheduler"
+
+var (
+	requestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "requests_total",
+		Help:      "The total number of handled requests",
+	}, []string{"route", "method", "code"})
+	requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "request_duration_seconds",
+		Help:      "Duration of handled requests",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "code"})
+	requestsInFlight = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "requests_in_flight",
+		Help:      "The number of requests currently being handled",
+	}, []string{"route", "method"})
+	jobsCreated = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "jobs_created_total",
+		Help:      "The total number of created jobs",
+	})
+	jobsStopped = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "jobs_stopped_total",
+		Help:      "The total number of stopped jobs",
+	})
+	jobsDeleted = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "jobs_deleted_total",
+		Help:      "The total number of deleted jobs",
+	})
+	batchesCreated = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "batches_created_total",
+		Help:      "The total number of created batches",
+	})
+	historyLimitFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "history_limit_failures_total",
+		Help:      "The total number of failures to maintain the job or batch history limit",
+	}, []string{"kind"})
+	callbackDeliveries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "callback_deliveries_total",
+		Help:      "The total number of attempts to deliver completion callbacks, by kind and result",
+	}, []string{"kind", "result"})
+)
+
+// RequestStarted Registers a request in flight for the route template and method
+func RequestStarted(route, method string) {
+	requestsInFlight.WithLabelValues(route, method).Inc()
+}
+
+// RequestCompleted Registers a handled request for the route template and method, with its status code and duration
+func RequestCompleted(route, method string, statusCode int, duration time.Duration) {
+	code := strconv.Itoa(statusCode)
+	requestsInFlight.WithLabelValues(route, method).Dec()
+	requestsTotal.WithLabelValues(route, method, code).Inc()
+	requestDuration.WithLabelValues(route, method, code).Observe(duration.Seconds())
+}
+
+// AddJobCreated Increments the number of created jobs
+func AddJobCreated() {
+	jobsCreated.Inc()
+}
+
+// AddJobStopped Increments the number of stopped jobs
+func AddJobStopped() {
+	jobsStopped.Inc()
+}
+
+// AddJobDeleted Increments the number of deleted jobs
+func AddJobDeleted() {
+	jobsDeleted.Inc()
+}
+
+// AddBatchCreated Increments the number of created batches
+func AddBatchCreated() {
+	batchesCreated.Inc()
+}
+
+// AddHistoryLimitFailure Increments the number of failures to maintain the history limit for the kind, job or batch
+func AddHistoryLimitFailure(kind string) {
+	historyLimitFailures.WithLabelValues(kind).Inc()
+}
+
+// AddCallbackDelivery Increments the number of attempts to deliver a callback for the kind, job or batch,
+// with the result delivered, retry or failed
+func AddCallbackDelivery(kind, result string) {
+	callbackDeliveries.WithLabelValues(kind, result).Inc()
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	schedulerModels "github.com/equinor/radix-job-scheduler/models/common"
+)
+
+// Selector Selects the jobs or batches of a bulk operation. Jobs and batches must match all conditions which are set,
+// and at least one condition must be set
+// swagger:model Selector
+type Selector struct {
+	// Names of the jobs or batches
+	//
+	// required: false
+	// example: ["batch-compute-20220302155333-hrwl53mw-fjhcqwj7"]
+	Names []string `json:"names,omitempty"`
+
+	// Statuses of the jobs or batches. One of Scheduled, Queued, Waiting, Running, Succeeded, Failed, Stopping, Stopped
+	//
+	// required: false
+	// example: ["Waiting", "Running"]
+	Status []string `json:"status,omitempty"`
+
+	// Prefix of the job ID set in the job schedule description. A batch matches when any of its jobs matches
+	//
+	// required: false
+	// example: import-
+	JobIdPrefix string `json:"jobIdPrefix,omitempty"`
+
+	// Jobs or batches created at or before the time, RFC3339
+	//
+	// required: false
+	// example: 2022-11-01T15:04:05Z
+	CreatedBefore string `json:"createdBefore,omitempty"`
+}
+
+// BulkItemResult Result of a bulk operation on a job or batch
+// swagger:model BulkItemResult
+type BulkItemResult struct {
+	// Name of the job or batch
+	//
+	// required: true
+	Name string `json:"name"`
+
+	schedulerModels.Status `json:",inline"`
+}
+
+// BulkReport Results of a bulk operation, in the order of the names in the selector, otherwise by creation time and name
+// swagger:model BulkReport
+type BulkReport struct {
+	// Number of jobs or batches the operation succeeded for
+	//
+	// required: true
+	Succeeded int `json:"succeeded"`
+
+	// Number of jobs or batches the operation failed for
+	//
+	// required: true
+	Failed int `json:"failed"`
+
+	// Result for each job or batch
+	//
+	// required: true
+	Results []BulkItemResult `json:"results"`
+}
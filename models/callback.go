@@ -0,0 +1,49 @@
+package models
+
+import (
+	schedulerModels "github.com/equinor/radix-job-scheduler/models/common"
+)
+
+// Callback Completion callback of a job or batch
+// swagger:model Callback
+type Callback struct {
+	// URL receiving an HTTP POST with the final status when the job or batch is Succeeded, Failed or Stopped.
+	// The host must be public, not a loopback, link-local, private or cluster-internal host
+	//
+	// required: false
+	// example: https://my-app.example.com/callbacks
+	CallbackURL string `json:"callbackUrl,omitempty"`
+
+	// Secret of the HMAC-SHA256 signature of the callback payload, in the X-Radix-Signature-256 header
+	//
+	// required: false
+	CallbackSecret string `json:"callbackSecret,omitempty"`
+}
+
+// JobScheduleRequest Job to schedule, with an optional completion callback
+// swagger:model JobScheduleRequest
+type JobScheduleRequest struct {
+	schedulerModels.JobScheduleDescription `json:",inline"`
+	Callback                               `json:",inline"`
+	QueuePriority                          `json:",inline"`
+
+	// Time the job is created at the earliest, RFC3339. The job is Scheduled until then
+	//
+	// required: false
+	// example: 2022-11-01T15:04:05Z
+	NotBefore string `json:"notBefore,omitempty"`
+}
+
+// BatchScheduleRequest Batch to schedule, with an optional completion callback
+// swagger:model BatchScheduleRequest
+type BatchScheduleRequest struct {
+	schedulerModels.BatchScheduleDescription `json:",inline"`
+	Callback                                 `json:",inline"`
+	QueuePriority                            `json:",inline"`
+
+	// Time the batch is created at the earliest, RFC3339. The batch is Scheduled until then
+	//
+	// required: false
+	// example: 2022-11-01T15:04:05Z
+	NotBefore string `json:"notBefore,omitempty"`
+}
@@ -0,0 +1,221 @@
+package models
+
+import (
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	schedulerModels "github.com/equinor/radix-job-scheduler/models"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// LogFormatJSON Log entries are written as JSON objects
+	LogFormatJSON = "json"
+	// LogFormatText Log entries are written as text lines
+	LogFormatText = "text"
+
+	// TracesExporterNone Traces are not exported
+	TracesExporterNone = "none"
+	// TracesExporterStdout Traces are written to stdout
+	TracesExporterStdout = "stdout"
+	// TracesExporterFile Traces are written to the file TracesFile
+	TracesExporterFile = "file"
+	// TracesExporterOTLP Traces are exported with OTLP over HTTP
+	TracesExporterOTLP = "otlp"
+
+	// AuthModeAnonymous Requests are not authenticated
+	AuthModeAnonymous = "anonymous"
+	// AuthModeToken Requests are authenticated with the bearer token in the file AuthTokenFile
+	AuthModeToken = "token"
+	// AuthModeServiceAccount Requests are authenticated with a Kubernetes TokenReview of the bearer token
+	AuthModeServiceAccount = "serviceaccount"
+
+	// RateLimitByIdentity Requests are rate limited by the authenticated caller, and anonymous requests by source IP
+	RateLimitByIdentity = "identity"
+	// RateLimitByIP Requests are rate limited by source IP
+	RateLimitByIP = "ip"
+
+	defaultShutdownTimeout    = 25 * time.Second
+	defaultIdempotencyKeyTTL  = 24 * time.Hour
+	defaultMaxRequestBodySize = 10 * 1024 * 1024
+	defaultBulkConcurrency    = 10
+	defaultPriorityAging      = 10 * time.Minute
+	defaultTracesFile         = "traces.json"
+	// defaultRateLimitAuthFailures A failed authentication per second, after a burst of 10
+	defaultRateLimitAuthFailures      = 1
+	defaultRateLimitAuthFailuresBurst = 10
+)
+
+// Env Settings of the job scheduler server
+type Env struct {
+	*schedulerModels.Env
+	// ShutdownTimeout Maximum time to wait for in-flight requests when the server is stopped
+	ShutdownTimeout time.Duration
+	// LogFormat Format of log entries, json or text
+	LogFormat string
+	// TracesExporter Exporter of traces, none, stdout, file or otlp
+	TracesExporter string
+	// TracesFile Name of the file traces are written to by the file exporter
+	TracesFile string
+	// AuthMode Authentication of requests, anonymous, token or serviceaccount
+	AuthMode string
+	// AuthTokenFile Name of the file with the static bearer token
+	AuthTokenFile string
+	// AuthAudiences Audiences of the bearer tokens reviewed by Kubernetes. Empty for the default audience of the API server
+	AuthAudiences []string
+	// AuthPolicyFile Name of the file with the authorization policy. Empty to allow all permissions to all callers
+	AuthPolicyFile string
+	// StateDir Directory where state surviving restarts is stored, e.g. a mounted volume. Empty to keep the state in memory
+	StateDir string
+	// IdempotencyKeyTTL Time the responses of requests with an Idempotency-Key header are kept
+	IdempotencyKeyTTL time.Duration
+	// MaxRequestBodySize Maximum size of request bodies in bytes
+	MaxRequestBodySize int64
+	// PayloadSchemaFile Name of the file with the JSON Schema of job payloads. Empty to use PayloadSchema
+	PayloadSchemaFile string
+	// PayloadSchema JSON Schema of job payloads. Empty to not validate payloads, unless PayloadSchemaFile is set
+	PayloadSchema string
+	// BulkConcurrency Maximum number of jobs or batches stopped or deleted at a time by a bulk operation
+	BulkConcurrency int
+	// MaxActiveJobs Maximum number of active jobs, including the jobs of batches. More jobs and batches are queued. 0 for no limit
+	MaxActiveJobs int
+	// PriorityClasses Priorities of queued jobs and batches by the names of priority classes
+	PriorityClasses map[string]int
+	// PriorityAging Time a queued job or batch waits for its priority to be raised by one. 0 to not raise priorities
+	PriorityAging time.Duration
+	// RateLimitRead Requests per second of each caller to routes with the read permission. 0 for no limit
+	RateLimitRead float64
+	// RateLimitReadBurst Requests of each caller to routes with the read permission allowed at once
+	RateLimitReadBurst int
+	// RateLimitMutate Requests per second of each caller to routes with the mutate permission. 0 for no limit
+	RateLimitMutate float64
+	// RateLimitMutateBurst Requests of each caller to routes with the mutate permission allowed at once
+	RateLimitMutateBurst int
+	// RateLimitBy Callers are rate limited by identity or ip
+	RateLimitBy string
+	// RateLimitAuthFailures Failed authentications per second of each source IP, before its requests are rejected
+	// without being authenticated. 0 for no limit
+	RateLimitAuthFailures float64
+	// RateLimitAuthFailuresBurst Failed authentications of each source IP allowed at once
+	RateLimitAuthFailuresBurst int
+}
+
+// NewEnv Constructor
+func NewEnv() *Env {
+	rateLimitRead := getFloat64EnvVar("RATE_LIMIT_READ", 0)
+	rateLimitMutate := getFloat64EnvVar("RATE_LIMIT_MUTATE", 0)
+	return &Env{
+		Env:                        schedulerModels.NewEnv(),
+		ShutdownTimeout:            getDurationEnvVar("SHUTDOWN_TIMEOUT", defaultShutdownTimeout),
+		LogFormat:                  getLogFormat(),
+		TracesExporter:             getStringEnvVar("OTEL_TRACES_EXPORTER", TracesExporterNone),
+		TracesFile:                 getStringEnvVar("OTEL_TRACES_FILE", defaultTracesFile),
+		AuthMode:                   strings.ToLower(getStringEnvVar("AUTH_MODE", AuthModeAnonymous)),
+		AuthTokenFile:              os.Getenv("AUTH_TOKEN_FILE"),
+		AuthAudiences:              getListEnvVar("AUTH_AUDIENCES"),
+		AuthPolicyFile:             os.Getenv("AUTH_POLICY_FILE"),
+		StateDir:                   os.Getenv("STATE_DIR"),
+		IdempotencyKeyTTL:          getDurationEnvVar("IDEMPOTENCY_KEY_TTL", defaultIdempotencyKeyTTL),
+		MaxRequestBodySize:         getInt64EnvVar("MAX_REQUEST_BODY_SIZE", defaultMaxRequestBodySize, 1),
+		PayloadSchemaFile:          os.Getenv("PAYLOAD_SCHEMA_FILE"),
+		PayloadSchema:              os.Getenv("PAYLOAD_SCHEMA"),
+		BulkConcurrency:            int(getInt64EnvVar("BULK_CONCURRENCY", defaultBulkConcurrency, 1)),
+		MaxActiveJobs:              int(getInt64EnvVar("MAX_ACTIVE_JOBS", 0, 0)),
+		PriorityClasses:            getPriorityClasses(),
+		PriorityAging:              getDurationEnvVar("PRIORITY_AGING", defaultPriorityAging),
+		RateLimitRead:              rateLimitRead,
+		RateLimitReadBurst:         int(getInt64EnvVar("RATE_LIMIT_READ_BURST", getDefaultBurst(rateLimitRead), 1)),
+		RateLimitMutate:            rateLimitMutate,
+		RateLimitMutateBurst:       int(getInt64EnvVar("RATE_LIMIT_MUTATE_BURST", getDefaultBurst(rateLimitMutate), 1)),
+		RateLimitBy:                strings.ToLower(getStringEnvVar("RATE_LIMIT_BY", RateLimitByIdentity)),
+		RateLimitAuthFailures:      getFloat64EnvVar("RATE_LIMIT_AUTH_FAILURES", defaultRateLimitAuthFailures),
+		RateLimitAuthFailuresBurst: int(getInt64EnvVar("RATE_LIMIT_AUTH_FAILURES_BURST", defaultRateLimitAuthFailuresBurst, 1)),
+	}
+}
+
+// getDefaultBurst The burst allowing a second of requests at the rate, at least one request
+func getDefaultBurst(rate float64) int64 {
+	return int64(math.Max(1, math.Ceil(rate)))
+}
+
+func getLogFormat() string {
+	if strings.EqualFold(os.Getenv("LOG_FORMAT"), LogFormatText) {
+		return LogFormatText
+	}
+	return LogFormatJSON
+}
+
+// getPriorityClasses Parses the priority classes, like urgent=100,bulk=-10
+func getPriorityClasses() map[string]int {
+	priorityClasses := make(map[string]int)
+	for _, value := range getListEnvVar("PRIORITY_CLASSES") {
+		name, priority, ok := strings.Cut(value, "=")
+		number, err := strconv.Atoi(strings.TrimSpace(priority))
+		if !ok || err != nil || len(strings.TrimSpace(name)) == 0 {
+			log.Warnf("invalid priority class %s in environment variable PRIORITY_CLASSES, expected name=priority", value)
+			continue
+		}
+		priorityClasses[strings.TrimSpace(name)] = number
+	}
+	return priorityClasses
+}
+
+func getStringEnvVar(name string, defaultValue string) string {
+	if value, ok := os.LookupEnv(name); ok && len(value) > 0 {
+		return value
+	}
+	return defaultValue
+}
+
+func getListEnvVar(name string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(name), ",") {
+		if value = strings.TrimSpace(value); len(value) > 0 {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func getDurationEnvVar(name string, defaultValue time.Duration) time.Duration {
+	value, ok := os.LookupEnv(name)
+	if !ok || len(value) == 0 {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		log.Warnf("invalid value %s for environment variable %s, using default %s", value, name, defaultValue)
+		return defaultValue
+	}
+	return duration
+}
+
+func getFloat64EnvVar(name string, defaultValue float64) float64 {
+	value, ok := os.LookupEnv(name)
+	if !ok || len(value) == 0 {
+		return defaultValue
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || number < 0 {
+		log.Warnf("invalid value %s for environment variable %s, using default %g", value, name, defaultValue)
+		return defaultValue
+	}
+	return number
+}
+
+// getInt64EnvVar Gets the number in the environment variable, or the default value when it is not set or less than min
+func getInt64EnvVar(name string, defaultValue, min int64) int64 {
+	value, ok := os.LookupEnv(name)
+	if !ok || len(value) == 0 {
+		return defaultValue
+	}
+	number, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || number < min {
+		log.Warnf("invalid value %s for environment variable %s, using default %d", value, name, defaultValue)
+		return defaultValue
+	}
+	return number
+}
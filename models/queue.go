@@ -0,0 +1,57 @@
+package models
+
+import (
+	modelsV1 "github.com/equinor/radix-job-scheduler/models/v1"
+)
+
+// QueuePriority Priority of a job or batch in the queue. At most one of priority and priorityClass can be set
+// swagger:model QueuePriority
+type QueuePriority struct {
+	// Priority in the queue, the highest first. Defaults to 0
+	//
+	// required: false
+	// example: 10
+	Priority *int `json:"priority,omitempty"`
+
+	// Name of a priority class configured on the server, setting the priority
+	//
+	// required: false
+	// example: urgent
+	PriorityClass string `json:"priorityClass,omitempty"`
+}
+
+// QueuedJobStatus Status of a job, with the position of a Queued job in the queue
+// swagger:model QueuedJobStatus
+type QueuedJobStatus struct {
+	modelsV1.JobStatus `json:",inline"`
+
+	// Priority of a Scheduled or Queued job in the queue
+	//
+	// required: false
+	// example: 10
+	Priority int `json:"priority,omitempty"`
+
+	// Position of a Queued job in the queue of jobs and batches waiting for active jobs to complete, from 1
+	//
+	// required: false
+	// example: 3
+	QueuePosition int `json:"queuePosition,omitempty"`
+}
+
+// QueuedBatchStatus Status of a batch, with the position of a Queued batch in the queue
+// swagger:model QueuedBatchStatus
+type QueuedBatchStatus struct {
+	modelsV1.BatchStatus `json:",inline"`
+
+	// Priority of a Scheduled or Queued batch in the queue
+	//
+	// required: false
+	// example: 10
+	Priority int `json:"priority,omitempty"`
+
+	// Position of a Queued batch in the queue of jobs and batches waiting for active jobs to complete, from 1
+	//
+	// required: false
+	// example: 3
+	QueuePosition int `json:"queuePosition,omitempty"`
+}
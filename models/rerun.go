@@ -0,0 +1,49 @@
+package models
+
+import (
+	modelsV1 "github.com/equinor/radix-job-scheduler/models/v1"
+)
+
+// RerunJobStatus Status of a job created by re-running a job
+// swagger:model RerunJobStatus
+type RerunJobStatus struct {
+	modelsV1.JobStatus `json:",inline"`
+
+	// Name of the job which was re-run
+	//
+	// required: true
+	// example: batch-compute-20220302155333-hrwl53mw-fjhcqwj7
+	RerunOf string `json:"rerunOf"`
+
+	// Name of the batch of the job which was re-run, when it was in a batch
+	//
+	// required: false
+	RerunOfBatch string `json:"rerunOfBatch,omitempty"`
+}
+
+// RetryFailedBatchStatus Status of a batch created with the failed jobs of a batch
+// swagger:model RetryFailedBatchStatus
+type RetryFailedBatchStatus struct {
+	QueuedBatchStatus `json:",inline"`
+
+	// Name of the batch the failed jobs were in
+	//
+	// required: true
+	// example: batch-compute-20220302155333-hrwl53mw
+	RetryOf string `json:"retryOf"`
+
+	// Names of the failed jobs, mapped to the names of the jobs re-running them.
+	// Jobs not created yet are not included
+	//
+	// required: true
+	// example: {"batch-compute-20220302155333-hrwl53mw-fjhcqwj7": "batch-compute-20220302160021-x8dkw0qc-b2kd9ql1"}
+	JobNames map[string]string `json:"jobNames"`
+}
+
+// BatchRetry The batch with the failed jobs retried by the jobs of a new batch
+type BatchRetry struct {
+	// BatchName Name of the batch the failed jobs were in
+	BatchName string `json:"batchName"`
+	// JobNames Names of the failed jobs, in the order of the descriptions of the new batch
+	JobNames []string `json:"jobNames"`
+}
@@ -2,9 +2,19 @@ package models
 
 import "net/http"
 
+const (
+	// PermissionRead Permission to read jobs and batches
+	PermissionRead Permission = "read"
+	// PermissionMutate Permission to create, change, stop and delete jobs and batches
+	PermissionMutate Permission = "mutate"
+)
+
 // RadixHandlerFunc Pattern for handler functions
 type RadixHandlerFunc func(http.ResponseWriter, *http.Request)
 
+// Permission Permission required to call a route
+type Permission string
+
 // Routes Holder of all routes
 type Routes []Route
 
@@ -12,5 +22,19 @@ type Routes []Route
 type Route struct {
 	Path        string
 	Method      string
+	Permission  Permission
 	HandlerFunc RadixHandlerFunc
 }
+
+// Authorizer Authorizes the caller of a request
+type Authorizer interface {
+	// Authorize Returns an error when the caller of the request does not have the permission
+	Authorize(r *http.Request, permission Permission) error
+}
+
+// RateLimiter Limits the rate of requests of each caller to the routes with a permission
+type RateLimiter interface {
+	// Limit Sets the rate limit headers of the response, and returns an error when the caller has exceeded the rate limit
+	// of the routes with the permission
+	Limit(w http.ResponseWriter, r *http.Request, permission Permission) error
+}
@@ -0,0 +1,128 @@
+package models
+
+import (
+	schedulerModels "github.com/equinor/radix-job-scheduler/models/common"
+)
+
+const (
+	// ConcurrencyPolicyAllow A run starts when the previous run is still active
+	ConcurrencyPolicyAllow = "Allow"
+	// ConcurrencyPolicyForbid A run is skipped when the previous run is still active
+	ConcurrencyPolicyForbid = "Forbid"
+	// ConcurrencyPolicyReplace The previous run is stopped when it is still active, and a run starts
+	ConcurrencyPolicyReplace = "Replace"
+
+	// ScheduleTriggerCron A run started at a time of the cron expression
+	ScheduleTriggerCron = "Cron"
+	// ScheduleTriggerManual A run started by a trigger request
+	ScheduleTriggerManual = "Manual"
+
+	// ScheduleRunCreated The job or batch of the run was created
+	ScheduleRunCreated = "Created"
+	// ScheduleRunSkipped The run was skipped by the concurrency policy Forbid
+	ScheduleRunSkipped = "Skipped"
+	// ScheduleRunFailed The job or batch of the run failed to be created
+	ScheduleRunFailed = "Failed"
+)
+
+// Schedule Recurring run of a job or a batch
+// swagger:model Schedule
+type Schedule struct {
+	// Name of the schedule, lowercase letters, digits and dashes, at most 63 characters
+	//
+	// required: true
+	// example: nightly-import
+	Name string `json:"name"`
+
+	// Cron expression with minute, hour, day of month, month and day of week, or a descriptor like @hourly
+	//
+	// required: true
+	// example: 30 2 * * 1-5
+	Cron string `json:"cron"`
+
+	// IANA time zone of the cron expression. Defaults to UTC
+	//
+	// required: false
+	// example: Europe/Oslo
+	TimeZone string `json:"timeZone,omitempty"`
+
+	// Policy when the previous run is still active: Allow, Forbid or Replace. Defaults to Allow
+	//
+	// required: false
+	// example: Forbid
+	ConcurrencyPolicy string `json:"concurrencyPolicy,omitempty"`
+
+	// Suspended schedules do not run at the times of the cron expression, but can be triggered
+	//
+	// required: false
+	Suspend bool `json:"suspend,omitempty"`
+
+	// Job created by each run. Either job or batch must be set
+	//
+	// required: false
+	Job *schedulerModels.JobScheduleDescription `json:"job,omitempty"`
+
+	// Batch created by each run. Either job or batch must be set
+	//
+	// required: false
+	Batch *schedulerModels.BatchScheduleDescription `json:"batch,omitempty"`
+}
+
+// ScheduleRun A run of a schedule
+// swagger:model ScheduleRun
+type ScheduleRun struct {
+	// Time of the run
+	//
+	// required: true
+	// example: 2022-11-01T02:30:00Z
+	Time string `json:"time"`
+
+	// What started the run, Cron or Manual
+	//
+	// required: true
+	// example: Cron
+	Trigger string `json:"trigger"`
+
+	// Result of the run, Created, Skipped or Failed
+	//
+	// required: true
+	// example: Created
+	Result string `json:"result"`
+
+	// Name of the job or batch created by the run
+	//
+	// required: false
+	// example: compute-20221101023000-x8dkw0qc
+	Name string `json:"name,omitempty"`
+
+	// Why the run was skipped or failed, or the name of the run it replaced
+	//
+	// required: false
+	Message string `json:"message,omitempty"`
+}
+
+// ScheduleStatus A schedule with its next run and the history of its runs
+// swagger:model ScheduleStatus
+type ScheduleStatus struct {
+	Schedule `json:",inline"`
+
+	// Time the schedule was created
+	//
+	// required: true
+	Created string `json:"created"`
+
+	// Time the schedule was last changed
+	//
+	// required: true
+	Updated string `json:"updated"`
+
+	// Time of the next run. Not set when the schedule is suspended
+	//
+	// required: false
+	NextRun string `json:"nextRun,omitempty"`
+
+	// The latest runs of the schedule, the newest first
+	//
+	// required: true
+	Runs []ScheduleRun `json:"runs"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	schedulerModels "github.com/equinor/radix-job-scheduler/models/common"
+)
+
+const (
+	// StatusReasonBadRequest The request is invalid, e.g. has an invalid query parameter
+	StatusReasonBadRequest schedulerModels.StatusReason = "BadRequest"
+	// StatusReasonUnauthorized The request is not authenticated
+	StatusReasonUnauthorized schedulerModels.StatusReason = "Unauthorized"
+	// StatusReasonForbidden The caller does not have the permission required by the route
+	StatusReasonForbidden schedulerModels.StatusReason = "Forbidden"
+	// StatusReasonConflict The request conflicts with an earlier request, e.g. reuses its idempotency key with a different body
+	StatusReasonConflict schedulerModels.StatusReason = "Conflict"
+	// StatusReasonRequestEntityTooLarge The request body is larger than the maximum size
+	StatusReasonRequestEntityTooLarge schedulerModels.StatusReason = "RequestEntityTooLarge"
+	// StatusReasonTooManyRequests The caller has exceeded the rate limit of the route
+	StatusReasonTooManyRequests schedulerModels.StatusReason = "TooManyRequests"
+	// StatusReasonServiceUnavailable The request could not be handled right now, e.g. its token could not be reviewed
+	StatusReasonServiceUnavailable schedulerModels.StatusReason = "ServiceUnavailable"
+)
+
+// Status Status of a request, extended with information to correlate the response with the server logs
+type Status struct {
+	schedulerModels.Status
+	// RequestID ID of the request, as in the response header X-Request-ID
+	RequestID string `json:"requestId,omitempty"`
+	// Timestamp Time of the response
+	Timestamp string `json:"timestamp,omitempty"`
+	// Causes Invalid fields of the request, for status reason Invalid
+	Causes []StatusCause `json:"causes,omitempty"`
+}
+
+// StatusCause An invalid field of a request
+type StatusCause struct {
+	// Field Path of the field in the request body, e.g. jobScheduleDescriptions[0].timeLimitSeconds. Empty when the error is not caused by a field
+	Field string `json:"field,omitempty"`
+	// Message Reason the field is invalid
+	Message string `json:"message"`
+}
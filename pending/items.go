@@ -0,0 +1,786 @@
+package pending
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	commonUtils "github.com/equinor/radix-common/utils"
+	serverErrors "github.com/equinor/radix-job-scheduler-server/api/errors"
+	"github.com/equinor/radix-job-scheduler-server/models"
+	"github.com/equinor/radix-job-scheduler-server/store"
+	modelsV1 "github.com/equinor/radix-job-scheduler/models/v1"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// KindJob A pending job
+	KindJob = "job"
+	// KindBatch A pending batch
+	KindBatch = "batch"
+
+	// StatusScheduled Status of a job or batch held until its notBefore time
+	StatusScheduled = "Scheduled"
+	// StatusQueued Status of a job or batch waiting for active jobs to complete
+	StatusQueued = "Queued"
+
+	itemKeyPrefix     = "item/"
+	releasedKeyPrefix = "released/"
+	releaseInterval   = time.Second
+	retryInterval     = 30 * time.Second
+	purgeInterval     = time.Hour
+	releasedTTL       = 24 * time.Hour
+	countInterval     = 10 * time.Second
+	nameCharacters    = "abcdefghijklmnopqrstuvwxyz0123456789"
+)
+
+// Item A job or batch held by the server until it is created
+type Item struct {
+	Kind    string    `json:"kind"`
+	Name    string    `json:"name"`
+	Created time.Time `json:"created"`
+	// NotBefore Time the job or batch is created at the earliest
+	NotBefore time.Time                    `json:"notBefore"`
+	Job       *models.JobScheduleRequest   `json:"job,omitempty"`
+	Batch     *models.BatchScheduleRequest `json:"batch,omitempty"`
+	// RerunOf Name of the job the job is a re-run of, if any
+	RerunOf string `json:"rerunOf,omitempty"`
+	// RetryOf The batch with the failed jobs the batch retries, if any
+	RetryOf *models.BatchRetry `json:"retryOf,omitempty"`
+	// Priority Priority of the item in the queue, the highest first
+	Priority int `json:"priority,omitempty"`
+	// Attempts Number of failed attempts to create the job or batch
+	Attempts    int       `json:"attempts,omitempty"`
+	LastError   string    `json:"lastError,omitempty"`
+	NextAttempt time.Time `json:"nextAttempt,omitempty"`
+	// Releasing Time the creation of the job or batch started, when it is being created
+	Releasing time.Time `json:"releasing,omitempty"`
+	// Status Scheduled or Queued, set when the item is read
+	Status string `json:"-"`
+	// QueuePosition Position of a Queued item in the queue, from 1, set when the item is read
+	QueuePosition int `json:"-"`
+}
+
+// released The name of the job or batch created for an item, to find it by the name of the item
+type released struct {
+	Name     string    `json:"name"`
+	Released time.Time `json:"released"`
+}
+
+// Creator Creates the job or batch of an item when it is released, and returns the name of the created job or batch
+type Creator func(ctx context.Context, item *Item) (string, error)
+
+// ActiveCounter Counts the active jobs, including the active jobs of batches
+type ActiveCounter func(ctx context.Context) (int, error)
+
+// Items The jobs and batches held by the server until their notBefore time, and queued until there is room for them
+// among the active jobs
+type Items struct {
+	store         store.Store
+	componentName string
+	creators      map[string]Creator
+	now           func() time.Time
+	// maxActiveJobs Maximum number of active jobs, including the jobs of batches. No limit when 0
+	maxActiveJobs   int
+	priorityClasses map[string]int
+	// priorityAging Time a queued item waits for its priority to be raised by one. Priorities are not raised when 0
+	priorityAging time.Duration
+	activeCounter ActiveCounter
+	// activeJobs Number of active jobs when last counted, plus the jobs created since
+	activeJobs int
+	counted    time.Time
+	// queueLength Number of queued items when last read, plus the items queued since
+	queueLength int
+	// index The items by key, loaded from the store once and then kept along with the store
+	index map[string]*Item
+	// queue The items of the index in the order of the queue, which does not change while they wait
+	queue []*Item
+	// mu Guards changes of the items, so an item is never both cancelled and created. It is not held while the job
+	// or batch of an item is created, the item is marked as releasing instead
+	mu sync.Mutex
+}
+
+// New Constructor. The names of the items are prefixed with the name of the job component, as the names of created jobs.
+// Jobs and batches are queued when they would exceed maxActiveJobs, unless it is 0, and released by their priority,
+// which is raised by one for each priorityAging they are queued
+func New(store store.Store, componentName string, maxActiveJobs int, priorityClasses map[string]int, priorityAging time.Duration) *Items {
+	if len(componentName) == 0 {
+		componentName = KindJob
+	}
+	return &Items{
+		store:           store,
+		componentName:   componentName,
+		creators:        make(map[string]Creator),
+		now:             time.Now,
+		maxActiveJobs:   maxActiveJobs,
+		priorityClasses: priorityClasses,
+		priorityAging:   priorityAging,
+		// Until the active jobs are counted, there is no room for more
+		activeJobs: maxActiveJobs,
+	}
+}
+
+// SetCreator Sets the creator of the released jobs or batches of the kind
+func (items *Items) SetCreator(kind string, creator Creator) {
+	items.mu.Lock()
+	defer items.mu.Unlock()
+	items.creators[kind] = creator
+}
+
+// SetActiveCounter Sets the counter of the active jobs, needed when there is a maximum number of active jobs
+func (items *Items) SetActiveCounter(activeCounter ActiveCounter) {
+	items.mu.Lock()
+	defer items.mu.Unlock()
+	items.activeCounter = activeCounter
+}
+
+// Admit Returns true when a job or batch with the number of jobs can be created right away, without exceeding the
+// maximum number of active jobs or passing queued jobs and batches. The jobs of an admitted job or batch count as active
+func (items *Items) Admit(jobCount int) bool {
+	if items.maxActiveJobs <= 0 {
+		return true
+	}
+	items.mu.Lock()
+	defer items.mu.Unlock()
+	if items.queueLength > 0 || !items.hasRoomFor(jobCount) {
+		return false
+	}
+	items.activeJobs += jobCount
+	return true
+}
+
+// CancelAdmit Frees the room of the jobs of an admitted job or batch with the number of jobs, when it failed to be created
+func (items *Items) CancelAdmit(jobCount int) {
+	if items.maxActiveJobs <= 0 {
+		return
+	}
+	items.mu.Lock()
+	defer items.mu.Unlock()
+	items.activeJobs -= jobCount
+	if items.activeJobs < 0 {
+		items.activeJobs = 0
+	}
+}
+
+// ParseNotBefore Parses the notBefore time of a request. Returns false when it is not set, or it has passed
+// and the job or batch can be created right away
+func (items *Items) ParseNotBefore(notBefore string) (time.Time, bool) {
+	if len(notBefore) == 0 {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339, notBefore)
+	return t, err == nil && t.After(items.now())
+}
+
+// ValidateNotBefore Gets the cause when the notBefore time is set and is not a RFC3339 timestamp
+func ValidateNotBefore(notBefore string) []models.StatusCause {
+	if len(notBefore) == 0 {
+		return nil
+	}
+	if _, err := time.Parse(time.RFC3339, notBefore); err != nil {
+		return []models.StatusCause{{Field: "notBefore", Message: "must be a RFC3339 timestamp, e.g. 2022-11-01T15:04:05Z"}}
+	}
+	return nil
+}
+
+// ValidatePriority Gets the causes when both the priority and the priority class are set, or the priority class is not configured
+func (items *Items) ValidatePriority(priority *models.QueuePriority) []models.StatusCause {
+	if len(priority.PriorityClass) == 0 {
+		return nil
+	}
+	if priority.Priority != nil {
+		return []models.StatusCause{{Field: "priorityClass", Message: "cannot be set together with priority"}}
+	}
+	if _, ok := items.priorityClasses[priority.PriorityClass]; !ok {
+		var names []string
+		for name := range items.priorityClasses {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return []models.StatusCause{{Field: "priorityClass", Message: fmt.Sprintf("must be one of the priority classes [%s]", strings.Join(names, ", "))}}
+	}
+	return nil
+}
+
+// GetPriority Gets the priority set by the priority or the priority class, 0 when none of them are set
+func (items *Items) GetPriority(priority *models.QueuePriority) int {
+	if priority.Priority != nil {
+		return *priority.Priority
+	}
+	return items.priorityClasses[priority.PriorityClass]
+}
+
+// AddJob Holds the job until the notBefore time, and queues it when the time has passed
+func (items *Items) AddJob(request *models.JobScheduleRequest, rerunOf string, notBefore time.Time) (*Item, error) {
+	return items.add(&Item{Kind: KindJob, NotBefore: notBefore, Job: request, RerunOf: rerunOf, Priority: items.GetPriority(&request.QueuePriority)})
+}
+
+// AddBatch Holds the batch until the notBefore time, and queues it when the time has passed
+func (items *Items) AddBatch(request *models.BatchScheduleRequest, retryOf *models.BatchRetry, notBefore time.Time) (*Item, error) {
+	return items.add(&Item{Kind: KindBatch, NotBefore: notBefore, Batch: request, RetryOf: retryOf, Priority: items.GetPriority(&request.QueuePriority)})
+}
+
+func (items *Items) add(item *Item) (*Item, error) {
+	name, err := items.newName(item.Kind)
+	if err != nil {
+		return nil, err
+	}
+	item.Name = name
+	item.Created = items.now()
+	item.Status = items.getStatus(item)
+	if err := items.put(item); err != nil {
+		return nil, err
+	}
+	if item.Status != StatusQueued {
+		return item, nil
+	}
+	queued, err := items.Get(item.Kind, item.Name)
+	if err != nil || queued == nil {
+		return item, err
+	}
+	return queued, nil
+}
+
+func (items *Items) put(item *Item) error {
+	items.mu.Lock()
+	defer items.mu.Unlock()
+	if err := items.loadIndex(); err != nil {
+		return err
+	}
+	if err := items.store.Put(getItemKey(item.Kind, item.Name), item); err != nil {
+		return err
+	}
+	items.indexItem(item)
+	if item.Status == StatusQueued {
+		items.queueLength++
+	}
+	return nil
+}
+
+// Get Gets the pending job or batch. Returns nil when there is no pending job or batch with the name
+func (items *Items) Get(kind, name string) (*Item, error) {
+	items.mu.Lock()
+	defer items.mu.Unlock()
+	if err := items.loadIndex(); err != nil {
+		return nil, err
+	}
+	stored, ok := items.index[getItemKey(kind, name)]
+	if !ok {
+		return nil, nil
+	}
+	item := *stored
+	item.Status = items.getStatus(&item)
+	if item.Status != StatusQueued {
+		return &item, nil
+	}
+	// The queued items before the item are the first in the queue
+	for _, other := range items.queue[:items.getQueueIndex(stored)] {
+		if items.getStatus(other) == StatusQueued {
+			item.QueuePosition++
+		}
+	}
+	item.QueuePosition++
+	return &item, nil
+}
+
+// SetPriority Changes the priority of the pending job or batch. Returns nil when there is no pending job or batch with the name
+func (items *Items) SetPriority(kind, name string, priority int) (*Item, error) {
+	if found, err := items.setPriority(kind, name, priority); err != nil || !found {
+		return nil, err
+	}
+	return items.Get(kind, name)
+}
+
+func (items *Items) setPriority(kind, name string, priority int) (bool, error) {
+	items.mu.Lock()
+	defer items.mu.Unlock()
+	if err := items.loadIndex(); err != nil {
+		return false, err
+	}
+	key := getItemKey(kind, name)
+	stored, ok := items.index[key]
+	if !ok {
+		return false, nil
+	}
+	if !stored.Releasing.IsZero() {
+		return false, newReleasingError(stored)
+	}
+	item := *stored
+	item.Priority = priority
+	if err := items.store.Put(key, &item); err != nil {
+		return false, err
+	}
+	items.indexItem(&item)
+	return true, nil
+}
+
+// List Gets the pending jobs or batches, the queued first in the order of the queue, then the scheduled
+func (items *Items) List(kind string) ([]Item, error) {
+	items.mu.Lock()
+	defer items.mu.Unlock()
+	if err := items.loadIndex(); err != nil {
+		return nil, err
+	}
+	var queued, scheduled []Item
+	for _, stored := range items.queue {
+		item := *stored
+		item.Status = items.getStatus(&item)
+		if item.Status == StatusQueued {
+			item.QueuePosition = len(queued) + 1
+			queued = append(queued, item)
+		} else {
+			scheduled = append(scheduled, item)
+		}
+	}
+	list := make([]Item, 0, len(queued)+len(scheduled))
+	for _, item := range append(queued, scheduled...) {
+		if len(kind) == 0 || item.Kind == kind {
+			list = append(list, item)
+		}
+	}
+	return list, nil
+}
+
+// Remove Removes the pending job or batch, which will not be created. Returns false when there is no pending job or batch with the name
+func (items *Items) Remove(kind, name string) (bool, error) {
+	items.mu.Lock()
+	defer items.mu.Unlock()
+	if err := items.loadIndex(); err != nil {
+		return false, err
+	}
+	key := getItemKey(kind, name)
+	stored, ok := items.index[key]
+	if !ok {
+		return false, nil
+	}
+	if !stored.Releasing.IsZero() {
+		return false, newReleasingError(stored)
+	}
+	if err := items.store.Delete(key); err != nil {
+		return false, err
+	}
+	items.unindexItem(key)
+	return true, nil
+}
+
+// GetReleasedName Gets the name of the job or batch created for a released item with the name.
+// Returns the name unchanged when it is not the name of a released item
+func (items *Items) GetReleasedName(kind, name string) string {
+	var record released
+	found, err := items.store.Get(getReleasedKey(kind, name), &record)
+	if err != nil {
+		log.Errorf("failed to read the released %s %s: %v", kind, name, err)
+	}
+	if err != nil || !found {
+		return name
+	}
+	return record.Name
+}
+
+// KeepReleasedName Keeps the name of the job or batch created for an item being released, as soon as it is created,
+// so the item is not created again when the server stops before the release is finished
+func (items *Items) KeepReleasedName(item *Item, createdName string) error {
+	return items.store.Put(getReleasedKey(item.Kind, item.Name), &released{Name: createdName, Released: items.now()})
+}
+
+// Run Queues the jobs and batches when their notBefore time arrives, and creates the queued jobs and batches when there
+// is room for them among the active jobs, until the context is done
+func (items *Items) Run(ctx context.Context) {
+	ticker := time.NewTicker(releaseInterval)
+	defer ticker.Stop()
+	purgeTicker := time.NewTicker(purgeInterval)
+	defer purgeTicker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			items.releaseDue(ctx)
+		case <-purgeTicker.C:
+			items.purgeReleased()
+		}
+	}
+}
+
+// releaseDue Creates the queued jobs and batches in the order of the queue, as long as there is room for them among the
+// active jobs. A job or batch which failed to be created does not hold back the others until it is retried
+func (items *Items) releaseDue(ctx context.Context) {
+	list, err := items.List("")
+	if err != nil {
+		log.Errorf("failed to read pending jobs: %v", err)
+		return
+	}
+	var queue []Item
+	for _, item := range list {
+		if !item.Releasing.IsZero() {
+			items.resolveInterrupted(&item)
+			continue
+		}
+		if item.Status == StatusQueued {
+			queue = append(queue, item)
+		}
+	}
+	items.countActiveJobs(ctx, len(queue) > 0)
+	items.mu.Lock()
+	items.queueLength = len(queue)
+	items.mu.Unlock()
+
+	now := items.now()
+	for i := range queue {
+		if ctx.Err() != nil {
+			return
+		}
+		if queue[i].NextAttempt.After(now) {
+			continue
+		}
+		// The creation is not cancelled by the shutdown, which waits for it, as the job or batch may be created anyway
+		if !items.release(context.Background(), queue[i].Kind, queue[i].Name) {
+			return
+		}
+	}
+}
+
+// resolveInterrupted Resolves an item marked as releasing by an earlier run of the server, which stopped while the job
+// or batch was created. The item is deleted when the name of its created job or batch was kept, otherwise it is queued
+// again, to be created at the next release
+func (items *Items) resolveInterrupted(item *Item) {
+	items.mu.Lock()
+	defer items.mu.Unlock()
+	key := getItemKey(item.Kind, item.Name)
+	var record released
+	found, err := items.store.Get(getReleasedKey(item.Kind, item.Name), &record)
+	if err != nil {
+		log.Errorf("failed to read the released %s %s: %v", item.Kind, item.Name, err)
+		return
+	}
+	if found {
+		log.Infof("the %s %s was created for the pending %s %s before the server stopped", item.Kind, record.Name, item.Kind, item.Name)
+		if err := items.store.Delete(key); err != nil {
+			log.Errorf("failed to delete the released %s %s: %v", item.Kind, item.Name, err)
+			return
+		}
+		items.unindexItem(key)
+		return
+	}
+	log.Warnf("the creation of the pending %s %s started at %s was interrupted before it was created, and it is queued again", item.Kind, item.Name, commonUtils.FormatTimestamp(item.Releasing))
+	queued := *item
+	queued.Releasing = time.Time{}
+	if err := items.store.Put(key, &queued); err != nil {
+		log.Errorf("failed to update the pending %s %s: %v", item.Kind, item.Name, err)
+		return
+	}
+	items.indexItem(&queued)
+}
+
+// countActiveJobs Counts the active jobs when there is a maximum number of them. They are counted each time there are
+// queued jobs or batches, otherwise at an interval, to notice the jobs which completed since the last count
+func (items *Items) countActiveJobs(ctx context.Context, queued bool) {
+	items.mu.Lock()
+	activeCounter := items.activeCounter
+	counted := items.counted
+	items.mu.Unlock()
+	if items.maxActiveJobs <= 0 || activeCounter == nil || (!queued && items.now().Sub(counted) < countInterval) {
+		return
+	}
+	activeJobs, err := activeCounter(ctx)
+	if err != nil {
+		log.Errorf("failed to count active jobs: %v", err)
+		return
+	}
+	items.mu.Lock()
+	defer items.mu.Unlock()
+	items.activeJobs = activeJobs
+	items.counted = items.now()
+}
+
+// release Creates the job or batch of the item, unless it was removed. Returns false when there is no room for the jobs
+// of the item among the active jobs, and the item stays first in the queue. A failed item is retried after a while
+func (items *Items) release(ctx context.Context, kind, name string) bool {
+	item, creator, hasRoom := items.startRelease(kind, name)
+	if item == nil {
+		return hasRoom
+	}
+	createdName, err := creator(ctx, item)
+	items.finishRelease(item, createdName, err)
+	return true
+}
+
+// startRelease Marks the item as releasing, so it cannot be removed or changed while its job or batch is created,
+// and counts its jobs as active instead of queued. Returns nil when the item is not to be created now
+func (items *Items) startRelease(kind, name string) (*Item, Creator, bool) {
+	items.mu.Lock()
+	defer items.mu.Unlock()
+	key := getItemKey(kind, name)
+	stored, ok := items.index[key]
+	if !ok {
+		return nil, nil, true
+	}
+	item := *stored
+	creator, ok := items.creators[item.Kind]
+	if !ok {
+		log.Errorf("no creator of pending %s %s", item.Kind, item.Name)
+		return nil, nil, true
+	}
+	jobCount := item.getJobCount()
+	if items.maxActiveJobs > 0 && !items.hasRoomFor(jobCount) {
+		return nil, nil, false
+	}
+	item.Releasing = items.now()
+	if err := items.store.Put(key, &item); err != nil {
+		log.Errorf("failed to update the pending %s %s: %v", item.Kind, item.Name, err)
+		return nil, nil, true
+	}
+	stored.Releasing = item.Releasing
+	items.activeJobs += jobCount
+	if items.queueLength > 0 {
+		items.queueLength--
+	}
+	return &item, creator, true
+}
+
+// finishRelease Deletes the item when its job or batch was created, and keeps the name of the created job or batch.
+// Otherwise the item is queued again, to be retried
+func (items *Items) finishRelease(item *Item, createdName string, err error) {
+	items.mu.Lock()
+	defer items.mu.Unlock()
+	key := getItemKey(item.Kind, item.Name)
+	if err != nil {
+		log.Warnf("failed to create the pending %s %s: %v", item.Kind, item.Name, err)
+		items.activeJobs -= item.getJobCount()
+		items.queueLength++
+		item.Releasing = time.Time{}
+		item.Attempts++
+		item.LastError = err.Error()
+		item.NextAttempt = items.now().Add(retryInterval)
+		if err := items.store.Put(key, item); err != nil {
+			log.Errorf("failed to update the pending %s %s: %v", item.Kind, item.Name, err)
+		}
+		// The item keeps its place in the queue, which does not depend on the attempts
+		if stored, ok := items.index[key]; ok {
+			*stored = *item
+		}
+		return
+	}
+	log.Infof("created the %s %s for the pending %s %s", item.Kind, createdName, item.Kind, item.Name)
+	if err := items.store.Put(getReleasedKey(item.Kind, item.Name), &released{Name: createdName, Released: items.now()}); err != nil {
+		log.Errorf("failed to keep the name of the %s created for %s: %v", item.Kind, item.Name, err)
+	}
+	if err := items.store.Delete(key); err != nil {
+		log.Errorf("failed to delete the released %s %s: %v", item.Kind, item.Name, err)
+		return
+	}
+	items.unindexItem(key)
+}
+
+// hasRoomFor Returns true when the jobs can be created without exceeding the maximum number of active jobs.
+// A batch with more jobs than the maximum is created when there are no active jobs
+func (items *Items) hasRoomFor(jobCount int) bool {
+	return items.activeJobs == 0 || items.activeJobs+jobCount <= items.maxActiveJobs
+}
+
+// loadIndex Reads the items from the store into the index, when it was not read yet
+func (items *Items) loadIndex() error {
+	if items.index != nil {
+		return nil
+	}
+	keys, err := items.store.Keys()
+	if err != nil {
+		return err
+	}
+	items.index = make(map[string]*Item)
+	items.queue = nil
+	for _, key := range keys {
+		if !strings.HasPrefix(key, itemKeyPrefix) {
+			continue
+		}
+		var item Item
+		found, err := items.store.Get(key, &item)
+		if err != nil {
+			items.index = nil
+			return err
+		}
+		if found {
+			items.indexItem(&item)
+		}
+	}
+	return nil
+}
+
+// indexItem Adds a copy of the item to the index, in its place in the queue, or replaces the indexed item
+func (items *Items) indexItem(item *Item) {
+	key := getItemKey(item.Kind, item.Name)
+	items.unindexItem(key)
+	indexed := *item
+	indexed.Status = ""
+	indexed.QueuePosition = 0
+	i := sort.Search(len(items.queue), func(i int) bool { return items.before(&indexed, items.queue[i]) })
+	items.queue = append(items.queue, nil)
+	copy(items.queue[i+1:], items.queue[i:])
+	items.queue[i] = &indexed
+	items.index[key] = &indexed
+}
+
+// unindexItem Removes the item with the key from the index
+func (items *Items) unindexItem(key string) {
+	indexed, ok := items.index[key]
+	if !ok {
+		return
+	}
+	delete(items.index, key)
+	i := items.getQueueIndex(indexed)
+	items.queue = append(items.queue[:i], items.queue[i+1:]...)
+}
+
+// getQueueIndex The index in the queue of an indexed item
+func (items *Items) getQueueIndex(indexed *Item) int {
+	i := sort.Search(len(items.queue), func(i int) bool { return !items.before(items.queue[i], indexed) })
+	for ; i < len(items.queue); i++ {
+		if items.queue[i] == indexed {
+			return i
+		}
+	}
+	return len(items.queue) - 1
+}
+
+// before Returns true when the item is before the other item in the queue: the item with the highest priority first,
+// then the item queued first. The priority of an item is raised by one for each priorityAging it has been queued,
+// so items with a low priority are not held back forever by items with a higher priority. As the priorities of all
+// the queued items are raised alike, the order does not change while they wait
+func (items *Items) before(item, other *Item) bool {
+	if items.priorityAging > 0 {
+		rank := float64(item.queuedAt().UnixNano())/float64(items.priorityAging) - float64(item.Priority)
+		otherRank := float64(other.queuedAt().UnixNano())/float64(items.priorityAging) - float64(other.Priority)
+		if rank != otherRank {
+			return rank < otherRank
+		}
+	} else if item.Priority != other.Priority {
+		return item.Priority > other.Priority
+	}
+	if !item.queuedAt().Equal(other.queuedAt()) {
+		return item.queuedAt().Before(other.queuedAt())
+	}
+	return item.Created.Before(other.Created)
+}
+
+// getStatus Scheduled until the notBefore time of the item, then Queued
+func (items *Items) getStatus(item *Item) string {
+	if item.NotBefore.After(items.now()) {
+		return StatusScheduled
+	}
+	return StatusQueued
+}
+
+// purgeReleased Deletes the names of the jobs and batches created for released items after a day
+func (items *Items) purgeReleased() {
+	keys, err := items.store.Keys()
+	if err != nil {
+		log.Errorf("failed to read released jobs: %v", err)
+		return
+	}
+	for _, key := range keys {
+		if !strings.HasPrefix(key, releasedKeyPrefix) {
+			continue
+		}
+		var record released
+		if found, err := items.store.Get(key, &record); err != nil || !found || items.now().Sub(record.Released) < releasedTTL {
+			continue
+		}
+		if err := items.store.Delete(key); err != nil {
+			log.Errorf("failed to delete the released %s: %v", key, err)
+		}
+	}
+}
+
+// JobStatus The status of the pending job
+func (item *Item) JobStatus() models.QueuedJobStatus {
+	status := models.QueuedJobStatus{
+		JobStatus: modelsV1.JobStatus{
+			Name:    item.Name,
+			Created: commonUtils.FormatTimestamp(item.Created),
+			Status:  item.Status,
+			Message: item.getMessage(),
+		},
+		Priority:      item.Priority,
+		QueuePosition: item.QueuePosition,
+	}
+	if item.Job != nil {
+		status.JobId = item.Job.JobId
+	}
+	return status
+}
+
+// BatchStatus The status of the pending batch
+func (item *Item) BatchStatus() models.QueuedBatchStatus {
+	return models.QueuedBatchStatus{
+		BatchStatus: modelsV1.BatchStatus{JobStatus: modelsV1.JobStatus{
+			Name:    item.Name,
+			Created: commonUtils.FormatTimestamp(item.Created),
+			Status:  item.Status,
+			Message: item.getMessage(),
+		}},
+		Priority:      item.Priority,
+		QueuePosition: item.QueuePosition,
+	}
+}
+
+// queuedAt The time the item joined the queue, its notBefore time or the time it was added
+func (item *Item) queuedAt() time.Time {
+	if item.NotBefore.After(item.Created) {
+		return item.NotBefore
+	}
+	return item.Created
+}
+
+// getJobCount The number of jobs of the item, 1 for a job
+func (item *Item) getJobCount() int {
+	if item.Batch != nil && len(item.Batch.JobScheduleDescriptions) > 1 {
+		return len(item.Batch.JobScheduleDescriptions)
+	}
+	return 1
+}
+
+func (item *Item) getMessage() string {
+	message := fmt.Sprintf("Starts at %s", commonUtils.FormatTimestamp(item.NotBefore))
+	if item.Status == StatusQueued {
+		message = fmt.Sprintf("Waiting at position %d in the queue for active jobs to complete", item.QueuePosition)
+	}
+	if item.Attempts > 0 {
+		message = fmt.Sprintf("%s. Attempt %d to create the %s failed: %s", message, item.Attempts, item.Kind, item.LastError)
+	}
+	return message
+}
+
+// newName A name like the names of created jobs and batches: the job component name, the time and a random suffix
+func (items *Items) newName(kind string) (string, error) {
+	suffix := make([]byte, 8)
+	for i := range suffix {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(nameCharacters))))
+		if err != nil {
+			return "", err
+		}
+		suffix[i] = nameCharacters[n.Int64()]
+	}
+	name := fmt.Sprintf("%s-%s-%s", items.componentName, items.now().UTC().Format("20060102150405"), suffix)
+	if kind == KindBatch {
+		name = "batch-" + name
+	}
+	return name, nil
+}
+
+// newReleasingError The error of a change of an item while its job or batch is created
+func newReleasingError(item *Item) error {
+	return serverErrors.NewConflict(fmt.Sprintf("The %s %s is being created", item.Kind, item.Name))
+}
+
+func getItemKey(kind, name string) string {
+	return itemKeyPrefix + kind + "/" + name
+}
+
+func getReleasedKey(kind, name string) string {
+	return releasedKeyPrefix + kind + "/" + name
+}
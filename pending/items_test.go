@@ -0,0 +1,310 @@
+package pending
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	serverErrors "github.com/equinor/radix-job-scheduler-server/api/errors"
+	"github.com/equinor/radix-job-scheduler-server/models"
+	"github.com/equinor/radix-job-scheduler-server/store"
+	schedulerModels "github.com/equinor/radix-job-scheduler/models/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestItems(now *time.Time) *Items {
+	return newTestItemsWithMax(now, 0)
+}
+
+func newTestItemsWithMax(now *time.Time, maxActiveJobs int) *Items {
+	items := New(store.NewMemoryStore(), "compute", maxActiveJobs, map[string]int{"urgent": 100, "bulk": -10}, time.Hour)
+	items.now = func() time.Time { return *now }
+	return items
+}
+
+func TestItems_ReleaseDue(t *testing.T) {
+	now := time.Date(2022, 11, 1, 12, 0, 0, 0, time.UTC)
+	items := newTestItems(&now)
+	var created []string
+	items.SetCreator(KindJob, func(ctx context.Context, item *Item) (string, error) {
+		created = append(created, item.Job.Payload)
+		return "compute-" + item.Job.Payload, nil
+	})
+	later, err := items.AddJob(&models.JobScheduleRequest{JobScheduleDescription: schedulerModels.JobScheduleDescription{Payload: "later"}}, "", now.Add(time.Hour))
+	assert.NoError(t, err)
+	soon, err := items.AddJob(&models.JobScheduleRequest{JobScheduleDescription: schedulerModels.JobScheduleDescription{Payload: "soon"}}, "", now.Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Regexp(t, `^compute-20221101120000-[a-z0-9]{8}$`, soon.Name)
+
+	items.releaseDue(context.Background())
+	assert.Empty(t, created)
+
+	now = now.Add(2 * time.Hour)
+	items.releaseDue(context.Background())
+	assert.Equal(t, []string{"soon", "later"}, created)
+	list, err := items.List(KindJob)
+	assert.NoError(t, err)
+	assert.Empty(t, list)
+	assert.Equal(t, "compute-later", items.GetReleasedName(KindJob, later.Name))
+	assert.Equal(t, "other", items.GetReleasedName(KindJob, "other"))
+
+	now = now.Add(releasedTTL)
+	items.purgeReleased()
+	assert.Equal(t, later.Name, items.GetReleasedName(KindJob, later.Name))
+}
+
+func TestItems_RetryFailed(t *testing.T) {
+	now := time.Date(2022, 11, 1, 12, 0, 0, 0, time.UTC)
+	items := newTestItems(&now)
+	attempts := 0
+	items.SetCreator(KindBatch, func(ctx context.Context, item *Item) (string, error) {
+		attempts++
+		if attempts == 1 {
+			return "", errors.New("unavailable")
+		}
+		return "batch-compute-1", nil
+	})
+	item, err := items.AddBatch(&models.BatchScheduleRequest{}, nil, now)
+	assert.NoError(t, err)
+
+	items.releaseDue(context.Background())
+	failed, err := items.Get(KindBatch, item.Name)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, failed.Attempts)
+	assert.Equal(t, "Waiting at position 1 in the queue for active jobs to complete. Attempt 1 to create the batch failed: unavailable", failed.BatchStatus().Message)
+
+	items.releaseDue(context.Background())
+	assert.Equal(t, 1, attempts)
+
+	now = now.Add(retryInterval)
+	items.releaseDue(context.Background())
+	assert.Equal(t, 2, attempts)
+	released, err := items.Get(KindBatch, item.Name)
+	assert.NoError(t, err)
+	assert.Nil(t, released)
+}
+
+func TestItems_Remove(t *testing.T) {
+	now := time.Date(2022, 11, 1, 12, 0, 0, 0, time.UTC)
+	items := newTestItems(&now)
+	items.SetCreator(KindJob, func(ctx context.Context, item *Item) (string, error) {
+		t.Fatal("a removed job must not be created")
+		return "", nil
+	})
+	item, err := items.AddJob(&models.JobScheduleRequest{}, "", now.Add(time.Minute))
+	assert.NoError(t, err)
+
+	removed, err := items.Remove(KindJob, item.Name)
+	assert.NoError(t, err)
+	assert.True(t, removed)
+	removed, err = items.Remove(KindJob, item.Name)
+	assert.NoError(t, err)
+	assert.False(t, removed)
+
+	now = now.Add(time.Hour)
+	items.releaseDue(context.Background())
+}
+
+func TestItems_RemoveReleasing(t *testing.T) {
+	now := time.Date(2022, 11, 1, 12, 0, 0, 0, time.UTC)
+	items := newTestItemsWithMax(&now, 2)
+	items.activeJobs = 0
+	var item *Item
+	items.SetCreator(KindJob, func(ctx context.Context, releasing *Item) (string, error) {
+		// The items are not locked while the job is created
+		removed, err := items.Remove(KindJob, item.Name)
+		assert.False(t, removed)
+		assert.Equal(t, http.StatusConflict, err.(*serverErrors.StatusError).Status().Code)
+		_, err = items.SetPriority(KindJob, item.Name, 10)
+		assert.Equal(t, http.StatusConflict, err.(*serverErrors.StatusError).Status().Code)
+		assert.True(t, items.Admit(1))
+		assert.False(t, items.Admit(1))
+		return "compute-1", nil
+	})
+	item, err := items.AddJob(&models.JobScheduleRequest{}, "", now)
+	assert.NoError(t, err)
+
+	items.releaseDue(context.Background())
+	released, err := items.Get(KindJob, item.Name)
+	assert.NoError(t, err)
+	assert.Nil(t, released)
+	assert.Equal(t, "compute-1", items.GetReleasedName(KindJob, item.Name))
+}
+
+func TestItems_ResolveInterrupted(t *testing.T) {
+	now := time.Date(2022, 11, 1, 12, 0, 0, 0, time.UTC)
+	stopped := newTestItems(&now)
+	// The server stopped while the jobs were created, after the first job was created
+	createdItem, err := stopped.AddJob(&models.JobScheduleRequest{JobScheduleDescription: schedulerModels.JobScheduleDescription{Payload: "created"}}, "", now)
+	assert.NoError(t, err)
+	createdItem.Releasing = now
+	assert.NoError(t, stopped.store.Put(getItemKey(KindJob, createdItem.Name), createdItem))
+	assert.NoError(t, stopped.KeepReleasedName(createdItem, "compute-created"))
+	interruptedItem, err := stopped.AddJob(&models.JobScheduleRequest{JobScheduleDescription: schedulerModels.JobScheduleDescription{Payload: "interrupted"}}, "", now)
+	assert.NoError(t, err)
+	interruptedItem.Releasing = now
+	assert.NoError(t, stopped.store.Put(getItemKey(KindJob, interruptedItem.Name), interruptedItem))
+
+	// The items are read from the store when the server starts again
+	items := New(stopped.store, "compute", 0, nil, time.Hour)
+	items.now = func() time.Time { return now }
+	var created []string
+	items.SetCreator(KindJob, func(ctx context.Context, item *Item) (string, error) {
+		created = append(created, item.Job.Payload)
+		return "compute-" + item.Job.Payload, nil
+	})
+	items.releaseDue(context.Background())
+	list, err := items.List(KindJob)
+	assert.NoError(t, err)
+	if assert.Len(t, list, 1) {
+		assert.Equal(t, interruptedItem.Name, list[0].Name)
+		assert.Equal(t, StatusQueued, list[0].Status)
+		assert.True(t, list[0].Releasing.IsZero())
+	}
+	assert.Equal(t, "compute-created", items.GetReleasedName(KindJob, createdItem.Name))
+
+	items.releaseDue(context.Background())
+	assert.Equal(t, []string{"interrupted"}, created)
+	list, err = items.List(KindJob)
+	assert.NoError(t, err)
+	assert.Empty(t, list)
+}
+
+func TestItems_ParseNotBefore(t *testing.T) {
+	now := time.Date(2022, 11, 1, 12, 0, 0, 0, time.UTC)
+	items := newTestItems(&now)
+	_, ok := items.ParseNotBefore("")
+	assert.False(t, ok)
+	_, ok = items.ParseNotBefore("2022-11-01T11:00:00Z")
+	assert.False(t, ok)
+	notBefore, ok := items.ParseNotBefore("2022-11-01T14:00:00+01:00")
+	assert.True(t, ok)
+	assert.True(t, notBefore.Equal(now.Add(time.Hour)))
+	assert.Len(t, ValidateNotBefore("12:00"), 1)
+}
+
+func TestItems_MaxActiveJobs(t *testing.T) {
+	now := time.Date(2022, 11, 1, 12, 0, 0, 0, time.UTC)
+	items := newTestItemsWithMax(&now, 3)
+	activeJobs := 0
+	items.SetActiveCounter(func(ctx context.Context) (int, error) { return activeJobs, nil })
+	var created []string
+	creator := func(ctx context.Context, item *Item) (string, error) {
+		name := item.Kind + "-" + string(rune('a'+len(created)))
+		created = append(created, name)
+		activeJobs += item.getJobCount()
+		return name, nil
+	}
+	items.SetCreator(KindJob, creator)
+	items.SetCreator(KindBatch, creator)
+
+	// Nothing is admitted until the active jobs are counted
+	assert.False(t, items.Admit(1))
+	items.releaseDue(context.Background())
+	assert.True(t, items.Admit(1))
+	assert.True(t, items.Admit(2))
+	assert.False(t, items.Admit(1))
+	// The room of a job which failed to be created is freed
+	items.CancelAdmit(1)
+	assert.True(t, items.Admit(1))
+	activeJobs = 3
+
+	first, err := items.AddJob(&models.JobScheduleRequest{}, "", time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, StatusQueued, first.Status)
+	assert.Equal(t, 1, first.QueuePosition)
+	now = now.Add(time.Second)
+	batch, err := items.AddBatch(&models.BatchScheduleRequest{BatchScheduleDescription: schedulerModels.BatchScheduleDescription{
+		JobScheduleDescriptions: []schedulerModels.JobScheduleDescription{{}, {}, {}, {}},
+	}}, nil, time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, batch.QueuePosition)
+	now = now.Add(time.Second)
+	last, err := items.AddJob(&models.JobScheduleRequest{}, "", time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, "Waiting at position 3 in the queue for active jobs to complete", last.JobStatus().Message)
+	assert.Equal(t, 3, last.JobStatus().QueuePosition)
+
+	items.releaseDue(context.Background())
+	assert.Empty(t, created)
+
+	// The batch waits for all active jobs to complete, as it has more jobs than the maximum, and the last job waits behind it
+	activeJobs = 2
+	items.releaseDue(context.Background())
+	assert.Equal(t, []string{"job-a"}, created)
+	activeJobs = 0
+	items.releaseDue(context.Background())
+	assert.Equal(t, []string{"job-a", "batch-b"}, created)
+	list, err := items.List("")
+	assert.NoError(t, err)
+	assert.Len(t, list, 1)
+	assert.Equal(t, 1, list[0].QueuePosition)
+	assert.False(t, items.Admit(1))
+
+	activeJobs = 0
+	items.releaseDue(context.Background())
+	assert.Equal(t, []string{"job-a", "batch-b", "job-c"}, created)
+	assert.True(t, items.Admit(1))
+}
+
+func TestItems_Priority(t *testing.T) {
+	now := time.Date(2022, 11, 1, 12, 0, 0, 0, time.UTC)
+	items := newTestItemsWithMax(&now, 1)
+	items.SetActiveCounter(func(ctx context.Context) (int, error) { return 1, nil })
+	add := func(jobId string, priority models.QueuePriority) *Item {
+		item, err := items.AddJob(&models.JobScheduleRequest{JobScheduleDescription: schedulerModels.JobScheduleDescription{JobId: jobId}, QueuePriority: priority}, "", time.Time{})
+		assert.NoError(t, err)
+		now = now.Add(time.Minute)
+		return item
+	}
+	getJobIds := func() []string {
+		list, err := items.List(KindJob)
+		assert.NoError(t, err)
+		var jobIds []string
+		for i, item := range list {
+			assert.Equal(t, i+1, item.QueuePosition)
+			jobIds = append(jobIds, item.Job.JobId)
+		}
+		return jobIds
+	}
+	high := 1
+	bulk := add("bulk", models.QueuePriority{PriorityClass: "bulk"})
+	normal := add("normal", models.QueuePriority{})
+	add("high", models.QueuePriority{Priority: &high})
+	urgent := add("urgent", models.QueuePriority{PriorityClass: "urgent"})
+	assert.Equal(t, 100, urgent.Priority)
+	assert.Equal(t, []string{"urgent", "high", "normal", "bulk"}, getJobIds())
+
+	changed, err := items.SetPriority(KindJob, normal.Name, 200)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, changed.QueuePosition)
+	assert.Equal(t, 200, changed.JobStatus().Priority)
+	missing, err := items.SetPriority(KindJob, "other", 200)
+	assert.NoError(t, err)
+	assert.Nil(t, missing)
+
+	// The priorities of queued jobs are raised by one each hour, so the bulk job passes jobs with a higher priority
+	// queued more than 11 hours after it
+	now = bulk.Created.Add(10 * time.Hour)
+	add("late", models.QueuePriority{Priority: &high})
+	assert.Equal(t, []string{"normal", "urgent", "high", "late", "bulk"}, getJobIds())
+	now = bulk.Created.Add(12 * time.Hour)
+	add("later", models.QueuePriority{Priority: &high})
+	assert.Equal(t, []string{"normal", "urgent", "high", "late", "bulk", "later"}, getJobIds())
+}
+
+func TestItems_ValidatePriority(t *testing.T) {
+	now := time.Date(2022, 11, 1, 12, 0, 0, 0, time.UTC)
+	items := newTestItems(&now)
+	priority := 10
+	assert.Empty(t, items.ValidatePriority(&models.QueuePriority{Priority: &priority}))
+	assert.Empty(t, items.ValidatePriority(&models.QueuePriority{PriorityClass: "urgent"}))
+	assert.Equal(t, []models.StatusCause{{Field: "priorityClass", Message: "cannot be set together with priority"}},
+		items.ValidatePriority(&models.QueuePriority{Priority: &priority, PriorityClass: "urgent"}))
+	assert.Equal(t, []models.StatusCause{{Field: "priorityClass", Message: "must be one of the priority classes [bulk, urgent]"}},
+		items.ValidatePriority(&models.QueuePriority{PriorityClass: "nightly"}))
+	assert.Equal(t, 0, items.GetPriority(&models.QueuePriority{}))
+	assert.Equal(t, -10, items.GetPriority(&models.QueuePriority{PriorityClass: "bulk"}))
+}
@@ -0,0 +1,224 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/equinor/radix-job-scheduler-server/auth"
+	"github.com/equinor/radix-job-scheduler-server/models"
+	"github.com/equinor/radix-job-scheduler-server/utils"
+	schedulerModels "github.com/equinor/radix-job-scheduler/models/common"
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/negroni/v2"
+)
+
+const (
+	// LimitHeader Number of requests the caller can make at once
+	LimitHeader = "X-RateLimit-Limit"
+	// RemainingHeader Number of requests the caller can make right now
+	RemainingHeader = "X-RateLimit-Remaining"
+	// ResetHeader Seconds until the caller can make the number of requests in LimitHeader again
+	ResetHeader = "X-RateLimit-Reset"
+	// RetryAfterHeader Seconds until the caller can make a request again, when it has exceeded the rate limit
+	RetryAfterHeader = "Retry-After"
+
+	purgeInterval = time.Minute
+	// authFailures The bucket of the failed authentications of a source IP, taken from when a request gets 401
+	authFailures models.Permission = "authentication"
+)
+
+// Limit Token bucket of each caller to the routes with a permission
+type Limit struct {
+	// Rate Requests per second, the rate tokens are added to the bucket. No limit when 0
+	Rate float64
+	// Burst Requests allowed at once, the size of the bucket
+	Burst int
+}
+
+// Limiter Limits the rate of requests of each caller with a token bucket per caller and permission
+type Limiter struct {
+	limits map[models.Permission]Limit
+	by     string
+	now    func() time.Time
+	// mu Guards the buckets
+	mu      sync.Mutex
+	buckets map[bucketKey]*bucket
+	purged  time.Time
+}
+
+type bucketKey struct {
+	permission models.Permission
+	caller     string
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// NewLimiter Creates the limiter with the rate limits of the environment
+func NewLimiter(env *models.Env) (*Limiter, error) {
+	if env.RateLimitBy != models.RateLimitByIdentity && env.RateLimitBy != models.RateLimitByIP {
+		return nil, fmt.Errorf("unsupported rate limit by %s", env.RateLimitBy)
+	}
+	limits := map[models.Permission]Limit{
+		models.PermissionRead:   {Rate: env.RateLimitRead, Burst: env.RateLimitReadBurst},
+		models.PermissionMutate: {Rate: env.RateLimitMutate, Burst: env.RateLimitMutateBurst},
+		authFailures:            {Rate: env.RateLimitAuthFailures, Burst: env.RateLimitAuthFailuresBurst},
+	}
+	for permission, limit := range limits {
+		// A bucket which cannot hold a token rejects all requests
+		if limit.Rate > 0 && limit.Burst < 1 {
+			return nil, fmt.Errorf("the burst of the %s rate limit must be at least 1, not %d", permission, limit.Burst)
+		}
+	}
+	return New(limits, env.RateLimitBy), nil
+}
+
+// New Constructor. Callers are limited by their identity or IP, see models.RateLimitByIdentity and models.RateLimitByIP
+func New(limits map[models.Permission]Limit, by string) *Limiter {
+	return &Limiter{
+		limits:  limits,
+		by:      by,
+		now:     time.Now,
+		buckets: make(map[bucketKey]*bucket),
+	}
+}
+
+// Limit Takes a token from the bucket of the caller, and sets the rate limit headers of the response.
+// Returns an error, and sets the Retry-After header, when the bucket is empty
+func (limiter *Limiter) Limit(w http.ResponseWriter, r *http.Request, permission models.Permission) error {
+	limit, ok := limiter.limits[permission]
+	if !ok || limit.Rate <= 0 {
+		return nil
+	}
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+	b := limiter.getBucket(bucketKey{permission: permission, caller: limiter.getCaller(r)}, limit)
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+
+	w.Header().Set(LimitHeader, strconv.Itoa(limit.Burst))
+	w.Header().Set(RemainingHeader, strconv.Itoa(int(b.tokens)))
+	w.Header().Set(ResetHeader, strconv.Itoa(getSeconds(float64(limit.Burst)-b.tokens, limit.Rate)))
+	if allowed {
+		return nil
+	}
+	w.Header().Set(RetryAfterHeader, strconv.Itoa(getSeconds(1-b.tokens, limit.Rate)))
+	return fmt.Errorf("rate limit of %g %s requests per second exceeded", limit.Rate, permission)
+}
+
+// NewAuthenticationMiddleware Creates a middleware rejecting the requests of source IPs with too many failed
+// authentications with 429, before they are authenticated, so invalid tokens are not reviewed over and over.
+// Each response 401 takes a token from the bucket of the source IP
+func (limiter *Limiter) NewAuthenticationMiddleware() negroni.Handler {
+	return negroni.HandlerFunc(func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		limit, ok := limiter.limits[authFailures]
+		if !ok || limit.Rate <= 0 {
+			next(w, r)
+			return
+		}
+		key := bucketKey{permission: authFailures, caller: getSourceIP(r)}
+		if retryAfter, limited := limiter.isAuthLimited(key, limit); limited {
+			log.WithContext(r.Context()).Warnf("rate limit of %g failed authentications per second of %s exceeded", limit.Rate, key.caller)
+			w.Header().Set(RetryAfterHeader, strconv.Itoa(retryAfter))
+			utils.StatusResponse(w, r, &schedulerModels.Status{
+				Status:  schedulerModels.StatusFailure,
+				Code:    http.StatusTooManyRequests,
+				Reason:  models.StatusReasonTooManyRequests,
+				Message: "too many failed authentications",
+			})
+			return
+		}
+		next(w, r)
+		if response, ok := w.(negroni.ResponseWriter); ok && response.Status() == http.StatusUnauthorized {
+			limiter.takeAuthFailure(key, limit)
+		}
+	})
+}
+
+// isAuthLimited Returns true, and the seconds until a request can be made again, when the bucket of failed
+// authentications is empty
+func (limiter *Limiter) isAuthLimited(key bucketKey, limit Limit) (int, bool) {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+	b := limiter.getBucket(key, limit)
+	return getSeconds(1-b.tokens, limit.Rate), b.tokens < 1
+}
+
+func (limiter *Limiter) takeAuthFailure(key bucketKey, limit Limit) {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+	if b := limiter.getBucket(key, limit); b.tokens >= 1 {
+		b.tokens--
+	}
+}
+
+// getBucket Gets the bucket, refilled until now, or a full bucket when the caller has none
+func (limiter *Limiter) getBucket(key bucketKey, limit Limit) *bucket {
+	now := limiter.now()
+	limiter.purge(now)
+	b, ok := limiter.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), updated: now}
+		limiter.buckets[key] = b
+	}
+	b.refill(limit, now)
+	return b
+}
+
+// getCaller The name of the authenticated caller, or the source IP of anonymous requests and when limiting by IP
+func (limiter *Limiter) getCaller(r *http.Request) string {
+	if limiter.by == models.RateLimitByIdentity {
+		if identity := auth.GetIdentity(r.Context()); identity != nil && identity.Name != auth.AnonymousName {
+			return identity.Name
+		}
+	}
+	return getSourceIP(r)
+}
+
+// getSourceIP The IP the request is sent from. The X-Forwarded-For header is not used, as clients can set it
+func getSourceIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// purge Deletes the buckets which are full again, at an interval, so callers which stopped calling are forgotten
+func (limiter *Limiter) purge(now time.Time) {
+	if now.Sub(limiter.purged) < purgeInterval {
+		return
+	}
+	limiter.purged = now
+	for key, b := range limiter.buckets {
+		limit := limiter.limits[key.permission]
+		if b.refill(limit, now); b.tokens >= float64(limit.Burst) {
+			delete(limiter.buckets, key)
+		}
+	}
+}
+
+// refill Adds the tokens for the time since the bucket was last updated, up to the burst
+func (b *bucket) refill(limit Limit, now time.Time) {
+	if elapsed := now.Sub(b.updated).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(float64(limit.Burst), b.tokens+elapsed*limit.Rate)
+	}
+	b.updated = now
+}
+
+// getSeconds The whole seconds until the tokens are added at the rate
+func getSeconds(tokens, rate float64) int {
+	if tokens <= 0 {
+		return 0
+	}
+	return int(math.Ceil(tokens / rate))
+}
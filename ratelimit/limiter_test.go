@@ -0,0 +1,130 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/equinor/radix-job-scheduler-server/auth"
+	"github.com/equinor/radix-job-scheduler-server/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/negroni/v2"
+)
+
+func newTestLimiter(now *time.Time, by string) *Limiter {
+	limiter := New(map[models.Permission]Limit{
+		models.PermissionMutate: {Rate: 0.5, Burst: 2},
+	}, by)
+	limiter.now = func() time.Time { return *now }
+	return limiter
+}
+
+func newRequest(remoteAddr string, identity *auth.Identity) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/jobs", nil)
+	r.RemoteAddr = remoteAddr
+	if identity != nil {
+		r = r.WithContext(auth.WithIdentity(r.Context(), identity))
+	}
+	return r
+}
+
+func TestLimiter_Limit(t *testing.T) {
+	now := time.Date(2022, 11, 1, 12, 0, 0, 0, time.UTC)
+	limiter := newTestLimiter(&now, models.RateLimitByIdentity)
+	r := newRequest("10.0.0.1:41234", &auth.Identity{Name: "system:serviceaccount:app:worker"})
+
+	for _, remaining := range []string{"1", "0"} {
+		w := httptest.NewRecorder()
+		assert.NoError(t, limiter.Limit(w, r, models.PermissionMutate))
+		assert.Equal(t, "2", w.Header().Get(LimitHeader))
+		assert.Equal(t, remaining, w.Header().Get(RemainingHeader))
+	}
+	w := httptest.NewRecorder()
+	assert.EqualError(t, limiter.Limit(w, r, models.PermissionMutate), "rate limit of 0.5 mutate requests per second exceeded")
+	assert.Equal(t, "0", w.Header().Get(RemainingHeader))
+	assert.Equal(t, "4", w.Header().Get(ResetHeader))
+	assert.Equal(t, "2", w.Header().Get(RetryAfterHeader))
+
+	// Reads are not limited, and other callers have their own bucket
+	w = httptest.NewRecorder()
+	assert.NoError(t, limiter.Limit(w, r, models.PermissionRead))
+	assert.Empty(t, w.Header().Get(LimitHeader))
+	assert.NoError(t, limiter.Limit(httptest.NewRecorder(), newRequest("10.0.0.1:41234", &auth.Identity{Name: "system:serviceaccount:app:api"}), models.PermissionMutate))
+
+	now = now.Add(2 * time.Second)
+	w = httptest.NewRecorder()
+	assert.NoError(t, limiter.Limit(w, r, models.PermissionMutate))
+	assert.Equal(t, "0", w.Header().Get(RemainingHeader))
+	assert.Error(t, limiter.Limit(httptest.NewRecorder(), r, models.PermissionMutate))
+}
+
+func TestLimiter_Caller(t *testing.T) {
+	now := time.Date(2022, 11, 1, 12, 0, 0, 0, time.UTC)
+	byIdentity := newTestLimiter(&now, models.RateLimitByIdentity)
+	assert.Equal(t, "worker", byIdentity.getCaller(newRequest("10.0.0.1:41234", &auth.Identity{Name: "worker"})))
+	assert.Equal(t, "10.0.0.1", byIdentity.getCaller(newRequest("10.0.0.1:41234", &auth.Identity{Name: auth.AnonymousName})))
+	assert.Equal(t, "10.0.0.2", byIdentity.getCaller(newRequest("10.0.0.2:41234", nil)))
+	byIP := newTestLimiter(&now, models.RateLimitByIP)
+	assert.Equal(t, "10.0.0.1", byIP.getCaller(newRequest("10.0.0.1:41234", &auth.Identity{Name: "worker"})))
+}
+
+func TestLimiter_Purge(t *testing.T) {
+	now := time.Date(2022, 11, 1, 12, 0, 0, 0, time.UTC)
+	limiter := newTestLimiter(&now, models.RateLimitByIP)
+	assert.NoError(t, limiter.Limit(httptest.NewRecorder(), newRequest("10.0.0.1:41234", nil), models.PermissionMutate))
+	now = now.Add(purgeInterval)
+	assert.NoError(t, limiter.Limit(httptest.NewRecorder(), newRequest("10.0.0.2:41234", nil), models.PermissionMutate))
+	assert.Len(t, limiter.buckets, 1)
+}
+
+func TestLimiter_AuthenticationMiddleware(t *testing.T) {
+	now := time.Date(2022, 11, 1, 12, 0, 0, 0, time.UTC)
+	limiter := New(map[models.Permission]Limit{authFailures: {Rate: 0.5, Burst: 2}}, models.RateLimitByIdentity)
+	limiter.now = func() time.Time { return now }
+	authenticated := 0
+	handler := negroni.New(limiter.NewAuthenticationMiddleware(), negroni.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authenticated++
+		if r.Header.Get("Authorization") != "Bearer valid" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	})))
+	serve := func(token, remoteAddr string) *httptest.ResponseRecorder {
+		r := newRequest(remoteAddr, nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	// Successful authentications are not limited
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, serve("valid", "10.0.0.1:41234").Code)
+	}
+	assert.Equal(t, http.StatusUnauthorized, serve("invalid", "10.0.0.1:41234").Code)
+	assert.Equal(t, http.StatusUnauthorized, serve("invalid", "10.0.0.1:41234").Code)
+	authenticated = 0
+	w := serve("valid", "10.0.0.1:41234")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "2", w.Header().Get(RetryAfterHeader))
+	assert.Equal(t, 0, authenticated)
+	assert.Equal(t, http.StatusOK, serve("valid", "10.0.0.2:41234").Code)
+
+	now = now.Add(2 * time.Second)
+	assert.Equal(t, http.StatusOK, serve("valid", "10.0.0.1:41234").Code)
+}
+
+func TestNewLimiter(t *testing.T) {
+	_, err := NewLimiter(&models.Env{RateLimitBy: "header"})
+	assert.Error(t, err)
+	_, err = NewLimiter(&models.Env{RateLimitBy: models.RateLimitByIP, RateLimitMutate: 1, RateLimitMutateBurst: 0})
+	assert.EqualError(t, err, "the burst of the mutate rate limit must be at least 1, not 0")
+	_, err = NewLimiter(&models.Env{RateLimitBy: models.RateLimitByIP, RateLimitRead: 1, RateLimitReadBurst: -1})
+	assert.Error(t, err)
+	_, err = NewLimiter(&models.Env{RateLimitBy: models.RateLimitByIP, RateLimitAuthFailures: 1})
+	assert.Error(t, err)
+	limiter, err := NewLimiter(&models.Env{RateLimitBy: models.RateLimitByIP, RateLimitRead: 10, RateLimitReadBurst: 20})
+	assert.NoError(t, err)
+	assert.Equal(t, Limit{Rate: 10, Burst: 20}, limiter.limits[models.PermissionRead])
+	assert.Equal(t, Limit{}, limiter.limits[authFailures])
+}
@@ -0,0 +1,353 @@
+package rerun
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	serverErrors "github.com/equinor/radix-job-scheduler-server/api/errors"
+	"github.com/equinor/radix-job-scheduler-server/events"
+	"github.com/equinor/radix-job-scheduler-server/models"
+	"github.com/equinor/radix-job-scheduler-server/store"
+	"github.com/equinor/radix-job-scheduler-server/utils"
+	batchApi "github.com/equinor/radix-job-scheduler/api/v1/batches"
+	jobApi "github.com/equinor/radix-job-scheduler/api/v1/jobs"
+	schedulerModels "github.com/equinor/radix-job-scheduler/models/common"
+	modelsV1 "github.com/equinor/radix-job-scheduler/models/v1"
+	"github.com/equinor/radix-operator/pkg/apis/kube"
+	jsonpatch "github.com/evanphx/json-patch"
+	log "github.com/sirupsen/logrus"
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	jobKeyPrefix   = "job/"
+	batchKeyPrefix = "batch/"
+	purgeInterval  = time.Hour
+)
+
+// jobRecord The description a job was created with
+type jobRecord struct {
+	Description schedulerModels.JobScheduleDescription `json:"description"`
+	// RerunOf Name of the job the job is a re-run of, if any
+	RerunOf string `json:"rerunOf,omitempty"`
+}
+
+// batchRecord The description a batch was created with
+type batchRecord struct {
+	Description schedulerModels.BatchScheduleDescription `json:"description"`
+	// RetryOf The batch with the failed jobs the batch retries, if any
+	RetryOf *models.BatchRetry `json:"retryOf,omitempty"`
+}
+
+// JobRerunner Validates and schedules the job re-running the job rerunOf
+type JobRerunner func(ctx context.Context, jobScheduleRequest *models.JobScheduleRequest, rerunOf string) (*models.QueuedJobStatus, error)
+
+// Descriptions The descriptions of the jobs and batches created by the server, kept to re-run the jobs
+// until the jobs and batches are deleted
+type Descriptions struct {
+	store        store.Store
+	jobHandler   jobApi.JobHandler
+	batchHandler batchApi.BatchHandler
+	jobWatcher   *events.JobWatcher
+	jobRerunner  JobRerunner
+}
+
+// NewDescriptions Constructor
+func NewDescriptions(store store.Store, jobHandler jobApi.JobHandler, batchHandler batchApi.BatchHandler, jobWatcher *events.JobWatcher) *Descriptions {
+	return &Descriptions{
+		store:        store,
+		jobHandler:   jobHandler,
+		batchHandler: batchHandler,
+		jobWatcher:   jobWatcher,
+	}
+}
+
+// SetJobRerunner Sets the rerunner scheduling the re-runs of jobs, in or outside of batches
+func (descriptions *Descriptions) SetJobRerunner(jobRerunner JobRerunner) {
+	descriptions.jobRerunner = jobRerunner
+}
+
+// RerunJob Schedules a job with the description of the job rerunOf, with the JSON merge patch overrides applied
+func (descriptions *Descriptions) RerunJob(ctx context.Context, description *schedulerModels.JobScheduleDescription, overrides []byte, rerunOf string) (*models.QueuedJobStatus, error) {
+	if descriptions.jobRerunner == nil {
+		return nil, fmt.Errorf("no rerunner of jobs is set")
+	}
+	jobScheduleRequest, err := ApplyOverrides(description, overrides)
+	if err != nil {
+		return nil, err
+	}
+	return descriptions.jobRerunner(ctx, jobScheduleRequest, rerunOf)
+}
+
+// PutJob Keeps the description of the created job, and the name of the job it is a re-run of, if any
+func (descriptions *Descriptions) PutJob(jobName string, description *schedulerModels.JobScheduleDescription, rerunOf string) error {
+	return descriptions.store.Put(jobKeyPrefix+jobName, &jobRecord{Description: *description, RerunOf: rerunOf})
+}
+
+// PutBatch Keeps the description of the created batch, and the batch with the failed jobs it retries, if any
+func (descriptions *Descriptions) PutBatch(batchName string, description *schedulerModels.BatchScheduleDescription, retryOf *models.BatchRetry) error {
+	return descriptions.store.Put(batchKeyPrefix+batchName, &batchRecord{Description: *description, RetryOf: retryOf})
+}
+
+// DeleteJob Deletes the description of the job
+func (descriptions *Descriptions) DeleteJob(jobName string) error {
+	return descriptions.store.Delete(jobKeyPrefix + jobName)
+}
+
+// DeleteBatch Deletes the description of the batch
+func (descriptions *Descriptions) DeleteBatch(batchName string) error {
+	return descriptions.store.Delete(batchKeyPrefix + batchName)
+}
+
+// GetJob Gets the description the job was created with
+func (descriptions *Descriptions) GetJob(jobName string) (*schedulerModels.JobScheduleDescription, error) {
+	var record jobRecord
+	found, err := descriptions.store.Get(jobKeyPrefix+jobName, &record)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, serverErrors.NewNotFound(fmt.Sprintf("The description of the job %s is not known. Only jobs created by this server can be re-run", jobName))
+	}
+	return &record.Description, nil
+}
+
+// GetBatchJob Gets the description the job in the batch was created with, with the default config of the batch applied
+func (descriptions *Descriptions) GetBatchJob(ctx context.Context, batch *modelsV1.BatchStatus, jobName string) (*schedulerModels.JobScheduleDescription, error) {
+	batchDescription, err := descriptions.getBatch(batch.Name)
+	if err != nil {
+		return nil, err
+	}
+	index, err := descriptions.getBatchJobIndex(ctx, batch, jobName, batchDescription.JobScheduleDescriptions)
+	if err != nil {
+		return nil, err
+	}
+	description := batchDescription.JobScheduleDescriptions[index]
+	if defaults := batchDescription.DefaultRadixJobComponentConfig; defaults != nil {
+		applyDefaults(&description.RadixJobComponentConfig, defaults)
+	}
+	return &description, nil
+}
+
+// GetFailedBatchJobs Gets the names of the failed jobs in the batch, in the order of their descriptions, and the description
+// of a batch with the descriptions the failed jobs were created with, in the same order, and the default config of the batch
+func (descriptions *Descriptions) GetFailedBatchJobs(ctx context.Context, batch *modelsV1.BatchStatus) ([]string, *schedulerModels.BatchScheduleDescription, error) {
+	batchDescription, err := descriptions.getBatch(batch.Name)
+	if err != nil {
+		return nil, nil, err
+	}
+	indexes, err := descriptions.getBatchJobIndexes(ctx, batch, batchDescription.JobScheduleDescriptions)
+	if err != nil {
+		return nil, nil, err
+	}
+	var failedJobs []modelsV1.JobStatus
+	for _, jobStatus := range batch.JobStatuses {
+		if jobStatus.Status != "Failed" {
+			continue
+		}
+		if _, ok := indexes[jobStatus.Name]; !ok {
+			return nil, nil, serverErrors.NewConflict(fmt.Sprintf("The failed job %s in the batch %s has no payload and no unique jobId, and cannot be matched to its description. Set the jobId of the jobs in the batch to retry them", jobStatus.Name, batch.Name))
+		}
+		failedJobs = append(failedJobs, jobStatus)
+	}
+	sort.Slice(failedJobs, func(i, j int) bool { return indexes[failedJobs[i].Name] < indexes[failedJobs[j].Name] })
+
+	failedDescription := schedulerModels.BatchScheduleDescription{DefaultRadixJobComponentConfig: batchDescription.DefaultRadixJobComponentConfig}
+	jobNames := make([]string, 0, len(failedJobs))
+	for _, jobStatus := range failedJobs {
+		jobNames = append(jobNames, jobStatus.Name)
+		failedDescription.JobScheduleDescriptions = append(failedDescription.JobScheduleDescriptions, batchDescription.JobScheduleDescriptions[indexes[jobStatus.Name]])
+	}
+	return jobNames, &failedDescription, nil
+}
+
+// GetRetriedJobNames Gets the name of the batch with the failed jobs the batch retries, and the names of the failed jobs
+// mapped to the names of the jobs of the batch retrying them. Jobs not created yet, or which cannot be matched to their
+// descriptions, are left out. The name of the batch is empty when the batch does not retry the jobs of a batch
+func (descriptions *Descriptions) GetRetriedJobNames(ctx context.Context, batch *modelsV1.BatchStatus) (string, map[string]string, error) {
+	var record batchRecord
+	found, err := descriptions.store.Get(batchKeyPrefix+batch.Name, &record)
+	if err != nil || !found || record.RetryOf == nil {
+		return "", nil, err
+	}
+	indexes, err := descriptions.getBatchJobIndexes(ctx, batch, record.Description.JobScheduleDescriptions)
+	if err != nil {
+		return "", nil, err
+	}
+	jobNames := make(map[string]string, len(indexes))
+	for jobName, index := range indexes {
+		if index < len(record.RetryOf.JobNames) {
+			jobNames[record.RetryOf.JobNames[index]] = jobName
+		}
+	}
+	return record.RetryOf.BatchName, jobNames, nil
+}
+
+func (descriptions *Descriptions) getBatch(batchName string) (*schedulerModels.BatchScheduleDescription, error) {
+	var record batchRecord
+	found, err := descriptions.store.Get(batchKeyPrefix+batchName, &record)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, serverErrors.NewNotFound(fmt.Sprintf("The description of the batch %s is not known. Only jobs in batches created by this server can be re-run", batchName))
+	}
+	return &record.Description, nil
+}
+
+// ApplyOverrides Applies the overrides, a JSON merge patch (RFC 7386), to the description. Empty overrides leave it unchanged.
+// The result is decoded strictly, as a job schedule request, so the overrides can also set a callback
+func ApplyOverrides(description *schedulerModels.JobScheduleDescription, overrides []byte) (*models.JobScheduleRequest, error) {
+	document, err := json.Marshal(description)
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(overrides)) > 0 {
+		if !bytes.HasPrefix(bytes.TrimSpace(overrides), []byte("{")) {
+			return nil, serverErrors.NewInvalidFields([]models.StatusCause{{Message: "the overrides must be a JSON object"}})
+		}
+		if document, err = jsonpatch.MergePatch(document, overrides); err != nil {
+			return nil, serverErrors.NewInvalidFields([]models.StatusCause{{Message: fmt.Sprintf("invalid overrides: %v", err)}})
+		}
+	}
+	var request models.JobScheduleRequest
+	if err := utils.DecodeJSON(document, &request); err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+// Run Deletes the descriptions of deleted jobs and batches periodically, e.g. by the history limit, until the context is done
+func (descriptions *Descriptions) Run(ctx context.Context) {
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			descriptions.purge()
+		}
+	}
+}
+
+func (descriptions *Descriptions) purge() {
+	keys, err := descriptions.store.Keys()
+	if err != nil {
+		log.Errorf("failed to read job descriptions: %v", err)
+		return
+	}
+	for _, key := range keys {
+		switch {
+		case strings.HasPrefix(key, jobKeyPrefix):
+			_, err = descriptions.jobHandler.GetJob(strings.TrimPrefix(key, jobKeyPrefix))
+		case strings.HasPrefix(key, batchKeyPrefix):
+			_, err = descriptions.batchHandler.GetBatch(strings.TrimPrefix(key, batchKeyPrefix))
+		default:
+			continue
+		}
+		if !serverErrors.IsNotFound(err) {
+			continue
+		}
+		if err := descriptions.store.Delete(key); err != nil {
+			log.Errorf("failed to delete the job description %s: %v", key, err)
+		}
+	}
+}
+
+// getBatchJobIndexes Gets the indexes of the descriptions of the jobs of the batch by the names of the jobs. The jobs of
+// a batch mount their payload from the payload secret of the batch, by the key which is the index of their description.
+// A job without a payload is found by its job ID, when it is unique in the batch. Other jobs are left out
+func (descriptions *Descriptions) getBatchJobIndexes(ctx context.Context, batch *modelsV1.BatchStatus, jobDescriptions []schedulerModels.JobScheduleDescription) (map[string]int, error) {
+	jobs, err := descriptions.jobWatcher.GetJobs(ctx, labels.Set{kube.RadixBatchNameLabel: batch.Name})
+	if err != nil {
+		return nil, err
+	}
+	payloadIndexes := make(map[string]int, len(jobs))
+	for i := range jobs {
+		if index, ok := getPayloadIndex(&jobs[i]); ok && index < len(jobDescriptions) {
+			payloadIndexes[jobs[i].Name] = index
+		}
+	}
+	jobIdIndexes := make(map[string]int, len(jobDescriptions))
+	for i, description := range jobDescriptions {
+		if len(description.JobId) == 0 {
+			continue
+		}
+		if _, ok := jobIdIndexes[description.JobId]; ok {
+			// The job ID is not unique in the batch
+			jobIdIndexes[description.JobId] = -1
+			continue
+		}
+		jobIdIndexes[description.JobId] = i
+	}
+
+	indexes := make(map[string]int, len(batch.JobStatuses))
+	for _, jobStatus := range batch.JobStatuses {
+		if index, ok := payloadIndexes[jobStatus.Name]; ok {
+			indexes[jobStatus.Name] = index
+		} else if index, ok := jobIdIndexes[jobStatus.JobId]; ok && index >= 0 && len(jobStatus.JobId) > 0 {
+			indexes[jobStatus.Name] = index
+		}
+	}
+	return indexes, nil
+}
+
+// getBatchJobIndex The index of the description of the job in the batch
+func (descriptions *Descriptions) getBatchJobIndex(ctx context.Context, batch *modelsV1.BatchStatus, jobName string, jobDescriptions []schedulerModels.JobScheduleDescription) (int, error) {
+	if !hasJob(batch, jobName) {
+		return -1, serverErrors.NewNotFound(fmt.Sprintf("The job %s is not in the batch %s", jobName, batch.Name))
+	}
+	indexes, err := descriptions.getBatchJobIndexes(ctx, batch, jobDescriptions)
+	if err != nil {
+		return -1, err
+	}
+	index, ok := indexes[jobName]
+	if !ok {
+		return -1, serverErrors.NewConflict(fmt.Sprintf("The job %s in the batch %s has no payload and no unique jobId, and cannot be matched to its description. Set the jobId of the jobs in the batch to re-run them", jobName, batch.Name))
+	}
+	return index, nil
+}
+
+// getPayloadIndex Gets the index of the description of a job in a batch, from the key of the payload secret of the batch
+// mounted by the job. Returns false when the job has no payload
+func getPayloadIndex(job *batchv1.Job) (int, bool) {
+	for _, volume := range job.Spec.Template.Spec.Volumes {
+		if volume.Secret == nil || len(volume.Secret.Items) != 1 {
+			continue
+		}
+		if index, err := strconv.Atoi(volume.Secret.Items[0].Key); err == nil && index >= 0 {
+			return index, true
+		}
+	}
+	return -1, false
+}
+
+func hasJob(batch *modelsV1.BatchStatus, jobName string) bool {
+	for _, jobStatus := range batch.JobStatuses {
+		if jobStatus.Name == jobName {
+			return true
+		}
+	}
+	return false
+}
+
+// applyDefaults Sets the fields of the config which are not set to the defaults
+func applyDefaults(config, defaults *schedulerModels.RadixJobComponentConfig) {
+	if config.Resources == nil {
+		config.Resources = defaults.Resources
+	}
+	if config.Node == nil {
+		config.Node = defaults.Node
+	}
+	if config.TimeLimitSeconds == nil {
+		config.TimeLimitSeconds = defaults.TimeLimitSeconds
+	}
+}
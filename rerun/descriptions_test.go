@@ -0,0 +1,296 @@
+package rerun
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	serverErrors "github.com/equinor/radix-job-scheduler-server/api/errors"
+	"github.com/equinor/radix-job-scheduler-server/events"
+	"github.com/equinor/radix-job-scheduler-server/models"
+	"github.com/equinor/radix-job-scheduler-server/store"
+	apiErrors "github.com/equinor/radix-job-scheduler/api/errors"
+	batchMock "github.com/equinor/radix-job-scheduler/api/v1/batches/mock"
+	jobMock "github.com/equinor/radix-job-scheduler/api/v1/jobs/mock"
+	schedulerModels "github.com/equinor/radix-job-scheduler/models/common"
+	modelsV1 "github.com/equinor/radix-job-scheduler/models/v1"
+	"github.com/equinor/radix-operator/pkg/apis/kube"
+	v1 "github.com/equinor/radix-operator/pkg/apis/radix/v1"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+func int64Ptr(value int64) *int64 {
+	return &value
+}
+
+func TestApplyOverrides(t *testing.T) {
+	description := schedulerModels.JobScheduleDescription{
+		JobId:   "job1",
+		Payload: "payload",
+		RadixJobComponentConfig: schedulerModels.RadixJobComponentConfig{
+			Resources:        &v1.ResourceRequirements{Limits: v1.ResourceList{"cpu": "100m", "memory": "1Gi"}},
+			TimeLimitSeconds: int64Ptr(60),
+		},
+	}
+
+	t.Run("no overrides", func(t *testing.T) {
+		request, err := ApplyOverrides(&description, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, description, request.JobScheduleDescription)
+		assert.Empty(t, request.CallbackURL)
+	})
+
+	t.Run("merged overrides", func(t *testing.T) {
+		request, err := ApplyOverrides(&description, []byte(`{"payload":"new payload","resources":{"limits":{"cpu":null,"memory":"2Gi"}},"timeLimitSeconds":null,"callbackUrl":"https://example.com/callback"}`))
+		assert.NoError(t, err)
+		assert.Equal(t, schedulerModels.JobScheduleDescription{
+			JobId:   "job1",
+			Payload: "new payload",
+			RadixJobComponentConfig: schedulerModels.RadixJobComponentConfig{
+				Resources: &v1.ResourceRequirements{Limits: v1.ResourceList{"memory": "2Gi"}},
+			},
+		}, request.JobScheduleDescription)
+		assert.Equal(t, "https://example.com/callback", request.CallbackURL)
+		// The original description is not changed
+		assert.Equal(t, "payload", description.Payload)
+	})
+
+	t.Run("not an object", func(t *testing.T) {
+		_, err := ApplyOverrides(&description, []byte(`["payload"]`))
+		var statusError *serverErrors.StatusError
+		assert.ErrorAs(t, err, &statusError)
+		assert.Equal(t, schedulerModels.StatusReasonInvalid, statusError.Status().Reason)
+	})
+
+	t.Run("unknown field", func(t *testing.T) {
+		_, err := ApplyOverrides(&description, []byte(`{"timeLimit":600}`))
+		var statusError *serverErrors.StatusError
+		assert.ErrorAs(t, err, &statusError)
+		assert.Equal(t, []models.StatusCause{{Field: "timeLimit", Message: "unknown field"}}, statusError.Causes)
+	})
+
+	t.Run("wrong type", func(t *testing.T) {
+		_, err := ApplyOverrides(&description, []byte(`{"timeLimitSeconds":"600"}`))
+		var statusError *serverErrors.StatusError
+		assert.ErrorAs(t, err, &statusError)
+		assert.Len(t, statusError.Causes, 1)
+	})
+}
+
+func TestDescriptions_RerunJob(t *testing.T) {
+	description := schedulerModels.JobScheduleDescription{Payload: "payload"}
+
+	t.Run("no rerunner", func(t *testing.T) {
+		descriptions := NewDescriptions(store.NewMemoryStore(), nil, nil, nil)
+		_, err := descriptions.RerunJob(context.Background(), &description, nil, "job1")
+		assert.Error(t, err)
+	})
+
+	t.Run("overrides applied", func(t *testing.T) {
+		descriptions := NewDescriptions(store.NewMemoryStore(), nil, nil, nil)
+		descriptions.SetJobRerunner(func(ctx context.Context, jobScheduleRequest *models.JobScheduleRequest, rerunOf string) (*models.QueuedJobStatus, error) {
+			assert.Equal(t, "job1", rerunOf)
+			assert.Equal(t, "new payload", jobScheduleRequest.Payload)
+			return &models.QueuedJobStatus{JobStatus: modelsV1.JobStatus{Name: "job2"}}, nil
+		})
+		job, err := descriptions.RerunJob(context.Background(), &description, []byte(`{"payload":"new payload"}`), "job1")
+		assert.NoError(t, err)
+		assert.Equal(t, "job2", job.Name)
+	})
+}
+
+func TestDescriptions_GetJob(t *testing.T) {
+	descriptions := NewDescriptions(store.NewMemoryStore(), nil, nil, nil)
+	description := schedulerModels.JobScheduleDescription{Payload: "payload"}
+	assert.NoError(t, descriptions.PutJob("job1", &description, ""))
+
+	stored, err := descriptions.GetJob("job1")
+	assert.NoError(t, err)
+	assert.Equal(t, description, *stored)
+
+	assert.NoError(t, descriptions.DeleteJob("job1"))
+	_, err = descriptions.GetJob("job1")
+	assert.True(t, serverErrors.IsNotFound(err))
+}
+
+func TestDescriptions_GetBatchJob(t *testing.T) {
+	// batch1-a has no payload, batch1-x has no payload and no job ID
+	descriptions := newTestDescriptions(
+		newTestBatchJob("batch1", "batch1-a", ""),
+		newTestBatchJob("batch1", "batch1-b", "1"),
+		newTestBatchJob("batch1", "batch1-c", "2"),
+		newTestBatchJob("batch1", "batch1-x", ""),
+	)
+	assert.NoError(t, descriptions.PutBatch("batch1", &schedulerModels.BatchScheduleDescription{
+		JobScheduleDescriptions: []schedulerModels.JobScheduleDescription{
+			{JobId: "a"},
+			{JobId: "b", Payload: "payload b", RadixJobComponentConfig: schedulerModels.RadixJobComponentConfig{TimeLimitSeconds: int64Ptr(10)}},
+			{Payload: "payload c"},
+			{},
+		},
+		DefaultRadixJobComponentConfig: &schedulerModels.RadixJobComponentConfig{
+			Resources:        &v1.ResourceRequirements{Limits: v1.ResourceList{"memory": "1Gi"}},
+			TimeLimitSeconds: int64Ptr(60),
+		},
+	}, nil))
+	// Listed out of order, and created in the same second
+	batch := modelsV1.BatchStatus{
+		JobStatus: modelsV1.JobStatus{Name: "batch1"},
+		JobStatuses: []modelsV1.JobStatus{
+			{Name: "batch1-c", Created: "2022-10-01T12:00:00Z"},
+			{Name: "batch1-x", Created: "2022-10-01T12:00:00Z"},
+			{Name: "batch1-b", JobId: "b", Created: "2022-10-01T12:00:00Z"},
+			{Name: "batch1-a", JobId: "a", Created: "2022-10-01T12:00:00Z"},
+		},
+	}
+
+	t.Run("by payload, with the defaults of the batch", func(t *testing.T) {
+		description, err := descriptions.GetBatchJob(context.Background(), &batch, "batch1-b")
+		assert.NoError(t, err)
+		assert.Equal(t, "payload b", description.Payload)
+		assert.Equal(t, int64(10), *description.TimeLimitSeconds)
+		assert.Equal(t, "1Gi", description.Resources.Limits["memory"])
+
+		description, err = descriptions.GetBatchJob(context.Background(), &batch, "batch1-c")
+		assert.NoError(t, err)
+		assert.Equal(t, "payload c", description.Payload)
+		assert.Equal(t, int64(60), *description.TimeLimitSeconds)
+	})
+
+	t.Run("by job ID, without payload", func(t *testing.T) {
+		description, err := descriptions.GetBatchJob(context.Background(), &batch, "batch1-a")
+		assert.NoError(t, err)
+		assert.Equal(t, "a", description.JobId)
+	})
+
+	t.Run("no payload and no job ID", func(t *testing.T) {
+		_, err := descriptions.GetBatchJob(context.Background(), &batch, "batch1-x")
+		assert.Equal(t, http.StatusConflict, err.(*serverErrors.StatusError).Status().Code)
+		failedBatch := modelsV1.BatchStatus{JobStatus: batch.JobStatus, JobStatuses: append([]modelsV1.JobStatus{}, batch.JobStatuses...)}
+		_, _, err = descriptions.GetFailedBatchJobs(context.Background(), &failedBatch)
+		assert.NoError(t, err)
+		failedBatch.JobStatuses[1].Status = "Failed"
+		_, _, err = descriptions.GetFailedBatchJobs(context.Background(), &failedBatch)
+		assert.Equal(t, http.StatusConflict, err.(*serverErrors.StatusError).Status().Code)
+	})
+
+	t.Run("unknown job", func(t *testing.T) {
+		_, err := descriptions.GetBatchJob(context.Background(), &batch, "batch1-d")
+		assert.True(t, serverErrors.IsNotFound(err))
+	})
+
+	t.Run("jobs deleted from the batch", func(t *testing.T) {
+		partialBatch := modelsV1.BatchStatus{JobStatus: batch.JobStatus, JobStatuses: batch.JobStatuses[:1]}
+		description, err := descriptions.GetBatchJob(context.Background(), &partialBatch, "batch1-c")
+		assert.NoError(t, err)
+		assert.Equal(t, "payload c", description.Payload)
+	})
+
+	t.Run("unknown batch", func(t *testing.T) {
+		_, err := descriptions.GetBatchJob(context.Background(), &modelsV1.BatchStatus{JobStatus: modelsV1.JobStatus{Name: "batch2"}}, "batch2-a")
+		assert.True(t, serverErrors.IsNotFound(err))
+	})
+}
+
+func TestDescriptions_Purge(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	jobHandler := jobMock.NewMockJobHandler(ctrl)
+	batchHandler := batchMock.NewMockBatchHandler(ctrl)
+	descriptions := NewDescriptions(store.NewMemoryStore(), jobHandler, batchHandler, nil)
+	assert.NoError(t, descriptions.PutJob("job1", &schedulerModels.JobScheduleDescription{}, ""))
+	assert.NoError(t, descriptions.PutJob("job2", &schedulerModels.JobScheduleDescription{}, "job1"))
+	assert.NoError(t, descriptions.PutBatch("batch1", &schedulerModels.BatchScheduleDescription{}, nil))
+	jobHandler.EXPECT().GetJob("job1").Return(nil, apiErrors.NewNotFound("job", "job1")).Times(1)
+	jobHandler.EXPECT().GetJob("job2").Return(&modelsV1.JobStatus{Name: "job2"}, nil).Times(1)
+	batchHandler.EXPECT().GetBatch("batch1").Return(nil, apiErrors.NewNotFound("batch", "batch1")).Times(1)
+
+	descriptions.purge()
+
+	keys, err := descriptions.store.Keys()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"job/job2"}, keys)
+}
+
+func TestDescriptions_GetFailedBatchJobs(t *testing.T) {
+	descriptions := newTestDescriptions(
+		newTestBatchJob("batch1", "batch1-a", "0"),
+		newTestBatchJob("batch1", "batch1-b", "1"),
+		newTestBatchJob("batch1", "batch1-c", "2"),
+		newTestBatchJob("batch2", "batch2-x", "0"),
+		newTestBatchJob("batch2", "batch2-y", "1"),
+	)
+	defaults := &schedulerModels.RadixJobComponentConfig{TimeLimitSeconds: int64Ptr(60)}
+	assert.NoError(t, descriptions.PutBatch("batch1", &schedulerModels.BatchScheduleDescription{
+		JobScheduleDescriptions:        []schedulerModels.JobScheduleDescription{{Payload: "payload a"}, {Payload: "payload b"}, {Payload: "payload c"}},
+		DefaultRadixJobComponentConfig: defaults,
+	}, nil))
+	batch := modelsV1.BatchStatus{
+		JobStatus: modelsV1.JobStatus{Name: "batch1"},
+		JobStatuses: []modelsV1.JobStatus{
+			{Name: "batch1-c", Status: "Failed"},
+			{Name: "batch1-b", Status: "Succeeded"},
+			{Name: "batch1-a", Status: "Failed"},
+		},
+	}
+
+	jobNames, description, err := descriptions.GetFailedBatchJobs(context.Background(), &batch)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"batch1-a", "batch1-c"}, jobNames)
+	assert.Equal(t, schedulerModels.BatchScheduleDescription{
+		JobScheduleDescriptions:        []schedulerModels.JobScheduleDescription{{Payload: "payload a"}, {Payload: "payload c"}},
+		DefaultRadixJobComponentConfig: defaults,
+	}, *description)
+
+	retryOf, _, err := descriptions.GetRetriedJobNames(context.Background(), &batch)
+	assert.NoError(t, err)
+	assert.Empty(t, retryOf)
+
+	// The jobs of the new batch are mapped as they are created
+	assert.NoError(t, descriptions.PutBatch("batch2", description, &models.BatchRetry{BatchName: "batch1", JobNames: jobNames}))
+	newBatch := modelsV1.BatchStatus{
+		JobStatus:   modelsV1.JobStatus{Name: "batch2"},
+		JobStatuses: []modelsV1.JobStatus{{Name: "batch2-x"}},
+	}
+	retryOf, newJobNames, err := descriptions.GetRetriedJobNames(context.Background(), &newBatch)
+	assert.NoError(t, err)
+	assert.Equal(t, "batch1", retryOf)
+	assert.Equal(t, map[string]string{"batch1-a": "batch2-x"}, newJobNames)
+
+	newBatch.JobStatuses = append(newBatch.JobStatuses, modelsV1.JobStatus{Name: "batch2-y"})
+	_, newJobNames, err = descriptions.GetRetriedJobNames(context.Background(), &newBatch)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"batch1-a": "batch2-x", "batch1-c": "batch2-y"}, newJobNames)
+}
+
+// newTestDescriptions Descriptions with the Kubernetes jobs of the batches
+func newTestDescriptions(jobs ...runtime.Object) *Descriptions {
+	env := models.NewEnv()
+	env.RadixAppName, env.RadixComponentName, env.RadixDeploymentNamespace = "app", "compute", "app-dev"
+	return NewDescriptions(store.NewMemoryStore(), nil, nil, events.NewJobWatcher(kubefake.NewSimpleClientset(jobs...), env))
+}
+
+// newTestBatchJob A Kubernetes job in the batch, mounting the payload with the key from the payload secret of the batch
+func newTestBatchJob(batchName, jobName, payloadKey string) *batchv1.Job {
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+		Name:      jobName,
+		Namespace: "app-dev",
+		Labels:    map[string]string{kube.RadixAppLabel: "app", kube.RadixComponentLabel: "compute", kube.RadixBatchNameLabel: batchName},
+	}}
+	if len(payloadKey) > 0 {
+		job.Spec.Template.Spec.Volumes = []corev1.Volume{{
+			Name: "job-payload",
+			VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{
+				SecretName: batchName + "-payloads-0",
+				Items:      []corev1.KeyToPath{{Key: payloadKey, Path: "payload"}},
+			}},
+		}}
+	}
+	return job
+}
@@ -1,30 +1,60 @@
 package router
 
 import (
+	"fmt"
 	"net/http"
 
+	"github.com/equinor/radix-job-scheduler-server/auth"
+	"github.com/equinor/radix-job-scheduler-server/health"
 	"github.com/equinor/radix-job-scheduler-server/models"
+	"github.com/equinor/radix-job-scheduler-server/ratelimit"
 	"github.com/equinor/radix-job-scheduler-server/utils"
-	schedulerModels "github.com/equinor/radix-job-scheduler/models"
+	"github.com/equinor/radix-operator/pkg/apis/kube"
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rakyll/statik/fs"
+	log "github.com/sirupsen/logrus"
 	"github.com/urfave/negroni/v2"
 )
 
-const apiVersionRoute = "/api/v1"
+const (
+	apiVersionRoute = "/api/v1"
+	livenessRoute   = "/healthz"
+	readinessRoute  = "/readyz"
+	metricsRoute    = "/metrics"
+)
 
-// NewServer creates a new Radix job scheduler REST service
-func NewServer(env *schedulerModels.Env, controllers ...models.Controller) http.Handler {
+// NewServer creates a new Radix job scheduler REST service. Returns an error when the authentication, authorization
+// or rate limits are not configured correctly
+func NewServer(env *models.Env, kubeUtil *kube.Kube, controllers ...models.Controller) (http.Handler, error) {
 	router := mux.NewRouter().StrictSlash(true)
 
 	if env.UseSwagger {
-		initSwagger(router)
+		if err := initSwagger(router); err != nil {
+			return nil, fmt.Errorf("failed to load the swagger UI: %w", err)
+		}
 	}
 
-	initializeAPIServer(router, controllers)
+	authenticator, err := auth.NewAuthenticator(env, kubeUtil.KubeClient())
+	if err != nil {
+		return nil, fmt.Errorf("invalid authentication: %w", err)
+	}
+	authorizer, err := auth.NewAuthorizer(env.AuthPolicyFile)
+	if err != nil {
+		return nil, fmt.Errorf("invalid authorization policy: %w", err)
+	}
+	rateLimiter, err := ratelimit.NewLimiter(env)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rate limits: %w", err)
+	}
+
+	initializeAPIServer(router, authorizer, rateLimiter, controllers)
 
 	serveMux := http.NewServeMux()
-	serveMux.Handle(apiVersionRoute+"/", router)
+	serveMux.Handle(apiVersionRoute+"/", negroni.New(utils.NewUnmatchedRouteMiddleware(), rateLimiter.NewAuthenticationMiddleware(), auth.NewMiddleware(authenticator), utils.NewRequestBodyLimitMiddleware(env.MaxRequestBodySize), negroni.Wrap(router)))
+	serveMux.Handle(livenessRoute, health.NewHandler())
+	serveMux.Handle(readinessRoute, health.NewHandler(getReadinessChecks(env, kubeUtil)...))
+	serveMux.Handle(metricsRoute, promhttp.Handler())
 
 	if env.UseSwagger {
 		serveMux.Handle("/swaggerui/", negroni.New(negroni.Wrap(router)))
@@ -32,33 +62,54 @@ func NewServer(env *schedulerModels.Env, controllers ...models.Controller) http.
 
 	recovery := negroni.NewRecovery()
 	recovery.PrintStack = false
+	recovery.Logger = log.StandardLogger()
 
-	n := negroni.New(recovery)
+	n := negroni.New(utils.NewRequestContextMiddleware(), recovery)
 	n.UseHandler(serveMux)
-	return n
+	return n, nil
+}
+
+func getReadinessChecks(env *models.Env, kubeUtil *kube.Kube) []health.Check {
+	return []health.Check{
+		health.NewKubernetesCheck(kubeUtil.KubeClient()),
+		health.NewRadixCheck(kubeUtil.RadixClient(), env.RadixDeploymentNamespace, env.RadixDeploymentName),
+	}
 }
 
-func initSwagger(router *mux.Router) {
+func initSwagger(router *mux.Router) error {
 	statikFS, err := fs.New()
 	if err != nil {
-		panic(err)
+		return err
 	}
 
 	staticServer := http.FileServer(statikFS)
 	sh := http.StripPrefix("/swaggerui/", staticServer)
 	router.PathPrefix("/swaggerui/").Handler(sh)
+	return nil
 }
 
-func initializeAPIServer(router *mux.Router, controllers []models.Controller) {
+func initializeAPIServer(router *mux.Router, authorizer models.Authorizer, rateLimiter models.RateLimiter, controllers []models.Controller) {
 	for _, controller := range controllers {
 		for _, route := range controller.GetRoutes() {
-			addHandlerRoute(router, route)
+			addHandlerRoute(router, authorizer, rateLimiter, route)
 		}
 	}
 }
 
-func addHandlerRoute(router *mux.Router, route models.Route) {
+func addHandlerRoute(router *mux.Router, authorizer models.Authorizer, rateLimiter models.RateLimiter, route models.Route) {
 	path := apiVersionRoute + route.Path
 	router.HandleFunc(path,
-		utils.NewRadixMiddleware(path, route.Method, route.HandlerFunc).Handle).Methods(route.Method)
+		utils.NewRadixMiddleware(path, route.Method, getPermission(route), authorizer, rateLimiter, route.HandlerFunc).Handle).Methods(route.Method)
+}
+
+// getPermission The permission declared by the route, or by default read for GET and mutate for other methods
+func getPermission(route models.Route) models.Permission {
+	switch {
+	case len(route.Permission) > 0:
+		return route.Permission
+	case route.Method == http.MethodGet:
+		return models.PermissionRead
+	default:
+		return models.PermissionMutate
+	}
 }
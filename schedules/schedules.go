@@ -0,0 +1,430 @@
+package schedules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	commonUtils "github.com/equinor/radix-common/utils"
+	serverErrors "github.com/equinor/radix-job-scheduler-server/api/errors"
+	"github.com/equinor/radix-job-scheduler-server/models"
+	"github.com/equinor/radix-job-scheduler-server/store"
+	"github.com/robfig/cron/v3"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// KindJob Schedules creating a job
+	KindJob = "job"
+	// KindBatch Schedules creating a batch
+	KindBatch = "batch"
+
+	scheduleKeyPrefix = "schedule/"
+	runInterval       = time.Second
+	// runHistoryLimit Maximum number of runs kept for each schedule
+	runHistoryLimit = 20
+	maxNameLength   = 63
+)
+
+var namePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// Runner Creates, checks and stops the jobs or batches of the runs of schedules
+type Runner struct {
+	// Create Creates the job or batch of a run of the schedule, and returns its name
+	Create func(ctx context.Context, schedule *models.Schedule) (string, error)
+	// IsActive Returns true when the job or batch is not completed
+	IsActive func(ctx context.Context, name string) (bool, error)
+	// Stop Stops the job or batch
+	Stop func(ctx context.Context, name string) error
+}
+
+// record A stored schedule
+type record struct {
+	Schedule models.Schedule `json:"schedule"`
+	Created  time.Time       `json:"created"`
+	Updated  time.Time       `json:"updated"`
+	// NextRun Time of the next run, zero when the schedule is suspended
+	NextRun time.Time            `json:"nextRun,omitempty"`
+	Runs    []models.ScheduleRun `json:"runs,omitempty"`
+}
+
+// Schedules Recurring runs of jobs and batches
+type Schedules struct {
+	store   store.Store
+	runners map[string]*Runner
+	now     func() time.Time
+	// running The names of the schedules with a run in progress
+	running map[string]bool
+	// mu Guards changes of the schedules. It is not held while a run creates its job or batch
+	mu sync.Mutex
+}
+
+// New Constructor
+func New(store store.Store) *Schedules {
+	return &Schedules{
+		store:   store,
+		runners: make(map[string]*Runner),
+		now:     time.Now,
+		running: make(map[string]bool),
+	}
+}
+
+// SetRunner Sets the runner of the schedules of the kind
+func (schedules *Schedules) SetRunner(kind string, runner *Runner) {
+	schedules.mu.Lock()
+	defer schedules.mu.Unlock()
+	schedules.runners[kind] = runner
+}
+
+// Validate Gets the invalid fields of the schedule, except the fields of its job or batch
+func Validate(schedule *models.Schedule) []models.StatusCause {
+	var causes []models.StatusCause
+	if len(schedule.Name) > maxNameLength || !namePattern.MatchString(schedule.Name) {
+		causes = append(causes, models.StatusCause{Field: "name", Message: fmt.Sprintf("must be lowercase letters, digits and dashes, at most %d characters, starting and ending with a letter or digit", maxNameLength)})
+	}
+	if strings.HasPrefix(schedule.Cron, "TZ=") || strings.HasPrefix(schedule.Cron, "CRON_TZ=") {
+		causes = append(causes, models.StatusCause{Field: "cron", Message: "must not have a time zone, set timeZone instead"})
+	} else if _, err := cron.ParseStandard(schedule.Cron); err != nil {
+		causes = append(causes, models.StatusCause{Field: "cron", Message: fmt.Sprintf("must be a cron expression with 5 fields or a descriptor: %v", err)})
+	}
+	if _, err := time.LoadLocation(schedule.TimeZone); err != nil {
+		causes = append(causes, models.StatusCause{Field: "timeZone", Message: fmt.Sprintf("must be an IANA time zone, e.g. Europe/Oslo, not %q", schedule.TimeZone)})
+	}
+	switch schedule.ConcurrencyPolicy {
+	case "", models.ConcurrencyPolicyAllow, models.ConcurrencyPolicyForbid, models.ConcurrencyPolicyReplace:
+	default:
+		causes = append(causes, models.StatusCause{Field: "concurrencyPolicy", Message: fmt.Sprintf("must be one of %s, %s, %s", models.ConcurrencyPolicyAllow, models.ConcurrencyPolicyForbid, models.ConcurrencyPolicyReplace)})
+	}
+	if (schedule.Job == nil) == (schedule.Batch == nil) {
+		causes = append(causes, models.StatusCause{Field: "job", Message: "either job or batch must be set"})
+	}
+	return causes
+}
+
+// GetKind Gets the kind of the schedule, job or batch
+func GetKind(schedule *models.Schedule) string {
+	if schedule.Batch != nil {
+		return KindBatch
+	}
+	return KindJob
+}
+
+// Create Creates a valid schedule. Returns a conflict error when a schedule with the name exists
+func (schedules *Schedules) Create(schedule *models.Schedule) (*models.ScheduleStatus, error) {
+	schedules.mu.Lock()
+	defer schedules.mu.Unlock()
+	var existing record
+	found, err := schedules.store.Get(getKey(schedule.Name), &existing)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		return nil, serverErrors.NewConflict(fmt.Sprintf("schedule %s already exists", schedule.Name))
+	}
+	now := schedules.now()
+	rec := record{Schedule: *schedule, Created: now}
+	return schedules.put(&rec)
+}
+
+// Update Replaces a valid schedule. The history of its runs is kept
+func (schedules *Schedules) Update(schedule *models.Schedule) (*models.ScheduleStatus, error) {
+	schedules.mu.Lock()
+	defer schedules.mu.Unlock()
+	rec, err := schedules.get(schedule.Name)
+	if err != nil {
+		return nil, err
+	}
+	rec.Schedule = *schedule
+	return schedules.put(rec)
+}
+
+// SetSuspend Suspends or resumes the schedule
+func (schedules *Schedules) SetSuspend(name string, suspend bool) (*models.ScheduleStatus, error) {
+	schedules.mu.Lock()
+	defer schedules.mu.Unlock()
+	rec, err := schedules.get(name)
+	if err != nil {
+		return nil, err
+	}
+	rec.Schedule.Suspend = suspend
+	return schedules.put(rec)
+}
+
+// Get Gets the schedule
+func (schedules *Schedules) Get(name string) (*models.ScheduleStatus, error) {
+	rec, err := schedules.get(name)
+	if err != nil {
+		return nil, err
+	}
+	return rec.status(), nil
+}
+
+// List Gets the schedules, sorted by name
+func (schedules *Schedules) List() ([]models.ScheduleStatus, error) {
+	records, err := schedules.list()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]models.ScheduleStatus, 0, len(records))
+	for i := range records {
+		list = append(list, *records[i].status())
+	}
+	return list, nil
+}
+
+func (schedules *Schedules) list() ([]record, error) {
+	keys, err := schedules.store.Keys()
+	if err != nil {
+		return nil, err
+	}
+	var records []record
+	for _, key := range keys {
+		if !strings.HasPrefix(key, scheduleKeyPrefix) {
+			continue
+		}
+		var rec record
+		found, err := schedules.store.Get(key, &rec)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			records = append(records, rec)
+		}
+	}
+	return records, nil
+}
+
+// Delete Deletes the schedule. The jobs and batches created by its runs are not deleted
+func (schedules *Schedules) Delete(name string) error {
+	schedules.mu.Lock()
+	defer schedules.mu.Unlock()
+	if _, err := schedules.get(name); err != nil {
+		return err
+	}
+	return schedules.store.Delete(getKey(name))
+}
+
+// Trigger Runs the schedule now, also when it is suspended. The concurrency policy of the schedule applies.
+// Returns the run, and an error when the run was skipped or failed
+func (schedules *Schedules) Trigger(ctx context.Context, name string) (*models.ScheduleRun, error) {
+	rec, runner, err := schedules.startRun(name, false)
+	if err != nil {
+		return nil, err
+	}
+	run, err := schedules.run(ctx, runner, rec, schedules.now(), models.ScheduleTriggerManual)
+	schedules.finishRun(name, run)
+	return run, err
+}
+
+// Run Runs the schedules at the times of their cron expressions, until the context is done.
+// A run missed while the server was stopped runs once when the server starts
+func (schedules *Schedules) Run(ctx context.Context) {
+	ticker := time.NewTicker(runInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			schedules.runDue(ctx)
+		}
+	}
+}
+
+// runDue Runs the schedules with a next run time which has passed
+func (schedules *Schedules) runDue(ctx context.Context) {
+	records, err := schedules.list()
+	if err != nil {
+		log.Errorf("failed to read schedules: %v", err)
+		return
+	}
+	now := schedules.now()
+	for _, rec := range records {
+		if ctx.Err() != nil {
+			return
+		}
+		if !rec.NextRun.IsZero() && !rec.NextRun.After(now) {
+			schedules.runIfDue(ctx, rec.Schedule.Name)
+		}
+	}
+}
+
+// runIfDue Runs the schedule when its next run time has passed, and it was not changed or deleted meanwhile
+func (schedules *Schedules) runIfDue(ctx context.Context, name string) {
+	rec, runner, err := schedules.startRun(name, true)
+	if err != nil || rec == nil {
+		return
+	}
+	run, err := schedules.run(ctx, runner, rec, rec.NextRun, models.ScheduleTriggerCron)
+	if err != nil {
+		log.Warnf("run of the schedule %s did not create a %s: %v", name, GetKind(&rec.Schedule), err)
+	}
+	schedules.finishRun(name, run)
+}
+
+// startRun Gets the schedule and its runner, and marks it as running, so it has one run at a time. When due is true,
+// returns nil when the next run time of the schedule has not passed. Returns a conflict error when a run is in progress
+func (schedules *Schedules) startRun(name string, due bool) (*record, *Runner, error) {
+	schedules.mu.Lock()
+	defer schedules.mu.Unlock()
+	rec, err := schedules.get(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if due && (rec.NextRun.IsZero() || rec.NextRun.After(schedules.now())) {
+		return nil, nil, nil
+	}
+	if schedules.running[name] {
+		return nil, nil, serverErrors.NewConflict(fmt.Sprintf("a run of the schedule %s is in progress", name))
+	}
+	schedules.running[name] = true
+	return rec, schedules.runners[GetKind(&rec.Schedule)], nil
+}
+
+// finishRun Adds the run to the history of the schedule, unless the schedule was deleted during the run
+func (schedules *Schedules) finishRun(name string, run *models.ScheduleRun) {
+	schedules.mu.Lock()
+	defer schedules.mu.Unlock()
+	delete(schedules.running, name)
+	rec, err := schedules.get(name)
+	if err != nil {
+		log.Infof("the run of the schedule %s is not saved: %v", name, err)
+		return
+	}
+	rec.Runs = append([]models.ScheduleRun{*run}, rec.Runs...)
+	if len(rec.Runs) > runHistoryLimit {
+		rec.Runs = rec.Runs[:runHistoryLimit]
+	}
+	if _, err := schedules.put(rec); err != nil {
+		log.Errorf("failed to save the run of the schedule %s: %v", name, err)
+	}
+}
+
+// run Creates the job or batch of the schedule with the runner, applying the concurrency policy
+func (schedules *Schedules) run(ctx context.Context, runner *Runner, rec *record, runTime time.Time, trigger string) (*models.ScheduleRun, error) {
+	run := models.ScheduleRun{Time: commonUtils.FormatTimestamp(runTime), Trigger: trigger}
+	err := schedules.createRun(ctx, runner, rec, &run)
+	if err != nil {
+		run.Message = err.Error()
+		if len(run.Result) == 0 {
+			run.Result = models.ScheduleRunFailed
+		}
+	}
+	return &run, err
+}
+
+func (schedules *Schedules) createRun(ctx context.Context, runner *Runner, rec *record, run *models.ScheduleRun) error {
+	if runner == nil {
+		return fmt.Errorf("no runner of %s schedules", GetKind(&rec.Schedule))
+	}
+
+	var replaced string
+	if previous := rec.getLastCreatedRun(); previous != nil && rec.Schedule.ConcurrencyPolicy != "" && rec.Schedule.ConcurrencyPolicy != models.ConcurrencyPolicyAllow {
+		active, err := runner.IsActive(ctx, previous.Name)
+		if err != nil {
+			return fmt.Errorf("failed to get the status of the previous run %s: %w", previous.Name, err)
+		}
+		if active && rec.Schedule.ConcurrencyPolicy == models.ConcurrencyPolicyForbid {
+			run.Result = models.ScheduleRunSkipped
+			return serverErrors.NewConflict(fmt.Sprintf("the previous run %s is still active", previous.Name))
+		}
+		if active {
+			if err := runner.Stop(ctx, previous.Name); err != nil {
+				return fmt.Errorf("failed to stop the previous run %s: %w", previous.Name, err)
+			}
+			replaced = previous.Name
+		}
+	}
+
+	name, err := runner.Create(ctx, &rec.Schedule)
+	if err != nil {
+		return err
+	}
+	run.Result = models.ScheduleRunCreated
+	run.Name = name
+	if len(replaced) > 0 {
+		run.Message = fmt.Sprintf("replaced the previous run %s", replaced)
+	}
+	return nil
+}
+
+func (schedules *Schedules) get(name string) (*record, error) {
+	var rec record
+	found, err := schedules.store.Get(getKey(name), &rec)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, serverErrors.NewNotFound(fmt.Sprintf("schedule %s not found", name))
+	}
+	return &rec, nil
+}
+
+// put Stores the schedule with its next run time
+func (schedules *Schedules) put(rec *record) (*models.ScheduleStatus, error) {
+	now := schedules.now()
+	rec.Updated = now
+	rec.NextRun = time.Time{}
+	if !rec.Schedule.Suspend {
+		nextRun, err := getNextRun(&rec.Schedule, now)
+		if err != nil {
+			return nil, err
+		}
+		rec.NextRun = nextRun
+	}
+	if err := schedules.store.Put(getKey(rec.Schedule.Name), rec); err != nil {
+		return nil, err
+	}
+	return rec.status(), nil
+}
+
+// getNextRun Gets the first time of the cron expression after the time, in the time zone of the schedule
+func getNextRun(schedule *models.Schedule, after time.Time) (time.Time, error) {
+	cronSchedule, err := cron.ParseStandard(schedule.Cron)
+	if err != nil {
+		return time.Time{}, err
+	}
+	location, err := time.LoadLocation(schedule.TimeZone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	nextRun := cronSchedule.Next(after.In(location))
+	if nextRun.IsZero() {
+		return time.Time{}, errors.New("the cron expression has no next run time")
+	}
+	return nextRun, nil
+}
+
+// getLastCreatedRun Gets the newest run which created a job or batch
+func (rec *record) getLastCreatedRun() *models.ScheduleRun {
+	for i := range rec.Runs {
+		if rec.Runs[i].Result == models.ScheduleRunCreated {
+			return &rec.Runs[i]
+		}
+	}
+	return nil
+}
+
+func (rec *record) status() *models.ScheduleStatus {
+	status := models.ScheduleStatus{
+		Schedule: rec.Schedule,
+		Created:  commonUtils.FormatTimestamp(rec.Created),
+		Updated:  commonUtils.FormatTimestamp(rec.Updated),
+		Runs:     rec.Runs,
+	}
+	if !rec.NextRun.IsZero() {
+		status.NextRun = commonUtils.FormatTimestamp(rec.NextRun)
+	}
+	if status.Runs == nil {
+		status.Runs = []models.ScheduleRun{}
+	}
+	return &status
+}
+
+func getKey(name string) string {
+	return scheduleKeyPrefix + name
+}
@@ -0,0 +1,206 @@
+package schedules
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	serverErrors "github.com/equinor/radix-job-scheduler-server/api/errors"
+	"github.com/equinor/radix-job-scheduler-server/models"
+	"github.com/equinor/radix-job-scheduler-server/store"
+	schedulerModels "github.com/equinor/radix-job-scheduler/models/common"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeRunner Creates jobs named by the number of created jobs, which are active until stopped
+type fakeRunner struct {
+	created []string
+	stopped []string
+	active  map[string]bool
+	err     error
+}
+
+func (runner *fakeRunner) runner() *Runner {
+	runner.active = make(map[string]bool)
+	return &Runner{
+		Create: func(ctx context.Context, schedule *models.Schedule) (string, error) {
+			if runner.err != nil {
+				return "", runner.err
+			}
+			name := schedule.Name + "-" + string(rune('a'+len(runner.created)))
+			runner.created = append(runner.created, name)
+			runner.active[name] = true
+			return name, nil
+		},
+		IsActive: func(ctx context.Context, name string) (bool, error) {
+			return runner.active[name], nil
+		},
+		Stop: func(ctx context.Context, name string) error {
+			runner.stopped = append(runner.stopped, name)
+			runner.active[name] = false
+			return nil
+		},
+	}
+}
+
+func newTestSchedules(now *time.Time, runner *fakeRunner) *Schedules {
+	schedules := New(store.NewMemoryStore())
+	schedules.now = func() time.Time { return *now }
+	schedules.SetRunner(KindJob, runner.runner())
+	return schedules
+}
+
+func TestValidate(t *testing.T) {
+	valid := models.Schedule{Name: "nightly-import", Cron: "30 2 * * 1-5", TimeZone: "Europe/Oslo", Job: &schedulerModels.JobScheduleDescription{}}
+	assert.Empty(t, Validate(&valid))
+	descriptor := models.Schedule{Name: "hourly", Cron: "@hourly", Batch: &schedulerModels.BatchScheduleDescription{}}
+	assert.Empty(t, Validate(&descriptor))
+
+	invalid := models.Schedule{Name: "Nightly_Import", Cron: "CRON_TZ=UTC 30 2 * * *", TimeZone: "Oslo", ConcurrencyPolicy: "Never"}
+	var fields []string
+	for _, cause := range Validate(&invalid) {
+		fields = append(fields, cause.Field)
+	}
+	assert.Equal(t, []string{"name", "cron", "timeZone", "concurrencyPolicy", "job"}, fields)
+
+	invalid = models.Schedule{Name: "a", Cron: "30 2 * *", Job: &schedulerModels.JobScheduleDescription{}, Batch: &schedulerModels.BatchScheduleDescription{}}
+	fields = nil
+	for _, cause := range Validate(&invalid) {
+		fields = append(fields, cause.Field)
+	}
+	assert.Equal(t, []string{"cron", "job"}, fields)
+}
+
+func TestSchedules_NextRun(t *testing.T) {
+	now := time.Date(2022, 11, 1, 12, 0, 0, 0, time.UTC)
+	schedules := newTestSchedules(&now, &fakeRunner{})
+	status, err := schedules.Create(&models.Schedule{Name: "nightly", Cron: "30 2 * * *", TimeZone: "Europe/Oslo", Job: &schedulerModels.JobScheduleDescription{}})
+	assert.NoError(t, err)
+	assert.Equal(t, "2022-11-02T02:30:00+01:00", status.NextRun)
+	assert.Equal(t, []models.ScheduleRun{}, status.Runs)
+
+	_, err = schedules.Create(&models.Schedule{Name: "nightly", Cron: "@daily", Job: &schedulerModels.JobScheduleDescription{}})
+	var statusError *serverErrors.StatusError
+	assert.ErrorAs(t, err, &statusError)
+	assert.Equal(t, models.StatusReasonConflict, statusError.Status().Reason)
+
+	status, err = schedules.SetSuspend("nightly", true)
+	assert.NoError(t, err)
+	assert.Empty(t, status.NextRun)
+	status, err = schedules.SetSuspend("nightly", false)
+	assert.NoError(t, err)
+	assert.Equal(t, "2022-11-02T02:30:00+01:00", status.NextRun)
+
+	_, err = schedules.Get("weekly")
+	assert.True(t, serverErrors.IsNotFound(err))
+}
+
+func TestSchedules_RunDue(t *testing.T) {
+	now := time.Date(2022, 11, 1, 12, 0, 30, 0, time.UTC)
+	runner := &fakeRunner{}
+	schedules := newTestSchedules(&now, runner)
+	_, err := schedules.Create(&models.Schedule{Name: "every-minute", Cron: "* * * * *", Job: &schedulerModels.JobScheduleDescription{}})
+	assert.NoError(t, err)
+	_, err = schedules.Create(&models.Schedule{Name: "suspended", Cron: "* * * * *", Suspend: true, Job: &schedulerModels.JobScheduleDescription{}})
+	assert.NoError(t, err)
+
+	schedules.runDue(context.Background())
+	assert.Empty(t, runner.created)
+
+	now = now.Add(time.Minute)
+	schedules.runDue(context.Background())
+	schedules.runDue(context.Background())
+	assert.Equal(t, []string{"every-minute-a"}, runner.created)
+	status, err := schedules.Get("every-minute")
+	assert.NoError(t, err)
+	assert.Equal(t, []models.ScheduleRun{{Time: "2022-11-01T12:01:00Z", Trigger: models.ScheduleTriggerCron, Result: models.ScheduleRunCreated, Name: "every-minute-a"}}, status.Runs)
+	assert.Equal(t, "2022-11-01T12:02:00Z", status.NextRun)
+}
+
+func TestSchedules_ConcurrencyPolicy(t *testing.T) {
+	now := time.Date(2022, 11, 1, 12, 0, 0, 0, time.UTC)
+	runner := &fakeRunner{}
+	schedules := newTestSchedules(&now, runner)
+	_, err := schedules.Create(&models.Schedule{Name: "forbid", Cron: "@daily", ConcurrencyPolicy: models.ConcurrencyPolicyForbid, Job: &schedulerModels.JobScheduleDescription{}})
+	assert.NoError(t, err)
+	_, err = schedules.Create(&models.Schedule{Name: "replace", Cron: "@daily", ConcurrencyPolicy: models.ConcurrencyPolicyReplace, Job: &schedulerModels.JobScheduleDescription{}})
+	assert.NoError(t, err)
+	_, err = schedules.Create(&models.Schedule{Name: "allow", Cron: "@daily", Job: &schedulerModels.JobScheduleDescription{}})
+	assert.NoError(t, err)
+
+	for _, name := range []string{"forbid", "replace", "allow"} {
+		run, err := schedules.Trigger(context.Background(), name)
+		assert.NoError(t, err)
+		assert.Equal(t, models.ScheduleRunCreated, run.Result)
+	}
+
+	run, err := schedules.Trigger(context.Background(), "forbid")
+	var statusError *serverErrors.StatusError
+	assert.ErrorAs(t, err, &statusError)
+	assert.Equal(t, models.StatusReasonConflict, statusError.Status().Reason)
+	assert.Equal(t, models.ScheduleRunSkipped, run.Result)
+
+	run, err = schedules.Trigger(context.Background(), "replace")
+	assert.NoError(t, err)
+	assert.Equal(t, "replaced the previous run replace-b", run.Message)
+	assert.Equal(t, []string{"replace-b"}, runner.stopped)
+
+	_, err = schedules.Trigger(context.Background(), "allow")
+	assert.NoError(t, err)
+	assert.True(t, runner.active["allow-c"])
+
+	runner.err = errors.New("unavailable")
+	run, err = schedules.Trigger(context.Background(), "allow")
+	assert.Error(t, err)
+	assert.Equal(t, models.ScheduleRunFailed, run.Result)
+	status, err := schedules.Get("allow")
+	assert.NoError(t, err)
+	assert.Len(t, status.Runs, 3)
+	assert.Equal(t, "unavailable", status.Runs[0].Message)
+}
+
+func TestSchedules_ChangeDuringRun(t *testing.T) {
+	now := time.Date(2022, 11, 1, 12, 0, 0, 0, time.UTC)
+	schedules := New(store.NewMemoryStore())
+	schedules.now = func() time.Time { return now }
+	schedules.SetRunner(KindJob, &Runner{
+		Create: func(ctx context.Context, schedule *models.Schedule) (string, error) {
+			// The schedules are not locked while the job is created
+			_, err := schedules.SetSuspend(schedule.Name, true)
+			assert.NoError(t, err)
+			_, err = schedules.Trigger(ctx, schedule.Name)
+			var statusError *serverErrors.StatusError
+			assert.ErrorAs(t, err, &statusError)
+			assert.Equal(t, models.StatusReasonConflict, statusError.Status().Reason)
+			return schedule.Name + "-a", nil
+		},
+	})
+	_, err := schedules.Create(&models.Schedule{Name: "hourly", Cron: "@hourly", Job: &schedulerModels.JobScheduleDescription{}})
+	assert.NoError(t, err)
+
+	run, err := schedules.Trigger(context.Background(), "hourly")
+	assert.NoError(t, err)
+	assert.Equal(t, "hourly-a", run.Name)
+	status, err := schedules.Get("hourly")
+	assert.NoError(t, err)
+	assert.True(t, status.Suspend)
+	assert.Equal(t, []models.ScheduleRun{*run}, status.Runs)
+}
+
+func TestSchedules_RunHistoryLimit(t *testing.T) {
+	now := time.Date(2022, 11, 1, 12, 0, 0, 0, time.UTC)
+	runner := &fakeRunner{err: errors.New("unavailable")}
+	schedules := newTestSchedules(&now, runner)
+	_, err := schedules.Create(&models.Schedule{Name: "hourly", Cron: "@hourly", Job: &schedulerModels.JobScheduleDescription{}})
+	assert.NoError(t, err)
+	for i := 0; i < runHistoryLimit+5; i++ {
+		_, _ = schedules.Trigger(context.Background(), "hourly")
+	}
+	status, err := schedules.Get("hourly")
+	assert.NoError(t, err)
+	assert.Len(t, status.Runs, runHistoryLimit)
+
+	assert.NoError(t, schedules.Delete("hourly"))
+	assert.True(t, serverErrors.IsNotFound(schedules.Delete("hourly")))
+}
@@ -0,0 +1,192 @@
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const fileExtension = ".json"
+
+// Store Documents stored as JSON by key
+type Store interface {
+	// Get Reads the document with the key into v. Returns false when there is no document with the key
+	Get(key string, v interface{}) (bool, error)
+	// Put Writes v as the document with the key
+	Put(key string, v interface{}) error
+	// Delete Deletes the document with the key, if any
+	Delete(key string) error
+	// Keys Gets the sorted keys of all documents
+	Keys() ([]string, error)
+}
+
+// New Creates a store in the named sub directory of the state directory, or in memory when the state directory is empty
+func New(stateDir, name string) (Store, error) {
+	if len(stateDir) == 0 {
+		return NewMemoryStore(), nil
+	}
+	return NewFileStore(filepath.Join(stateDir, name))
+}
+
+type fileStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// fileDocument The content of the file of a document, with the key, as the file is named by a hash of the key
+type fileDocument struct {
+	Key      string          `json:"key"`
+	Document json.RawMessage `json:"document"`
+}
+
+// NewFileStore Creates a store with a file for each document in the directory. The directory is created when missing
+func NewFileStore(dir string) (Store, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &fileStore{dir: dir}, nil
+}
+
+func (store *fileStore) Get(key string, v interface{}) (bool, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	data, err := os.ReadFile(store.fileName(key))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	var doc fileDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return false, err
+	}
+	return true, json.Unmarshal(doc.Document, v)
+}
+
+func (store *fileStore) Put(key string, v interface{}) error {
+	document, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(&fileDocument{Key: key, Document: document})
+	if err != nil {
+		return err
+	}
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	// Write to a temporary file and rename it, so a document is never read half written
+	file, err := os.CreateTemp(store.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), store.fileName(key))
+}
+
+func (store *fileStore) Delete(key string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if err := os.Remove(store.fileName(key)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (store *fileStore) Keys() ([]string, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	entries, err := os.ReadDir(store.dir)
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, fileExtension) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(store.dir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		var doc fileDocument
+		if err := json.Unmarshal(data, &doc); err != nil {
+			continue
+		}
+		keys = append(keys, doc.Key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// fileName The name of the file of a document. Keys are hashed, as they can have any characters and any length,
+// and file names are limited to 255 bytes
+func (store *fileStore) fileName(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return filepath.Join(store.dir, hex.EncodeToString(hash[:])+fileExtension)
+}
+
+type memoryStore struct {
+	documents map[string][]byte
+	mu        sync.Mutex
+}
+
+// NewMemoryStore Creates a store keeping the documents in memory
+func NewMemoryStore() Store {
+	return &memoryStore{documents: make(map[string][]byte)}
+}
+
+func (store *memoryStore) Get(key string, v interface{}) (bool, error) {
+	store.mu.Lock()
+	data, ok := store.documents[key]
+	store.mu.Unlock()
+	if !ok {
+		return false, nil
+	}
+	return true, json.Unmarshal(data, v)
+}
+
+func (store *memoryStore) Put(key string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.documents[key] = data
+	return nil
+}
+
+func (store *memoryStore) Delete(key string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	delete(store.documents, key)
+	return nil
+}
+
+func (store *memoryStore) Keys() ([]string, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	keys := make([]string, 0, len(store.documents))
+	for key := range store.documents {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
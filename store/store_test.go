@@ -0,0 +1,74 @@
+package store
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type document struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func testStore(t *testing.T, store Store) {
+	var doc document
+	found, err := store.Get("missing", &doc)
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	assert.NoError(t, store.Put("b/key with spaces", &document{Name: "b", Count: 1}))
+	assert.NoError(t, store.Put("a", &document{Name: "a", Count: 2}))
+	assert.NoError(t, store.Put("a", &document{Name: "a", Count: 3}))
+
+	found, err = store.Get("a", &doc)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, document{Name: "a", Count: 3}, doc)
+
+	keys, err := store.Keys()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b/key with spaces"}, keys)
+
+	assert.NoError(t, store.Delete("a"))
+	assert.NoError(t, store.Delete("a"))
+	keys, err = store.Keys()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"b/key with spaces"}, keys)
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, NewMemoryStore())
+}
+
+func TestFileStore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := New(dir, "documents")
+	assert.NoError(t, err)
+	testStore(t, store)
+
+	// The documents survive a new store in the same directory
+	reopened, err := New(dir, "documents")
+	assert.NoError(t, err)
+	var doc document
+	found, err := reopened.Get("b/key with spaces", &doc)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, document{Name: "b", Count: 1}, doc)
+}
+
+func TestFileStore_LongKey(t *testing.T) {
+	store, err := New(t.TempDir(), "documents")
+	assert.NoError(t, err)
+	key := "jobs/" + strings.Repeat("k", 255)
+	assert.NoError(t, store.Put(key, &document{Name: "long", Count: 1}))
+	var doc document
+	found, err := store.Get(key, &doc)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, document{Name: "long", Count: 1}, doc)
+	keys, err := store.Keys()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{key}, keys)
+}
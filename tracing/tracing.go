@@ -0,0 +1,87 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/equinor/radix-job-scheduler-server/models"
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	serviceName = "radix-job-scheduler"
+	tracerName  = "github.com/equinor/radix-job-scheduler-server"
+)
+
+// Init Configures the global tracer provider with the exporter from the environment, and the W3C trace context propagator.
+// The returned function flushes and stops the exporter
+func Init(ctx context.Context, env *models.Env) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	exporter, err := newExporter(ctx, env)
+	if err != nil || exporter == nil {
+		return func(context.Context) error { return nil }, err
+	}
+
+	tracerProvider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(newResource(env)),
+	)
+	otel.SetTracerProvider(tracerProvider)
+	log.Infof("Tracing is enabled with the exporter %s", env.TracesExporter)
+	return tracerProvider.Shutdown, nil
+}
+
+func newExporter(ctx context.Context, env *models.Env) (sdktrace.SpanExporter, error) {
+	switch env.TracesExporter {
+	case models.TracesExporterNone:
+		return nil, nil
+	case models.TracesExporterStdout:
+		return stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case models.TracesExporterFile:
+		file, err := os.OpenFile(env.TracesFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open the traces file %s: %w", env.TracesFile, err)
+		}
+		return stdouttrace.New(stdouttrace.WithWriter(file))
+	case models.TracesExporterOTLP:
+		// The endpoint and headers are configured with the OTEL_EXPORTER_OTLP_* environment variables
+		return otlptracehttp.New(ctx)
+	default:
+		return nil, fmt.Errorf("unsupported traces exporter %s", env.TracesExporter)
+	}
+}
+
+func newResource(env *models.Env) *resource.Resource {
+	return resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceNameKey.String(serviceName),
+		semconv.ServiceNamespaceKey.String(env.RadixAppName),
+		semconv.DeploymentEnvironmentKey.String(env.RadixEnv),
+		semconv.K8SNamespaceNameKey.String(env.RadixDeploymentNamespace),
+		semconv.K8SDeploymentNameKey.String(env.RadixComponentName),
+	)
+}
+
+// StartSpan Starts a child span of the span in ctx
+func StartSpan(ctx context.Context, name string, options ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, options...)
+}
+
+// EndSpan Records the error, when not nil, and ends the span
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
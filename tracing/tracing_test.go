@@ -0,0 +1,121 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/equinor/radix-job-scheduler-server/models"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+)
+
+func newEnv(exporter string) *models.Env {
+	env := models.NewEnv()
+	env.TracesExporter = exporter
+	return env
+}
+
+// useTracerProvider Sets the global tracer provider and propagator until the test ends
+func useTracerProvider(t *testing.T) {
+	tracerProvider, propagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(tracerProvider)
+		otel.SetTextMapPropagator(propagator)
+	})
+}
+
+func TestNewExporter(t *testing.T) {
+	ctx := context.Background()
+	exporter, err := newExporter(ctx, newEnv(models.TracesExporterNone))
+	assert.NoError(t, err)
+	assert.Nil(t, exporter)
+
+	for _, name := range []string{models.TracesExporterStdout, models.TracesExporterFile, models.TracesExporterOTLP} {
+		env := newEnv(name)
+		env.TracesFile = filepath.Join(t.TempDir(), "traces.json")
+		exporter, err = newExporter(ctx, env)
+		assert.NoError(t, err, name)
+		assert.NotNil(t, exporter, name)
+	}
+
+	_, err = newExporter(ctx, newEnv("jaeger"))
+	assert.EqualError(t, err, "unsupported traces exporter jaeger")
+
+	env := newEnv(models.TracesExporterFile)
+	env.TracesFile = filepath.Join(t.TempDir(), "missing", "traces.json")
+	_, err = newExporter(ctx, env)
+	assert.ErrorContains(t, err, "failed to open the traces file")
+}
+
+func TestInit_None(t *testing.T) {
+	useTracerProvider(t)
+	tracerProvider := otel.GetTracerProvider()
+
+	shutdown, err := Init(context.Background(), newEnv(models.TracesExporterNone))
+	assert.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+	assert.Equal(t, tracerProvider, otel.GetTracerProvider())
+	assert.ElementsMatch(t, propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}).Fields(), otel.GetTextMapPropagator().Fields())
+}
+
+func TestInit_File(t *testing.T) {
+	useTracerProvider(t)
+	env := newEnv(models.TracesExporterFile)
+	env.RadixComponentName = "compute"
+	env.TracesFile = filepath.Join(t.TempDir(), "traces.json")
+
+	shutdown, err := Init(context.Background(), env)
+	assert.NoError(t, err)
+	assert.IsType(t, &sdktrace.TracerProvider{}, otel.GetTracerProvider())
+	_, span := StartSpan(context.Background(), "JobHandler.GetJob")
+	EndSpan(span, nil)
+	assert.NoError(t, shutdown(context.Background()))
+
+	traces, err := os.ReadFile(env.TracesFile)
+	assert.NoError(t, err)
+	assert.Contains(t, string(traces), `"Name":"JobHandler.GetJob"`)
+	assert.Contains(t, string(traces), serviceName)
+	assert.Contains(t, string(traces), "compute")
+}
+
+func TestInit_UnsupportedExporter(t *testing.T) {
+	useTracerProvider(t)
+	tracerProvider := otel.GetTracerProvider()
+
+	shutdown, err := Init(context.Background(), newEnv("jaeger"))
+	assert.Error(t, err)
+	assert.NotNil(t, shutdown)
+	assert.Equal(t, tracerProvider, otel.GetTracerProvider())
+}
+
+func TestStartSpan(t *testing.T) {
+	useTracerProvider(t)
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	ctx, parent := StartSpan(context.Background(), "GET /api/v1/jobs/{jobName}")
+	_, child := StartSpan(ctx, "JobHandler.GetJob")
+	EndSpan(child, errors.New("job not found"))
+	EndSpan(parent, nil)
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 2)
+	assert.Equal(t, "JobHandler.GetJob", spans[0].Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), spans[0].Parent().SpanID())
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Equal(t, "job not found", spans[0].Status().Description)
+	assert.Len(t, spans[0].Events(), 1)
+	assert.Equal(t, semconv.ExceptionEventName, spans[0].Events()[0].Name)
+	assert.Equal(t, "GET /api/v1/jobs/{jobName}", spans[1].Name())
+	assert.False(t, spans[1].Parent().IsValid())
+	assert.Equal(t, codes.Unset, spans[1].Status().Code)
+	assert.Equal(t, tracerName, spans[1].InstrumentationLibrary().Name)
+}
@@ -0,0 +1,186 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	serverErrors "github.com/equinor/radix-job-scheduler-server/api/errors"
+	modelsV1 "github.com/equinor/radix-job-scheduler/models/v1"
+)
+
+const (
+	// StatusParam Query parameter with statuses to include, repeated or comma separated
+	StatusParam = "status"
+	// JobIdParam Query parameter with the job ID set in the job schedule description
+	JobIdParam = "jobId"
+	// BatchNameParam Query parameter with the name of the batch
+	BatchNameParam = "batchName"
+	// CreatedAfterParam Query parameter with the earliest creation time, RFC3339
+	CreatedAfterParam = "createdAfter"
+	// CreatedBeforeParam Query parameter with the latest creation time, RFC3339
+	CreatedBeforeParam = "createdBefore"
+	// EndedAfterParam Query parameter with the earliest end time, RFC3339
+	EndedAfterParam = "endedAfter"
+)
+
+// JobStatuses Statuses of jobs and batches
+var JobStatuses = []string{"Scheduled", "Queued", "Waiting", "Running", "Succeeded", "Failed", "Stopping", "Stopped"}
+
+// JobStatusFilter Filter of jobs and batches
+type JobStatusFilter struct {
+	Statuses      []string
+	JobId         string
+	BatchName     string
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	EndedAfter    *time.Time
+}
+
+// ParseJobStatusFilter Parses the filter query parameters of the request
+func ParseJobStatusFilter(r *http.Request) (*JobStatusFilter, error) {
+	query := r.URL.Query()
+	filter := JobStatusFilter{
+		JobId:     query.Get(JobIdParam),
+		BatchName: query.Get(BatchNameParam),
+	}
+
+	for _, values := range query[StatusParam] {
+		for _, value := range strings.Split(values, ",") {
+			if value = strings.TrimSpace(value); len(value) == 0 {
+				continue
+			}
+			status, ok := getJobStatus(value)
+			if !ok {
+				return nil, serverErrors.NewBadRequest(fmt.Sprintf("%s %s is not one of %s", StatusParam, value, strings.Join(JobStatuses, ", ")))
+			}
+			filter.Statuses = append(filter.Statuses, status)
+		}
+	}
+
+	var err error
+	if filter.CreatedAfter, err = parseTimeParam(r, CreatedAfterParam); err != nil {
+		return nil, err
+	}
+	if filter.CreatedBefore, err = parseTimeParam(r, CreatedBeforeParam); err != nil {
+		return nil, err
+	}
+	if filter.EndedAfter, err = parseTimeParam(r, EndedAfterParam); err != nil {
+		return nil, err
+	}
+	if filter.CreatedAfter != nil && filter.CreatedBefore != nil && filter.CreatedBefore.Before(*filter.CreatedAfter) {
+		return nil, serverErrors.NewBadRequest(fmt.Sprintf("%s must not be before %s", CreatedBeforeParam, CreatedAfterParam))
+	}
+	return &filter, nil
+}
+
+// Matches Returns true when the job matches all conditions of the filter
+func (filter *JobStatusFilter) Matches(jobStatus *modelsV1.JobStatus) bool {
+	if len(filter.JobId) > 0 && jobStatus.JobId != filter.JobId {
+		return false
+	}
+	if len(filter.BatchName) > 0 && jobStatus.BatchName != filter.BatchName {
+		return false
+	}
+	return filter.matchesStatusAndTime(jobStatus)
+}
+
+// MatchesBatch Returns true when the batch matches all conditions of the filter.
+// The job ID matches when any job in the batch has the job ID
+func (filter *JobStatusFilter) MatchesBatch(batchStatus *modelsV1.BatchStatus) bool {
+	if len(filter.BatchName) > 0 && batchStatus.Name != filter.BatchName {
+		return false
+	}
+	if len(filter.JobId) > 0 && !hasJobWithJobId(batchStatus, filter.JobId) {
+		return false
+	}
+	return filter.matchesStatusAndTime(&batchStatus.JobStatus)
+}
+
+// FilterJobStatuses Returns the jobs matching the filter
+func (filter *JobStatusFilter) FilterJobStatuses(jobStatuses []modelsV1.JobStatus) []modelsV1.JobStatus {
+	filtered := make([]modelsV1.JobStatus, 0, len(jobStatuses))
+	for i := range jobStatuses {
+		if filter.Matches(&jobStatuses[i]) {
+			filtered = append(filtered, jobStatuses[i])
+		}
+	}
+	return filtered
+}
+
+// FilterBatchStatuses Returns the batches matching the filter
+func (filter *JobStatusFilter) FilterBatchStatuses(batchStatuses []modelsV1.BatchStatus) []modelsV1.BatchStatus {
+	filtered := make([]modelsV1.BatchStatus, 0, len(batchStatuses))
+	for i := range batchStatuses {
+		if filter.MatchesBatch(&batchStatuses[i]) {
+			filtered = append(filtered, batchStatuses[i])
+		}
+	}
+	return filtered
+}
+
+func (filter *JobStatusFilter) matchesStatusAndTime(jobStatus *modelsV1.JobStatus) bool {
+	if len(filter.Statuses) > 0 && !containsStatus(filter.Statuses, jobStatus.Status) {
+		return false
+	}
+	if filter.CreatedAfter != nil && !isTimestampAfter(jobStatus.Created, *filter.CreatedAfter) {
+		return false
+	}
+	if filter.CreatedBefore != nil && !isTimestampBefore(jobStatus.Created, *filter.CreatedBefore) {
+		return false
+	}
+	if filter.EndedAfter != nil && !isTimestampAfter(jobStatus.Ended, *filter.EndedAfter) {
+		return false
+	}
+	return true
+}
+
+func getJobStatus(value string) (string, bool) {
+	for _, status := range JobStatuses {
+		if strings.EqualFold(status, value) {
+			return status, true
+		}
+	}
+	return "", false
+}
+
+func containsStatus(statuses []string, status string) bool {
+	for _, s := range statuses {
+		if strings.EqualFold(s, status) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasJobWithJobId(batchStatus *modelsV1.BatchStatus, jobId string) bool {
+	for _, jobStatus := range batchStatus.JobStatuses {
+		if jobStatus.JobId == jobId {
+			return true
+		}
+	}
+	return false
+}
+
+func parseTimeParam(r *http.Request, name string) (*time.Time, error) {
+	value := r.URL.Query().Get(name)
+	if len(value) == 0 {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, serverErrors.NewBadRequest(fmt.Sprintf("%s must be a RFC3339 timestamp, e.g. 2022-11-01T15:04:05Z", name))
+	}
+	return &t, nil
+}
+
+func isTimestampAfter(timestamp string, t time.Time) bool {
+	parsed, err := time.Parse(time.RFC3339, timestamp)
+	return err == nil && !parsed.Before(t)
+}
+
+func isTimestampBefore(timestamp string, t time.Time) bool {
+	parsed, err := time.Parse(time.RFC3339, timestamp)
+	return err == nil && !parsed.After(t)
+}
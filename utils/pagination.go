@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	serverErrors "github.com/equinor/radix-job-scheduler-server/api/errors"
+	modelsV1 "github.com/equinor/radix-job-scheduler/models/v1"
+)
+
+const (
+	// LimitParam Query parameter with the maximum number of items in a page
+	LimitParam = "limit"
+	// ContinueParam Query parameter with the continuation token from the previous page
+	ContinueParam = "continue"
+	// TotalCountHeader Header with the total number of items in all pages
+	TotalCountHeader = "X-Total-Count"
+
+	maxLimit = 1000
+)
+
+// PageRequest The requested page of a list
+type PageRequest struct {
+	// Limit Maximum number of items in the page, 0 for all items
+	Limit int
+	// after Sort key of the last item in the previous page
+	after string
+}
+
+type continueToken struct {
+	After string `json:"after"`
+}
+
+// ParsePageRequest Parses the limit and continue query parameters of the request
+func ParsePageRequest(r *http.Request) (*PageRequest, error) {
+	pageRequest := PageRequest{}
+	query := r.URL.Query()
+	if limit := query.Get(LimitParam); len(limit) > 0 {
+		value, err := strconv.Atoi(limit)
+		if err != nil || value < 1 || value > maxLimit {
+			return nil, serverErrors.NewBadRequest(fmt.Sprintf("%s must be a number between 1 and %d", LimitParam, maxLimit))
+		}
+		pageRequest.Limit = value
+	}
+	if token := query.Get(ContinueParam); len(token) > 0 {
+		after, err := decodeContinueToken(token)
+		if err != nil {
+			return nil, serverErrors.NewBadRequest(fmt.Sprintf("%s is not a valid continuation token", ContinueParam))
+		}
+		pageRequest.after = after
+	}
+	return &pageRequest, nil
+}
+
+// Page Returns the start and end index of the requested page, in a list of count items sorted by key.
+// The total count and the Link header to the next page, with the continuation token, are set in the response
+func (pageRequest *PageRequest) Page(w http.ResponseWriter, r *http.Request, count int, key func(i int) string) (int, int) {
+	start := sort.Search(count, func(i int) bool { return key(i) > pageRequest.after })
+	end := count
+	if pageRequest.Limit > 0 && start+pageRequest.Limit < count {
+		end = start + pageRequest.Limit
+		setNextLink(w, r, pageRequest.Limit, encodeContinueToken(key(end-1)))
+	}
+	w.Header().Set(TotalCountHeader, strconv.Itoa(count))
+	return start, end
+}
+
+// JobStatusSortKey Key sorting jobs and batches by creation time, then name
+func JobStatusSortKey(jobStatus *modelsV1.JobStatus) string {
+	return jobStatus.Created + "/" + jobStatus.Name
+}
+
+// SortJobStatuses Sorts the jobs by JobStatusSortKey
+func SortJobStatuses(jobStatuses []modelsV1.JobStatus) {
+	sort.SliceStable(jobStatuses, func(i, j int) bool {
+		return JobStatusSortKey(&jobStatuses[i]) < JobStatusSortKey(&jobStatuses[j])
+	})
+}
+
+// SortBatchStatuses Sorts the batches by JobStatusSortKey
+func SortBatchStatuses(batchStatuses []modelsV1.BatchStatus) {
+	sort.SliceStable(batchStatuses, func(i, j int) bool {
+		return JobStatusSortKey(&batchStatuses[i].JobStatus) < JobStatusSortKey(&batchStatuses[j].JobStatus)
+	})
+}
+
+func setNextLink(w http.ResponseWriter, r *http.Request, limit int, token string) {
+	nextURL := *r.URL
+	query := nextURL.Query()
+	query.Set(LimitParam, strconv.Itoa(limit))
+	query.Set(ContinueParam, token)
+	nextURL.RawQuery = query.Encode()
+	w.Header().Add("Link", fmt.Sprintf(`<%s>; rel="next"`, nextURL.RequestURI()))
+}
+
+func encodeContinueToken(after string) string {
+	body, _ := json.Marshal(continueToken{After: after})
+	return base64.RawURLEncoding.EncodeToString(body)
+}
+
+func decodeContinueToken(token string) (string, error) {
+	body, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return "", err
+	}
+	var decoded continueToken
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		return "", err
+	}
+	if len(decoded.After) == 0 {
+		return "", fmt.Errorf("empty continuation token")
+	}
+	return decoded.After, nil
+}
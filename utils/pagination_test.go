@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	serverErrors "github.com/equinor/radix-job-scheduler-server/api/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func newPageRequest(t *testing.T, query url.Values) (*PageRequest, error) {
+	request, err := http.NewRequest(http.MethodGet, "/api/v1/jobs?"+query.Encode(), nil)
+	assert.NoError(t, err)
+	return ParsePageRequest(request)
+}
+
+func TestParsePageRequest(t *testing.T) {
+	pageRequest, err := newPageRequest(t, url.Values{})
+	assert.NoError(t, err)
+	assert.Equal(t, &PageRequest{}, pageRequest)
+
+	for _, limit := range []int{1, maxLimit} {
+		pageRequest, err = newPageRequest(t, url.Values{LimitParam: {strconv.Itoa(limit)}})
+		assert.NoError(t, err)
+		assert.Equal(t, limit, pageRequest.Limit)
+	}
+
+	for _, limit := range []string{"0", "-1", strconv.Itoa(maxLimit + 1), "ten"} {
+		_, err = newPageRequest(t, url.Values{LimitParam: {limit}})
+		assert.Equal(t, http.StatusBadRequest, err.(*serverErrors.StatusError).Status().Code, limit)
+	}
+
+	pageRequest, err = newPageRequest(t, url.Values{ContinueParam: {encodeContinueToken("2022-11-01T12:00:00Z/job1")}})
+	assert.NoError(t, err)
+	assert.Equal(t, "2022-11-01T12:00:00Z/job1", pageRequest.after)
+}
+
+func TestParsePageRequest_InvalidContinueToken(t *testing.T) {
+	for name, token := range map[string]string{
+		"invalid base64": "not base64!",
+		"invalid JSON":   base64.RawURLEncoding.EncodeToString([]byte("after")),
+		"empty after":    base64.RawURLEncoding.EncodeToString([]byte(`{"after":""}`)),
+		"no after":       base64.RawURLEncoding.EncodeToString([]byte(`{}`)),
+	} {
+		_, err := newPageRequest(t, url.Values{ContinueParam: {token}})
+		assert.Equal(t, http.StatusBadRequest, err.(*serverErrors.StatusError).Status().Code, name)
+	}
+}
+
+func TestPageRequest_Page(t *testing.T) {
+	keys := []string{"a", "b", "c", "d", "e"}
+	key := func(i int) string { return keys[i] }
+	request := httptest.NewRequest(http.MethodGet, "/api/v1/jobs?status=Running&limit=2", nil)
+
+	recorder := httptest.NewRecorder()
+	start, end := (&PageRequest{Limit: 2}).Page(recorder, request, len(keys), key)
+	assert.Equal(t, 0, start)
+	assert.Equal(t, 2, end)
+	assert.Equal(t, "5", recorder.Header().Get(TotalCountHeader))
+	link := recorder.Header().Get("Link")
+	assert.True(t, strings.HasSuffix(link, `>; rel="next"`), link)
+	nextURL, err := url.Parse(link[strings.Index(link, "<")+1 : strings.Index(link, ">")])
+	assert.NoError(t, err)
+	assert.Equal(t, "Running", nextURL.Query().Get("status"))
+	after, err := decodeContinueToken(nextURL.Query().Get(ContinueParam))
+	assert.NoError(t, err)
+	assert.Equal(t, "b", after)
+
+	recorder = httptest.NewRecorder()
+	start, end = (&PageRequest{Limit: 2, after: "d"}).Page(recorder, request, len(keys), key)
+	assert.Equal(t, 4, start)
+	assert.Equal(t, 5, end)
+	assert.Empty(t, recorder.Header().Get("Link"))
+
+	recorder = httptest.NewRecorder()
+	start, end = (&PageRequest{}).Page(recorder, request, len(keys), key)
+	assert.Equal(t, 0, start)
+	assert.Equal(t, 5, end)
+	assert.Empty(t, recorder.Header().Get("Link"))
+}
@@ -1,21 +1,38 @@
 package utils
 
 import (
-	"github.com/equinor/radix-job-scheduler-server/models"
 	"net/http"
+	"time"
+
+	"github.com/equinor/radix-job-scheduler-server/metrics"
+	"github.com/equinor/radix-job-scheduler-server/models"
+	"github.com/equinor/radix-job-scheduler-server/tracing"
+	schedulerModels "github.com/equinor/radix-job-scheduler/models/common"
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/negroni/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // RadixMiddleware The middleware between router and radix handler functions
 type RadixMiddleware struct {
-	path    string
-	method  string
-	handler models.RadixHandlerFunc
+	path        string
+	method      string
+	permission  models.Permission
+	authorizer  models.Authorizer
+	rateLimiter models.RateLimiter
+	handler     models.RadixHandlerFunc
 }
 
-func NewRadixMiddleware(path, method string, handler models.RadixHandlerFunc) *RadixMiddleware {
+func NewRadixMiddleware(path, method string, permission models.Permission, authorizer models.Authorizer, rateLimiter models.RateLimiter, handler models.RadixHandlerFunc) *RadixMiddleware {
 	mw := &RadixMiddleware{
 		path,
 		method,
+		permission,
+		authorizer,
+		rateLimiter,
 		handler,
 	}
 
@@ -24,5 +41,59 @@ func NewRadixMiddleware(path, method string, handler models.RadixHandlerFunc) *R
 
 // Handle Wraps radix handler methods
 func (mw *RadixMiddleware) Handle(w http.ResponseWriter, r *http.Request) {
-	mw.handler(w, r)
+	start := time.Now()
+	setRoute(r.Context(), mw.path)
+	metrics.RequestStarted(mw.path, mw.method)
+	ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	ctx, span := tracing.StartSpan(ctx, mw.method+" "+mw.path,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(semconv.HTTPServerAttributesFromHTTPRequest("", mw.path, r)...))
+	rw := negroni.NewResponseWriter(w)
+	completed := false
+	defer func() {
+		statusCode := getStatusCode(rw, completed)
+		span.SetAttributes(semconv.HTTPAttributesFromHTTPStatusCode(statusCode)...)
+		span.SetStatus(semconv.SpanStatusFromHTTPStatusCodeAndSpanKind(statusCode, trace.SpanKindServer))
+		span.End()
+		metrics.RequestCompleted(mw.path, mw.method, statusCode, time.Since(start))
+	}()
+
+	r = r.WithContext(ctx)
+	if err := mw.rateLimiter.Limit(rw, r, mw.permission); err != nil {
+		log.WithContext(ctx).Warnf("rate limited: %v", err)
+		StatusResponse(rw, r, &schedulerModels.Status{
+			Status:  schedulerModels.StatusFailure,
+			Code:    http.StatusTooManyRequests,
+			Reason:  models.StatusReasonTooManyRequests,
+			Message: err.Error(),
+		})
+		completed = true
+		return
+	}
+	if err := mw.authorizer.Authorize(r, mw.permission); err != nil {
+		log.WithContext(ctx).Warnf("authorization failed: %v", err)
+		StatusResponse(rw, r, &schedulerModels.Status{
+			Status:  schedulerModels.StatusFailure,
+			Code:    http.StatusForbidden,
+			Reason:  models.StatusReasonForbidden,
+			Message: err.Error(),
+		})
+		completed = true
+		return
+	}
+
+	mw.handler(rw, r)
+	completed = true
+}
+
+// getStatusCode The status code sent to the client, also when the handler did not write a response or panicked
+func getStatusCode(rw negroni.ResponseWriter, completed bool) int {
+	switch {
+	case rw.Written():
+		return rw.Status()
+	case completed:
+		return http.StatusOK
+	default:
+		return http.StatusInternalServerError
+	}
 }
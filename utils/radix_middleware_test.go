@@ -0,0 +1,212 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/equinor/radix-job-scheduler-server/models"
+	"github.com/equinor/radix-job-scheduler-server/tracing"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type allowAllAuthorizer struct{}
+
+func (authorizer *allowAllAuthorizer) Authorize(*http.Request, models.Permission) error {
+	return nil
+}
+
+type noRateLimiter struct{}
+
+func (rateLimiter *noRateLimiter) Limit(http.ResponseWriter, *http.Request, models.Permission) error {
+	return nil
+}
+
+func getRequestsTotal(t *testing.T, route, method, code string) float64 {
+	metricFamilies, err := prometheus.DefaultGatherer.Gather()
+	assert.NoError(t, err)
+	for _, metricFamily := range metricFamilies {
+		if metricFamily.GetName() != "radix_job_scheduler_requests_total" {
+			continue
+		}
+		for _, metric := range metricFamily.GetMetric() {
+			labels := map[string]string{}
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			if labels["route"] == route && labels["method"] == method && labels["code"] == code {
+				return metric.GetCounter().GetValue()
+			}
+		}
+	}
+	return 0
+}
+
+func TestRadixMiddleware_RecordsRequestMetrics(t *testing.T) {
+	scenarios := []struct {
+		name         string
+		route        string
+		handler      func(w http.ResponseWriter, r *http.Request)
+		expectedCode string
+	}{
+		{
+			name:         "status code written by handler",
+			route:        "/api/v1/test/{name}",
+			handler:      func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNotFound) },
+			expectedCode: "404",
+		},
+		{
+			name:         "no response written by handler",
+			route:        "/api/v1/test/empty",
+			handler:      func(w http.ResponseWriter, r *http.Request) {},
+			expectedCode: "200",
+		},
+		{
+			name:         "handler panics",
+			route:        "/api/v1/test/panic",
+			handler:      func(w http.ResponseWriter, r *http.Request) { panic("failed") },
+			expectedCode: "500",
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			mw := NewRadixMiddleware(scenario.route, http.MethodGet, models.PermissionRead, &allowAllAuthorizer{}, &noRateLimiter{}, scenario.handler)
+			func() {
+				defer func() { recover() }()
+				mw.Handle(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+			}()
+			assert.Equal(t, float64(1), getRequestsTotal(t, scenario.route, http.MethodGet, scenario.expectedCode))
+		})
+	}
+}
+
+type denyAllAuthorizer struct{}
+
+func (authorizer *denyAllAuthorizer) Authorize(*http.Request, models.Permission) error {
+	return errors.New("forbidden")
+}
+
+func TestRadixMiddleware_Forbidden(t *testing.T) {
+	handled := false
+	mw := NewRadixMiddleware("/api/v1/test/forbidden", http.MethodPost, models.PermissionMutate, &denyAllAuthorizer{}, &noRateLimiter{},
+		func(w http.ResponseWriter, r *http.Request) { handled = true })
+	recorder := httptest.NewRecorder()
+	mw.Handle(recorder, httptest.NewRequest(http.MethodPost, "/api/v1/test/forbidden", nil))
+
+	assert.False(t, handled)
+	assert.Equal(t, http.StatusForbidden, recorder.Code)
+	var status models.Status
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &status))
+	assert.Equal(t, models.StatusReasonForbidden, status.Reason)
+}
+
+type exceededRateLimiter struct{}
+
+func (rateLimiter *exceededRateLimiter) Limit(w http.ResponseWriter, r *http.Request, permission models.Permission) error {
+	w.Header().Set("Retry-After", "2")
+	return errors.New("rate limit exceeded")
+}
+
+func TestRadixMiddleware_TooManyRequests(t *testing.T) {
+	handled := false
+	mw := NewRadixMiddleware("/api/v1/test/limited", http.MethodPost, models.PermissionMutate, &allowAllAuthorizer{}, &exceededRateLimiter{},
+		func(w http.ResponseWriter, r *http.Request) { handled = true })
+	recorder := httptest.NewRecorder()
+	mw.Handle(recorder, httptest.NewRequest(http.MethodPost, "/api/v1/test/limited", nil))
+
+	assert.False(t, handled)
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	assert.Equal(t, "2", recorder.Header().Get("Retry-After"))
+	var status models.Status
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &status))
+	assert.Equal(t, models.StatusReasonTooManyRequests, status.Reason)
+	assert.Equal(t, float64(1), getRequestsTotal(t, "/api/v1/test/limited", http.MethodPost, "429"))
+}
+
+// getAttributes The attributes of the span by key
+func getAttributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attributes := map[attribute.Key]attribute.Value{}
+	for _, keyValue := range span.Attributes() {
+		attributes[keyValue.Key] = keyValue.Value
+	}
+	return attributes
+}
+
+func TestRadixMiddleware_Traces(t *testing.T) {
+	tracerProvider, propagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	defer func() {
+		otel.SetTracerProvider(tracerProvider)
+		otel.SetTextMapPropagator(propagator)
+	}()
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	mw := NewRadixMiddleware("/api/v1/test/{name}", http.MethodGet, models.PermissionRead, &allowAllAuthorizer{}, &noRateLimiter{},
+		func(w http.ResponseWriter, r *http.Request) {
+			_, span := tracing.StartSpan(r.Context(), "JobHandler.GetJob")
+			tracing.EndSpan(span, errors.New("job not found"))
+			w.WriteHeader(http.StatusNotFound)
+		})
+	request := httptest.NewRequest(http.MethodGet, "/api/v1/test/job1", nil)
+	request.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	mw.Handle(httptest.NewRecorder(), request)
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 2)
+	if len(spans) != 2 {
+		return
+	}
+	child, route := spans[0], spans[1]
+	assert.Equal(t, "GET /api/v1/test/{name}", route.Name())
+	assert.Equal(t, trace.SpanKindServer, route.SpanKind())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", route.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", route.Parent().SpanID().String())
+	assert.True(t, route.Parent().IsRemote())
+	attributes := getAttributes(route)
+	assert.Equal(t, "/api/v1/test/{name}", attributes[semconv.HTTPRouteKey].AsString())
+	assert.Equal(t, http.MethodGet, attributes[semconv.HTTPMethodKey].AsString())
+	assert.Equal(t, int64(http.StatusNotFound), attributes[semconv.HTTPStatusCodeKey].AsInt64())
+	assert.Equal(t, codes.Unset, route.Status().Code)
+
+	assert.Equal(t, "JobHandler.GetJob", child.Name())
+	assert.Equal(t, route.SpanContext().SpanID(), child.Parent().SpanID())
+	assert.Equal(t, route.SpanContext().TraceID(), child.SpanContext().TraceID())
+	assert.Equal(t, codes.Error, child.Status().Code)
+}
+
+func TestRadixMiddleware_TracesPanic(t *testing.T) {
+	tracerProvider := otel.GetTracerProvider()
+	defer otel.SetTracerProvider(tracerProvider)
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	mw := NewRadixMiddleware("/api/v1/test/traced-panic", http.MethodPost, models.PermissionMutate, &allowAllAuthorizer{}, &noRateLimiter{},
+		func(w http.ResponseWriter, r *http.Request) { panic("failed") })
+	func() {
+		defer func() { recover() }()
+		mw.Handle(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/v1/test/traced-panic", nil))
+	}()
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 1)
+	if len(spans) != 1 {
+		return
+	}
+	assert.Equal(t, "POST /api/v1/test/traced-panic", spans[0].Name())
+	assert.False(t, spans[0].Parent().IsValid())
+	assert.Equal(t, int64(http.StatusInternalServerError), getAttributes(spans[0])[semconv.HTTPStatusCodeKey].AsInt64())
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+}
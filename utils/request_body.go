@@ -0,0 +1,349 @@
+package utils
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	serverErrors "github.com/equinor/radix-job-scheduler-server/api/errors"
+	"github.com/equinor/radix-job-scheduler-server/models"
+	"github.com/urfave/negroni/v2"
+)
+
+var (
+	unknownFieldPattern = regexp.MustCompile(`^json: unknown field "(.*)"$`)
+	unmarshalerType     = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// limitedBody A request body failing with a RequestEntityTooLarge error when more than maxBytes is read
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+	maxBytes  int64
+}
+
+func (body *limitedBody) Read(p []byte) (int, error) {
+	if body.remaining < 0 {
+		return 0, serverErrors.NewRequestEntityTooLarge(body.maxBytes)
+	}
+	// Read one byte more than allowed, to know if the body is too large
+	if int64(len(p)) > body.remaining+1 {
+		p = p[:body.remaining+1]
+	}
+	n, err := body.ReadCloser.Read(p)
+	body.remaining -= int64(n)
+	if body.remaining < 0 {
+		return n + int(body.remaining), serverErrors.NewRequestEntityTooLarge(body.maxBytes)
+	}
+	return n, err
+}
+
+// NewRequestBodyLimitMiddleware Rejects requests with a body larger than maxBytes. Requests with a larger Content-Length
+// get 413 right away, others fail with a RequestEntityTooLarge error when the handler reads more than maxBytes
+func NewRequestBodyLimitMiddleware(maxBytes int64) negroni.Handler {
+	return negroni.HandlerFunc(func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		if r.ContentLength > maxBytes {
+			StatusResponse(w, r, serverErrors.NewRequestEntityTooLarge(maxBytes).Status())
+			return
+		}
+		if r.Body != nil && r.Body != http.NoBody {
+			r.Body = &limitedBody{ReadCloser: r.Body, remaining: maxBytes, maxBytes: maxBytes}
+		}
+		next(w, r)
+	})
+}
+
+// DecodeRequestBody Decodes the JSON request body into v with DecodeJSON. An empty body leaves v unchanged
+func DecodeRequestBody(r *http.Request, v interface{}) error {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		var statusError *serverErrors.StatusError
+		if errors.As(err, &statusError) {
+			return statusError
+		}
+		return serverErrors.NewBadRequest(fmt.Sprintf("Failed to read the request body: %v", err))
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+	return DecodeJSON(body, v)
+}
+
+// DecodeJSON Decodes the JSON document into v.
+// Unknown fields and values of the wrong type are returned as an Invalid error with a cause for each field
+func DecodeJSON(data []byte, v interface{}) error {
+	checker := json.NewDecoder(bytes.NewReader(data))
+	checker.UseNumber()
+	var causes []models.StatusCause
+	if err := checkJSONValue(checker, reflect.TypeOf(v), "", &causes); err != nil {
+		var syntaxError *json.SyntaxError
+		if errors.As(err, &syntaxError) && syntaxError.Offset >= int64(len(data)) {
+			err = io.ErrUnexpectedEOF
+		}
+		return serverErrors.NewInvalidFields([]models.StatusCause{getDecodeErrorCause(err, reflect.TypeOf(v))})
+	}
+	if _, err := checker.Token(); err != io.EOF {
+		return serverErrors.NewInvalidFields([]models.StatusCause{{Message: "unexpected data after the JSON object"}})
+	}
+	if len(causes) > 0 {
+		return serverErrors.NewInvalidFields(causes)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return serverErrors.NewInvalidFields([]models.StatusCause{getDecodeErrorCause(err, reflect.TypeOf(v))})
+	}
+	return nil
+}
+
+// checkJSONValue Reads the next JSON value from the decoder, and adds a cause for each unknown field and each value
+// of the wrong type for t, with the JSON path of the field from path. Returns an error when the JSON is not valid.
+// Values of types decoding themselves, and of interface types, are left to the decoder
+func checkJSONValue(decoder *json.Decoder, t reflect.Type, path string, causes *[]models.StatusCause) error {
+	token, err := decoder.Token()
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return err
+	}
+	t = derefType(t)
+	if t != nil && (t.Kind() == reflect.Interface || reflect.PointerTo(t).Implements(unmarshalerType) || reflect.PointerTo(t).Implements(textUnmarshalerType)) {
+		t = nil
+	}
+
+	var actual string
+	switch token := token.(type) {
+	case nil:
+		return nil
+	case json.Delim:
+		if token == '[' {
+			return checkJSONArray(decoder, t, path, causes)
+		}
+		return checkJSONObject(decoder, t, path, causes)
+	case string:
+		actual = "string"
+		if t == nil || t.Kind() == reflect.String || (t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8) {
+			return nil
+		}
+	case bool:
+		actual = "bool"
+		if t == nil || t.Kind() == reflect.Bool {
+			return nil
+		}
+	case json.Number:
+		actual = "number"
+		if t == nil || isNumber(t, token) {
+			return nil
+		}
+		if isNumberKind(t.Kind()) {
+			*causes = append(*causes, models.StatusCause{Field: path, Message: fmt.Sprintf("must be %s, not %s", getNumberType(t), token)})
+			return nil
+		}
+	}
+	*causes = append(*causes, models.StatusCause{Field: path, Message: fmt.Sprintf("must be %s, not %s", getJSONType(t), actual)})
+	return nil
+}
+
+func checkJSONArray(decoder *json.Decoder, t reflect.Type, path string, causes *[]models.StatusCause) error {
+	var elemType reflect.Type
+	switch {
+	case t == nil:
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		elemType = t.Elem()
+	default:
+		*causes = append(*causes, models.StatusCause{Field: path, Message: fmt.Sprintf("must be %s, not array", getJSONType(t))})
+	}
+	for i := 0; decoder.More(); i++ {
+		if err := checkJSONValue(decoder, elemType, fmt.Sprintf("%s[%d]", path, i), causes); err != nil {
+			return err
+		}
+	}
+	_, err := decoder.Token()
+	return err
+}
+
+func checkJSONObject(decoder *json.Decoder, t reflect.Type, path string, causes *[]models.StatusCause) error {
+	isStruct, isMap := t != nil && t.Kind() == reflect.Struct, t != nil && t.Kind() == reflect.Map
+	if t != nil && !isStruct && !isMap {
+		*causes = append(*causes, models.StatusCause{Field: path, Message: fmt.Sprintf("must be %s, not object", getJSONType(t))})
+		t = nil
+	}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		key, _ := token.(string)
+		name, fieldType := key, reflect.Type(nil)
+		switch {
+		case isStruct:
+			if name, fieldType = getJSONField(t, key); fieldType == nil {
+				*causes = append(*causes, models.StatusCause{Field: joinPath(path, key), Message: "unknown field"})
+			}
+		case isMap:
+			fieldType = t.Elem()
+		}
+		if err := checkJSONValue(decoder, fieldType, joinPath(path, name), causes); err != nil {
+			return err
+		}
+	}
+	_, err := decoder.Token()
+	return err
+}
+
+func joinPath(path, field string) string {
+	if len(path) == 0 {
+		return field
+	}
+	return path + "." + field
+}
+
+func isNumberKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// isNumber The number can be decoded into a value of type t
+func isNumber(t reflect.Type, number json.Number) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		_, err := strconv.ParseInt(number.String(), 10, t.Bits())
+		return err == nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		_, err := strconv.ParseUint(number.String(), 10, t.Bits())
+		return err == nil
+	case reflect.Float32, reflect.Float64:
+		_, err := strconv.ParseFloat(number.String(), t.Bits())
+		return err == nil
+	}
+	return false
+}
+
+// getNumberType The numbers accepted by the number type t
+func getNumberType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return fmt.Sprintf("an integer between %d and %d", int64(-1)<<(t.Bits()-1), int64(1)<<(t.Bits()-1)-1)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return fmt.Sprintf("an integer between 0 and %d", uint64(1)<<t.Bits()-1)
+	}
+	return "a number in range"
+}
+
+func getDecodeErrorCause(err error, t reflect.Type) models.StatusCause {
+	var typeError *json.UnmarshalTypeError
+	var syntaxError *json.SyntaxError
+	switch {
+	case errors.As(err, &typeError):
+		return models.StatusCause{Field: getJSONPath(t, typeError.Field), Message: fmt.Sprintf("must be %s, not %s", getJSONType(typeError.Type), typeError.Value)}
+	case errors.As(err, &syntaxError):
+		return models.StatusCause{Message: fmt.Sprintf("invalid JSON at offset %d: %v", syntaxError.Offset, syntaxError)}
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return models.StatusCause{Message: "invalid JSON: unexpected end of the request body"}
+	}
+	if match := unknownFieldPattern.FindStringSubmatch(err.Error()); match != nil {
+		return models.StatusCause{Field: match[1], Message: "unknown field"}
+	}
+	return models.StatusCause{Message: err.Error()}
+}
+
+// getJSONPath Converts the field of a type error, the keys from the root of the decoded type t separated by dots, to the
+// path of the field as in validation causes, with the JSON names of the fields and the indexes of arrays in brackets,
+// e.g. jobScheduleDescriptions[1].timeLimitSeconds. Indexes are left out when the field of the error has none
+func getJSONPath(t reflect.Type, field string) string {
+	if len(field) == 0 {
+		return field
+	}
+	var path string
+	for _, key := range strings.Split(field, ".") {
+		t = derefType(t)
+		if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+			t = derefType(t.Elem())
+			if _, err := strconv.Atoi(key); err == nil {
+				path += "[" + key + "]"
+				continue
+			}
+		}
+		name := key
+		switch {
+		case t != nil && t.Kind() == reflect.Struct:
+			name, t = getJSONField(t, key)
+		case t != nil && t.Kind() == reflect.Map:
+			t = t.Elem()
+		default:
+			t = nil
+		}
+		if len(path) > 0 {
+			name = "." + name
+		}
+		path += name
+	}
+	return path
+}
+
+// getJSONField Gets the JSON name and the type of the field of the struct matching the key, as the key is matched
+// by the decoder, also in embedded structs. Returns the key and nil when there is no such field
+func getJSONField(t reflect.Type, key string) (string, reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" || (!field.IsExported() && !field.Anonymous) {
+			continue
+		}
+		if field.Anonymous && len(name) == 0 {
+			if embedded := derefType(field.Type); embedded.Kind() == reflect.Struct {
+				if name, fieldType := getJSONField(embedded, key); fieldType != nil {
+					return name, fieldType
+				}
+				continue
+			}
+		}
+		if len(name) == 0 {
+			name = field.Name
+		}
+		if strings.EqualFold(name, key) {
+			return name, field.Type
+		}
+	}
+	return key, nil
+}
+
+func derefType(t reflect.Type) reflect.Type {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+// getJSONType The JSON type of the Go type expected for the field
+func getJSONType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Map, reflect.Struct:
+		return "an object"
+	}
+	return t.String()
+}
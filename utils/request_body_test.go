@@ -0,0 +1,151 @@
+package utils
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	serverErrors "github.com/equinor/radix-job-scheduler-server/api/errors"
+	"github.com/equinor/radix-job-scheduler-server/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/negroni/v2"
+)
+
+type decodedRequest struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func decodeBody(body string) (*decodedRequest, error) {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	var request decodedRequest
+	err := DecodeRequestBody(r, &request)
+	return &request, err
+}
+
+func getCauses(t *testing.T, err error) []models.StatusCause {
+	var statusError *serverErrors.StatusError
+	if !assert.True(t, errors.As(err, &statusError)) {
+		return nil
+	}
+	assert.Equal(t, http.StatusUnprocessableEntity, statusError.Status().Code)
+	return statusError.Causes
+}
+
+func TestDecodeRequestBody(t *testing.T) {
+	request, err := decodeBody(`{"name": "a", "count": 1}`)
+	assert.NoError(t, err)
+	assert.Equal(t, decodedRequest{Name: "a", Count: 1}, *request)
+
+	request, err = decodeBody("  \n")
+	assert.NoError(t, err)
+	assert.Equal(t, decodedRequest{}, *request)
+
+	_, err = decodeBody(`{"name": "a", "size": 1}`)
+	assert.Equal(t, []models.StatusCause{{Field: "size", Message: "unknown field"}}, getCauses(t, err))
+
+	_, err = decodeBody(`{"name": "a", "count": "1"}`)
+	assert.Equal(t, []models.StatusCause{{Field: "count", Message: "must be a number, not string"}}, getCauses(t, err))
+
+	_, err = decodeBody(`{"name": "a"} {}`)
+	assert.Equal(t, []models.StatusCause{{Message: "unexpected data after the JSON object"}}, getCauses(t, err))
+
+	_, err = decodeBody(`{"name": "a",}`)
+	causes := getCauses(t, err)
+	assert.Len(t, causes, 1)
+	assert.Contains(t, causes[0].Message, "invalid JSON")
+
+	_, err = decodeBody(`{"name": "a"`)
+	assert.Equal(t, []models.StatusCause{{Message: "invalid JSON: unexpected end of the request body"}}, getCauses(t, err))
+}
+
+type decodedConfig struct {
+	TimeLimitSeconds *int64           `json:"timeLimitSeconds"`
+	Resources        map[string]int64 `json:"resources"`
+}
+
+type decodedJob struct {
+	decodedConfig
+	Payload string `json:"payload"`
+}
+
+type decodedBatch struct {
+	Jobs     []decodedJob   `json:"jobScheduleDescriptions"`
+	Defaults *decodedConfig `json:"defaultConfig"`
+}
+
+func TestDecodeJSON_TypeErrorPath(t *testing.T) {
+	for field, expected := range map[string]string{
+		"":                        "",
+		"JobScheduleDescriptions": "jobScheduleDescriptions",
+		"jobScheduleDescriptions.1.timeLimitSeconds": "jobScheduleDescriptions[1].timeLimitSeconds",
+		"jobScheduleDescriptions.timeLimitSeconds":   "jobScheduleDescriptions.timeLimitSeconds",
+		"jobScheduleDescriptions.0.resources.memory": "jobScheduleDescriptions[0].resources.memory",
+		"DefaultConfig.TimeLimitSeconds":             "defaultConfig.timeLimitSeconds",
+		"unknown.field":                              "unknown.field",
+	} {
+		assert.Equal(t, expected, getJSONPath(reflect.TypeOf(&decodedBatch{}), field), field)
+	}
+
+	var batch decodedBatch
+	err := DecodeJSON([]byte(`{"jobScheduleDescriptions": [{}, {"payload": 1}]}`), &batch)
+	assert.Equal(t, []models.StatusCause{{Field: "jobScheduleDescriptions[1].payload", Message: "must be a string, not number"}}, getCauses(t, err))
+}
+
+func TestDecodeJSON_AllCauses(t *testing.T) {
+	var batch decodedBatch
+	err := DecodeJSON([]byte(`{
+		"jobScheduleDescriptions": [
+			{"payload": "a", "foo": 1, "timeLimitSeconds": 1.5},
+			{"TimeLimitSeconds": "10", "resources": {"memory": 1, "cpu": {}}}
+		],
+		"defaultConfig": [],
+		"bar": {"baz": []}
+	}`), &batch)
+	assert.Equal(t, []models.StatusCause{
+		{Field: "jobScheduleDescriptions[0].foo", Message: "unknown field"},
+		{Field: "jobScheduleDescriptions[0].timeLimitSeconds", Message: "must be an integer between -9223372036854775808 and 9223372036854775807, not 1.5"},
+		{Field: "jobScheduleDescriptions[1].timeLimitSeconds", Message: "must be a number, not string"},
+		{Field: "jobScheduleDescriptions[1].resources.cpu", Message: "must be a number, not object"},
+		{Field: "defaultConfig", Message: "must be an object, not array"},
+		{Field: "bar", Message: "unknown field"},
+	}, getCauses(t, err))
+
+	err = DecodeJSON([]byte(`{"jobScheduleDescriptions": [{"payload": "a", "resources": {"memory": 64}}, null], "defaultConfig": null}`), &batch)
+	assert.NoError(t, err)
+	assert.Equal(t, "a", batch.Jobs[0].Payload)
+	assert.Equal(t, int64(64), batch.Jobs[0].Resources["memory"])
+}
+
+func TestRequestBodyLimitMiddleware(t *testing.T) {
+	var readErr error
+	n := negroni.New(NewRequestBodyLimitMiddleware(10))
+	n.UseHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, readErr = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	recorder := httptest.NewRecorder()
+	n.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("0123456789")))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.NoError(t, readErr)
+
+	// The Content-Length is known, and the request is rejected before the handler
+	recorder = httptest.NewRecorder()
+	readErr = nil
+	n.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("0123456789a")))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+
+	// The Content-Length is unknown, and reading the body fails
+	request := httptest.NewRequest(http.MethodPost, "/", io.NopCloser(strings.NewReader("0123456789a")))
+	request.ContentLength = -1
+	recorder = httptest.NewRecorder()
+	n.ServeHTTP(recorder, request)
+	var statusError *serverErrors.StatusError
+	assert.True(t, errors.As(readErr, &statusError))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, statusError.Status().Code)
+}
@@ -0,0 +1,127 @@
+package utils
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/negroni/v2"
+)
+
+// RequestIDHeader Header holding the ID of a request, received from the client or generated by the server
+const RequestIDHeader = "X-Request-ID"
+
+const (
+	maxRequestIDLength = 128
+	// unmatchedRoute Route of API requests rejected before they are routed, e.g. by authentication
+	unmatchedRoute = "unmatched"
+)
+
+type requestContextKey struct{}
+
+// requestInfo Information about the request being served, shared by the middlewares and the handlers
+type requestInfo struct {
+	requestID string
+	route     string
+}
+
+// NewRequestContextMiddleware Assigns a request ID to each request, echoes it in the response
+// and writes an access log entry when the request is handled
+func NewRequestContextMiddleware() negroni.Handler {
+	return negroni.HandlerFunc(func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		start := time.Now()
+		info := requestInfo{requestID: getOrCreateRequestID(r)}
+		w.Header().Set(RequestIDHeader, info.requestID)
+
+		rw := negroni.NewResponseWriter(w)
+		next(rw, r.WithContext(context.WithValue(r.Context(), requestContextKey{}, &info)))
+		logAccess(r, rw, &info, time.Since(start))
+	})
+}
+
+// NewUnmatchedRouteMiddleware Logs the API requests rejected before they reach a route, like failed authentications,
+// with the route unmatched. The route is replaced by the route template when the request is routed
+func NewUnmatchedRouteMiddleware() negroni.Handler {
+	return negroni.HandlerFunc(func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		setRoute(r.Context(), unmatchedRoute)
+		next(w, r)
+	})
+}
+
+// GetRequestID Gets the ID of the request being served, or an empty string when ctx does not belong to a request
+func GetRequestID(ctx context.Context) string {
+	if info := getRequestInfo(ctx); info != nil {
+		return info.requestID
+	}
+	return ""
+}
+
+func getRequestInfo(ctx context.Context) *requestInfo {
+	if ctx == nil {
+		return nil
+	}
+	info, _ := ctx.Value(requestContextKey{}).(*requestInfo)
+	return info
+}
+
+func setRoute(ctx context.Context, route string) {
+	if info := getRequestInfo(ctx); info != nil {
+		info.route = route
+	}
+}
+
+func getOrCreateRequestID(r *http.Request) string {
+	if requestID := r.Header.Get(RequestIDHeader); isValidRequestID(requestID) {
+		return requestID
+	}
+	return uuid.New().String()
+}
+
+func isValidRequestID(requestID string) bool {
+	if len(requestID) == 0 || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range requestID {
+		if c <= ' ' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func logAccess(r *http.Request, rw negroni.ResponseWriter, info *requestInfo, duration time.Duration) {
+	entry := log.WithFields(log.Fields{
+		"requestId":  info.requestID,
+		"method":     r.Method,
+		"path":       r.URL.Path,
+		"status":     getStatusCode(rw, true),
+		"durationMs": duration.Milliseconds(),
+		"bytes":      rw.Size(),
+		"remoteAddr": r.RemoteAddr,
+		"userAgent":  r.UserAgent(),
+	})
+	if len(info.route) == 0 {
+		// Requests outside the API, like probes, metrics and swagger, are only logged when debugging
+		entry.Debug("request handled")
+		return
+	}
+	entry.WithField("route", info.route).Info("request handled")
+}
+
+// RequestIDHook Adds the request ID to log entries created with a request context, e.g. log.WithContext(r.Context())
+type RequestIDHook struct{}
+
+// Levels All levels are supported
+func (hook *RequestIDHook) Levels() []log.Level {
+	return log.AllLevels
+}
+
+// Fire Adds the request ID field to the entry
+func (hook *RequestIDHook) Fire(entry *log.Entry) error {
+	if requestID := GetRequestID(entry.Context); len(requestID) > 0 {
+		entry.Data["requestId"] = requestID
+	}
+	return nil
+}
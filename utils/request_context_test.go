@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/negroni/v2"
+)
+
+func executeWithRequestContext(requestID string, handler http.HandlerFunc) (*httptest.ResponseRecorder, string) {
+	var handledRequestID string
+	n := negroni.New(NewRequestContextMiddleware())
+	n.UseHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handledRequestID = GetRequestID(r.Context())
+		handler(w, r)
+	})
+	request := httptest.NewRequest(http.MethodGet, "/api/v1/jobs", nil)
+	if len(requestID) > 0 {
+		request.Header.Set(RequestIDHeader, requestID)
+	}
+	recorder := httptest.NewRecorder()
+	n.ServeHTTP(recorder, request)
+	return recorder, handledRequestID
+}
+
+func TestRequestContextMiddleware(t *testing.T) {
+	t.Run("request ID from client is echoed", func(t *testing.T) {
+		t.Parallel()
+		recorder, handledRequestID := executeWithRequestContext("client-request-1", func(w http.ResponseWriter, r *http.Request) {})
+		assert.Equal(t, "client-request-1", handledRequestID)
+		assert.Equal(t, "client-request-1", recorder.Header().Get(RequestIDHeader))
+	})
+
+	t.Run("request ID is generated when missing", func(t *testing.T) {
+		t.Parallel()
+		recorder, handledRequestID := executeWithRequestContext("", func(w http.ResponseWriter, r *http.Request) {})
+		assert.NotEmpty(t, handledRequestID)
+		assert.Equal(t, handledRequestID, recorder.Header().Get(RequestIDHeader))
+	})
+
+	t.Run("invalid request ID from client is replaced", func(t *testing.T) {
+		t.Parallel()
+		invalidRequestID := strings.Repeat("x", maxRequestIDLength+1)
+		recorder, handledRequestID := executeWithRequestContext(invalidRequestID, func(w http.ResponseWriter, r *http.Request) {})
+		assert.NotEmpty(t, handledRequestID)
+		assert.NotEqual(t, invalidRequestID, handledRequestID)
+		assert.Equal(t, handledRequestID, recorder.Header().Get(RequestIDHeader))
+	})
+}
+
+func TestRequestIDHook(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	logger.AddHook(&RequestIDHook{})
+
+	executeWithRequestContext("request-with-logs", func(w http.ResponseWriter, r *http.Request) {
+		logger.WithContext(r.Context()).Info("handling request")
+	})
+	logger.Info("outside request")
+
+	entries := hook.AllEntries()
+	assert.Len(t, entries, 2)
+	assert.Equal(t, "request-with-logs", entries[0].Data["requestId"])
+	assert.NotContains(t, entries[1].Data, "requestId")
+	assert.Equal(t, log.InfoLevel, entries[0].Level)
+}
+
+func TestUnmatchedRouteMiddleware(t *testing.T) {
+	hook := test.NewGlobal()
+	defer log.StandardLogger().ReplaceHooks(log.LevelHooks{})
+	n := negroni.New(NewRequestContextMiddleware(), NewUnmatchedRouteMiddleware())
+	n.UseHandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusUnauthorized) })
+	n.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/jobs", nil))
+
+	entries := hook.AllEntries()
+	assert.Len(t, entries, 1)
+	assert.Equal(t, log.InfoLevel, entries[0].Level)
+	assert.Equal(t, unmatchedRoute, entries[0].Data["route"])
+	assert.Equal(t, http.StatusUnauthorized, entries[0].Data["status"])
+}
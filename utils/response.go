@@ -3,11 +3,19 @@ package utils
 import (
 	"encoding/json"
 	"net/http"
+	"time"
 
-	models "github.com/equinor/radix-job-scheduler/models/common"
+	commonUtils "github.com/equinor/radix-common/utils"
+	"github.com/equinor/radix-job-scheduler-server/models"
+	schedulerModels "github.com/equinor/radix-job-scheduler/models/common"
 )
 
 func JSONResponse(w http.ResponseWriter, result interface{}) {
+	JSONResponseWithStatusCode(w, http.StatusOK, result)
+}
+
+// JSONResponseWithStatusCode Writes the result as JSON with the status code
+func JSONResponseWithStatusCode(w http.ResponseWriter, statusCode int, result interface{}) {
 	body, err := json.Marshal(result)
 	if err != nil {
 		WriteResponse(w, http.StatusInternalServerError)
@@ -15,12 +23,18 @@ func JSONResponse(w http.ResponseWriter, result interface{}) {
 	}
 
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	w.WriteHeader(http.StatusOK)
+	w.WriteHeader(statusCode)
 	w.Write(body)
 }
 
-func StatusResponse(w http.ResponseWriter, status *models.Status) {
-	body, err := json.Marshal(status)
+// StatusResponse Writes the status with the ID of the request, the time of the response and the invalid fields of the request, if any
+func StatusResponse(w http.ResponseWriter, r *http.Request, status *schedulerModels.Status, causes ...models.StatusCause) {
+	body, err := json.Marshal(models.Status{
+		Status:    *status,
+		RequestID: GetRequestID(r.Context()),
+		Timestamp: commonUtils.FormatTimestamp(time.Now()),
+		Causes:    causes,
+	})
 	if err != nil {
 		WriteResponse(w, http.StatusInternalServerError)
 		return
@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/equinor/radix-job-scheduler-server/models"
+	schedulerModels "github.com/equinor/radix-job-scheduler/models/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatusResponse(t *testing.T) {
+	status := schedulerModels.Status{
+		Status:  schedulerModels.StatusFailure,
+		Code:    http.StatusNotFound,
+		Reason:  schedulerModels.StatusReasonNotFound,
+		Message: "job not found",
+	}
+	recorder, requestID := executeWithRequestContext("request-1", func(w http.ResponseWriter, r *http.Request) {
+		StatusResponse(w, r, &status)
+	})
+
+	assert.Equal(t, "request-1", requestID)
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	var returnedStatus models.Status
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &returnedStatus))
+	assert.Equal(t, status, returnedStatus.Status)
+	assert.Equal(t, "request-1", returnedStatus.RequestID)
+	timestamp, err := time.Parse(time.RFC3339, returnedStatus.Timestamp)
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), timestamp, time.Minute)
+}
@@ -0,0 +1,126 @@
+package validation
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/equinor/radix-job-scheduler-server/models"
+	schedulerModels "github.com/equinor/radix-job-scheduler/models/common"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+const payloadSchemaURL = "payload-schema.json"
+
+// PayloadSchema JSON Schema the payloads of the jobs must match
+type PayloadSchema struct {
+	schema   *jsonschema.Schema
+	document json.RawMessage
+	// required Jobs without a payload are not valid, as the schema has the annotation x-payload-required
+	required bool
+}
+
+// schemaAnnotations Annotations of the payload schema, which are ignored by JSON Schema
+type schemaAnnotations struct {
+	PayloadRequired bool `json:"x-payload-required"`
+}
+
+// NewPayloadSchema Loads the payload schema from the file, or from the JSON document when the file name is empty.
+// Returns nil when neither is set, and payloads are not validated
+func NewPayloadSchema(fileName, document string) (*PayloadSchema, error) {
+	data := []byte(document)
+	if len(fileName) > 0 {
+		var err error
+		if data, err = os.ReadFile(fileName); err != nil {
+			return nil, fmt.Errorf("failed to read the payload schema: %w", err)
+		}
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
+
+	compiler := jsonschema.NewCompiler()
+	compiler.Draft = jsonschema.Draft2020
+	compiler.AssertFormat = true
+	if err := compiler.AddResource(payloadSchemaURL, bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("failed to parse the payload schema: %w", err)
+	}
+	schema, err := compiler.Compile(payloadSchemaURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid payload schema: %w", err)
+	}
+	// A boolean schema has no annotations
+	var annotations schemaAnnotations
+	_ = json.Unmarshal(data, &annotations)
+	return &PayloadSchema{schema: schema, document: data, required: annotations.PayloadRequired}, nil
+}
+
+// Document The JSON document of the schema
+func (payloadSchema *PayloadSchema) Document() json.RawMessage {
+	return payloadSchema.document
+}
+
+// ValidateJobPayload Gets the violations of the schema by the payload of the job, with paths relative to path.
+// Nothing is validated when there is no schema, or when the job has no payload and the schema does not require it
+func (payloadSchema *PayloadSchema) ValidateJobPayload(path string, description *schedulerModels.JobScheduleDescription) []models.StatusCause {
+	if payloadSchema == nil {
+		return nil
+	}
+	field := join(path, "payload")
+	if len(strings.TrimSpace(description.Payload)) == 0 {
+		if payloadSchema.required {
+			return []models.StatusCause{{Field: field, Message: "is required"}}
+		}
+		return nil
+	}
+	decoder := json.NewDecoder(strings.NewReader(description.Payload))
+	decoder.UseNumber()
+	var payload interface{}
+	if err := decoder.Decode(&payload); err != nil {
+		return []models.StatusCause{{Field: field, Message: "must be a JSON document matching the payload schema"}}
+	}
+	if _, err := decoder.Token(); err == nil {
+		return []models.StatusCause{{Field: field, Message: "must be a single JSON document matching the payload schema"}}
+	}
+
+	err := payloadSchema.schema.Validate(payload)
+	if err == nil {
+		return nil
+	}
+	validationError, ok := err.(*jsonschema.ValidationError)
+	if !ok {
+		return []models.StatusCause{{Field: field, Message: err.Error()}}
+	}
+	var causes []models.StatusCause
+	for _, leaf := range getLeafErrors(validationError) {
+		location := leaf.InstanceLocation
+		if len(location) == 0 {
+			location = "/"
+		}
+		causes = append(causes, models.StatusCause{Field: field, Message: fmt.Sprintf("at %s: %s", location, leaf.Message)})
+	}
+	return causes
+}
+
+// ValidateBatchPayloads Gets the violations of the schema by the payloads of the jobs in the batch, by the index of each job
+func (payloadSchema *PayloadSchema) ValidateBatchPayloads(description *schedulerModels.BatchScheduleDescription) []models.StatusCause {
+	var causes []models.StatusCause
+	for i := range description.JobScheduleDescriptions {
+		causes = append(causes, payloadSchema.ValidateJobPayload(fmt.Sprintf("jobScheduleDescriptions[%d]", i), &description.JobScheduleDescriptions[i])...)
+	}
+	return causes
+}
+
+// getLeafErrors The errors without causes, which tell what is wrong in the payload
+func getLeafErrors(validationError *jsonschema.ValidationError) []*jsonschema.ValidationError {
+	if len(validationError.Causes) == 0 {
+		return []*jsonschema.ValidationError{validationError}
+	}
+	var leaves []*jsonschema.ValidationError
+	for _, cause := range validationError.Causes {
+		leaves = append(leaves, getLeafErrors(cause)...)
+	}
+	return leaves
+}
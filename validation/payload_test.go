@@ -0,0 +1,101 @@
+package validation
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/equinor/radix-job-scheduler-server/models"
+	schedulerModels "github.com/equinor/radix-job-scheduler/models/common"
+	"github.com/stretchr/testify/assert"
+)
+
+const testPayloadSchema = `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"type": "object",
+	"properties": {
+		"name": {"type": "string"},
+		"count": {"type": "integer", "minimum": 1}
+	},
+	"required": ["name"],
+	"additionalProperties": false
+}`
+
+func TestNewPayloadSchema(t *testing.T) {
+	payloadSchema, err := NewPayloadSchema("", "")
+	assert.NoError(t, err)
+	assert.Nil(t, payloadSchema)
+
+	payloadSchema, err = NewPayloadSchema("", testPayloadSchema)
+	assert.NoError(t, err)
+	assert.JSONEq(t, testPayloadSchema, string(payloadSchema.Document()))
+
+	fileName := filepath.Join(t.TempDir(), "schema.json")
+	assert.NoError(t, os.WriteFile(fileName, []byte(testPayloadSchema), 0o600))
+	payloadSchema, err = NewPayloadSchema(fileName, `{"type": "string"}`)
+	assert.NoError(t, err)
+	assert.JSONEq(t, testPayloadSchema, string(payloadSchema.Document()))
+
+	_, err = NewPayloadSchema(filepath.Join(t.TempDir(), "missing.json"), "")
+	assert.Error(t, err)
+	_, err = NewPayloadSchema("", `{"type": 1}`)
+	assert.Error(t, err)
+	_, err = NewPayloadSchema("", `{"type": `)
+	assert.Error(t, err)
+}
+
+func TestValidateJobPayload(t *testing.T) {
+	payloadSchema, err := NewPayloadSchema("", testPayloadSchema)
+	assert.NoError(t, err)
+
+	scenarios := []struct {
+		payload string
+		causes  []models.StatusCause
+	}{
+		{payload: `{"name": "a", "count": 2}`},
+		{payload: `{"name": "a", "count": 0, "size": 1}`, causes: []models.StatusCause{
+			{Field: "payload", Message: "at /: additionalProperties 'size' not allowed"},
+			{Field: "payload", Message: "at /count: must be >= 1 but found 0"},
+		}},
+		{payload: `{"count": 1.5}`, causes: []models.StatusCause{
+			{Field: "payload", Message: "at /: missing properties: 'name'"},
+			{Field: "payload", Message: "at /count: expected integer, but got number"},
+		}},
+		{payload: ``},
+		{payload: ` `},
+		{payload: `name=a`, causes: []models.StatusCause{{Field: "payload", Message: "must be a JSON document matching the payload schema"}}},
+		{payload: `{"name": "a"} {}`, causes: []models.StatusCause{{Field: "payload", Message: "must be a single JSON document matching the payload schema"}}},
+	}
+	for _, scenario := range scenarios {
+		causes := payloadSchema.ValidateJobPayload("", &schedulerModels.JobScheduleDescription{Payload: scenario.payload})
+		assert.ElementsMatch(t, scenario.causes, causes, scenario.payload)
+	}
+
+	requiredSchema, err := NewPayloadSchema("", `{"x-payload-required": true, "type": "object"}`)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.StatusCause{{Field: "payload", Message: "is required"}},
+		requiredSchema.ValidateJobPayload("", &schedulerModels.JobScheduleDescription{}))
+	assert.Empty(t, requiredSchema.ValidateJobPayload("", &schedulerModels.JobScheduleDescription{Payload: "{}"}))
+
+	// Without a schema any payload is valid
+	var noSchema *PayloadSchema
+	assert.Empty(t, noSchema.ValidateJobPayload("", &schedulerModels.JobScheduleDescription{Payload: "name=a"}))
+}
+
+func TestValidateBatchPayloads(t *testing.T) {
+	payloadSchema, err := NewPayloadSchema("", testPayloadSchema)
+	assert.NoError(t, err)
+
+	batch := schedulerModels.BatchScheduleDescription{
+		JobScheduleDescriptions: []schedulerModels.JobScheduleDescription{
+			{Payload: `{"name": "a"}`},
+			{Payload: `{"name": 1}`},
+			{Payload: `{"name": "c"}`},
+			{Payload: `[]`},
+		},
+	}
+	assert.Equal(t, []models.StatusCause{
+		{Field: "jobScheduleDescriptions[1].payload", Message: "at /name: expected string, but got number"},
+		{Field: "jobScheduleDescriptions[3].payload", Message: "at /: expected object, but got array"},
+	}, payloadSchema.ValidateBatchPayloads(&batch))
+}
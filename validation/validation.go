@@ -0,0 +1,77 @@
+package validation
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/equinor/radix-job-scheduler-server/models"
+	schedulerModels "github.com/equinor/radix-job-scheduler/models/common"
+	v1 "github.com/equinor/radix-operator/pkg/apis/radix/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// ValidateJobScheduleDescription Gets the invalid fields of the job, with paths relative to path
+func ValidateJobScheduleDescription(path string, description *schedulerModels.JobScheduleDescription) []models.StatusCause {
+	return ValidateRadixJobComponentConfig(path, &description.RadixJobComponentConfig)
+}
+
+// ValidateBatchScheduleDescription Gets the invalid fields of the batch and its jobs
+func ValidateBatchScheduleDescription(description *schedulerModels.BatchScheduleDescription) []models.StatusCause {
+	var causes []models.StatusCause
+	if len(description.JobScheduleDescriptions) == 0 {
+		causes = append(causes, models.StatusCause{Field: "jobScheduleDescriptions", Message: "must have at least one job"})
+	}
+	if description.DefaultRadixJobComponentConfig != nil {
+		causes = append(causes, ValidateRadixJobComponentConfig("defaultRadixJobComponentConfig", description.DefaultRadixJobComponentConfig)...)
+	}
+	for i := range description.JobScheduleDescriptions {
+		causes = append(causes, ValidateJobScheduleDescription(fmt.Sprintf("jobScheduleDescriptions[%d]", i), &description.JobScheduleDescriptions[i])...)
+	}
+	return causes
+}
+
+// ValidateRadixJobComponentConfig Gets the invalid fields of the config, with paths relative to path
+func ValidateRadixJobComponentConfig(path string, config *schedulerModels.RadixJobComponentConfig) []models.StatusCause {
+	var causes []models.StatusCause
+	if config.TimeLimitSeconds != nil && *config.TimeLimitSeconds <= 0 {
+		causes = append(causes, models.StatusCause{Field: join(path, "timeLimitSeconds"), Message: "must be a positive number of seconds"})
+	}
+	if config.Resources != nil {
+		causes = append(causes, validateResourceList(join(path, "resources.limits"), config.Resources.Limits)...)
+		causes = append(causes, validateResourceList(join(path, "resources.requests"), config.Resources.Requests)...)
+	}
+	if config.Node != nil && len(config.Node.GpuCount) > 0 {
+		if count, err := strconv.Atoi(config.Node.GpuCount); err != nil || count <= 0 {
+			causes = append(causes, models.StatusCause{Field: join(path, "node.gpuCount"), Message: fmt.Sprintf("must be a positive integer, not %q", config.Node.GpuCount)})
+		}
+	}
+	return causes
+}
+
+func validateResourceList(path string, resources v1.ResourceList) []models.StatusCause {
+	names := make([]string, 0, len(resources))
+	for name := range resources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var causes []models.StatusCause
+	for _, name := range names {
+		quantity, err := resource.ParseQuantity(resources[name])
+		switch {
+		case err != nil:
+			causes = append(causes, models.StatusCause{Field: join(path, name), Message: fmt.Sprintf("%q is not a valid quantity, e.g. 100m or 256Mi", resources[name])})
+		case quantity.Sign() < 0:
+			causes = append(causes, models.StatusCause{Field: join(path, name), Message: "must not be negative"})
+		}
+	}
+	return causes
+}
+
+func join(path, field string) string {
+	if len(path) == 0 {
+		return field
+	}
+	return path + "." + field
+}
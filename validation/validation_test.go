@@ -0,0 +1,65 @@
+package validation
+
+import (
+	"testing"
+
+	"github.com/equinor/radix-job-scheduler-server/models"
+	schedulerModels "github.com/equinor/radix-job-scheduler/models/common"
+	v1 "github.com/equinor/radix-operator/pkg/apis/radix/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func int64Ptr(value int64) *int64 {
+	return &value
+}
+
+func TestValidateJobScheduleDescription(t *testing.T) {
+	valid := schedulerModels.JobScheduleDescription{
+		Payload: "a_payload",
+		RadixJobComponentConfig: schedulerModels.RadixJobComponentConfig{
+			TimeLimitSeconds: int64Ptr(60),
+			Resources: &v1.ResourceRequirements{
+				Limits:   v1.ResourceList{"cpu": "1", "memory": "1Gi"},
+				Requests: v1.ResourceList{"cpu": "100m", "memory": "256M"},
+			},
+			Node: &v1.RadixNode{Gpu: "nvidia-v100", GpuCount: "2"},
+		},
+	}
+	assert.Empty(t, ValidateJobScheduleDescription("", &valid))
+
+	invalid := schedulerModels.JobScheduleDescription{
+		RadixJobComponentConfig: schedulerModels.RadixJobComponentConfig{
+			TimeLimitSeconds: int64Ptr(-5),
+			Resources: &v1.ResourceRequirements{
+				Limits:   v1.ResourceList{"memory": "1 GB", "cpu": "-1"},
+				Requests: v1.ResourceList{"cpu": "abc"},
+			},
+			Node: &v1.RadixNode{GpuCount: "two"},
+		},
+	}
+	assert.Equal(t, []models.StatusCause{
+		{Field: "timeLimitSeconds", Message: "must be a positive number of seconds"},
+		{Field: "resources.limits.cpu", Message: "must not be negative"},
+		{Field: "resources.limits.memory", Message: `"1 GB" is not a valid quantity, e.g. 100m or 256Mi`},
+		{Field: "resources.requests.cpu", Message: `"abc" is not a valid quantity, e.g. 100m or 256Mi`},
+		{Field: "node.gpuCount", Message: `must be a positive integer, not "two"`},
+	}, ValidateJobScheduleDescription("", &invalid))
+}
+
+func TestValidateBatchScheduleDescription(t *testing.T) {
+	assert.Equal(t, []models.StatusCause{
+		{Field: "jobScheduleDescriptions", Message: "must have at least one job"},
+	}, ValidateBatchScheduleDescription(&schedulerModels.BatchScheduleDescription{}))
+
+	batch := schedulerModels.BatchScheduleDescription{
+		DefaultRadixJobComponentConfig: &schedulerModels.RadixJobComponentConfig{TimeLimitSeconds: int64Ptr(0)},
+		JobScheduleDescriptions: []schedulerModels.JobScheduleDescription{
+			{Payload: "a_payload"},
+			{RadixJobComponentConfig: schedulerModels.RadixJobComponentConfig{TimeLimitSeconds: int64Ptr(-1)}},
+		},
+	}
+	assert.Equal(t, []models.StatusCause{
+		{Field: "defaultRadixJobComponentConfig.timeLimitSeconds", Message: "must be a positive number of seconds"},
+		{Field: "jobScheduleDescriptions[1].timeLimitSeconds", Message: "must be a positive number of seconds"},
+	}, ValidateBatchScheduleDescription(&batch))
+}
@@ -0,0 +1,600 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	commonUtils "github.com/equinor/radix-common/utils"
+	serverErrors "github.com/equinor/radix-job-scheduler-server/api/errors"
+	"github.com/equinor/radix-job-scheduler-server/events"
+	"github.com/equinor/radix-job-scheduler-server/metrics"
+	"github.com/equinor/radix-job-scheduler-server/models"
+	"github.com/equinor/radix-job-scheduler-server/store"
+	batchApi "github.com/equinor/radix-job-scheduler/api/v1/batches"
+	jobApi "github.com/equinor/radix-job-scheduler/api/v1/jobs"
+	modelsV1 "github.com/equinor/radix-job-scheduler/models/v1"
+	"github.com/equinor/radix-operator/pkg/apis/kube"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+const (
+	// KindJob Callback of a job
+	KindJob = "job"
+	// KindBatch Callback of a batch
+	KindBatch = "batch"
+
+	// EventJobCompleted Event of a callback when a job is completed
+	EventJobCompleted = "job.completed"
+	// EventBatchCompleted Event of a callback when a batch is completed
+	EventBatchCompleted = "batch.completed"
+
+	// EventHeader Header with the event of a callback
+	EventHeader = "X-Radix-Event"
+	// DeliveryHeader Header with the ID of a callback, the same in all attempts to deliver it
+	DeliveryHeader = "X-Radix-Delivery"
+	// SignatureHeader Header with the HMAC-SHA256 signature of the payload, sha256=<hex>, when the callback has a secret
+	SignatureHeader = "X-Radix-Signature-256"
+
+	resultDelivered = "delivered"
+	resultRetry     = "retry"
+	resultFailed    = "failed"
+
+	defaultMaxAttempts    = 10
+	defaultMinBackoff     = 5 * time.Second
+	defaultMaxBackoff     = 10 * time.Minute
+	defaultResyncInterval = time.Minute
+	defaultTimeout        = 10 * time.Second
+	defaultWorkers        = 4
+	deliveryInterval      = time.Second
+	checkQueueSize        = 1000
+	deliveryQueueSize     = 1000
+)
+
+// internalHostSuffixes Suffixes of the names of hosts in the cluster or in the local network
+var internalHostSuffixes = []string{".localhost", ".local", ".internal", ".svc"}
+
+// Payload Body of a callback
+// swagger:model CallbackPayload
+type Payload struct {
+	// Event of the callback, job.completed or batch.completed
+	//
+	// required: true
+	Event string `json:"event"`
+
+	// Time the job or batch was found completed
+	//
+	// required: true
+	Timestamp string `json:"timestamp"`
+
+	// Final status of the job, for job.completed
+	//
+	// required: false
+	Job *modelsV1.JobStatus `json:"job,omitempty"`
+
+	// Final status of the batch, for batch.completed
+	//
+	// required: false
+	Batch *modelsV1.BatchStatus `json:"batch,omitempty"`
+}
+
+// registration A callback of a job or batch, stored until it is delivered or all attempts have failed
+type registration struct {
+	Kind            string `json:"kind"`
+	Name            string `json:"name"`
+	models.Callback `json:",inline"`
+	DeliveryID      string `json:"deliveryId"`
+	// Pending The callback of a pending job or batch, which is moved to the job or batch created for it
+	Pending bool `json:"pending,omitempty"`
+	// Payload The payload to deliver, set when the job or batch is completed
+	Payload     json.RawMessage `json:"payload,omitempty"`
+	Attempts    int             `json:"attempts"`
+	NextAttempt time.Time       `json:"nextAttempt"`
+}
+
+// checkRequest A check of a callback, with the Kubernetes job of the watch event which queued it, if any
+type checkRequest struct {
+	key     string
+	job     *batchv1.Job
+	deleted bool
+}
+
+// Notifier Delivers callbacks when jobs and batches are completed
+type Notifier struct {
+	store        store.Store
+	jobHandler   jobApi.JobHandler
+	batchHandler batchApi.BatchHandler
+	jobWatcher   *events.JobWatcher
+	client       *http.Client
+	// MaxAttempts Maximum number of attempts to deliver a callback
+	MaxAttempts int
+	// MinBackoff Delay after the first failed attempt, doubled after each failed attempt up to MaxBackoff
+	MinBackoff time.Duration
+	// MaxBackoff Maximum delay between attempts
+	MaxBackoff time.Duration
+	// ResyncInterval Interval between checks of all jobs and batches with callbacks, in case changes were missed by the watch
+	ResyncInterval time.Duration
+	// Workers Number of callbacks delivered at the same time, apart from the checks of the jobs and batches
+	Workers    int
+	now        func() time.Time
+	checks     chan checkRequest
+	deliveries chan string
+	// delivering The callbacks queued for delivery or being delivered, so a callback is not delivered twice at the same time
+	delivering map[string]bool
+	mu         sync.Mutex
+}
+
+// NewNotifier Constructor
+func NewNotifier(store store.Store, jobHandler jobApi.JobHandler, batchHandler batchApi.BatchHandler, jobWatcher *events.JobWatcher) *Notifier {
+	return &Notifier{
+		store:          store,
+		jobHandler:     jobHandler,
+		batchHandler:   batchHandler,
+		jobWatcher:     jobWatcher,
+		client:         newClient(),
+		MaxAttempts:    defaultMaxAttempts,
+		MinBackoff:     defaultMinBackoff,
+		MaxBackoff:     defaultMaxBackoff,
+		ResyncInterval: defaultResyncInterval,
+		Workers:        defaultWorkers,
+		now:            time.Now,
+		checks:         make(chan checkRequest, checkQueueSize),
+		deliveries:     make(chan string, deliveryQueueSize),
+		delivering:     make(map[string]bool),
+	}
+}
+
+// ValidateCallback Gets the invalid fields of the callback: a URL which is not an absolute http or https URL, a URL of
+// an internal host, or a secret without a URL
+func ValidateCallback(callback *models.Callback) []models.StatusCause {
+	if len(callback.CallbackURL) == 0 {
+		if len(callback.CallbackSecret) > 0 {
+			return []models.StatusCause{{Field: "callbackSecret", Message: "is set without callbackUrl"}}
+		}
+		return nil
+	}
+	callbackURL, err := url.Parse(callback.CallbackURL)
+	if err != nil || (callbackURL.Scheme != "http" && callbackURL.Scheme != "https") || len(callbackURL.Host) == 0 {
+		return []models.StatusCause{{Field: "callbackUrl", Message: "must be an absolute http or https URL"}}
+	}
+	if isInternalHost(callbackURL.Hostname()) {
+		return []models.StatusCause{{Field: "callbackUrl", Message: "must not be a loopback, link-local, private or cluster-internal host"}}
+	}
+	return nil
+}
+
+// isInternalHost Returns true when the host is the local host, a name without a domain, a name in the cluster or in
+// the local network, or an address which is not public
+func isInternalHost(host string) bool {
+	if ip := net.ParseIP(host); ip != nil {
+		return isInternalIP(ip)
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "localhost" || !strings.Contains(host, ".") {
+		return true
+	}
+	for _, suffix := range internalHostSuffixes {
+		if strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isInternalIP Returns true when the address is a loopback, link-local, private or unspecified address
+func isInternalIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+		ip.IsPrivate() || ip.IsUnspecified()
+}
+
+// newClient The client of the callbacks. It does not connect to internal addresses, which a public host name may
+// resolve to, or a callback URL may redirect to
+func newClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: defaultTimeout,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || isInternalIP(ip) {
+				return fmt.Errorf("the callback URL resolves to the internal address %s", host)
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout: defaultTimeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			ForceAttemptHTTP2:   true,
+			TLSHandshakeTimeout: defaultTimeout,
+		},
+	}
+}
+
+// Register Stores the callback of the job or batch, delivered when it is completed. Does nothing when the callback has no URL
+func (notifier *Notifier) Register(kind, name string, callback *models.Callback) error {
+	if len(callback.CallbackURL) == 0 {
+		return nil
+	}
+	reg := registration{
+		Kind:       kind,
+		Name:       name,
+		Callback:   *callback,
+		DeliveryID: uuid.New().String(),
+	}
+	if err := notifier.store.Put(getKey(kind, name), &reg); err != nil {
+		return err
+	}
+	notifier.queueCheck(checkRequest{key: getKey(kind, name)})
+	return nil
+}
+
+// RegisterPending Stores the callback of a pending job or batch, so its secret is not kept in the pending item. It is
+// moved to the created job or batch by Release. Does nothing when the callback has no URL
+func (notifier *Notifier) RegisterPending(kind, name string, callback *models.Callback) error {
+	if len(callback.CallbackURL) == 0 {
+		return nil
+	}
+	reg := registration{
+		Kind:       kind,
+		Name:       name,
+		Callback:   *callback,
+		DeliveryID: uuid.New().String(),
+		Pending:    true,
+	}
+	return notifier.store.Put(getKey(kind, name), &reg)
+}
+
+// Release Moves the callback of a pending job or batch to the job or batch created for it. Does nothing when the
+// pending job or batch has no callback
+func (notifier *Notifier) Release(kind, pendingName, name string) error {
+	pendingKey := getKey(kind, pendingName)
+	var reg registration
+	if found, err := notifier.store.Get(pendingKey, &reg); err != nil || !found {
+		return err
+	}
+	reg.Name = name
+	reg.Pending = false
+	if err := notifier.store.Put(getKey(kind, name), &reg); err != nil {
+		return err
+	}
+	notifier.queueCheck(checkRequest{key: getKey(kind, name)})
+	return notifier.store.Delete(pendingKey)
+}
+
+// Unregister Deletes the callback of a cancelled pending job or batch
+func (notifier *Notifier) Unregister(kind, name string) error {
+	return notifier.store.Delete(getKey(kind, name))
+}
+
+// Run Watches the jobs and delivers the callbacks until the context is done, and then waits for the deliveries in progress
+func (notifier *Notifier) Run(ctx context.Context) {
+	var workers sync.WaitGroup
+	defer workers.Wait()
+	for i := 0; i < notifier.Workers; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			notifier.runWorker(ctx)
+		}()
+	}
+	go notifier.jobWatcher.WatchChanges(ctx, func(eventType watch.EventType, job *batchv1.Job) {
+		deleted := eventType == watch.Deleted
+		notifier.queueCheck(checkRequest{key: getKey(KindJob, job.Name), job: job, deleted: deleted})
+		notifier.queueCheck(checkRequest{key: getKey(KindBatch, job.Name), job: job, deleted: deleted})
+		if batchName, ok := job.Labels[kube.RadixBatchNameLabel]; ok && batchName != job.Name {
+			notifier.queueCheck(checkRequest{key: getKey(KindBatch, batchName), job: job, deleted: deleted})
+		}
+	})
+
+	notifier.checkAll()
+	resync := time.NewTicker(notifier.ResyncInterval)
+	defer resync.Stop()
+	delivery := time.NewTicker(deliveryInterval)
+	defer delivery.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case request := <-notifier.checks:
+			notifier.check(request)
+		case <-resync.C:
+			notifier.checkAll()
+		case <-delivery.C:
+			notifier.deliverDue()
+		}
+	}
+}
+
+// runWorker Delivers the queued callbacks until the context is done
+func (notifier *Notifier) runWorker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case key := <-notifier.deliveries:
+			notifier.deliverQueued(ctx, key)
+		}
+	}
+}
+
+// queueCheck Queues a check of a callback. The check is dropped when the queue is full, and done by the next resync
+func (notifier *Notifier) queueCheck(request checkRequest) {
+	select {
+	case notifier.checks <- request:
+	default:
+	}
+}
+
+func (notifier *Notifier) checkAll() {
+	keys, err := notifier.store.Keys()
+	if err != nil {
+		log.Errorf("failed to read callbacks: %v", err)
+		return
+	}
+	for _, key := range keys {
+		notifier.queueCheck(checkRequest{key: key})
+	}
+}
+
+// check Sets the payload of the callback when the job or batch is completed, and queues its delivery. When the job or
+// batch was already removed, the payload is built from the Kubernetes job of the watch event which queued the check
+func (notifier *Notifier) check(request checkRequest) {
+	key := request.key
+	var reg registration
+	if found, err := notifier.store.Get(key, &reg); err != nil || !found || reg.Pending || reg.Payload != nil {
+		return
+	}
+	payload, err := notifier.getPayload(&reg)
+	if serverErrors.IsNotFound(err) && request.job != nil {
+		payload, err = notifier.getEventPayload(&reg, request.job, request.deleted), nil
+		if payload == nil {
+			return
+		}
+	}
+	switch {
+	case serverErrors.IsNotFound(err):
+		log.Warnf("%s %s was deleted before it was completed, dropping its callback", reg.Kind, reg.Name)
+		notifier.delete(key)
+		return
+	case err != nil:
+		log.Warnf("failed to get the status of the %s %s: %v", reg.Kind, reg.Name, err)
+		return
+	case payload == nil:
+		return
+	}
+	if reg.Payload, err = json.Marshal(payload); err != nil {
+		log.Errorf("failed to create the callback of the %s %s: %v", reg.Kind, reg.Name, err)
+		return
+	}
+	reg.NextAttempt = notifier.now()
+	if err := notifier.store.Put(key, &reg); err != nil {
+		log.Errorf("failed to save the callback of the %s %s: %v", reg.Kind, reg.Name, err)
+		return
+	}
+	notifier.queueDelivery(key)
+}
+
+// getPayload Gets the payload of the callback, or nil when the job or batch is not completed
+func (notifier *Notifier) getPayload(reg *registration) (*Payload, error) {
+	payload := Payload{Timestamp: commonUtils.FormatTimestamp(notifier.now())}
+	var status string
+	switch reg.Kind {
+	case KindBatch:
+		batch, err := notifier.batchHandler.GetBatch(reg.Name)
+		if err != nil {
+			return nil, err
+		}
+		payload.Event, payload.Batch, status = EventBatchCompleted, batch, batch.Status
+	default:
+		job, err := notifier.jobHandler.GetJob(reg.Name)
+		if err != nil {
+			return nil, err
+		}
+		payload.Event, payload.Job, status = EventJobCompleted, job, job.Status
+	}
+	if !events.IsTerminal(status) {
+		return nil, nil
+	}
+	return &payload, nil
+}
+
+// getEventPayload Gets the payload of the callback from the Kubernetes job of a watch event, when it is the job, or the
+// job of the batch, and it is completed or deleted. Returns nil otherwise
+func (notifier *Notifier) getEventPayload(reg *registration, job *batchv1.Job, deleted bool) *Payload {
+	if job.Name != reg.Name {
+		return nil
+	}
+	jobStatus := notifier.getJobStatus(job, deleted)
+	if !events.IsTerminal(jobStatus.Status) {
+		return nil
+	}
+	payload := Payload{Timestamp: commonUtils.FormatTimestamp(notifier.now())}
+	if reg.Kind == KindBatch {
+		jobStatus.BatchName = ""
+		payload.Event, payload.Batch = EventBatchCompleted, &modelsV1.BatchStatus{JobStatus: *jobStatus}
+	} else {
+		payload.Event, payload.Job = EventJobCompleted, jobStatus
+	}
+	return &payload
+}
+
+// getJobStatus The status of the Kubernetes job: Succeeded or Failed by its conditions, otherwise Stopped when it was
+// deleted
+func (notifier *Notifier) getJobStatus(job *batchv1.Job, deleted bool) *modelsV1.JobStatus {
+	jobStatus := modelsV1.JobStatus{
+		Name:      job.Name,
+		BatchName: job.Labels[kube.RadixBatchNameLabel],
+		Created:   commonUtils.FormatTimestamp(job.CreationTimestamp.Time),
+	}
+	if job.Status.StartTime != nil {
+		jobStatus.Started = commonUtils.FormatTimestamp(job.Status.StartTime.Time)
+	}
+	for _, condition := range job.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case batchv1.JobComplete:
+			jobStatus.Status = "Succeeded"
+		case batchv1.JobFailed:
+			jobStatus.Status = "Failed"
+		default:
+			continue
+		}
+		jobStatus.Ended = commonUtils.FormatTimestamp(condition.LastTransitionTime.Time)
+		jobStatus.Message = condition.Message
+	}
+	if len(jobStatus.Status) == 0 && deleted {
+		jobStatus.Status = "Stopped"
+		jobStatus.Ended = commonUtils.FormatTimestamp(notifier.now())
+	}
+	return &jobStatus
+}
+
+// deliverDue Queues the delivery of the callbacks whose next attempt is due
+func (notifier *Notifier) deliverDue() {
+	keys, err := notifier.store.Keys()
+	if err != nil {
+		log.Errorf("failed to read callbacks: %v", err)
+		return
+	}
+	now := notifier.now()
+	for _, key := range keys {
+		var reg registration
+		if found, err := notifier.store.Get(key, &reg); err != nil || !found || reg.Payload == nil || now.Before(reg.NextAttempt) {
+			continue
+		}
+		notifier.queueDelivery(key)
+	}
+}
+
+// queueDelivery Queues the delivery of the callback with the key, unless it is already queued or being delivered.
+// The delivery is dropped when the queue is full, and queued again when the next attempt is due
+func (notifier *Notifier) queueDelivery(key string) {
+	notifier.mu.Lock()
+	defer notifier.mu.Unlock()
+	if notifier.delivering[key] {
+		return
+	}
+	select {
+	case notifier.deliveries <- key:
+		notifier.delivering[key] = true
+	default:
+	}
+}
+
+// deliverQueued Delivers the queued callback with the key, when its next attempt is due
+func (notifier *Notifier) deliverQueued(ctx context.Context, key string) {
+	defer func() {
+		notifier.mu.Lock()
+		defer notifier.mu.Unlock()
+		delete(notifier.delivering, key)
+	}()
+	var reg registration
+	if found, err := notifier.store.Get(key, &reg); err != nil || !found || reg.Payload == nil || notifier.now().Before(reg.NextAttempt) {
+		return
+	}
+	notifier.deliver(ctx, key, &reg)
+}
+
+// deliver Sends the callback. It is deleted when delivered or after the last attempt, otherwise the next attempt is scheduled
+func (notifier *Notifier) deliver(ctx context.Context, key string, reg *registration) {
+	err := notifier.send(ctx, reg)
+	reg.Attempts++
+	switch {
+	case err == nil:
+		metrics.AddCallbackDelivery(reg.Kind, resultDelivered)
+		log.Infof("delivered the callback of the %s %s to %s", reg.Kind, reg.Name, reg.CallbackURL)
+		notifier.delete(key)
+	case reg.Attempts >= notifier.MaxAttempts:
+		metrics.AddCallbackDelivery(reg.Kind, resultFailed)
+		log.Errorf("failed to deliver the callback of the %s %s to %s after %d attempts: %v", reg.Kind, reg.Name, reg.CallbackURL, reg.Attempts, err)
+		notifier.delete(key)
+	default:
+		metrics.AddCallbackDelivery(reg.Kind, resultRetry)
+		reg.NextAttempt = notifier.now().Add(notifier.getBackoff(reg.Attempts))
+		log.Warnf("failed to deliver the callback of the %s %s to %s, attempt %d, retrying at %s: %v", reg.Kind, reg.Name, reg.CallbackURL, reg.Attempts, commonUtils.FormatTimestamp(reg.NextAttempt), err)
+		if err := notifier.store.Put(key, reg); err != nil {
+			log.Errorf("failed to save the callback of the %s %s: %v", reg.Kind, reg.Name, err)
+		}
+	}
+}
+
+func (notifier *Notifier) send(ctx context.Context, reg *registration) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, reg.CallbackURL, bytes.NewReader(reg.Payload))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "radix-job-scheduler-server")
+	request.Header.Set(EventHeader, getEvent(reg.Kind))
+	request.Header.Set(DeliveryHeader, reg.DeliveryID)
+	if len(reg.CallbackSecret) > 0 {
+		request.Header.Set(SignatureHeader, Sign([]byte(reg.CallbackSecret), reg.Payload))
+	}
+	response, err := notifier.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(response.Body, 64*1024))
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("the callback URL responded with status code %d", response.StatusCode)
+	}
+	return nil
+}
+
+// getBackoff The delay after the failed attempt
+func (notifier *Notifier) getBackoff(attempts int) time.Duration {
+	backoff := notifier.MinBackoff
+	for i := 1; i < attempts && backoff < notifier.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > notifier.MaxBackoff {
+		return notifier.MaxBackoff
+	}
+	return backoff
+}
+
+func (notifier *Notifier) delete(key string) {
+	if err := notifier.store.Delete(key); err != nil {
+		log.Errorf("failed to delete the callback %s: %v", key, err)
+	}
+}
+
+// Sign Gets the HMAC-SHA256 signature of the payload with the secret, as sha256=<hex>
+func Sign(secret, payload []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func getKey(kind, name string) string {
+	return kind + "/" + name
+}
+
+func getEvent(kind string) string {
+	if kind == KindBatch {
+		return EventBatchCompleted
+	}
+	return EventJobCompleted
+}
@@ -0,0 +1,361 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/equinor/radix-job-scheduler-server/models"
+	"github.com/equinor/radix-job-scheduler-server/store"
+	apiErrors "github.com/equinor/radix-job-scheduler/api/errors"
+	batchMock "github.com/equinor/radix-job-scheduler/api/v1/batches/mock"
+	jobMock "github.com/equinor/radix-job-scheduler/api/v1/jobs/mock"
+	modelsV1 "github.com/equinor/radix-job-scheduler/models/v1"
+	"github.com/equinor/radix-operator/pkg/apis/kube"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type receivedCallback struct {
+	header  http.Header
+	payload []byte
+}
+
+// receiver A callback URL responding with the status codes in turn, and 200 after the last one
+type receiver struct {
+	server      *httptest.Server
+	statusCodes []int
+	received    []receivedCallback
+	mu          sync.Mutex
+}
+
+func newReceiver(t *testing.T, statusCodes ...int) *receiver {
+	r := &receiver{statusCodes: statusCodes}
+	r.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		payload, _ := io.ReadAll(req.Body)
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.received = append(r.received, receivedCallback{header: req.Header, payload: payload})
+		statusCode := http.StatusOK
+		if len(r.received) <= len(r.statusCodes) {
+			statusCode = r.statusCodes[len(r.received)-1]
+		}
+		w.WriteHeader(statusCode)
+	}))
+	t.Cleanup(r.server.Close)
+	return r
+}
+
+func (r *receiver) getReceived() []receivedCallback {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]receivedCallback{}, r.received...)
+}
+
+func setupNotifier(ctrl *gomock.Controller) (*Notifier, *jobMock.MockJobHandler, *batchMock.MockBatchHandler, *time.Time) {
+	jobHandler := jobMock.NewMockJobHandler(ctrl)
+	batchHandler := batchMock.NewMockBatchHandler(ctrl)
+	notifier := NewNotifier(store.NewMemoryStore(), jobHandler, batchHandler, nil)
+	// The receivers of the tests listen on the loopback address, which the client of the notifier does not connect to
+	notifier.client = &http.Client{Timeout: defaultTimeout}
+	now := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	notifier.now = func() time.Time { return now }
+	return notifier, jobHandler, batchHandler, &now
+}
+
+// runDeliveries Delivers the queued callbacks, as the workers do
+func runDeliveries(notifier *Notifier) {
+	for len(notifier.deliveries) > 0 {
+		notifier.deliverQueued(context.Background(), <-notifier.deliveries)
+	}
+}
+
+func getKeys(t *testing.T, notifier *Notifier) []string {
+	keys, err := notifier.store.Keys()
+	assert.NoError(t, err)
+	return keys
+}
+
+func TestNotifier_DeliversSignedJobCallback(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	notifier, jobHandler, _, _ := setupNotifier(ctrl)
+	receiver := newReceiver(t)
+	gomock.InOrder(
+		jobHandler.EXPECT().GetJob("job1").Return(&modelsV1.JobStatus{Name: "job1", Status: "Running"}, nil),
+		jobHandler.EXPECT().GetJob("job1").Return(&modelsV1.JobStatus{Name: "job1", Status: "Succeeded"}, nil),
+	)
+
+	err := notifier.Register(KindJob, "job1", &models.Callback{CallbackURL: receiver.server.URL, CallbackSecret: "secret"})
+	assert.NoError(t, err)
+	request := <-notifier.checks
+	assert.Equal(t, "job/job1", request.key)
+
+	// Nothing is delivered while the job is running
+	notifier.check(request)
+	runDeliveries(notifier)
+	assert.Empty(t, receiver.getReceived())
+	assert.Equal(t, []string{request.key}, getKeys(t, notifier))
+
+	notifier.check(request)
+	runDeliveries(notifier)
+	received := receiver.getReceived()
+	assert.Len(t, received, 1)
+	assert.Equal(t, EventJobCompleted, received[0].header.Get(EventHeader))
+	assert.NotEmpty(t, received[0].header.Get(DeliveryHeader))
+	assert.Equal(t, "application/json", received[0].header.Get("Content-Type"))
+	assert.Equal(t, Sign([]byte("secret"), received[0].payload), received[0].header.Get(SignatureHeader))
+	var payload Payload
+	assert.NoError(t, json.Unmarshal(received[0].payload, &payload))
+	assert.Equal(t, EventJobCompleted, payload.Event)
+	assert.Equal(t, "2022-10-01T12:00:00Z", payload.Timestamp)
+	assert.Equal(t, "Succeeded", payload.Job.Status)
+	assert.Nil(t, payload.Batch)
+	assert.Empty(t, getKeys(t, notifier))
+}
+
+func TestNotifier_DeliversUnsignedBatchCallback(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	notifier, _, batchHandler, _ := setupNotifier(ctrl)
+	receiver := newReceiver(t)
+	batch := modelsV1.BatchStatus{
+		JobStatus:   modelsV1.JobStatus{Name: "batch1", Status: "Failed"},
+		JobStatuses: []modelsV1.JobStatus{{Name: "batch1-job1", Status: "Failed"}},
+	}
+	batchHandler.EXPECT().GetBatch("batch1").Return(&batch, nil)
+
+	assert.NoError(t, notifier.Register(KindBatch, "batch1", &models.Callback{CallbackURL: receiver.server.URL}))
+	notifier.check(<-notifier.checks)
+	runDeliveries(notifier)
+
+	received := receiver.getReceived()
+	assert.Len(t, received, 1)
+	assert.Equal(t, EventBatchCompleted, received[0].header.Get(EventHeader))
+	assert.Empty(t, received[0].header.Get(SignatureHeader))
+	var payload Payload
+	assert.NoError(t, json.Unmarshal(received[0].payload, &payload))
+	assert.Equal(t, EventBatchCompleted, payload.Event)
+	assert.Equal(t, batch, *payload.Batch)
+	assert.Nil(t, payload.Job)
+}
+
+func TestNotifier_RetriesWithBackoff(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	notifier, jobHandler, _, now := setupNotifier(ctrl)
+	notifier.MinBackoff, notifier.MaxBackoff = time.Second, 3*time.Second
+	receiver := newReceiver(t, http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable)
+	jobHandler.EXPECT().GetJob("job1").Return(&modelsV1.JobStatus{Name: "job1", Status: "Failed"}, nil).Times(1)
+
+	assert.NoError(t, notifier.Register(KindJob, "job1", &models.Callback{CallbackURL: receiver.server.URL}))
+	notifier.check(<-notifier.checks)
+	runDeliveries(notifier)
+	assert.Len(t, receiver.getReceived(), 1)
+
+	// Attempts are not repeated before the backoff has passed: 1s, 2s, then capped at 3s
+	for i, backoff := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second} {
+		*now = now.Add(backoff - time.Millisecond)
+		notifier.deliverDue()
+		runDeliveries(notifier)
+		assert.Len(t, receiver.getReceived(), i+1)
+		*now = now.Add(time.Millisecond)
+		notifier.deliverDue()
+		runDeliveries(notifier)
+		assert.Len(t, receiver.getReceived(), i+2)
+	}
+	assert.Empty(t, getKeys(t, notifier))
+
+	// All attempts deliver the same payload with the same delivery ID
+	received := receiver.getReceived()
+	for _, callback := range received[1:] {
+		assert.Equal(t, received[0].header.Get(DeliveryHeader), callback.header.Get(DeliveryHeader))
+		assert.Equal(t, received[0].payload, callback.payload)
+	}
+}
+
+func TestNotifier_QueuesDeliveryOnce(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	notifier, jobHandler, _, _ := setupNotifier(ctrl)
+	receiver := newReceiver(t)
+	jobHandler.EXPECT().GetJob("job1").Return(&modelsV1.JobStatus{Name: "job1", Status: "Succeeded"}, nil).Times(1)
+
+	assert.NoError(t, notifier.Register(KindJob, "job1", &models.Callback{CallbackURL: receiver.server.URL}))
+	notifier.check(<-notifier.checks)
+	notifier.deliverDue()
+
+	// The check does not deliver the callback itself, and the callback is queued once for the workers
+	assert.Empty(t, receiver.getReceived())
+	assert.Len(t, notifier.deliveries, 1)
+	runDeliveries(notifier)
+	assert.Len(t, receiver.getReceived(), 1)
+	assert.Empty(t, getKeys(t, notifier))
+}
+
+func TestNotifier_GivesUpAfterMaxAttempts(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	notifier, jobHandler, _, now := setupNotifier(ctrl)
+	notifier.MaxAttempts = 2
+	receiver := newReceiver(t, http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError)
+	jobHandler.EXPECT().GetJob("job1").Return(&modelsV1.JobStatus{Name: "job1", Status: "Stopped"}, nil).Times(1)
+
+	assert.NoError(t, notifier.Register(KindJob, "job1", &models.Callback{CallbackURL: receiver.server.URL}))
+	notifier.check(<-notifier.checks)
+	runDeliveries(notifier)
+	*now = now.Add(time.Hour)
+	notifier.deliverDue()
+	runDeliveries(notifier)
+	*now = now.Add(time.Hour)
+	notifier.deliverDue()
+	runDeliveries(notifier)
+
+	assert.Len(t, receiver.getReceived(), 2)
+	assert.Empty(t, getKeys(t, notifier))
+}
+
+func TestNotifier_DropsCallbackOfDeletedJob(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	notifier, jobHandler, _, _ := setupNotifier(ctrl)
+	receiver := newReceiver(t)
+	jobHandler.EXPECT().GetJob("job1").Return(nil, apiErrors.NewNotFound("job", "job1")).Times(1)
+
+	assert.NoError(t, notifier.Register(KindJob, "job1", &models.Callback{CallbackURL: receiver.server.URL}))
+	notifier.check(<-notifier.checks)
+	runDeliveries(notifier)
+
+	assert.Empty(t, receiver.getReceived())
+	assert.Empty(t, getKeys(t, notifier))
+}
+
+func TestNotifier_BuildsPayloadOfRemovedJobFromWatchEvent(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	notifier, jobHandler, batchHandler, now := setupNotifier(ctrl)
+	receiver := newReceiver(t)
+	jobHandler.EXPECT().GetJob(gomock.Any()).Return(nil, apiErrors.NewNotFound("job", "job1")).AnyTimes()
+	batchHandler.EXPECT().GetBatch(gomock.Any()).Return(nil, apiErrors.NewNotFound("batch", "batch1")).AnyTimes()
+	created := metav1.NewTime(now.Add(-time.Hour))
+	ended := metav1.NewTime(now.Add(-time.Minute))
+	completedJob := batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "job1", CreationTimestamp: created},
+		Status: batchv1.JobStatus{
+			StartTime:  &created,
+			Conditions: []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue, LastTransitionTime: ended}},
+		},
+	}
+	runningBatch := batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "batch1", CreationTimestamp: created, Labels: map[string]string{kube.RadixBatchNameLabel: "batch1"}},
+	}
+	assert.NoError(t, notifier.Register(KindJob, "job1", &models.Callback{CallbackURL: receiver.server.URL}))
+	assert.NoError(t, notifier.Register(KindBatch, "batch1", &models.Callback{CallbackURL: receiver.server.URL}))
+
+	// A running job which is not found yet is waited for
+	notifier.check(checkRequest{key: "batch/batch1", job: &runningBatch})
+	notifier.check(checkRequest{key: "job/job1", job: &completedJob})
+	notifier.check(checkRequest{key: "batch/batch1", job: &runningBatch, deleted: true})
+	runDeliveries(notifier)
+
+	received := receiver.getReceived()
+	if assert.Len(t, received, 2) {
+		var jobPayload, batchPayload Payload
+		assert.NoError(t, json.Unmarshal(received[0].payload, &jobPayload))
+		assert.Equal(t, modelsV1.JobStatus{Name: "job1", Created: "2022-10-01T11:00:00Z", Started: "2022-10-01T11:00:00Z", Ended: "2022-10-01T11:59:00Z", Status: "Succeeded"}, *jobPayload.Job)
+		assert.NoError(t, json.Unmarshal(received[1].payload, &batchPayload))
+		assert.Equal(t, EventBatchCompleted, batchPayload.Event)
+		assert.Equal(t, modelsV1.JobStatus{Name: "batch1", Created: "2022-10-01T11:00:00Z", Ended: "2022-10-01T12:00:00Z", Status: "Stopped"}, batchPayload.Batch.JobStatus)
+	}
+	assert.Empty(t, getKeys(t, notifier))
+}
+
+func TestNotifier_ReleasesCallbackOfPendingJob(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	notifier, jobHandler, _, _ := setupNotifier(ctrl)
+	receiver := newReceiver(t)
+	jobHandler.EXPECT().GetJob("compute-1").Return(&modelsV1.JobStatus{Name: "compute-1", Status: "Succeeded"}, nil).Times(1)
+
+	// The pending job is not checked until its job is created
+	assert.NoError(t, notifier.RegisterPending(KindJob, "pending-1", &models.Callback{CallbackURL: receiver.server.URL, CallbackSecret: "secret"}))
+	assert.NoError(t, notifier.RegisterPending(KindJob, "pending-2", &models.Callback{CallbackURL: receiver.server.URL}))
+	assert.Empty(t, notifier.checks)
+	notifier.checkAll()
+	for len(notifier.checks) > 0 {
+		notifier.check(<-notifier.checks)
+	}
+
+	assert.NoError(t, notifier.Release(KindJob, "pending-1", "compute-1"))
+	assert.NoError(t, notifier.Unregister(KindJob, "pending-2"))
+	assert.Equal(t, []string{"job/compute-1"}, getKeys(t, notifier))
+	notifier.check(<-notifier.checks)
+	runDeliveries(notifier)
+
+	received := receiver.getReceived()
+	if assert.Len(t, received, 1) {
+		assert.Equal(t, Sign([]byte("secret"), received[0].payload), received[0].header.Get(SignatureHeader))
+	}
+	assert.Empty(t, getKeys(t, notifier))
+}
+
+func TestNotifier_RegisterWithoutURL(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	notifier, _, _, _ := setupNotifier(ctrl)
+
+	assert.NoError(t, notifier.Register(KindJob, "job1", &models.Callback{}))
+	assert.Empty(t, getKeys(t, notifier))
+}
+
+func TestNotifier_DoesNotConnectToInternalAddress(t *testing.T) {
+	receiver := newReceiver(t)
+	notifier := NewNotifier(store.NewMemoryStore(), nil, nil, nil)
+
+	err := notifier.send(context.Background(), &registration{Kind: KindJob, Name: "job1", Callback: models.Callback{CallbackURL: receiver.server.URL}, Payload: []byte("{}")})
+	assert.ErrorContains(t, err, "internal address")
+	assert.Empty(t, receiver.getReceived())
+}
+
+func TestValidateCallback(t *testing.T) {
+	scenarios := []struct {
+		name     string
+		callback models.Callback
+		valid    bool
+	}{
+		{name: "no callback", valid: true},
+		{name: "https URL", callback: models.Callback{CallbackURL: "https://example.com/callback", CallbackSecret: "secret"}, valid: true},
+		{name: "http URL", callback: models.Callback{CallbackURL: "http://receiver.example.com:8080/callback"}, valid: true},
+		{name: "public address", callback: models.Callback{CallbackURL: "https://93.184.216.34/callback"}, valid: true},
+		{name: "host without domain", callback: models.Callback{CallbackURL: "http://receiver:8080/callback"}},
+		{name: "cluster service", callback: models.Callback{CallbackURL: "http://receiver.my-app-dev.svc.cluster.local/callback"}},
+		{name: "localhost", callback: models.Callback{CallbackURL: "http://localhost:8080/callback"}},
+		{name: "loopback address", callback: models.Callback{CallbackURL: "http://127.0.0.1:8080/callback"}},
+		{name: "IPv6 loopback address", callback: models.Callback{CallbackURL: "http://[::1]:8080/callback"}},
+		{name: "link-local address", callback: models.Callback{CallbackURL: "http://169.254.169.254/latest/meta-data"}},
+		{name: "private address", callback: models.Callback{CallbackURL: "http://10.0.0.1/callback"}},
+		{name: "relative URL", callback: models.Callback{CallbackURL: "/callback"}},
+		{name: "other scheme", callback: models.Callback{CallbackURL: "ftp://example.com/callback"}},
+		{name: "malformed URL", callback: models.Callback{CallbackURL: "https://exa mple.com/%zz"}},
+		{name: "secret without URL", callback: models.Callback{CallbackSecret: "secret"}},
+	}
+	for _, scenario := range scenarios {
+		scenario := scenario
+		t.Run(scenario.name, func(t *testing.T) {
+			causes := ValidateCallback(&scenario.callback)
+			if scenario.valid {
+				assert.Empty(t, causes)
+			} else {
+				assert.Len(t, causes, 1)
+			}
+		})
+	}
+}